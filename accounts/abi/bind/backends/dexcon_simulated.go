@@ -0,0 +1,366 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+const (
+	dexconSimulatedNodes       = 4  // Number of nodes staked in the genesis
+	dexconSimulatedRoundLength = 20 // Number of blocks of a round
+)
+
+// DexconSimulatedBackend is a SimulatedBackend whose blocks are generated the
+// way DEXON delivers them. The governance contract is initialised with a set
+// of simulated nodes, which propose the blocks in turn and run the CRS and DKG
+// of the upcoming rounds with governance transactions, so the rounds advance
+// as on a real chain.
+//
+// The randomness of a block is the hash of its number from round 1 on, so the
+// values returned by the RAND opcode are reproducible across test runs.
+type DexconSimulatedBackend struct {
+	*SimulatedBackend
+	generator *dexconGenerator
+}
+
+// NewDexconSimulatedBackend creates a new binding backend using a simulated
+// DEXON blockchain for testing purposes.
+func NewDexconSimulatedBackend(alloc core.GenesisAlloc, gasLimit uint64) *DexconSimulatedBackend {
+	ether := big.NewInt(1e18)
+
+	dexconConfig := *params.TestnetChainConfig.Dexcon
+	dexconConfig.BlockGasLimit = gasLimit
+	dexconConfig.MinGasPrice = big.NewInt(params.GWei)
+	dexconConfig.RoundLength = dexconSimulatedRoundLength
+
+	config := *params.TestnetChainConfig
+	config.ChainID = big.NewInt(1337)
+	config.DMoment = 0
+	config.Dexcon = &dexconConfig
+
+	genesis := core.Genesis{
+		Config:     &config,
+		GasLimit:   gasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      core.GenesisAlloc{},
+	}
+	for addr, account := range alloc {
+		if account.Staked == nil {
+			account.Staked = new(big.Int)
+		}
+		genesis.Alloc[addr] = account
+	}
+	keys := make([]*ecdsa.PrivateKey, dexconSimulatedNodes)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		genesis.Alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{
+			Balance:   new(big.Int).Mul(big.NewInt(2e6), ether),
+			Staked:    new(big.Int).Set(dexconConfig.MinStake),
+			PublicKey: crypto.FromECDSAPub(&keys[i].PublicKey),
+			NodeInfo:  core.NodeInfo{Name: fmt.Sprintf("simulated-%d", i)},
+		}
+	}
+
+	database := ethdb.NewMemDatabase()
+	genesis.MustCommit(database)
+
+	signer := types.NewEIP155Signer(config.ChainID)
+	nodes := dexcon.NewNodeSet(0, []byte(dexconConfig.GenesisCRSText), signer, keys)
+	engine := dexcon.NewFakerWithRandomness(nodes, dexconRandomness)
+	blockchain, _ := core.NewBlockChain(database, nil, &config, engine, vm.Config{}, nil)
+
+	gov := core.NewGovernance(core.NewGovernanceStateDB(blockchain))
+	engine.SetGovStateFetcher(gov)
+
+	generator := &dexconGenerator{
+		database:   database,
+		blockchain: blockchain,
+		config:     &config,
+		engine:     engine,
+		gov:        gov,
+		nodes:      nodes,
+		keys:       keys,
+		dkgKeys:    map[uint64][]*ecdsa.PrivateKey{0: keys},
+	}
+	return &DexconSimulatedBackend{
+		SimulatedBackend: newSimulatedBackend(database, blockchain, generator),
+		generator:        generator,
+	}
+}
+
+// Round returns the round of the latest block.
+func (b *DexconSimulatedBackend) Round() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockchain.CurrentBlock().Round()
+}
+
+// AdvanceRound imports blocks until the next round starts. The pending
+// transactions are imported in the first block.
+func (b *DexconSimulatedBackend) AdvanceRound() error {
+	round := b.Round()
+
+	// The round is extended if the DKG of the next round doesn't complete in
+	// time, give up after a few round lengths.
+	for i := 0; i < 3*dexconSimulatedRoundLength; i++ {
+		b.Commit()
+		if b.Round() > round {
+			return nil
+		}
+	}
+	return fmt.Errorf("round %d not advanced", round)
+}
+
+// RegisterNode sends the transaction registering the node of key, which is
+// also the owner of the node, to the governance contract and staking stake.
+// The node joins the simulated nodes proposing blocks and running the DKGs
+// once it is selected in the notary set of a round.
+func (b *DexconSimulatedBackend) RegisterNode(ctx context.Context, key *ecdsa.PrivateKey, name string, stake *big.Int) error {
+	data, err := vm.GovernanceABI.ABI.Pack("register",
+		crypto.FromECDSAPub(&key.PublicKey), name, "", "", "")
+	if err != nil {
+		return err
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := b.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return err
+	}
+	price, err := b.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := types.SignTx(types.NewTransaction(nonce, vm.GovernanceContractAddress,
+		stake, 1000000, price, data), types.NewEIP155Signer(b.config.ChainID), key)
+	if err != nil {
+		return err
+	}
+	if err := b.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.generator.keys = append(b.generator.keys, key)
+	return nil
+}
+
+// SuggestGasPrice implements ContractTransactor.SuggestGasPrice, returning the
+// minimum gas price of the governance state.
+func (b *DexconSimulatedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.generator.gov.GetHeadState().MinGasPrice(), nil
+}
+
+// dexconRandomness returns the randomness of the simulated block of header.
+// Blocks before DKGDelayRound have no randomness like on a real chain.
+func dexconRandomness(header *types.Header) []byte {
+	if header.Round < dexCore.DKGDelayRound {
+		return dexCore.NoRand
+	}
+	return crypto.Keccak256(common.BigToHash(header.Number).Bytes())
+}
+
+// dexconGenerator generates the blocks of a simulated DEXON chain with the
+// Dexcon faker.
+type dexconGenerator struct {
+	database   ethdb.Database
+	blockchain *core.BlockChain
+	config     *params.ChainConfig
+	engine     *dexcon.FakeDexcon
+	gov        *core.Governance
+	nodes      *dexcon.NodeSet
+
+	keys    []*ecdsa.PrivateKey            // Keys of all simulated nodes
+	dkgKeys map[uint64][]*ecdsa.PrivateKey // Keys of the DKG participants of rounds
+
+	govParent common.Hash        // Parent block of the governance transactions
+	govTxs    types.Transactions // Governance transactions of the next block
+}
+
+func (g *dexconGenerator) generate(parent *types.Block, txs types.Transactions, offset time.Duration) *types.Block {
+	position := g.nextPosition(parent)
+	proposers := g.dkgKeys[position.Round]
+	coinbase := crypto.PubkeyToAddress(
+		proposers[position.Height%uint64(len(proposers))].PublicKey)
+
+	// Governance transactions are deferred to the next block if they don't
+	// fit in the block with the pending transactions.
+	govTxs := g.governanceTxs(parent, position.Round)
+	var gas uint64
+	for _, tx := range govTxs {
+		gas += tx.Gas()
+	}
+	for _, tx := range txs {
+		gas += tx.Gas()
+	}
+	if gas > g.config.Dexcon.BlockGasLimit {
+		govTxs = nil
+	}
+
+	blocks, _ := core.GenerateDexonChain(g.config, parent, g.engine, g.database, 1, func(i int, block *core.DexonBlockGen) {
+		block.SetCoinbase(coinbase)
+		block.SetPosition(position)
+		if offset != 0 {
+			block.OffsetTime(int64(offset / time.Millisecond))
+		}
+		for _, tx := range govTxs {
+			block.AddTx(tx)
+		}
+		for _, tx := range txs {
+			block.AddTx(tx)
+		}
+	})
+	return blocks[0]
+}
+
+func (g *dexconGenerator) insert(block *types.Block) error {
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+		return err
+	}
+	_, err := g.blockchain.ProcessBlock(block, &coreBlock.Witness)
+	return err
+}
+
+// nextPosition returns the position of the block following parent. The next
+// round starts once the current round reaches its length and the DKG and CRS
+// of the next round are ready, the current round is extended otherwise.
+func (g *dexconGenerator) nextPosition(parent *types.Block) coreTypes.Position {
+	round := parent.Round()
+	height := parent.NumberU64() + 1
+
+	roundEnd := g.gov.GetRoundHeight(round) + g.gov.Configuration(round).RoundLength
+	// Round 0 starts at height 1 instead of height 0.
+	if round == 0 {
+		roundEnd++
+	}
+	if height >= roundEnd && g.roundReady(round+1) {
+		round++
+	}
+	return coreTypes.Position{Round: round, Height: height}
+}
+
+func (g *dexconGenerator) roundReady(round uint64) bool {
+	if round > dexCore.DKGDelayRound && g.gov.CRSRound() < round {
+		return false
+	}
+	if _, ok := g.dkgKeys[round]; !ok {
+		return false
+	}
+	return g.gov.IsDKGFinal(round)
+}
+
+// governanceTxs returns the governance transactions the simulated nodes send
+// in the next block of parent in round, which propose the CRS and run the DKG
+// of the next round a step per block.
+func (g *dexconGenerator) governanceTxs(parent *types.Block, round uint64) types.Transactions {
+	// The DKG is run once per parent, the pending block is generated again
+	// for every transaction sent.
+	if parent.Hash() == g.govParent {
+		return g.govTxs
+	}
+	g.govParent = parent.Hash()
+	g.govTxs = nil
+
+	statedb, err := g.blockchain.StateAt(parent.Root())
+	if err != nil {
+		log.Error("Failed to get state", "err", err)
+		return nil
+	}
+	gs := g.gov.GetHeadState()
+	next := round + 1
+
+	addTx := func(node *dexcon.Node, data []byte, err error) {
+		if err != nil {
+			log.Error("Failed to pack governance transaction", "err", err)
+			return
+		}
+		nonce := statedb.GetNonce(node.Address())
+		g.govTxs = append(g.govTxs, node.CreateGovTx(nonce, data))
+	}
+
+	switch {
+	case next > dexCore.DKGDelayRound && gs.CRSRound().Uint64() < next:
+		// CRS of next round is signed with the DKG of current round, it must
+		// be proposed before the DKG of next round starts.
+		signedCRS := g.nodes.TSig(round, common.Hash(g.gov.CRS(round)))
+		data, err := vm.PackProposeCRS(next, signedCRS)
+		addTx(g.nodes.Nodes(round)[0], data, err)
+
+	case gs.DKGRound().Uint64() < next || gs.LenDKGMasterPublicKeys().Sign() == 0:
+		// The DKG participants are the simulated nodes in the notary set.
+		notarySet, err := g.gov.NotarySet(next)
+		if err != nil {
+			log.Error("Failed to get notary set", "round", next, "err", err)
+			return nil
+		}
+		var keys []*ecdsa.PrivateKey
+		for _, key := range g.keys {
+			id := hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))
+			if _, ok := notarySet[id]; ok {
+				keys = append(keys, key)
+			}
+		}
+		threshold := coreUtils.GetDKGThreshold(g.gov.Configuration(next))
+		g.nodes.RunDKGWithKeys(next, threshold, keys)
+		g.dkgKeys[next] = keys
+		for _, node := range g.nodes.Nodes(next) {
+			data, err := vm.PackAddDKGMasterPublicKey(node.MasterPublicKey(next))
+			addTx(node, data, err)
+		}
+
+	case !g.gov.IsDKGMPKReady(next):
+		for _, node := range g.nodes.Nodes(next) {
+			data, err := vm.PackAddDKGMPKReady(node.DKGMPKReady(next))
+			addTx(node, data, err)
+		}
+
+	case !g.gov.IsDKGFinal(next):
+		for _, node := range g.nodes.Nodes(next) {
+			data, err := vm.PackAddDKGFinalize(node.DKGFinalize(next))
+			addTx(node, data, err)
+		}
+	}
+	return g.govTxs
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	dexon "github.com/dexon-foundation/dexon"
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+func TestDexconSimulatedBackend(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	nodeKey, _ := crypto.GenerateKey()
+	nodeAddr := crypto.PubkeyToAddress(nodeKey.PublicKey)
+
+	// The contract returns a random number: RAND PUSH1 0 MSTORE PUSH1 32
+	// PUSH1 0 RETURN.
+	contract := common.Address{0xaa}
+	code := []byte{byte(vm.RAND), byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN)}
+
+	ether := big.NewInt(1e18)
+	sim := NewDexconSimulatedBackend(core.GenesisAlloc{
+		addr:     {Balance: ether},
+		nodeAddr: {Balance: new(big.Int).Mul(big.NewInt(2e6), ether)},
+		contract: {Balance: new(big.Int), Code: code},
+	}, 10000000)
+
+	price, err := sim.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatalf("Suggest gas price fail: %v", err)
+	}
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1),
+		21000, price, nil), types.HomesteadSigner{}, key)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("Send transaction fail: %v", err)
+	}
+	stake := new(big.Int).Mul(big.NewInt(1e6), ether)
+	if err := sim.RegisterNode(ctx, nodeKey, "test", stake); err != nil {
+		t.Fatalf("Register node fail: %v", err)
+	}
+	sim.Commit()
+
+	receipt, _ := sim.TransactionReceipt(ctx, tx.Hash())
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction not executed: %v", receipt)
+	}
+	gs := sim.generator.gov.GetHeadState()
+	if offset := gs.NodesOffsetByAddress(nodeAddr); offset.Sign() < 0 {
+		t.Fatalf("node not registered")
+	}
+
+	// Advance the rounds with the CRS and DKG run by the simulated nodes.
+	for round := uint64(1); round <= 3; round++ {
+		if err := sim.AdvanceRound(); err != nil {
+			t.Fatalf("Advance round fail: %v", err)
+		}
+		header, _ := sim.HeaderByNumber(ctx, nil)
+		if header.Round != round {
+			t.Fatalf("round mismatch: have %d, want %d", header.Round, round)
+		}
+		if sim.Round() != round {
+			t.Errorf("round mismatch: have %d, want %d", sim.Round(), round)
+		}
+		if !bytes.Equal(header.Randomness, dexconRandomness(header)) {
+			t.Errorf("randomness mismatch: have %x, want %x",
+				header.Randomness, dexconRandomness(header))
+		}
+	}
+	gs = sim.generator.gov.GetHeadState()
+	// The CRS of next round is proposed at the beginning of a round.
+	if crsRound := gs.CRSRound().Uint64(); crsRound != 4 {
+		t.Errorf("CRS round mismatch: have %d, want 4", crsRound)
+	}
+	input, _ := vm.GovernanceABI.ABI.Pack("crsRound")
+	output, err := sim.CallContract(ctx, dexon.CallMsg{
+		From: addr, To: &vm.GovernanceContractAddress, Data: input}, nil)
+	if err != nil {
+		t.Fatalf("Call governance contract fail: %v", err)
+	}
+	if crsRound := new(big.Int).SetBytes(output); crsRound.Uint64() != 4 {
+		t.Errorf("CRS round of contract call mismatch: have %d, want 4", crsRound)
+	}
+	if n := len(gs.QualifiedNodes()); n != dexconSimulatedNodes+1 {
+		t.Errorf("qualified node count mismatch: have %d, want %d",
+			n, dexconSimulatedNodes+1)
+	}
+
+	// The RAND opcode uses the randomness of the block, the nonce of the
+	// caller is increased before the call.
+	header, _ := sim.HeaderByNumber(ctx, nil)
+	res, err := sim.CallContract(ctx, dexon.CallMsg{From: addr, To: &contract}, nil)
+	if err != nil {
+		t.Fatalf("Call contract fail: %v", err)
+	}
+	nonce := make([]byte, binary.MaxVarintLen64)
+	binary.PutUvarint(nonce, 2)
+	index := make([]byte, binary.MaxVarintLen64)
+	if want := crypto.Keccak256(header.Randomness, addr.Bytes(), nonce, index); !bytes.Equal(res, want) {
+		t.Errorf("random number mismatch: have %x, want %x", res, want)
+	}
+}
@@ -53,16 +53,53 @@ var errGasEstimationFailed = errors.New("gas required exceeds allowance or alway
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	generator  blockGenerator   // Generator of the blocks of the consensus engine
 
 	mu           sync.Mutex
-	pendingBlock *types.Block   // Currently pending block that will be imported on request
-	pendingState *state.StateDB // Currently pending state that will be the active on on request
+	pendingTxs   types.Transactions // Transactions sent to be included in the pending block
+	pendingBlock *types.Block       // Currently pending block that will be imported on request
+	pendingState *state.StateDB     // Currently pending state that will be the active on on request
 
 	events *filters.EventSystem // Event system for filtering log events live
 
 	config *params.ChainConfig
 }
 
+// blockGenerator generates the pending blocks of a simulated backend and
+// imports them into the chain, in the way of the consensus engine of the chain.
+type blockGenerator interface {
+	// generate creates a block on top of parent including txs, the block time
+	// is shifted by offset.
+	generate(parent *types.Block, txs types.Transactions, offset time.Duration) *types.Block
+
+	// insert imports a block created by generate into the chain.
+	insert(block *types.Block) error
+}
+
+// ethashGenerator generates the blocks of the ethash faker.
+type ethashGenerator struct {
+	database   ethdb.Database
+	blockchain *core.BlockChain
+	config     *params.ChainConfig
+}
+
+func (g *ethashGenerator) generate(parent *types.Block, txs types.Transactions, offset time.Duration) *types.Block {
+	blocks, _ := core.GenerateChain(g.config, parent, ethash.NewFaker(), g.database, 1, func(number int, block *core.BlockGen) {
+		for _, tx := range txs {
+			block.AddTxWithChain(g.blockchain, tx)
+		}
+		if offset != 0 {
+			block.OffsetTime(int64(offset.Seconds()))
+		}
+	})
+	return blocks[0]
+}
+
+func (g *ethashGenerator) insert(block *types.Block) error {
+	_, err := g.blockchain.InsertChain([]*types.Block{block})
+	return err
+}
+
 // NewSimulatedBackend creates a new binding backend using a simulated blockchain
 // for testing purposes.
 func NewSimulatedBackend(alloc core.GenesisAlloc, gasLimit uint64) *SimulatedBackend {
@@ -71,10 +108,15 @@ func NewSimulatedBackend(alloc core.GenesisAlloc, gasLimit uint64) *SimulatedBac
 	genesis.MustCommit(database)
 	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil)
 
+	return newSimulatedBackend(database, blockchain, &ethashGenerator{database, blockchain, genesis.Config})
+}
+
+func newSimulatedBackend(database ethdb.Database, blockchain *core.BlockChain, generator blockGenerator) *SimulatedBackend {
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
-		config:     genesis.Config,
+		generator:  generator,
+		config:     blockchain.Config(),
 		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
 	}
 	backend.rollback()
@@ -87,7 +129,7 @@ func (b *SimulatedBackend) Commit() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
+	if err := b.generator.insert(b.pendingBlock); err != nil {
 		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
 	}
 	b.rollback()
@@ -102,10 +144,15 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	b.pendingTxs = nil
+	b.setPendingBlock(0)
+}
+
+// setPendingBlock regenerates the pending block with the pending transactions.
+func (b *SimulatedBackend) setPendingBlock(offset time.Duration) {
 	statedb, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = b.generator.generate(b.blockchain.CurrentBlock(), b.pendingTxs, offset)
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 }
 
@@ -164,6 +211,22 @@ func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common
 	return receipt, nil
 }
 
+// HeaderByNumber returns a block header from the current canonical chain. If
+// number is nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentHeader(), nil
+	}
+	header := b.blockchain.GetHeaderByNumber(number.Uint64())
+	if header == nil {
+		return nil, errBlockNumberUnsupported
+	}
+	return header, nil
+}
+
 // PendingCodeAt returns the code associated with an account in the pending state.
 func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
 	b.mu.Lock()
@@ -172,19 +235,24 @@ func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Ad
 	return b.pendingState.GetCode(contract), nil
 }
 
-// CallContract executes a contract call.
+// CallContract executes a contract call at the given block, or the latest block
+// if blockNumber is nil.
 func (b *SimulatedBackend) CallContract(ctx context.Context, call dexon.CallMsg, blockNumber *big.Int) ([]byte, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
-		return nil, errBlockNumberUnsupported
+	block := b.blockchain.CurrentBlock()
+	if blockNumber != nil && blockNumber.Cmp(block.Number()) != 0 {
+		block = b.blockchain.GetBlockByNumber(blockNumber.Uint64())
+		if block == nil {
+			return nil, errBlockNumberUnsupported
+		}
 	}
-	state, err := b.blockchain.State()
+	state, err := b.blockchain.StateAt(block.Root())
 	if err != nil {
 		return nil, err
 	}
-	rval, _, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	rval, _, _, err := b.callContract(ctx, call, block, state)
 	return rval, err
 }
 
@@ -297,7 +365,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(types.NewEIP155Signer(b.config.ChainID), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
@@ -306,16 +374,8 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
-		}
-		block.AddTxWithChain(b.blockchain, tx)
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingTxs = append(b.pendingTxs, tx)
+	b.setPendingBlock(0)
 	return nil
 }
 
@@ -391,17 +451,8 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query dexon.
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTx(tx)
-		}
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
-	statedb, _ := b.blockchain.State()
-
-	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
 
+	b.setPendingBlock(adjustment)
 	return nil
 }
 
@@ -95,6 +95,38 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	exportCheckpointCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportCheckpoint),
+		Name:      "export-checkpoint",
+		Usage:     "Export a checkpoint new nodes can bootstrap from",
+		ArgsUsage: "<filename> [<blockNum>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.CheckpointKeyFileFlag,
+			utils.CheckpointKeyPasswordFileFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Requires a first argument of the file to write to. The optional second
+argument is the number of the block, the head block is used if omitted.
+The states of the block and of the last rounds must be available. The
+checkpoint is signed with the --checkpoint.key account if it's set.`,
+	}
+	signCheckpointCommand = cli.Command{
+		Action:    utils.MigrateFlags(signCheckpoint),
+		Name:      "sign-checkpoint",
+		Usage:     "Sign a checkpoint",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.CheckpointKeyFileFlag,
+			utils.CheckpointKeyPasswordFileFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Adds the signature of the --checkpoint.key account to the checkpoint file.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -329,6 +361,63 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// exportCheckpoint exports the checkpoint of a block, signed if the signing
+// key is specified.
+func exportCheckpoint(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+
+	number := chain.CurrentBlock().NumberU64()
+	if len(ctx.Args()) > 1 {
+		n, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		if err != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+		}
+		number = n
+	}
+	cp, err := chain.MakeCheckpoint(number)
+	if err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	if key := utils.MakeCheckpointKey(ctx); key != nil {
+		if err := cp.Sign(key); err != nil {
+			utils.Fatalf("Failed to sign checkpoint: %v", err)
+		}
+	}
+	if err := utils.SaveCheckpoint(cp, ctx.Args().First()); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Exported checkpoint #%d, hash %x\n", number, cp.Hash())
+	return nil
+}
+
+// signCheckpoint adds the signature of the signing key to a checkpoint.
+func signCheckpoint(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	key := utils.MakeCheckpointKey(ctx)
+	if key == nil {
+		utils.Fatalf("No signing key specified.")
+	}
+	fn := ctx.Args().First()
+	cp, err := utils.LoadCheckpoint(fn)
+	if err != nil {
+		utils.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if err := cp.Sign(key); err != nil {
+		utils.Fatalf("Failed to sign checkpoint: %v", err)
+	}
+	if err := utils.SaveCheckpoint(cp, fn); err != nil {
+		utils.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	fmt.Printf("Signed checkpoint #%d, hash %x\n", cp.Block.NumberU64(), cp.Hash())
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
@@ -0,0 +1,92 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/dexon-foundation/dexon/cmd/utils"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	debugCommand = cli.Command{
+		Name:     "debug",
+		Usage:    "Debug the consensus of the node",
+		Category: "DEBUG COMMANDS",
+		Description: `
+
+Tools for post-mortem debugging of the consensus.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "replay",
+				Usage:     "Replay a consensus journal",
+				Action:    utils.MigrateFlags(debugReplay),
+				ArgsUsage: "<journal>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					configFileFlag,
+					utils.TestnetFlag,
+					utils.TaipeiFlag,
+					utils.YilanFlag,
+					utils.NodeKeyFileFlag,
+					utils.DKGSealKeyFileFlag,
+					utils.DKGSealKeyPasswordFileFlag,
+				},
+				Description: `
+    gdex debug replay <journal>
+
+Runs the consensus core of the node isolated from the network, feeding it the
+consensus messages recorded with --bp.journal at the pace they were received.
+The votes, blocks and DKG messages the core would send are logged instead, to
+reproduce its decision path. <journal> is a journal file or directory.
+
+The replay continues the local chain, so it should run on a copy of the data
+directory of the recording node taken before the journaled messages. Interrupt
+the command to stop it once the journal is replayed.`,
+			},
+		},
+	}
+)
+
+func debugReplay(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	journal, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Invalid journal path: %v", err)
+	}
+
+	// The node replays on its own, it must not talk to the network.
+	ctx.GlobalSet(utils.MaxPeersFlag.Name, "0")
+	ctx.GlobalSet(utils.NoDiscoverFlag.Name, "true")
+	ctx.GlobalSet(utils.ListenPortFlag.Name, "0")
+
+	stack, cfg := makeConfigNode(ctx)
+	cfg.Dex.BlockProposerEnabled = true
+	cfg.Dex.SyncMode = downloader.FullSync
+	cfg.Dex.ConsensusJournal = ""
+	cfg.Dex.ReplayJournal = journal
+	utils.RegisterDexService(stack, &cfg.Dex)
+
+	utils.StartNode(stack)
+	stack.Wait()
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dexon-foundation/dexon/accounts/keystore"
+	"github.com/dexon-foundation/dexon/cmd/utils"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/crypto"
+	dexDB "github.com/dexon-foundation/dexon/dex/db"
+	"github.com/pborman/uuid"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dkgNewSealKeyFlag = cli.StringFlag{
+		Name:  "newsealkey",
+		Usage: "File to write the new DKG seal key to",
+	}
+	dkgNewPasswordFileFlag = cli.StringFlag{
+		Name:  "newpassword",
+		Usage: "Password file to encrypt the new DKG seal key with",
+	}
+
+	dkgCommand = cli.Command{
+		Name:     "dkg",
+		Usage:    "Manage DKG private keys",
+		Category: "DKG COMMANDS",
+		Description: `
+
+Manage the DKG private keys stored in the node database.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "rotate-sealkey",
+				Usage:     "Reseal all DKG private keys with a new seal key",
+				Action:    utils.MigrateFlags(dkgRotateSealKey),
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.DKGSealKeyFileFlag,
+					utils.DKGSealKeyPasswordFileFlag,
+					dkgNewSealKeyFlag,
+					dkgNewPasswordFileFlag,
+				},
+				Description: `
+    gdex dkg rotate-sealkey --newsealkey <keyfile>
+
+Generates a new DKG seal key, writes it encrypted to <keyfile> and reseals all
+DKG private keys stored in the database with it. The current seal key is read
+from --dkg.sealkey, or the node key if it's not given.
+
+The node must be stopped while rotating. After rotation, start the node with
+--dkg.sealkey <keyfile> to use the new key. An interrupted rotation can be
+resumed by running the command again with the same new key file.`,
+			},
+		},
+	}
+)
+
+// dkgRotateSealKey reseals all DKG private keys with a newly generated seal
+// key.
+func dkgRotateSealKey(ctx *cli.Context) error {
+	keyfile := ctx.String(dkgNewSealKeyFlag.Name)
+	if keyfile == "" {
+		utils.Fatalf("Missing new seal key file, use --%s", dkgNewSealKeyFlag.Name)
+	}
+	stack, cfg := makeConfigNode(ctx)
+
+	oldKey := cfg.Dex.DKGSealKey
+	if oldKey == nil {
+		oldKey = cfg.Node.NodeKey()
+	}
+
+	// Reuse the new key if the file already exists, this happens when resuming
+	// an interrupted rotation.
+	password := dkgNewPassword(ctx)
+	var newKey *keystore.Key
+	if keyjson, err := ioutil.ReadFile(keyfile); err == nil {
+		newKey, err = keystore.DecryptKey(keyjson, password)
+		if err != nil {
+			utils.Fatalf("Failed to decrypt new seal key: %v", err)
+		}
+	} else if os.IsNotExist(err) {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			utils.Fatalf("Failed to generate seal key: %v", err)
+		}
+		newKey = &keystore.Key{
+			Id:         uuid.NewRandom(),
+			Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+			PrivateKey: privateKey,
+		}
+		keyjson, err := keystore.EncryptKey(newKey, password,
+			keystore.StandardScryptN, keystore.StandardScryptP)
+		if err != nil {
+			utils.Fatalf("Failed to encrypt seal key: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(keyfile), 0700); err != nil {
+			utils.Fatalf("Failed to create directory for seal key file: %v", err)
+		}
+		if err := ioutil.WriteFile(keyfile, keyjson, 0600); err != nil {
+			utils.Fatalf("Failed to write seal key file: %v", err)
+		}
+	} else {
+		utils.Fatalf("Failed to read new seal key file: %v", err)
+	}
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	var maxRound uint64
+	if protocol := rawdb.ReadCoreDKGProtocol(chainDb); protocol != nil {
+		maxRound = protocol.Round + 1
+	}
+	db := dexDB.NewSealedDatabase(chainDb, dexDB.DeriveDKGSealKey(oldKey))
+	count, err := db.RotateDKGSealKey(
+		dexDB.DeriveDKGSealKey(newKey.PrivateKey), maxRound)
+	if err != nil {
+		utils.Fatalf("Failed to rotate seal key: %v", err)
+	}
+	fmt.Printf("Resealed %d DKG private keys\n", count)
+	fmt.Printf("Start the node with --%s %s to use the new seal key\n",
+		utils.DKGSealKeyFileFlag.Name, keyfile)
+	return nil
+}
+
+// dkgNewPassword reads the password for the new seal key from the file given
+// by --newpassword, or prompts the user for it.
+func dkgNewPassword(ctx *cli.Context) string {
+	if path := ctx.String(dkgNewPasswordFileFlag.Name); path != "" {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to read password file: %v", err)
+		}
+		return strings.TrimRight(strings.Split(string(text), "\n")[0], "\r")
+	}
+	return getPassPhrase("Please give a password for the new DKG seal key.",
+		true, 0, nil)
+}
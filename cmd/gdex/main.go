@@ -34,6 +34,7 @@ import (
 	"github.com/dexon-foundation/dexon/dex"
 	"github.com/dexon-foundation/dexon/eth"
 	"github.com/dexon-foundation/dexon/ethclient"
+	_ "github.com/dexon-foundation/dexon/indexer/fileindexer"
 	"github.com/dexon-foundation/dexon/internal/debug"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/metrics"
@@ -89,6 +90,9 @@ var (
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.WhitelistFlag,
+		utils.CheckpointFlag,
+		utils.CheckpointSignersFlag,
+		utils.CheckpointThresholdFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -98,6 +102,12 @@ var (
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.BlockProposerEnabledFlag,
+		utils.BlockProposerTxsPerSenderFlag,
+		utils.BlockProposerJournalFlag,
+		utils.DKGSealKeyFileFlag,
+		utils.DKGSealKeyPasswordFileFlag,
+		utils.ForkReporterKeyFileFlag,
+		utils.ForkReporterKeyPasswordFileFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerLegacyThreadsFlag,
@@ -117,6 +127,8 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.SentriesFlag,
+		utils.SentryForFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DeveloperFlag,
@@ -138,9 +150,11 @@ var (
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
 		utils.IndexerEnableFlag,
+		utils.IndexerNameFlag,
 		utils.IndexerPluginFlag,
 		utils.IndexerPluginFlagsFlag,
 		utils.RecoveryNetworkRPCFlag,
+		utils.RecoveryVoteDirFlag,
 		configFileFlag,
 	}
 
@@ -186,6 +200,8 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		exportCheckpointCommand,
+		signCheckpointCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		copydbCommand,
@@ -208,6 +224,10 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See dkgcmd.go
+		dkgCommand,
+		// See debugcmd.go
+		debugCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -339,7 +359,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		}
 	}()
 	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) {
 		// Mining only makes sense if a full Ethereum node is running
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support mining")
@@ -364,7 +384,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		}
 	}
 
-	if ctx.GlobalBool(utils.BlockProposerEnabledFlag.Name) {
+	if ctx.GlobalBool(utils.BlockProposerEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support proposing")
 		}
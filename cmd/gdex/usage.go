@@ -83,6 +83,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.CheckpointFlag,
+			utils.CheckpointSignersFlag,
+			utils.CheckpointThresholdFlag,
 		},
 	},
 	{
@@ -181,6 +184,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.SentriesFlag,
+			utils.SentryForFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -189,6 +194,12 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "BLOCK PROPOSER",
 		Flags: []cli.Flag{
 			utils.BlockProposerEnabledFlag,
+			utils.BlockProposerTxsPerSenderFlag,
+			utils.BlockProposerJournalFlag,
+			utils.DKGSealKeyFileFlag,
+			utils.DKGSealKeyPasswordFileFlag,
+			utils.ForkReporterKeyFileFlag,
+			utils.ForkReporterKeyPasswordFileFlag,
 		},
 	},
 	{
@@ -244,6 +255,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "INDEXER",
 		Flags: []cli.Flag{
 			utils.IndexerEnableFlag,
+			utils.IndexerNameFlag,
 			utils.IndexerPluginFlag,
 			utils.IndexerPluginFlagsFlag,
 		},
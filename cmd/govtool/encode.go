@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/dexon-foundation/dexon/accounts/abi"
+	"github.com/dexon-foundation/dexon/cmd/utils"
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/common/math"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// encodeMethods are the governance methods sent by node owners and the
+// governance owner. Methods sent by the node itself during DKG are excluded.
+var encodeMethods = []struct {
+	name    string
+	payable bool
+}{
+	{"register", true},
+	{"stake", true},
+	{"unstake", false},
+	{"withdraw", false},
+	{"delegate", true},
+	{"undelegate", false},
+	{"withdrawDelegation", false},
+	{"setCommissionRate", false},
+	{"claimRewards", false},
+	{"payFine", true},
+	{"transferOwnership", false},
+	{"transferNodeOwnership", false},
+	{"transferNodeOwnershipByFoundation", false},
+	{"replaceNodePublicKey", false},
+}
+
+// updateConfigurationFields maps the updateConfiguration flags to fields of
+// DexconConfig, in the order of the method inputs.
+var updateConfigurationFields = []struct {
+	flag string
+	set  func(*params.DexconConfig, string) error
+}{
+	{"minstake",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.MinStake, err = parseBig(s)
+			return
+		}},
+	{"lockupperiod",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.LockupPeriod, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"mingasprice",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.MinGasPrice, err = parseBig(s)
+			return
+		}},
+	{"blockgaslimit",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.BlockGasLimit, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"lambdaba",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.LambdaBA, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"lambdadkg",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.LambdaDKG, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"notaryparamalpha",
+		func(c *params.DexconConfig, s string) error {
+			v, err := strconv.ParseFloat(s, 32)
+			c.NotaryParamAlpha = float32(v)
+			return err
+		}},
+	{"notaryparambeta",
+		func(c *params.DexconConfig, s string) error {
+			v, err := strconv.ParseFloat(s, 32)
+			c.NotaryParamBeta = float32(v)
+			return err
+		}},
+	{"roundlength",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.RoundLength, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"minblockinterval",
+		func(c *params.DexconConfig, s string) (err error) {
+			c.MinBlockInterval, err = strconv.ParseUint(s, 0, 64)
+			return
+		}},
+	{"finevalues",
+		func(c *params.DexconConfig, s string) error {
+			c.FineValues = nil
+			for _, v := range strings.Split(s, ",") {
+				value, err := parseBig(strings.TrimSpace(v))
+				if err != nil {
+					return err
+				}
+				c.FineValues = append(c.FineValues, value)
+			}
+			return nil
+		}},
+}
+
+var commandEncode = cli.Command{
+	Name:        "encode",
+	Usage:       "encode governance tx input",
+	Description: `encode governance tx input of a method from flags, the output can be used as the data of a transaction to the governance contract`,
+	Subcommands: encodeSubcommands(),
+}
+
+func encodeSubcommands() []cli.Command {
+	var commands []cli.Command
+	for _, m := range encodeMethods {
+		method := vm.GovernanceABI.Name2Method[m.name]
+		var flags []cli.Flag
+		for _, input := range method.Inputs {
+			flags = append(flags, cli.StringFlag{
+				Name:  strings.ToLower(input.Name),
+				Usage: fmt.Sprintf("%s (%s)", input.Name, input.Type),
+			})
+		}
+		usage := fmt.Sprintf("encode %s", method.Sig())
+		if m.payable {
+			usage += ", value is sent with the tx"
+		}
+		commands = append(commands, cli.Command{
+			Name:   m.name,
+			Usage:  usage,
+			Flags:  flags,
+			Action: encodeAction(method),
+		})
+	}
+
+	flags := []cli.Flag{chainDataFlag, blockFlag}
+	for _, field := range updateConfigurationFields {
+		flags = append(flags, cli.StringFlag{Name: field.flag})
+	}
+	commands = append(commands, cli.Command{
+		Name:  "updateConfiguration",
+		Usage: "encode updateConfiguration",
+		Flags: flags,
+		Description: `encode updateConfiguration, unset fields are taken from the configuration
+at --block of --chaindata if given, otherwise all fields are required`,
+		Action: encodeUpdateConfiguration,
+	})
+	return commands
+}
+
+func parseBig(s string) (*big.Int, error) {
+	v, ok := math.ParseBig256(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return v, nil
+}
+
+// parseArgument parses the flag value s into the Go type of t.
+func parseArgument(t abi.Type, s string) (interface{}, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.UintTy:
+		return parseBig(s)
+	case abi.BytesTy:
+		return hexutil.Decode(s)
+	case abi.StringTy:
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// packInput packs the input of method from the flag values.
+func packInput(method abi.Method, values map[string]string) ([]byte, error) {
+	args := make([]interface{}, len(method.Inputs))
+	for i, input := range method.Inputs {
+		name := strings.ToLower(input.Name)
+		s, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("missing --%s", name)
+		}
+		arg, err := parseArgument(input.Type, s)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", name, err)
+		}
+		args[i] = arg
+	}
+	return vm.GovernanceABI.ABI.Pack(method.Name, args...)
+}
+
+func encodeAction(method abi.Method) func(*cli.Context) error {
+	return func(ctx *cli.Context) error {
+		values := make(map[string]string)
+		for _, input := range method.Inputs {
+			name := strings.ToLower(input.Name)
+			if ctx.IsSet(name) {
+				values[name] = ctx.String(name)
+			}
+		}
+		data, err := packInput(method, values)
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+		fmt.Println(hexutil.Encode(data))
+		return nil
+	}
+}
+
+// updateConfiguration applies the flag values on top of base and packs the
+// updateConfiguration input. base may be nil if all fields are given.
+func updateConfiguration(base *params.DexconConfig, values map[string]string) ([]byte, error) {
+	cfg := &params.DexconConfig{}
+	if base != nil {
+		*cfg = *base
+	}
+	for _, field := range updateConfigurationFields {
+		s, ok := values[field.flag]
+		if !ok {
+			if base == nil {
+				return nil, fmt.Errorf("missing --%s", field.flag)
+			}
+			continue
+		}
+		if err := field.set(cfg, s); err != nil {
+			return nil, fmt.Errorf("--%s: %v", field.flag, err)
+		}
+	}
+	return vm.PackUpdateConfiguration(cfg)
+}
+
+func encodeUpdateConfiguration(ctx *cli.Context) error {
+	var base *params.DexconConfig
+	if path := ctx.String(chainDataFlag.Name); path != "" {
+		db, err := ethdb.NewLDBDatabase(path, 16, 16)
+		if err != nil {
+			utils.Fatalf("failed to open database: %v", err)
+		}
+		i, err := newInspector(db, ctx.Int64(blockFlag.Name), -1)
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+		base = i.state.Configuration()
+		db.Close()
+	}
+
+	values := make(map[string]string)
+	for _, field := range updateConfigurationFields {
+		if ctx.IsSet(field.flag) {
+			values[field.flag] = ctx.String(field.flag)
+		}
+	}
+	data, err := updateConfiguration(base, values)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	fmt.Println(hexutil.Encode(data))
+	return nil
+}
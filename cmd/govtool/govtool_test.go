@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestPackInput(t *testing.T) {
+	method := vm.GovernanceABI.Name2Method["undelegate"]
+	addr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	data, err := packInput(method, map[string]string{
+		"nodeaddress": addr.Hex(),
+		"amount":      "0x10",
+	})
+	if err != nil {
+		t.Fatalf("failed to pack input: %v", err)
+	}
+	args := struct {
+		NodeAddress common.Address
+		Amount      *big.Int
+	}{}
+	if err := method.Inputs.Unpack(&args, data[4:]); err != nil {
+		t.Fatalf("failed to unpack input: %v", err)
+	}
+	if args.NodeAddress != addr || args.Amount.Cmp(big.NewInt(16)) != 0 {
+		t.Errorf("input mismatch: %+v", args)
+	}
+
+	if _, err := packInput(method, map[string]string{"amount": "1"}); err == nil {
+		t.Errorf("expect error for missing argument")
+	}
+	if _, err := packInput(method, map[string]string{
+		"nodeaddress": "0x11",
+		"amount":      "1",
+	}); err == nil {
+		t.Errorf("expect error for invalid address")
+	}
+}
+
+func TestUpdateConfiguration(t *testing.T) {
+	base := params.TestnetChainConfig.Dexcon
+	data, err := updateConfiguration(base, map[string]string{
+		"roundlength":      "1234",
+		"notaryparamalpha": "0.5",
+		"finevalues":       "1, 2,3",
+	})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	cfg := *base
+	cfg.RoundLength = 1234
+	cfg.NotaryParamAlpha = 0.5
+	cfg.FineValues = []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	expected, err := vm.PackUpdateConfiguration(&cfg)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("input mismatch")
+	}
+	if base.RoundLength == 1234 {
+		t.Errorf("base configuration is modified")
+	}
+
+	if _, err := updateConfiguration(nil, map[string]string{"minstake": "1"}); err == nil {
+		t.Errorf("expect error for missing fields without base")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	genesis := core.DefaultTestnetGenesisBlock()
+	genesis.MustCommit(db)
+
+	i, err := newInspector(db, -1, -1)
+	if err != nil {
+		t.Fatalf("failed to create inspector: %v", err)
+	}
+	if _, err := newInspector(db, 1, -1); err == nil {
+		t.Errorf("expect error for missing block")
+	}
+
+	var buf bytes.Buffer
+	if err := printNodes(&buf, i); err != nil {
+		t.Fatalf("failed to print nodes: %v", err)
+	}
+	// Summary and table header lines plus one line per node.
+	nodes := len(i.state.Nodes())
+	if nodes == 0 {
+		t.Fatalf("no nodes in genesis")
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != nodes+6 {
+		t.Errorf("line count mismatch: have %d, want %d", lines, nodes+6)
+	}
+
+	buf.Reset()
+	if err := printNotarySet(&buf, i); err != nil {
+		t.Fatalf("failed to print notary set: %v", err)
+	}
+	buf.Reset()
+	if err := printCRS(&buf, i); err != nil {
+		t.Fatalf("failed to print crs: %v", err)
+	}
+	if !strings.Contains(buf.String(), common.Hash(i.gov.CRS(0)).Hex()) {
+		t.Errorf("crs of round 0 is not printed")
+	}
+	buf.Reset()
+	if err := printProposers(&buf, i); err != nil {
+		t.Fatalf("failed to print proposers: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Round: 0, Blocks: 0, Empty blocks: 0\n") {
+		t.Errorf("proposer summary mismatch: %s", buf.String())
+	}
+	buf.Reset()
+	if err := printConfig(&buf, i); err != nil {
+		t.Fatalf("failed to print config: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"roundLength"`) {
+		t.Errorf("configuration is not printed")
+	}
+}
+
+func TestInspectConfigUpdated(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	genesis := core.DefaultTestnetGenesisBlock().MustCommit(db)
+
+	// Update the configuration at block 1, which is still in round 0.
+	statedb, err := state.New(genesis.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	gs := &vm.GovernanceState{StateDB: statedb}
+	cfg := gs.Configuration()
+	roundLength := cfg.RoundLength
+	cfg.RoundLength = roundLength + 1
+	gs.UpdateConfiguration(cfg)
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Root:       root,
+	}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), 1)
+	rawdb.WriteHeadBlockHash(db, header.Hash())
+
+	i, err := newInspector(db, -1, -1)
+	if err != nil {
+		t.Fatalf("failed to create inspector: %v", err)
+	}
+	if i.state.Configuration().RoundLength != roundLength+1 {
+		t.Fatalf("configuration is not updated")
+	}
+	var buf bytes.Buffer
+	if err := printConfig(&buf, i); err != nil {
+		t.Fatalf("failed to print config: %v", err)
+	}
+	want := fmt.Sprintf(`"roundLength": %d,`, roundLength)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("configuration not in effect is printed: %s", buf.String())
+	}
+}
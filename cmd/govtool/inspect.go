@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dexon-foundation/dexon/cmd/utils"
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	chainDataFlag = cli.StringFlag{
+		Name:  "chaindata",
+		Usage: "Path to the chaindata directory",
+	}
+	blockFlag = cli.Int64Flag{
+		Name:  "block",
+		Usage: "Block number to inspect (default = head block)",
+		Value: -1,
+	}
+	roundFlag = cli.Int64Flag{
+		Name:  "round",
+		Usage: "Round to inspect (default = round of the inspected block)",
+		Value: -1,
+	}
+
+	inspectFlags = []cli.Flag{chainDataFlag, blockFlag}
+
+	commandNodes = cli.Command{
+		Name:        "nodes",
+		Usage:       "list registered nodes",
+		Flags:       inspectFlags,
+		Description: `list registered nodes with their stake, fine and unstake status`,
+		Action:      inspectAction(printNodes),
+	}
+	commandNotarySet = cli.Command{
+		Name:        "notaryset",
+		Usage:       "print notary set of a round",
+		Flags:       append(inspectFlags, roundFlag),
+		Description: `print notary set of a round`,
+		Action:      inspectAction(printNotarySet),
+	}
+	commandDKG = cli.Command{
+		Name:        "dkg",
+		Usage:       "print DKG status of a round",
+		Flags:       append(inspectFlags, roundFlag),
+		Description: `print DKG master public keys, complaints, finalize and success status of a round`,
+		Action:      inspectAction(printDKG),
+	}
+	commandCRS = cli.Command{
+		Name:        "crs",
+		Usage:       "print CRS history",
+		Flags:       inspectFlags,
+		Description: `print CRS of all rounds up to the latest CRS round`,
+		Action:      inspectAction(printCRS),
+	}
+	commandProposers = cli.Command{
+		Name:        "proposers",
+		Usage:       "print proposer statistics of a round",
+		Flags:       append(inspectFlags, roundFlag),
+		Description: `print the blocks proposed, gas used and reward of the notary set nodes in a round, the nodes without blocks are disqualified at the next round`,
+		Action:      inspectAction(printProposers),
+	}
+	commandConfig = cli.Command{
+		Name:        "config",
+		Usage:       "print governance configuration",
+		Flags:       append(inspectFlags, roundFlag),
+		Description: `print the DexconConfig in effect at the round of the block, or at the round if given`,
+		Action:      inspectAction(printConfig),
+	}
+)
+
+// chainStateDB implements core.GovernanceStateDB on a chain database, the
+// head is the inspected block.
+type chainStateDB struct {
+	db      ethdb.Database
+	stateDB state.Database
+	head    *types.Header
+}
+
+func (c *chainStateDB) State() (*state.StateDB, error) {
+	return state.New(c.head.Root, c.stateDB)
+}
+
+func (c *chainStateDB) StateAt(height uint64) (*state.StateDB, error) {
+	if height > c.head.Number.Uint64() {
+		return nil, fmt.Errorf("height %d is after inspected block %d",
+			height, c.head.Number.Uint64())
+	}
+	header := rawdb.ReadHeader(c.db, rawdb.ReadCanonicalHash(c.db, height), height)
+	if header == nil {
+		return nil, fmt.Errorf("header at %d not exists", height)
+	}
+	return state.New(header.Root, c.stateDB)
+}
+
+// inspector holds the governance views of the inspected block.
+type inspector struct {
+	db     ethdb.Database
+	header *types.Header
+	state  *vm.GovernanceState
+	gov    *core.Governance
+	round  uint64
+}
+
+func newInspector(db ethdb.Database, number int64, round int64) (*inspector, error) {
+	var hash common.Hash
+	if number < 0 {
+		hash = rawdb.ReadHeadBlockHash(db)
+		n := rawdb.ReadHeaderNumber(db, hash)
+		if n == nil {
+			return nil, fmt.Errorf("head block not found")
+		}
+		number = int64(*n)
+	} else {
+		hash = rawdb.ReadCanonicalHash(db, uint64(number))
+	}
+	header := rawdb.ReadHeader(db, hash, uint64(number))
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+
+	stateDB := &chainStateDB{db: db, stateDB: state.NewDatabase(db), head: header}
+	s, err := stateDB.State()
+	if err != nil {
+		return nil, fmt.Errorf("state of block %d not available: %v", number, err)
+	}
+	i := &inspector{
+		db:     db,
+		header: header,
+		state:  &vm.GovernanceState{StateDB: s},
+		gov:    core.NewGovernance(stateDB),
+		round:  header.Round,
+	}
+	if round >= 0 {
+		i.round = uint64(round)
+	}
+	return i, nil
+}
+
+// inspectAction opens the chain database and runs fn on the inspected block.
+func inspectAction(fn func(io.Writer, *inspector) error) func(*cli.Context) error {
+	return func(ctx *cli.Context) (err error) {
+		path := ctx.String(chainDataFlag.Name)
+		if path == "" {
+			utils.Fatalf("chaindata directory is not specified")
+		}
+		db, err := ethdb.NewLDBDatabase(path, 16, 16)
+		if err != nil {
+			utils.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		round := int64(-1)
+		if ctx.IsSet(roundFlag.Name) {
+			round = ctx.Int64(roundFlag.Name)
+		}
+		i, err := newInspector(db, ctx.Int64(blockFlag.Name), round)
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+
+		// Governance helpers panic if the state is not available.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		return fn(os.Stdout, i)
+	}
+}
+
+func nodeKeyAddress(publicKey []byte) common.Address {
+	pub, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+func printNodes(w io.Writer, i *inspector) error {
+	fmt.Fprintf(w, "Block: %d, Round: %d\n", i.header.Number, i.header.Round)
+	fmt.Fprintf(w, "Total supply: %v\n", i.state.TotalSupply())
+	fmt.Fprintf(w, "Total staked: %v\n", i.state.TotalStaked())
+	fmt.Fprintf(w, "Min stake: %v\n\n", i.state.MinStake())
+
+	qualified := make(map[common.Address]struct{})
+	for _, n := range i.state.QualifiedNodes() {
+		qualified[n.Owner] = struct{}{}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OWNER\tNODE KEY ADDRESS\tNAME\tSTAKED\tFINED\tUNSTAKED\tUNSTAKED AT\tDELEGATED\tQUALIFIED")
+	for _, n := range i.state.Nodes() {
+		_, ok := qualified[n.Owner]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%v\t%v\t%v\t%v\t%t\n",
+			n.Owner.Hex(), nodeKeyAddress(n.PublicKey).Hex(), n.Name,
+			n.Staked, n.Fined, n.Unstaked, n.UnstakedAt,
+			i.state.TotalDelegated(n.Owner), ok)
+	}
+	return tw.Flush()
+}
+
+func printNotarySet(w io.Writer, i *inspector) error {
+	notarySet, err := i.gov.NotarySet(i.round)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Round: %d, Notary set size: %d\n\n", i.round, len(notarySet))
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OWNER\tNODE KEY ADDRESS\tNAME")
+	for _, n := range i.state.Nodes() {
+		if _, ok := notarySet[hex.EncodeToString(n.PublicKey)]; !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n",
+			n.Owner.Hex(), nodeKeyAddress(n.PublicKey).Hex(), n.Name)
+	}
+	return tw.Flush()
+}
+
+func printDKG(w io.Writer, i *inspector) error {
+	dkgRound := i.state.DKGRound().Uint64()
+	fmt.Fprintf(w, "Round: %d, DKG round: %d, Reset count: %d\n",
+		i.round, dkgRound, i.gov.DKGResetCount(i.round))
+	if i.round > dkgRound {
+		fmt.Fprintln(w, "DKG of the round is not started")
+		return nil
+	}
+	fmt.Fprintf(w, "MPK ready: %t, Final: %t, Success: %t\n",
+		i.gov.IsDKGMPKReady(i.round), i.gov.IsDKGFinal(i.round),
+		i.gov.IsDKGSuccess(i.round))
+
+	mpks := i.gov.DKGMasterPublicKeys(i.round)
+	complaints := i.gov.DKGComplaints(i.round)
+	fmt.Fprintf(w, "Master public keys: %d, Complaints: %d\n\n",
+		len(mpks), len(complaints))
+
+	// Per node status is only kept for the DKG round in the head state.
+	s := i.state
+	if i.round != dkgRound {
+		s = i.gov.GetStateAtRound(i.round)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROPOSER\tNODE KEY ADDRESS\tMPK READY\tFINALIZED\tSUCCESS")
+	for _, mpk := range mpks {
+		addr := vm.IdToAddress(mpk.ProposerID)
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%t\t%t\n",
+			mpk.ProposerID, addr.Hex(), s.DKGMPKReady(addr),
+			s.DKGFinalized(addr), s.DKGSuccess(addr))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(complaints) > 0 {
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROPOSER\tACCUSED\tNACK")
+		for _, c := range complaints {
+			fmt.Fprintf(tw, "%s\t%s\t%t\n", c.ProposerID,
+				c.PrivateShare.ProposerID, c.IsNack())
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+func printCRS(w io.Writer, i *inspector) error {
+	crsRound := i.state.CRSRound().Uint64()
+	fmt.Fprintf(w, "CRS round: %d\n\n", crsRound)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUND\tHEIGHT\tCRS")
+	for round := uint64(0); round <= crsRound; round++ {
+		height := i.state.RoundHeight(new(big.Int).SetUint64(round))
+		fmt.Fprintf(tw, "%d\t%v\t%s\n", round, height,
+			common.Hash(i.gov.CRS(round)).Hex())
+	}
+	return tw.Flush()
+}
+
+func printProposers(w io.Writer, i *inspector) error {
+	if i.round > i.header.Round {
+		return fmt.Errorf("round %d is after inspected block", i.round)
+	}
+	// The statistics of a round are indexed by the node once it's finalised.
+	stats := rawdb.ReadRoundStats(i.db, i.round)
+	if stats == nil {
+		from := i.gov.GetRoundHeight(i.round)
+		to := i.header.Number.Uint64()
+		if i.round < i.header.Round {
+			to = i.gov.GetRoundHeight(i.round+1) - 1
+		}
+		var err error
+		stats, err = core.ComputeRoundStats(i.db, i.round, from, to)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "Round: %d, Blocks: %d, Empty blocks: %d\n\n",
+		i.round, stats.Blocks, stats.EmptyBlocks)
+
+	notarySet, err := i.gov.NotarySet(i.round)
+	if err != nil {
+		return err
+	}
+	proposers := make(map[common.Address]*rawdb.ProposerStats)
+	for _, proposer := range stats.Proposers {
+		proposers[proposer.Proposer] = proposer
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "OWNER\tNODE KEY ADDRESS\tNAME\tBLOCKS\tGAS USED\tREWARD\tPROPOSED")
+	for _, n := range i.state.Nodes() {
+		if _, ok := notarySet[hex.EncodeToString(n.PublicKey)]; !ok {
+			continue
+		}
+		addr := nodeKeyAddress(n.PublicKey)
+		proposer, ok := proposers[addr]
+		if !ok {
+			proposer = &rawdb.ProposerStats{Proposer: addr, Reward: new(big.Int)}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%v\t%t\n",
+			n.Owner.Hex(), addr.Hex(), n.Name, proposer.Blocks,
+			proposer.GasUsed, proposer.Reward, proposer.Blocks > 0)
+	}
+	return tw.Flush()
+}
+
+func printConfig(w io.Writer, i *inspector) error {
+	// The configuration in the head state takes effect ConfigRoundShift
+	// rounds later.
+	s := i.gov.GetStateForConfigAtRound(i.round)
+	data, err := json.MarshalIndent(s.Configuration(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Round: %d\n%s\n", i.round, data)
+	return nil
+}
@@ -23,6 +23,13 @@ func init() {
 	app = utils.NewApp(gitCommit, "DEXON governance tool")
 	app.Commands = []cli.Command{
 		commandDecodeInput,
+		commandEncode,
+		commandNodes,
+		commandNotarySet,
+		commandDKG,
+		commandCRS,
+		commandConfig,
+		commandProposers,
 	}
 }
 
@@ -310,3 +310,41 @@ func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// LoadCheckpoint loads a checkpoint from the specified file.
+func LoadCheckpoint(fn string) (*core.Checkpoint, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	cp := new(core.Checkpoint)
+	if err := rlp.Decode(reader, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// SaveCheckpoint saves a checkpoint into the specified file, truncating any
+// data already present in the file.
+func SaveCheckpoint(cp *core.Checkpoint, fn string) error {
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	return rlp.Encode(writer, cp)
+}
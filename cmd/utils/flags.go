@@ -42,11 +42,11 @@ import (
 	"github.com/dexon-foundation/dexon/dashboard"
 	"github.com/dexon-foundation/dexon/dex"
 	"github.com/dexon-foundation/dexon/dex/downloader"
+	dexles "github.com/dexon-foundation/dexon/dex/les"
 	"github.com/dexon-foundation/dexon/eth"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/ethstats"
-	"github.com/dexon-foundation/dexon/les"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/metrics"
 	"github.com/dexon-foundation/dexon/metrics/influxdb"
@@ -151,11 +151,11 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral single node network with a pre-funded developer account, block proposing enabled",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
-		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
+		Usage: "Block period in seconds to use in developer mode (0 = propose only if transaction pending)",
 	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
@@ -195,6 +195,26 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Checkpoint file to bootstrap an empty chain from (fast sync only)",
+	}
+	CheckpointSignersFlag = cli.StringFlag{
+		Name:  "checkpoint.signers",
+		Usage: "Comma separated addresses of the accounts trusted to sign checkpoints",
+	}
+	CheckpointThresholdFlag = cli.IntFlag{
+		Name:  "checkpoint.threshold",
+		Usage: "Minimum number of trusted accounts signing a checkpoint (0 = all)",
+	}
+	CheckpointKeyFileFlag = cli.StringFlag{
+		Name:  "checkpoint.key",
+		Usage: "Keystore file of the account signing the checkpoints",
+	}
+	CheckpointKeyPasswordFileFlag = cli.StringFlag{
+		Name:  "checkpoint.key.password",
+		Usage: "Password file to decrypt the checkpoint signing keystore file",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -330,6 +350,30 @@ var (
 		Name:  "bp",
 		Usage: "Enable block proposer mode (node set)",
 	}
+	DKGSealKeyFileFlag = cli.StringFlag{
+		Name:  "dkg.sealkey",
+		Usage: "Keystore file of the key sealing DKG private keys (default = node key)",
+	}
+	DKGSealKeyPasswordFileFlag = cli.StringFlag{
+		Name:  "dkg.sealkey.password",
+		Usage: "Password file to decrypt the DKG seal key keystore file",
+	}
+	ForkReporterKeyFileFlag = cli.StringFlag{
+		Name:  "forkreporter.key",
+		Usage: "Keystore file of the account reporting the observed forks to the governance contract",
+	}
+	ForkReporterKeyPasswordFileFlag = cli.StringFlag{
+		Name:  "forkreporter.key.password",
+		Usage: "Password file to decrypt the fork reporter keystore file",
+	}
+	BlockProposerTxsPerSenderFlag = cli.IntFlag{
+		Name:  "bp.txspersender",
+		Usage: "Maximum number of transactions from a single sender in a block (0 = unlimited)",
+	}
+	BlockProposerJournalFlag = DirectoryFlag{
+		Name:  "bp.journal",
+		Usage: "Directory to record the received consensus messages to for debugging (disabled if empty)",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -561,6 +605,14 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	SentriesFlag = cli.StringFlag{
+		Name:  "sentries",
+		Usage: "Comma separated enode URLs of the sentries relaying the consensus messages (only connects to them)",
+	}
+	SentryForFlag = cli.StringFlag{
+		Name:  "sentry.for",
+		Usage: "Comma separated enode URLs of the validators to relay the consensus messages of as a sentry",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -654,6 +706,11 @@ var (
 		Name:  "indexer",
 		Usage: "Enable indexer",
 	}
+	IndexerNameFlag = cli.StringFlag{
+		Name:  "indexer.name",
+		Usage: "Built-in indexer name (e.g. \"file\"), takes precedence over --indexer.plugin",
+		Value: "",
+	}
 	IndexerPluginFlag = cli.StringFlag{
 		Name:  "indexer.plugin",
 		Usage: "External indexer plugin shared object path",
@@ -668,9 +725,13 @@ var (
 	// Dexcon settings.
 	RecoveryNetworkRPCFlag = cli.StringFlag{
 		Name:  "recovery.network-rpc",
-		Usage: "RPC URL of the recovery network",
+		Usage: "RPC URL of the recovery network, either an Ethereum or a DEXON network",
 		Value: "https://mainnet.infura.io",
 	}
+	RecoveryVoteDirFlag = DirectoryFlag{
+		Name:  "recovery.vote-dir",
+		Usage: "Directory to exchange signed recovery votes among notary nodes instead of using the recovery network",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -933,6 +994,48 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 }
 
+// MakeDKGSealKey loads the key sealing DKG private keys from the keystore file
+// specified by the command line flags, nil is returned if not specified.
+func MakeDKGSealKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	return makeKeystoreKey(ctx, DKGSealKeyFileFlag, DKGSealKeyPasswordFileFlag,
+		"DKG seal key")
+}
+
+// MakeForkReporterKey loads the key reporting the observed forks from the
+// keystore file specified by the command line flags, nil is returned if not
+// specified.
+func MakeForkReporterKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	return makeKeystoreKey(ctx, ForkReporterKeyFileFlag,
+		ForkReporterKeyPasswordFileFlag, "fork reporter key")
+}
+
+// makeKeystoreKey decrypts the keystore file of the file flag with the
+// password in the file of the password flag.
+func makeKeystoreKey(ctx *cli.Context, fileFlag, passwordFlag cli.StringFlag,
+	name string) *ecdsa.PrivateKey {
+	file := ctx.GlobalString(fileFlag.Name)
+	if file == "" {
+		return nil
+	}
+	keyjson, err := ioutil.ReadFile(file)
+	if err != nil {
+		Fatalf("Failed to read %s file: %v", name, err)
+	}
+	var password string
+	if path := ctx.GlobalString(passwordFlag.Name); path != "" {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			Fatalf("Failed to read %s password file: %v", name, err)
+		}
+		password = strings.TrimRight(strings.Split(string(text), "\n")[0], "\r")
+	}
+	key, err := keystore.DecryptKey(keyjson, password)
+	if err != nil {
+		Fatalf("Failed to decrypt %s: %v", name, err)
+	}
+	return key.PrivateKey
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -1149,6 +1252,65 @@ func setWhitelist(ctx *cli.Context, cfg *dex.Config) {
 	}
 }
 
+func setSentries(ctx *cli.Context, cfg *dex.Config) {
+	parse := func(flag cli.StringFlag) []*enode.Node {
+		var nodes []*enode.Node
+		for _, url := range strings.Split(ctx.GlobalString(flag.Name), ",") {
+			if url = strings.TrimSpace(url); url == "" {
+				continue
+			}
+			node, err := enode.ParseV4(url)
+			if err != nil {
+				Fatalf("Option %q: invalid enode %s: %v", flag.Name, url, err)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	if ctx.GlobalIsSet(SentriesFlag.Name) {
+		cfg.Sentries = parse(SentriesFlag)
+	}
+	if ctx.GlobalIsSet(SentryForFlag.Name) {
+		cfg.SentryFor = parse(SentryForFlag)
+	}
+}
+
+func setCheckpoint(ctx *cli.Context, cfg *dex.Config) {
+	file := ctx.GlobalString(CheckpointFlag.Name)
+	if file == "" {
+		return
+	}
+	if cfg.SyncMode != downloader.FastSync {
+		Fatalf("--%s requires --%s=fast", CheckpointFlag.Name, SyncModeFlag.Name)
+	}
+	cp, err := LoadCheckpoint(file)
+	if err != nil {
+		Fatalf("Failed to load checkpoint: %v", err)
+	}
+	cfg.Checkpoint = cp
+
+	if signers := ctx.GlobalString(CheckpointSignersFlag.Name); signers != "" {
+		cfg.CheckpointSigners = nil
+		for _, signer := range strings.Split(signers, ",") {
+			if !common.IsHexAddress(signer) {
+				Fatalf("Invalid checkpoint signer address: %s", signer)
+			}
+			cfg.CheckpointSigners = append(cfg.CheckpointSigners,
+				common.HexToAddress(signer))
+		}
+	}
+	if ctx.GlobalIsSet(CheckpointThresholdFlag.Name) {
+		cfg.CheckpointThreshold = ctx.GlobalInt(CheckpointThresholdFlag.Name)
+	}
+}
+
+// MakeCheckpointKey loads the key signing checkpoints from the keystore file
+// specified by the command line flags, nil is returned if not specified.
+func MakeCheckpointKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	return makeKeystoreKey(ctx, CheckpointKeyFileFlag,
+		CheckpointKeyPasswordFileFlag, "checkpoint key")
+}
+
 // checkExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1213,10 +1375,12 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setWhitelist(ctx, cfg)
+	setSentries(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	setCheckpoint(ctx, cfg)
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
@@ -1229,6 +1393,18 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	if ctx.GlobalIsSet(BlockProposerEnabledFlag.Name) {
 		cfg.BlockProposerEnabled = ctx.GlobalBool(BlockProposerEnabledFlag.Name)
 	}
+	if ctx.GlobalIsSet(DKGSealKeyFileFlag.Name) {
+		cfg.DKGSealKey = MakeDKGSealKey(ctx)
+	}
+	if ctx.GlobalIsSet(ForkReporterKeyFileFlag.Name) {
+		cfg.ForkReporterKey = MakeForkReporterKey(ctx)
+	}
+	if ctx.GlobalIsSet(BlockProposerTxsPerSenderFlag.Name) {
+		cfg.PayloadTxsPerSender = ctx.GlobalInt(BlockProposerTxsPerSenderFlag.Name)
+	}
+	if ctx.GlobalIsSet(BlockProposerJournalFlag.Name) {
+		cfg.ConsensusJournal = ctx.GlobalString(BlockProposerJournalFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1266,6 +1442,9 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	}
 
 	cfg.RecoveryNetworkRPC = ctx.GlobalString(RecoveryNetworkRPCFlag.Name)
+	if ctx.GlobalIsSet(RecoveryVoteDirFlag.Name) {
+		cfg.RecoveryVoteDir = ctx.GlobalString(RecoveryVoteDirFlag.Name)
+	}
 	defaultRecoveryNetworkRPC := "https://rinkeby.infura.io"
 
 	// Override any default configs for hard coded networks.
@@ -1319,7 +1498,12 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 		}
 		log.Info("Using developer account", "address", developer.Address)
 
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		// The genesis is built by the dexon service, since the developer node
+		// is part of the governance state.
+		cfg.Dev = true
+		cfg.DevFaucet = developer.Address
+		cfg.DevPeriod = time.Duration(ctx.GlobalInt(DeveloperPeriodFlag.Name)) * time.Second
+		cfg.BlockProposerEnabled = true
 	}
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
@@ -1336,6 +1520,7 @@ func setIndexerConfig(ctx *cli.Context, cfg *dex.Config) {
 		return
 	}
 
+	cfg.Indexer.Name = ctx.GlobalString(IndexerNameFlag.Name)
 	cfg.Indexer.Plugin = ctx.GlobalString(IndexerPluginFlag.Name)
 	cfg.Indexer.PluginFlags = ctx.GlobalString(IndexerPluginFlagsFlag.Name)
 	// copy required dex configs
@@ -1355,17 +1540,17 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 func RegisterDexService(stack *node.Node, cfg *dex.Config) {
 	var err error
 	if cfg.SyncMode == downloader.LightSync {
-		//err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		//	return les.New(ctx, cfg)
-		//})
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			return dexles.New(ctx, cfg)
+		})
 	} else {
 		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			cfg.PrivateKey = ctx.ServerConfig.PrivateKey
 			fullNode, err := dex.New(ctx, cfg)
-			//if fullNode != nil && cfg.LightServ > 0 {
-			//	ls, _ := les.NewLesServer(fullNode, cfg)
-			//	fullNode.AddLesServer(ls)
-			//}
+			if fullNode != nil && cfg.LightServ > 0 {
+				ls, _ := dexles.NewLesServer(fullNode, cfg)
+				fullNode.AddLesServer(ls)
+			}
 			return fullNode, err
 		})
 	}
@@ -1394,14 +1579,14 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 // the given node.
 func RegisterEthStatsService(stack *node.Node, url string) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		// Retrieve both eth and les services
-		var ethServ *eth.Ethereum
-		ctx.Service(&ethServ)
+		// Retrieve both dex and les services
+		var dexServ *dex.Dexon
+		ctx.Service(&dexServ)
 
-		var lesServ *les.LightEthereum
+		var lesServ *dexles.LightDexon
 		ctx.Service(&lesServ)
 
-		return ethstats.New(url, ethServ, lesServ)
+		return ethstats.New(url, dexServ, lesServ)
 	}); err != nil {
 		Fatalf("Failed to register the Ethereum Stats service: %v", err)
 	}
@@ -17,28 +17,60 @@
 package dexcon
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"runtime"
 
 	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/dexon-foundation/dexon/rpc"
 )
 
+// Various error messages to mark blocks invalid.
+var (
+	errInvalidDexconMeta  = errors.New("invalid dexcon meta")
+	errInvalidTimestamp   = errors.New("timestamp mismatch")
+	errInvalidHeight      = errors.New("height mismatch")
+	errInvalidRound       = errors.New("round mismatch")
+	errInvalidRoundHeight = errors.New("invalid round height")
+	errInvalidRandomness  = errors.New("randomness mismatch")
+	errInvalidCoinbase    = errors.New("coinbase mismatch")
+	errInvalidGasLimit    = errors.New("block gas limit mismatch")
+	errInvalidReward      = errors.New("block reward mismatch")
+	errInvalidParentHash  = errors.New("dexcon meta parent hash mismatch")
+	errInvalidBlockHash   = errors.New("dexcon meta block hash mismatch")
+	errInvalidSignature   = errors.New("invalid threshold signature")
+	errUnclesNotAllowed   = errors.New("uncles not allowed")
+	errDKGNotReady        = errors.New("DKG of round is not ready")
+	errUnknownGovState    = errors.New("governance state of round not found")
+)
+
+// GovernanceStateFetcher provides the governance states and DKG results the
+// Dexcon engine needs to finalize and verify blocks.
 type GovernanceStateFetcher interface {
+	dexCore.TSigVerifierCacheInterface
+
 	GetStateForConfigAtRound(round uint64) *vm.GovernanceState
+	GetRoundHeight(round uint64) uint64
 	DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error)
 }
 
 // Dexcon is a delegated proof-of-stake consensus engine.
 type Dexcon struct {
 	govStateFetcer GovernanceStateFetcher
+	verifierCache  *dexCore.TSigVerifierCache
 }
 
 // New creates a Clique proof-of-authority consensus engine with the initial
@@ -52,6 +84,18 @@ func New() *Dexcon {
 // dex backend.
 func (d *Dexcon) SetGovStateFetcher(fetcher GovernanceStateFetcher) {
 	d.govStateFetcer = fetcher
+	d.verifierCache = dexCore.NewTSigVerifierCache(fetcher, 5)
+}
+
+// WithGovStateFetcher returns a copy of the engine verifying headers with the
+// governance states and DKG results of fetcher, e.g. the ones synced along with
+// the headers by the downloader.
+func (d *Dexcon) WithGovStateFetcher(fetcher GovernanceStateFetcher,
+	verifierCache *dexCore.TSigVerifierCache) *Dexcon {
+	return &Dexcon{
+		govStateFetcer: fetcher,
+		verifierCache:  verifierCache,
+	}
 }
 
 // Author implements consensus.Engine, returning the Ethereum address recovered
@@ -62,6 +106,20 @@ func (d *Dexcon) Author(header *types.Header) (common.Address, error) {
 
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (d *Dexcon) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	// Short circuit if the header is known, or it's parent not
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	if chain.GetHeader(header.ParentHash, number-1) == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := d.verifyHeader(chain, header, nil); err != nil {
+		return err
+	}
+	if seal {
+		return d.VerifySeal(chain, header)
+	}
 	return nil
 }
 
@@ -69,13 +127,81 @@ func (d *Dexcon) VerifyHeader(chain consensus.ChainReader, header *types.Header,
 // method returns a quit channel to abort the operations and a results channel to
 // retrieve the async verifications (the order is that of the input slice).
 func (d *Dexcon) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
-	abort, results := make(chan struct{}), make(chan error)
+	if len(headers) == 0 {
+		return make(chan struct{}), make(chan error)
+	}
+
+	// Spawn as many workers as allowed threads
+	workers := runtime.GOMAXPROCS(0)
+	if len(headers) < workers {
+		workers = len(headers)
+	}
+
+	// Create a task channel and spawn the verifiers
+	var (
+		inputs = make(chan int)
+		done   = make(chan int, workers)
+		errors = make([]error, len(headers))
+		abort  = make(chan struct{})
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				errors[index] = d.verifyHeaderWorker(chain, headers, seals, index)
+				done <- index
+			}
+		}()
+	}
+
+	errorsOut := make(chan error, len(headers))
 	go func() {
-		for range headers {
-			results <- nil
+		defer close(inputs)
+		var (
+			in, out = 0, 0
+			checked = make([]bool, len(headers))
+			inputs  = inputs
+		)
+		for {
+			select {
+			case inputs <- in:
+				if in++; in == len(headers) {
+					// Reached end of headers. Stop sending to workers.
+					inputs = nil
+				}
+			case index := <-done:
+				for checked[index] = true; checked[out]; out++ {
+					errorsOut <- errors[out]
+					if out == len(headers)-1 {
+						return
+					}
+				}
+			case <-abort:
+				return
+			}
 		}
 	}()
-	return abort, results
+	return abort, errorsOut
+}
+
+func (d *Dexcon) verifyHeaderWorker(chain consensus.ChainReader, headers []*types.Header, seals []bool, index int) error {
+	header := headers[index]
+	if index == 0 {
+		if chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) == nil {
+			return consensus.ErrUnknownAncestor
+		}
+	} else if headers[index-1].Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if chain.GetHeader(header.Hash(), header.Number.Uint64()) != nil {
+		return nil // known block
+	}
+	if err := d.verifyHeader(chain, header, headers[:index]); err != nil {
+		return err
+	}
+	if seals[index] {
+		return d.VerifySeal(chain, header)
+	}
+	return nil
 }
 
 // verifyHeader checks whether a header conforms to the consensus rules.The
@@ -83,40 +209,224 @@ func (d *Dexcon) VerifyHeaders(chain consensus.ChainReader, headers []*types.Hea
 // looking those up from the database. This is useful for concurrently verifying
 // a batch of new headers.
 func (d *Dexcon) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
-	return nil
+	// Verify fields that should be the same as the ones in dexcon meta.
+	coreBlock, err := decodeDexconMeta(header)
+	if err != nil {
+		return err
+	}
+	if header.Number.Uint64() != coreBlock.Position.Height {
+		return errInvalidHeight
+	}
+	if header.Round != coreBlock.Position.Round {
+		return errInvalidRound
+	}
+	if header.Time != uint64(coreBlock.Timestamp.UnixNano()/1000000) {
+		return errInvalidTimestamp
+	}
+	if !bytes.Equal(header.Randomness, coreBlock.Randomness) {
+		return errInvalidRandomness
+	}
+
+	gs := d.govStateFetcer.GetStateForConfigAtRound(header.Round)
+	if gs == nil {
+		return errUnknownGovState
+	}
+	if coreBlock.IsEmpty() {
+		if header.Coinbase != (common.Address{}) {
+			return errInvalidCoinbase
+		}
+	} else {
+		node, err := gs.GetNodeByID(coreBlock.ProposerID)
+		if err != nil {
+			return err
+		}
+		if header.Coinbase != node.Owner {
+			return errInvalidCoinbase
+		}
+	}
+
+	if header.GasLimit != gs.Configuration().BlockGasLimit {
+		return errInvalidGasLimit
+	}
+
+	// All basic checks passed, verify cascading fields
+	return d.verifyCascadingFields(chain, header, coreBlock, parents)
 }
 
 // verifyCascadingFields verifies all the header fields that are not standalone,
 // rather depend on a batch of previous headers. The caller may optionally pass
 // in a batch of parents (ascending order) to avoid looking those up from the
 // database. This is useful for concurrently verifying a batch of new headers.
-func (d *Dexcon) verifyCascadingFields(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+func (d *Dexcon) verifyCascadingFields(chain consensus.ChainReader, header *types.Header, coreBlock *coreTypes.Block, parents []*types.Header) error {
+	number := header.Number.Uint64()
+
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+
+	// The consensus block must be chained to the parent consensus block.
+	if parent.Number.Uint64() != 0 {
+		parentCoreBlock, err := decodeDexconMeta(parent)
+		if err != nil {
+			return err
+		}
+		if coreBlock.ParentHash != parentCoreBlock.Hash {
+			return errInvalidParentHash
+		}
+	}
+
+	// A header either stays in the round of its parent or starts the next one.
+	roundStart, err := d.roundHeight(chain, header, parent, parents)
+	if err != nil {
+		return err
+	}
+
+	// Verify the block reward.
+	reward := new(big.Int)
+	if header.Coinbase != (common.Address{}) && !d.inExtendedRound(header, roundStart) {
+		reward = d.calculateBlockReward(header.Round)
+	}
+	if header.Reward == nil || header.Reward.Cmp(reward) != 0 {
+		return errInvalidReward
+	}
 	return nil
 }
 
+// roundHeight returns the height of the first block of the header's round,
+// checking that the header does not cross a round boundary recorded in the
+// governance state.
+func (d *Dexcon) roundHeight(chain consensus.ChainReader, header, parent *types.Header, parents []*types.Header) (uint64, error) {
+	number := header.Number.Uint64()
+
+	if header.Round != parent.Round && header.Round != parent.Round+1 {
+		return 0, errInvalidRound
+	}
+
+	// The next round must not have started before this header.
+	if next := d.govStateFetcer.GetRoundHeight(header.Round + 1); next != 0 && number >= next {
+		return 0, errInvalidRoundHeight
+	}
+
+	if header.Round == 0 {
+		return 0, nil
+	}
+
+	height := d.govStateFetcer.GetRoundHeight(header.Round)
+	if header.Round == parent.Round+1 {
+		// The header is the first block of its round.
+		if height != 0 && height != number {
+			return 0, errInvalidRoundHeight
+		}
+		return number, nil
+	}
+	if height != 0 {
+		if number <= height {
+			return 0, errInvalidRoundHeight
+		}
+		return height, nil
+	}
+
+	// The round has not been recorded in the governance state yet, walk back to
+	// the first block of the round.
+	for {
+		if len(parents) > 0 {
+			parents = parents[:len(parents)-1]
+		}
+		var grandparent *types.Header
+		if len(parents) > 0 {
+			grandparent = parents[len(parents)-1]
+		} else {
+			grandparent = chain.GetHeader(parent.ParentHash, parent.Number.Uint64()-1)
+		}
+		if grandparent == nil {
+			return 0, consensus.ErrUnknownAncestor
+		}
+		if grandparent.Round != parent.Round {
+			return parent.Number.Uint64(), nil
+		}
+		parent = grandparent
+	}
+}
+
 // VerifyUncles implements consensus.Engine, always returning an error for any
 // uncles as this consensus mechanism doesn't permit uncles.
 func (d *Dexcon) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errUnclesNotAllowed
+	}
 	return nil
 }
 
 // VerifySeal implements consensus.Engine, checking whether the signature contained
 // in the header satisfies the consensus protocol requirements.
 func (d *Dexcon) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	coreBlock, err := decodeDexconMeta(header)
+	if err != nil {
+		return err
+	}
+
+	// Verify the proposer signature of the consensus block.
+	if coreBlock.IsEmpty() {
+		hash, err := coreUtils.HashBlock(coreBlock)
+		if err != nil {
+			return err
+		}
+		if hash != coreBlock.Hash {
+			return errInvalidBlockHash
+		}
+	} else {
+		if err := coreUtils.VerifyBlockSignatureWithoutPayload(coreBlock); err != nil {
+			return err
+		}
+	}
+
+	// Randomness is not available before DKG starts.
+	if header.Round < dexCore.DKGDelayRound {
+		return nil
+	}
+
+	// Verify the threshold signature against the DKG group public key.
+	v, ok, err := d.verifierCache.UpdateAndGet(header.Round)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errDKGNotReady
+	}
+	if !v.VerifySignature(coreBlock.Hash, coreCrypto.Signature{
+		Type:      "bls",
+		Signature: header.Randomness,
+	}) {
+		return errInvalidSignature
+	}
 	return nil
 }
 
+// decodeDexconMeta decodes the consensus block stored in header.
+func decodeDexconMeta(header *types.Header) (*coreTypes.Block, error) {
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+		return nil, errInvalidDexconMeta
+	}
+	return &coreBlock, nil
+}
+
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
 // header for running the transactions on top.
 func (d *Dexcon) Prepare(chain consensus.ChainReader, header *types.Header) error {
 	return nil
 }
 
-func (d *Dexcon) inExtendedRound(header *types.Header, state *state.StateDB) bool {
-	gs := vm.GovernanceState{state}
+func (d *Dexcon) inExtendedRound(header *types.Header, roundHeight uint64) bool {
 	rgs := d.govStateFetcer.GetStateForConfigAtRound(header.Round)
 
-	roundEnd := gs.RoundHeight(new(big.Int).SetUint64(header.Round)).Uint64() + rgs.RoundLength().Uint64()
+	roundEnd := roundHeight + rgs.RoundLength().Uint64()
 
 	// Round 0 starts and height 0 instead of height 1.
 	if header.Round == 0 {
@@ -197,12 +507,22 @@ func (d *Dexcon) Finalize(chain consensus.ChainReader, header *types.Header, sta
 
 	// If this is not an empty block and we are not in extended round, calculate
 	// the block reward.
-	if header.Coinbase != (common.Address{}) && !d.inExtendedRound(header, state) {
+	roundHeight := gs.RoundHeight(new(big.Int).SetUint64(header.Round)).Uint64()
+	if header.Coinbase != (common.Address{}) && !d.inExtendedRound(header, roundHeight) {
 		reward = d.calculateBlockReward(header.Round)
 	}
 
 	header.Reward = reward
-	state.AddBalance(header.Coinbase, reward)
+	if chain.Config().IsRewardSplit(header.Number) {
+		// The reward is held by governance contract until claimed by the
+		// stakers.
+		state.AddBalance(vm.GovernanceContractAddress, reward)
+		if reward.Cmp(big.NewInt(0)) > 0 {
+			gs.DistributeReward(header.Coinbase, reward)
+		}
+	} else {
+		state.AddBalance(header.Coinbase, reward)
+	}
 	gs.IncTotalSupply(reward)
 
 	// Check if halving checkpoint reached.
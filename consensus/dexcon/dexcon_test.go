@@ -20,15 +20,22 @@ package dexcon
 import (
 	"math/big"
 	"testing"
+	"time"
 
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreTypesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus"
 	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
 )
 
 type govStateFetcher struct {
@@ -39,10 +46,82 @@ func (g *govStateFetcher) GetStateForConfigAtRound(_ uint64) *vm.GovernanceState
 	return &vm.GovernanceState{g.statedb}
 }
 
+func (g *govStateFetcher) GetRoundHeight(round uint64) uint64 {
+	return g.GetStateForConfigAtRound(round).RoundHeight(new(big.Int).SetUint64(round)).Uint64()
+}
+
 func (g *govStateFetcher) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
 	return make(map[common.Address]struct{}), nil
 }
 
+func (g *govStateFetcher) Configuration(_ uint64) *coreTypes.Config {
+	return &coreTypes.Config{}
+}
+
+func (g *govStateFetcher) DKGComplaints(_ uint64) []*coreTypesDKG.Complaint {
+	return nil
+}
+
+func (g *govStateFetcher) DKGMasterPublicKeys(_ uint64) []*coreTypesDKG.MasterPublicKey {
+	return nil
+}
+
+func (g *govStateFetcher) IsDKGFinal(_ uint64) bool {
+	return false
+}
+
+// prunedGovStateFetcher has no governance state of any round.
+type prunedGovStateFetcher struct {
+	govStateFetcher
+}
+
+func (g *prunedGovStateFetcher) GetStateForConfigAtRound(_ uint64) *vm.GovernanceState {
+	return nil
+}
+
+type chainReader struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+}
+
+func newChainReader(config *params.ChainConfig, headers ...*types.Header) *chainReader {
+	c := &chainReader{
+		config:  config,
+		headers: make(map[common.Hash]*types.Header),
+	}
+	for _, h := range headers {
+		c.headers[h.Hash()] = h
+	}
+	return c
+}
+
+func (c *chainReader) Config() *params.ChainConfig  { return c.config }
+func (c *chainReader) CurrentHeader() *types.Header { return nil }
+
+func (c *chainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if h, ok := c.headers[hash]; ok && h.Number.Uint64() == number {
+		return h
+	}
+	return nil
+}
+
+func (c *chainReader) GetHeaderByNumber(number uint64) *types.Header {
+	for _, h := range c.headers {
+		if h.Number.Uint64() == number {
+			return h
+		}
+	}
+	return nil
+}
+
+func (c *chainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *chainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return nil
+}
+
 type DexconTestSuite struct {
 	suite.Suite
 
@@ -99,6 +178,169 @@ func (d *DexconTestSuite) TestBlockRewardCalculation() {
 	d.Require().Equal(big.NewInt(5945585996), consensus.calculateBlockReward(0))
 }
 
+func (d *DexconTestSuite) newEmptyBlockHeader(parent *types.Header) *types.Header {
+	coreBlock := coreTypes.Block{
+		Position: coreTypes.Position{
+			Round:  parent.Round,
+			Height: parent.Number.Uint64() + 1,
+		},
+		Timestamp: time.Unix(int64(parent.Time/1000)+1, 0).UTC(),
+	}
+	if parent.Number.Uint64() != 0 {
+		var parentCoreBlock coreTypes.Block
+		d.Require().NoError(rlp.DecodeBytes(parent.DexconMeta, &parentCoreBlock))
+		coreBlock.ParentHash = parentCoreBlock.Hash
+	}
+	hash, err := coreUtils.HashBlock(&coreBlock)
+	d.Require().NoError(err)
+	coreBlock.Hash = hash
+
+	dexconMeta, err := rlp.EncodeToBytes(&coreBlock)
+	d.Require().NoError(err)
+
+	return &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Time:       uint64(coreBlock.Timestamp.UnixNano() / 1000000),
+		GasLimit:   d.config.BlockGasLimit,
+		Difficulty: big.NewInt(1),
+		Round:      coreBlock.Position.Round,
+		DexconMeta: dexconMeta,
+		Reward:     big.NewInt(0),
+	}
+}
+
+func (d *DexconTestSuite) TestVerifyHeader() {
+	engine := New()
+	engine.SetGovStateFetcher(&govStateFetcher{d.stateDB})
+
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Reward:     big.NewInt(0),
+	}
+	chain := newChainReader(params.TestnetChainConfig, genesis)
+
+	header := d.newEmptyBlockHeader(genesis)
+	d.Require().NoError(engine.VerifyHeader(chain, header, true))
+
+	// Unknown parent.
+	orphan := d.newEmptyBlockHeader(header)
+	d.Require().Equal(consensus.ErrUnknownAncestor,
+		engine.VerifyHeader(chain, orphan, true))
+
+	// Timestamp not matching dexcon meta.
+	header = d.newEmptyBlockHeader(genesis)
+	header.Time++
+	d.Require().Equal(errInvalidTimestamp, engine.VerifyHeader(chain, header, true))
+
+	// Empty block should not have coinbase.
+	header = d.newEmptyBlockHeader(genesis)
+	header.Coinbase = common.Address{1}
+	d.Require().Equal(errInvalidCoinbase, engine.VerifyHeader(chain, header, true))
+
+	// Empty block should not be rewarded.
+	header = d.newEmptyBlockHeader(genesis)
+	header.Reward = big.NewInt(1)
+	d.Require().Equal(errInvalidReward, engine.VerifyHeader(chain, header, true))
+
+	// Round can not be skipped.
+	header = d.newEmptyBlockHeader(genesis)
+	header.Round = 2
+	d.Require().Equal(errInvalidRound, engine.VerifyHeader(chain, header, true))
+
+	// Tampered dexcon meta.
+	header = d.newEmptyBlockHeader(genesis)
+	header.DexconMeta = header.DexconMeta[1:]
+	d.Require().Equal(errInvalidDexconMeta, engine.VerifyHeader(chain, header, true))
+
+	// Governance state of the round not known.
+	header = d.newEmptyBlockHeader(genesis)
+	engine = engine.WithGovStateFetcher(&prunedGovStateFetcher{
+		govStateFetcher{d.stateDB}}, engine.verifierCache)
+	d.Require().Equal(errUnknownGovState, engine.VerifyHeader(chain, header, true))
+}
+
+func (d *DexconTestSuite) TestVerifyHeaders() {
+	engine := New()
+	engine.SetGovStateFetcher(&govStateFetcher{d.stateDB})
+
+	genesis := &types.Header{
+		Number:     big.NewInt(0),
+		Difficulty: big.NewInt(1),
+		Reward:     big.NewInt(0),
+	}
+	chain := newChainReader(params.TestnetChainConfig, genesis)
+
+	headers := []*types.Header{d.newEmptyBlockHeader(genesis)}
+	for i := 1; i < 10; i++ {
+		headers = append(headers, d.newEmptyBlockHeader(headers[i-1]))
+	}
+	headers[len(headers)-1].Reward = big.NewInt(1)
+
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	abort, results := engine.VerifyHeaders(chain, headers, seals)
+	defer close(abort)
+
+	for i := range headers {
+		err := <-results
+		if i == len(headers)-1 {
+			d.Require().Equal(errInvalidReward, err)
+		} else {
+			d.Require().NoError(err)
+		}
+	}
+}
+
+func (d *DexconTestSuite) TestVerifyUncles() {
+	engine := New()
+
+	header := &types.Header{Number: big.NewInt(1)}
+	block := types.NewBlock(header, nil, nil, nil)
+	d.Require().NoError(engine.VerifyUncles(nil, block))
+
+	block = types.NewBlock(header, nil, []*types.Header{header}, nil)
+	d.Require().Equal(errUnclesNotAllowed, engine.VerifyUncles(nil, block))
+}
+
+func (d *DexconTestSuite) TestFinalizeRewardSplit() {
+	engine := New()
+	engine.SetGovStateFetcher(&govStateFetcher{d.stateDB})
+
+	d.s.IncTotalStaked(big.NewInt(1e18))
+	reward := engine.calculateBlockReward(0)
+	coinbase := common.BytesToAddress([]byte{0x1})
+
+	newHeader := func(number int64) *types.Header {
+		return &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: coinbase,
+		}
+	}
+
+	// Reward goes to coinbase before the fork.
+	config := *params.TestnetChainConfig
+	config.RewardSplitBlock = big.NewInt(2)
+	chain := newChainReader(&config)
+
+	_, err := engine.Finalize(chain, newHeader(1), d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().Equal(reward, d.stateDB.GetBalance(coinbase))
+	d.Require().Equal(0, d.s.PendingRewards(coinbase).Cmp(big.NewInt(0)))
+
+	// Reward is held by governance contract after the fork.
+	govBalance := d.stateDB.GetBalance(vm.GovernanceContractAddress)
+	_, err = engine.Finalize(chain, newHeader(2), d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().Equal(reward, d.stateDB.GetBalance(coinbase))
+	d.Require().Equal(new(big.Int).Add(govBalance, reward),
+		d.stateDB.GetBalance(vm.GovernanceContractAddress))
+	d.Require().Equal(reward, d.s.PendingRewards(coinbase))
+}
+
 func TestDexcon(t *testing.T) {
 	suite.Run(t, new(DexconTestSuite))
 }
@@ -23,7 +23,8 @@ import (
 
 type FakeDexcon struct {
 	*Dexcon
-	nodes *NodeSet
+	nodes      *NodeSet
+	randomness func(header *types.Header) []byte
 }
 
 func NewFaker(nodes *NodeSet) *FakeDexcon {
@@ -33,6 +34,15 @@ func NewFaker(nodes *NodeSet) *FakeDexcon {
 	}
 }
 
+// NewFakerWithRandomness creates a FakeDexcon which takes the randomness of
+// the blocks from fn instead of the threshold signature of the node set.
+func NewFakerWithRandomness(nodes *NodeSet,
+	fn func(header *types.Header) []byte) *FakeDexcon {
+	f := NewFaker(nodes)
+	f.randomness = fn
+	return f
+}
+
 func (f *FakeDexcon) Prepare(chain consensus.ChainReader, header *types.Header) error {
 	var coreBlock coreTypes.Block
 	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
@@ -53,8 +63,15 @@ func (f *FakeDexcon) Prepare(chain consensus.ChainReader, header *types.Header)
 		}
 	}
 
-	randomness := f.nodes.Randomness(header.Round, common.Hash(blockHash))
+	var randomness []byte
+	if f.randomness != nil {
+		randomness = f.randomness(header)
+	} else {
+		randomness = f.nodes.Randomness(header.Round, common.Hash(blockHash))
+	}
+	coreBlock.Hash = blockHash
 	coreBlock.Randomness = randomness
+	header.Randomness = randomness
 
 	dexconMeta, err := rlp.EncodeToBytes(&coreBlock)
 	if err != nil {
@@ -196,9 +213,16 @@ func (n *NodeSet) SignedCRS(round uint64) []byte {
 
 // Assume All nodes in NodeSet are in DKG Set too.
 func (n *NodeSet) RunDKG(round uint64, threshold int) {
+	n.RunDKGWithKeys(round, threshold, n.privkeys)
+}
+
+// RunDKGWithKeys runs the DKG of round among the nodes of privkeys only, for
+// the DKG sets which are not the whole node set.
+func (n *NodeSet) RunDKGWithKeys(round uint64, threshold int,
+	privkeys []*ecdsa.PrivateKey) {
 	var ids coreDKG.IDs
 	var nodes []*Node
-	for _, key := range n.privkeys {
+	for _, key := range privkeys {
 		node := newNode(key, n.signer)
 		nodes = append(nodes, node)
 		ids = append(ids, node.DKGID())
@@ -21,12 +21,17 @@ import (
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus"
+	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/params"
 )
 
+// checkpointWitnessWindow is the number of blocks before the checkpoint the
+// chain is bootstrapped from the blocks after it may witness.
+const checkpointWitnessWindow = 32
+
 // BlockValidator is responsible for validating block headers, uncles and
 // processed state.
 //
@@ -106,6 +111,9 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 func (v *BlockValidator) ValidateWitnessData(height uint64, blockHash common.Hash) error {
 	b := v.bc.GetHeaderByNumber(height)
 	if b == nil {
+		if cp := v.bc.Checkpoint(); cp != nil && height < cp.Number {
+			return v.validateCheckpointWitness(cp, height, blockHash)
+		}
 		log.Error("can not find block %v either pending or confirmed block", height)
 		return consensus.ErrWitnessMismatch
 	}
@@ -118,6 +126,26 @@ func (v *BlockValidator) ValidateWitnessData(height uint64, blockHash common.Has
 	return nil
 }
 
+// validateCheckpointWitness validates the witness of a block before the
+// checkpoint the chain is bootstrapped from, whose header is missing. Only the
+// blocks within checkpointWitnessWindow blocks before the checkpoint, whose
+// hashes are kept with the checkpoint, are witnessed by the blocks after it.
+func (v *BlockValidator) validateCheckpointWitness(cp *rawdb.CheckpointMeta,
+	height uint64, blockHash common.Hash) error {
+	index := cp.Number - height - 1
+	if index >= uint64(len(cp.Ancestors)) {
+		log.Error("witness too far before checkpoint", "height", height,
+			"checkpoint", cp.Number)
+		return consensus.ErrWitnessMismatch
+	}
+	if cp.Ancestors[index] != blockHash {
+		log.Error("invalid witness before checkpoint", "height", height,
+			"hash", blockHash, "want", cp.Ancestors[index])
+		return consensus.ErrWitnessMismatch
+	}
+	return nil
+}
+
 // CalcGasLimit computes the gas limit of the next block after parent. It aims
 // to keep the baseline gas above the provided floor, and increase it towards the
 // ceil if the blocks are full. If the ceil is exceeded, it will always decrease
@@ -69,6 +69,7 @@ const (
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
 	triesInMemory       = 128
+	govStorageLimit     = 16
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion uint64 = 3
@@ -149,6 +150,10 @@ type BlockChain struct {
 	gov             *Governance
 	verifierCache   *dexCore.TSigVerifierCache
 	nextTouchHeight uint64
+	roundIndexCh    chan uint64 // Rounds below are finalised and to be indexed
+	govStorageCache *lru.Cache  // Cache for the governance storage rebuilt from the gov states
+
+	bootstrap *rawdb.CheckpointMeta // Checkpoint the chain is bootstrapped from, nil for the genesis
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -168,6 +173,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
+	govStorageCache, _ := lru.New(govStorageLimit)
 
 	bc := &BlockChain{
 		chainConfig:   chainConfig,
@@ -184,6 +190,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		engine:        engine,
 		vmConfig:      vmConfig,
 		badBlocks:     badBlocks,
+		roundIndexCh:  make(chan uint64, roundIndexChanSize),
+		bootstrap:     rawdb.ReadCheckpointMeta(db),
+
+		govStorageCache: govStorageCache,
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
@@ -264,6 +274,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 
 	// Take ownership of this particular state
 	go bc.update()
+
+	bc.wg.Add(1)
+	go bc.roundIndexLoop()
 	return bc, nil
 }
 
@@ -585,6 +598,12 @@ func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
 }
 
+// Checkpoint retrieves the checkpoint the chain is bootstrapped from, nil if
+// it's bootstrapped from the genesis.
+func (bc *BlockChain) Checkpoint() *rawdb.CheckpointMeta {
+	return bc.bootstrap
+}
+
 // GetBody retrieves a block body (transactions and uncles) from the database by
 // hash, caching it if found.
 func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
@@ -1045,6 +1064,14 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		}()
 	}
 
+	// The previous round is finalised by the first block of a round.
+	if height == block.NumberU64() && block.Round() > 0 {
+		select {
+		case bc.roundIndexCh <- block.Round():
+		default:
+		}
+	}
+
 	// If we're running an archive node or the block is snapshot height, always flush
 	if bc.cacheConfig.Disabled || height == block.NumberU64() {
 		if err := triedb.Commit(root, false); err != nil {
@@ -1550,8 +1577,12 @@ func (bc *BlockChain) insertDexonChain(chain types.Blocks) (int, []interface{},
 		// Wait for the block's verification to complete
 		bstart := time.Now()
 
-		// VerifyDexonHeader will verify tsig, witness and ensure dexon header is correct.
+		// VerifyDexonHeader will verify witness and ensure dexon header is
+		// correct, the engine verifies the seal and tsig.
 		err := bc.hc.VerifyDexonHeader(block.Header(), bc.gov, bc.verifierCache, bc.Validator())
+		if err == nil {
+			err = bc.engine.VerifyHeader(bc, block.Header(), true)
+		}
 		if err == nil {
 			err = bc.Validator().ValidateBody(block)
 		}
@@ -1956,6 +1987,93 @@ func (bc *BlockChain) GetGovStateByNumber(number uint64) (*types.GovState, error
 	return state.GetGovState(statedb, header, vm.GovernanceContractAddress)
 }
 
+// GetGovStateSlice extracts at most amount slots of the governance contract's
+// storage at the given block hash starting from the hashed key origin. The
+// slots changed from the state at the base block hash are extracted if base
+// is not zero, a range of the storage is extracted instead if the base state
+// is not available.
+func (bc *BlockChain) GetGovStateSlice(hash, base common.Hash, origin []byte,
+	amount int) (*types.GovStateSlice, error) {
+	header, proof, storage, err := bc.govStorage(hash)
+	if err != nil {
+		return nil, err
+	}
+	var baseStorage *trie.Trie
+	if base != (common.Hash{}) {
+		if _, _, baseStorage, err = bc.govStorage(base); err != nil {
+			log.Debug("Base gov state not available", "base", base, "err", err)
+			base, baseStorage = common.Hash{}, nil
+		}
+	}
+	slice, err := state.GetGovStateSlice(header, proof, storage, baseStorage,
+		origin, amount)
+	if err != nil {
+		return nil, err
+	}
+	slice.Base = base
+	return slice, nil
+}
+
+// govStorage is the governance contract's storage rebuilt from a gov state.
+type govStorage struct {
+	proof  [][]byte
+	root   common.Hash
+	triedb *trie.Database
+}
+
+// govStorage returns the header, the merkle proof of the governance contract
+// and its storage trie at the given block hash, from the state or the gov
+// state on disk. The storage rebuilt from the gov state is cached, since it's
+// requested by every peer syncing from it.
+func (bc *BlockChain) govStorage(hash common.Hash) (*types.Header, [][]byte,
+	*trie.Trie, error) {
+	header := bc.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, nil, fmt.Errorf("header not found")
+	}
+	if statedb, err := bc.StateAt(header.Root); err == nil {
+		proof, err := statedb.GetProof(vm.GovernanceContractAddress)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		storage, err := state.GetGovStorageTrie(statedb,
+			vm.GovernanceContractAddress)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return header, proof, storage, nil
+	}
+
+	if cached, ok := bc.govStorageCache.Get(hash); ok {
+		s := cached.(*govStorage)
+		storage, err := trie.New(s.root, s.triedb)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return header, s.proof, storage, nil
+	}
+	bc.govmu.Lock()
+	govState := rawdb.ReadGovState(bc.db, header.Hash())
+	bc.govmu.Unlock()
+	if govState == nil {
+		return nil, nil, nil, fmt.Errorf("gov state not found")
+	}
+	root, triedb, err := state.NewGovStorageDatabase(govState)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	storage, err := trie.New(root, triedb)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bc.govStorageCache.Add(hash, &govStorage{
+		proof:  govState.Proof,
+		root:   root,
+		triedb: triedb,
+	})
+	return header, govState.Proof, storage, nil
+}
+
 // reorg takes two blocks, an old chain and a new chain and will reconstruct the
 // blocks and inserts them to be part of the new canonical chain and accumulates
 // potential missing transactions and post an event about them.
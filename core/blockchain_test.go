@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -1965,3 +1966,60 @@ func TestLowDiffLongChain(t *testing.T) {
 		header = chain.GetHeader(header.ParentHash, number-1)
 	}
 }
+
+// Tests that the governance storage rebuilt from the gov state of a block
+// without state is cached for the slices served from it.
+func TestGovStateSliceWithoutState(t *testing.T) {
+	engine := ethash.NewFaker()
+	gspec := &Genesis{Config: params.TestChainConfig}
+
+	db := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		for j := int64(0); j < 10; j++ {
+			b.statedb.SetState(vm.GovernanceContractAddress,
+				common.BigToHash(big.NewInt(j)), common.BigToHash(big.NewInt(j+1)))
+		}
+	})
+	header := blocks[0].Header()
+	statedb, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	govState, err := state.GetGovState(statedb, header, vm.GovernanceContractAddress)
+	if err != nil {
+		t.Fatalf("failed to get gov state: %v", err)
+	}
+	storage, err := state.GetGovStorageTrie(statedb, vm.GovernanceContractAddress)
+	if err != nil {
+		t.Fatalf("failed to get storage trie: %v", err)
+	}
+	want, err := state.GetGovStateSlice(header, govState.Proof, storage, nil, nil, 4)
+	if err != nil {
+		t.Fatalf("failed to get slice: %v", err)
+	}
+
+	// The header chain has the gov state of the block but not the state.
+	headerDb := ethdb.NewMemDatabase()
+	gspec.MustCommit(headerDb)
+	chain, _ := NewBlockChain(headerDb, nil, gspec.Config, engine, vm.Config{}, nil)
+	defer chain.Stop()
+	if n, err := chain.InsertHeaderChain([]*types.Header{header}, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	rawdb.WriteGovState(headerDb, header.Hash(), govState)
+
+	for i := 0; i < 2; i++ {
+		slice, err := chain.GetGovStateSlice(header.Hash(), common.Hash{}, nil, 4)
+		if err != nil {
+			t.Fatalf("request %d: failed to get slice: %v", i, err)
+		}
+		if len(slice.Storage) != 4 || !reflect.DeepEqual(slice, want) {
+			t.Errorf("request %d: slice mismatch", i)
+		}
+		if n := chain.govStorageCache.Len(); n != 1 {
+			t.Errorf("request %d: cached gov storages mismatch: have %d, want 1", i, n)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// checkpointRounds is the number of the last rounds the headers and the
+// governance states at the heights of are included in a checkpoint, the
+// governance of the checkpoint round is derived from them.
+const checkpointRounds = 4
+
+var (
+	errCheckpointGenesis   = errors.New("checkpoint of another chain")
+	errUntrustedCheckpoint = errors.New("checkpoint not signed by enough trusted signers")
+	errInvalidCheckpoint   = errors.New("invalid checkpoint")
+)
+
+// Checkpoint is a snapshot of the chain at a block which a new node can be
+// bootstrapped from, without the blocks before it. It is signed by the
+// trusted signers, or trusted by its hash configured for the network.
+type Checkpoint struct {
+	Genesis     common.Hash
+	Block       *types.Block
+	Receipts    []*types.ReceiptForStorage
+	Td          *big.Int
+	Ancestors   []common.Hash               // Hashes of the blocks before the block within the witness window, the parent first
+	GovState    *types.GovState             // Governance state at the block
+	RoundStates []*types.HeaderWithGovState // Headers and governance states at the heights of the last rounds
+	Rounds      []*rawdb.RoundMeta          // Current and next round, the height of the next round is zero
+	Signatures  [][]byte                    // Signatures of the hash of the checkpoint
+}
+
+// Hash returns the hash of the checkpoint the signers sign, the body and the
+// receipts of the block are covered by the block hash.
+func (cp *Checkpoint) Hash() (h common.Hash) {
+	hw := sha3.NewLegacyKeccak256()
+	rlp.Encode(hw, []interface{}{cp.Genesis, cp.Block.Hash(), cp.Td,
+		cp.Ancestors, cp.GovState, cp.RoundStates, cp.Rounds})
+	hw.Sum(h[:0])
+	return h
+}
+
+// Sign adds the signature of the key to the checkpoint.
+func (cp *Checkpoint) Sign(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(cp.Hash().Bytes(), key)
+	if err != nil {
+		return err
+	}
+	cp.Signatures = append(cp.Signatures, sig)
+	return nil
+}
+
+// Signers recovers the addresses of the signers of the checkpoint.
+func (cp *Checkpoint) Signers() ([]common.Address, error) {
+	hash := cp.Hash()
+	signers := make([]common.Address, 0, len(cp.Signatures))
+	for _, sig := range cp.Signatures {
+		pub, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, crypto.PubkeyToAddress(*pub))
+	}
+	return signers, nil
+}
+
+// checkpointStateDB is the governance state db of the governance states in a
+// checkpoint, or stored at the round heights.
+type checkpointStateDB struct {
+	head   *state.StateDB
+	states map[uint64]*state.StateDB // States at the heights of the last rounds
+}
+
+func (db *checkpointStateDB) State() (*state.StateDB, error) {
+	return db.head, nil
+}
+
+func (db *checkpointStateDB) StateAt(height uint64) (*state.StateDB, error) {
+	s, ok := db.states[height]
+	if !ok {
+		return nil, fmt.Errorf("state at %d not in checkpoint", height)
+	}
+	return s, nil
+}
+
+// lastRounds returns the last rounds up to round the governance states at
+// the heights of are included in a checkpoint.
+func lastRounds(round uint64) []uint64 {
+	from := uint64(0)
+	if round >= checkpointRounds {
+		from = round - checkpointRounds + 1
+	}
+	rounds := make([]uint64, 0, checkpointRounds)
+	for r := from; r <= round; r++ {
+		rounds = append(rounds, r)
+	}
+	return rounds
+}
+
+// numAncestors returns the number of the blocks before the block of the number
+// whose hashes are included in a checkpoint, the blocks after the checkpoint
+// witness them.
+func numAncestors(number uint64) uint64 {
+	if number < checkpointWitnessWindow {
+		return number
+	}
+	return checkpointWitnessWindow
+}
+
+// checkpointRoundMetas collects the metadata of the round of the checkpoint
+// and the next round if its CRS is proposed already.
+func checkpointRoundMetas(gov *Governance, round uint64) ([]*rawdb.RoundMeta, error) {
+	current, err := newRoundMeta(gov, round, gov.GetRoundHeight(round))
+	if err != nil {
+		return nil, err
+	}
+	metas := []*rawdb.RoundMeta{current}
+	if gov.CRSRound() > round {
+		next, err := newRoundMeta(gov, round+1, 0)
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, next)
+	}
+	return metas, nil
+}
+
+// MakeCheckpoint makes an unsigned checkpoint of the canonical block of the
+// number, the states of the block and the last rounds must be available.
+func (bc *BlockChain) MakeCheckpoint(number uint64) (*Checkpoint, error) {
+	block := bc.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	statedb, err := bc.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	govState, err := state.GetGovState(statedb, block.Header(),
+		vm.GovernanceContractAddress)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{
+		Genesis:  bc.genesisBlock.Hash(),
+		Block:    block,
+		Td:       bc.GetTd(block.Hash(), number),
+		GovState: govState,
+	}
+	for _, receipt := range bc.GetReceiptsByHash(block.Hash()) {
+		cp.Receipts = append(cp.Receipts, (*types.ReceiptForStorage)(receipt))
+	}
+	for i := uint64(1); i <= numAncestors(number); i++ {
+		cp.Ancestors = append(cp.Ancestors, rawdb.ReadCanonicalHash(bc.db, number-i))
+	}
+
+	db := &checkpointStateDB{
+		head:   statedb,
+		states: make(map[uint64]*state.StateDB),
+	}
+	gs := &vm.GovernanceState{StateDB: statedb}
+	for _, round := range lastRounds(block.Round()) {
+		height := gs.RoundHeight(new(big.Int).SetUint64(round)).Uint64()
+		header := bc.GetHeaderByNumber(height)
+		if header == nil {
+			return nil, fmt.Errorf("header #%d of round %d not found", height, round)
+		}
+		s, err := bc.StateAt(header.Root)
+		if err != nil {
+			return nil, err
+		}
+		roundState, err := state.GetGovState(s, header, vm.GovernanceContractAddress)
+		if err != nil {
+			return nil, err
+		}
+		cp.RoundStates = append(cp.RoundStates,
+			&types.HeaderWithGovState{Header: header, GovState: roundState})
+		db.states[height] = s
+	}
+	cp.Rounds, err = checkpointRoundMetas(NewGovernance(db), block.Round())
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// VerifyCheckpoint verifies the checkpoint of the chain of the genesis. It must
+// be the trusted checkpoint of the network, or signed by at least threshold of
+// the signers, all of them if threshold is not positive.
+func VerifyCheckpoint(cp *Checkpoint, genesis common.Hash,
+	signers []common.Address, threshold int) error {
+	if cp.Genesis != genesis {
+		return errCheckpointGenesis
+	}
+	if cp.Block == nil || cp.Td == nil || cp.GovState == nil {
+		return errInvalidCheckpoint
+	}
+	header := cp.Block.Header()
+	if trusted := params.TrustedBootstrapCheckpoints[genesis]; trusted == nil ||
+		trusted.Number != header.Number.Uint64() || trusted.Hash != cp.Hash() {
+		if err := verifyCheckpointSigners(cp, signers, threshold); err != nil {
+			return err
+		}
+	}
+
+	// The body, the receipts and the states are verified against the block.
+	if types.DeriveSha(cp.Block.Transactions()) != header.TxHash {
+		return fmt.Errorf("%v: transactions mismatch", errInvalidCheckpoint)
+	}
+	receipts := make(types.Receipts, len(cp.Receipts))
+	for i, receipt := range cp.Receipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	if types.DeriveSha(receipts) != header.ReceiptHash {
+		return fmt.Errorf("%v: receipts mismatch", errInvalidCheckpoint)
+	}
+	head, err := checkpointGovState(cp.GovState, header)
+	if err != nil {
+		return err
+	}
+
+	// The ancestors are linked to the block by its parent and reach the
+	// genesis if the block is within the witness window.
+	number := header.Number.Uint64()
+	if uint64(len(cp.Ancestors)) != numAncestors(number) ||
+		(number > 0 && cp.Ancestors[0] != header.ParentHash) {
+		return fmt.Errorf("%v: ancestors mismatch", errInvalidCheckpoint)
+	}
+	if number > 0 && number <= checkpointWitnessWindow &&
+		cp.Ancestors[number-1] != genesis {
+		return errCheckpointGenesis
+	}
+
+	// The round states are at the heights of the last rounds in the state of
+	// the block, the metadata of the rounds is derived from them.
+	rounds := lastRounds(header.Round)
+	if len(cp.RoundStates) != len(rounds) {
+		return fmt.Errorf("%v: round states mismatch", errInvalidCheckpoint)
+	}
+	db := &checkpointStateDB{
+		head:   head,
+		states: make(map[uint64]*state.StateDB),
+	}
+	gs := &vm.GovernanceState{StateDB: head}
+	for i, round := range rounds {
+		roundState := cp.RoundStates[i]
+		height := gs.RoundHeight(new(big.Int).SetUint64(round)).Uint64()
+		if roundState.Header == nil || roundState.Number.Uint64() != height ||
+			roundState.Round != round {
+			return fmt.Errorf("%v: round %d state mismatch", errInvalidCheckpoint, round)
+		}
+		if height == 0 && roundState.Hash() != genesis {
+			return errCheckpointGenesis
+		}
+		if height < number && number-height <= uint64(len(cp.Ancestors)) &&
+			cp.Ancestors[number-height-1] != roundState.Hash() {
+			return fmt.Errorf("%v: round %d header mismatch", errInvalidCheckpoint, round)
+		}
+		s, err := checkpointGovState(roundState.GovState, roundState.Header)
+		if err != nil {
+			return err
+		}
+		db.states[height] = s
+	}
+	metas, err := checkpointRoundMetas(NewGovernance(db), header.Round)
+	if err != nil {
+		return err
+	}
+	have, err := rlp.EncodeToBytes(cp.Rounds)
+	if err != nil {
+		return err
+	}
+	want, err := rlp.EncodeToBytes(metas)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(have, want) {
+		return fmt.Errorf("%v: rounds mismatch", errInvalidCheckpoint)
+	}
+	return nil
+}
+
+// verifyCheckpointSigners checks if the checkpoint is signed by enough of the
+// trusted signers.
+func verifyCheckpointSigners(cp *Checkpoint, signers []common.Address,
+	threshold int) error {
+	if len(signers) == 0 {
+		return errUntrustedCheckpoint
+	}
+	if threshold <= 0 || threshold > len(signers) {
+		threshold = len(signers)
+	}
+	trusted := make(map[common.Address]bool, len(signers))
+	for _, signer := range signers {
+		trusted[signer] = false
+	}
+	recovered, err := cp.Signers()
+	if err != nil {
+		return fmt.Errorf("%v: %v", errInvalidCheckpoint, err)
+	}
+	count := 0
+	for _, signer := range recovered {
+		if signed, ok := trusted[signer]; ok && !signed {
+			trusted[signer] = true
+			count++
+		}
+	}
+	if count < threshold {
+		return errUntrustedCheckpoint
+	}
+	return nil
+}
+
+// checkpointGovState verifies the governance state of a checkpoint against
+// the header, and rebuilds it in memory.
+func checkpointGovState(govState *types.GovState,
+	header *types.Header) (*state.StateDB, error) {
+	if govState == nil || govState.BlockHash != header.Hash() ||
+		govState.Root != header.Root || govState.Number == nil ||
+		govState.Number.Cmp(header.Number) != 0 {
+		return nil, fmt.Errorf("%v: gov state #%d mismatch",
+			errInvalidCheckpoint, header.Number)
+	}
+	s, err := state.NewGovStateDB(govState, vm.GovernanceContractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("%v: gov state #%d: %v",
+			errInvalidCheckpoint, header.Number, err)
+	}
+	return s, nil
+}
+
+// SetupCheckpoint bootstraps an empty chain from the checkpoint after it's
+// verified, the blocks after it are fast synced. The checkpoint is ignored if
+// the chain is not empty.
+func SetupCheckpoint(db ethdb.Database, cp *Checkpoint,
+	signers []common.Address, threshold int) error {
+	if cp.Block == nil {
+		return errInvalidCheckpoint
+	}
+	number := cp.Block.NumberU64()
+	if meta := rawdb.ReadCheckpointMeta(db); meta != nil && meta.Hash == cp.Block.Hash() {
+		return nil
+	}
+	if head := rawdb.ReadHeadHeaderHash(db); head != rawdb.ReadCanonicalHash(db, 0) {
+		log.Warn("Chain not empty, checkpoint ignored", "number", number,
+			"hash", cp.Block.Hash())
+		return nil
+	}
+	if err := VerifyCheckpoint(cp, rawdb.ReadCanonicalHash(db, 0),
+		signers, threshold); err != nil {
+		return err
+	}
+
+	batch := db.NewBatch()
+	for _, roundState := range cp.RoundStates {
+		if roundState.Number.Uint64() == 0 {
+			continue
+		}
+		rawdb.WriteHeader(batch, roundState.Header)
+		rawdb.WriteCanonicalHash(batch, roundState.Hash(), roundState.Number.Uint64())
+		rawdb.WriteGovState(batch, roundState.Hash(), roundState.GovState)
+	}
+	hash := cp.Block.Hash()
+	receipts := make(types.Receipts, len(cp.Receipts))
+	for i, receipt := range cp.Receipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	rawdb.WriteBlock(batch, cp.Block)
+	rawdb.WriteReceipts(batch, hash, number, receipts)
+	rawdb.WriteTd(batch, hash, number, cp.Td)
+	rawdb.WriteCanonicalHash(batch, hash, number)
+	rawdb.WriteGovState(batch, hash, cp.GovState)
+	rawdb.WriteHeadHeaderHash(batch, hash)
+	rawdb.WriteHeadFastBlockHash(batch, hash)
+
+	roundHeight := uint64(0)
+	for _, roundState := range cp.RoundStates {
+		if roundState.Round == cp.Block.Round() {
+			roundHeight = roundState.Number.Uint64()
+		}
+	}
+	rawdb.WriteCheckpointMeta(batch, &rawdb.CheckpointMeta{
+		Hash:        hash,
+		Number:      number,
+		Round:       cp.Block.Round(),
+		RoundHeight: roundHeight,
+		Ancestors:   cp.Ancestors,
+	})
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Bootstrapped chain from checkpoint", "number", number,
+		"hash", hash, "round", cp.Block.Round())
+	return nil
+}
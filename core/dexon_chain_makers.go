@@ -26,6 +26,7 @@ import (
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus"
+	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
@@ -86,6 +87,15 @@ func (b *DexonBlockGen) SetPosition(position coreTypes.Position) {
 	b.position = position
 }
 
+// OffsetTime shifts the time of the generated block by the given number of
+// milliseconds.
+func (b *DexonBlockGen) OffsetTime(ms int64) {
+	b.header.Time += uint64(ms)
+	if b.header.Time <= b.parent.Time() {
+		panic("block time out of range")
+	}
+}
+
 // AddTx adds a transaction to the generated block. If no coinbase has
 // been set, the block's coinbase is set to the zero address.
 //
@@ -164,15 +174,22 @@ func GenerateDexonChain(config *params.ChainConfig, parent *types.Block, engine
 	if engine == nil {
 		panic("engine is nil")
 	}
+	parentState, err := state.New(parent.Root(), state.NewDatabase(db))
+	if err != nil {
+		panic(err)
+	}
 	blocks, receipts := make(types.Blocks, n), make([]types.Receipts, n)
 	chain := &fakeDexonChain{
 		config:          config,
 		engine:          engine,
-		genesis:         parent,
 		db:              db,
 		headersByNumber: make(map[uint64]*types.Header),
 		roundHeight:     make(map[uint64]uint64),
+		parentGov:       &vm.GovernanceState{StateDB: parentState},
 	}
+	// The parent is not necessarily the genesis block, it is known to the
+	// chain for the engine to prepare the first block.
+	chain.headersByNumber[parent.NumberU64()] = parent.Header()
 	genblock := func(i int, parent *types.Block, statedb *state.StateDB) (*types.Block, types.Receipts) {
 		b := &DexonBlockGen{i: i, chain: blocks, parent: parent, statedb: statedb, config: config, engine: engine}
 		b.header = makeDexonHeader(chain, parent, statedb, b.engine)
@@ -239,14 +256,10 @@ func makeDexonHeader(chain consensus.ChainReader, parent *types.Block, state *st
 
 // Setup DexconMeata skeleton
 func makeDexconMeta(b *DexonBlockGen, parent *types.Block) []byte {
-	// Setup witness
-	h := parent.Number().Int64() - rand.Int63n(6)
-	if h < 0 {
-		h = 0
-	}
-	witnessedBlock := b.chain[h]
-	if witnessedBlock == nil {
-		witnessedBlock = parent
+	// Setup witness, one of the last few blocks generated or the parent.
+	witnessedBlock := parent
+	if i := b.i - 1 - int(rand.Int63n(6)); i >= 0 {
+		witnessedBlock = b.chain[i]
 	}
 	witnessedBlockHash := witnessedBlock.Hash()
 	data, err := rlp.EncodeToBytes(&witnessedBlockHash)
@@ -273,14 +286,17 @@ type fakeDexonChain struct {
 	config          *params.ChainConfig
 	engine          consensus.Engine
 	db              ethdb.Database
-	genesis         *types.Block
 	headersByNumber map[uint64]*types.Header
 	roundHeight     map[uint64]uint64
+
+	// Governance state of the parent, which records the heights of the
+	// rounds started before the generated blocks.
+	parentGov *vm.GovernanceState
 }
 
 func (f *fakeDexonChain) InsertBlock(block *types.Block) {
 	f.headersByNumber[block.NumberU64()] = block.Header()
-	if _, exists := f.roundHeight[block.Round()]; !exists {
+	if _, exists := f.GetRoundHeight(block.Round()); !exists {
 		f.roundHeight[block.Round()] = block.NumberU64()
 	}
 }
@@ -294,10 +310,12 @@ func (f *fakeDexonChain) GetHeader(hash common.Hash, number uint64) *types.Heade
 func (f *fakeDexonChain) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
 
 func (f *fakeDexonChain) GetHeaderByNumber(number uint64) *types.Header {
-	if number == 0 {
-		return f.genesis.Header()
+	if header, ok := f.headersByNumber[number]; ok {
+		return header
 	}
-	return f.headersByNumber[number]
+	// Fallback to the canonical chain in the database for the blocks before
+	// the parent.
+	return rawdb.ReadHeader(f.db, rawdb.ReadCanonicalHash(f.db, number), number)
 }
 
 func (f *fakeDexonChain) StateAt(hash common.Hash) (*state.StateDB, error) {
@@ -308,6 +326,9 @@ func (f *fakeDexonChain) GetRoundHeight(round uint64) (uint64, bool) {
 	if round == 0 {
 		return 0, true
 	}
-	height, ok := f.roundHeight[round]
-	return height, ok
+	if height, ok := f.roundHeight[round]; ok {
+		return height, true
+	}
+	height := f.parentGov.RoundHeight(new(big.Int).SetUint64(round)).Uint64()
+	return height, height != 0
 }
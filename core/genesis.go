@@ -18,6 +18,7 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -33,6 +34,7 @@ import (
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/params"
@@ -459,6 +461,42 @@ func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	}
 }
 
+// DexconDeveloperGenesisBlock returns the 'gdex --dev' genesis block. The node
+// is the only node in the governance state and the faucet is pre-funded as the
+// owner of the governance contract.
+func DexconDeveloperGenesisBlock(faucet common.Address, node *ecdsa.PublicKey) *Genesis {
+	ether := big.NewInt(1e18)
+
+	// Override the testnet config with short rounds and a low gas price.
+	dexconConfig := *params.TestnetChainConfig.Dexcon
+	dexconConfig.Owner = faucet
+	dexconConfig.MinGasPrice = big.NewInt(params.GWei)
+	dexconConfig.RoundLength = 60
+
+	config := *params.TestnetChainConfig
+	config.ChainID = big.NewInt(1337)
+	config.DMoment = 0
+	config.Dexcon = &dexconConfig
+
+	return &Genesis{
+		Config:     &config,
+		GasLimit:   40000000,
+		Difficulty: big.NewInt(1),
+		Alloc: GenesisAlloc{
+			faucet: {
+				Balance: new(big.Int).Mul(big.NewInt(1e8), ether),
+				Staked:  new(big.Int),
+			},
+			crypto.PubkeyToAddress(*node): {
+				Balance:   new(big.Int).Mul(dexconConfig.MinStake, big.NewInt(2)),
+				Staked:    new(big.Int).Set(dexconConfig.MinStake),
+				PublicKey: crypto.FromECDSAPub(node),
+				NodeInfo:  NodeInfo{Name: "developer"},
+			},
+		},
+	}
+}
+
 func decodePrealloc(data string) GenesisAlloc {
 	type accountData struct {
 		Balance   *big.Int
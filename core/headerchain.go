@@ -28,7 +28,6 @@ import (
 	"time"
 
 	dexCore "github.com/dexon-foundation/dexon-consensus/core"
-	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 	lru "github.com/hashicorp/golang-lru"
 
@@ -478,12 +477,26 @@ func (hc *HeaderChain) ValidateDexonHeaderChain(chain []*types.HeaderWithGovStat
 		}
 	}
 
-	// If the last TSig pass the verification, we don't need to verify others.
-	cache := newHeaderVerifierCache(verifierCache, gov)
-	verifyTSig := false
-	if err := hc.verifyDexonHeader(chain[len(chain)-1].Header, gov, cache, true); err != nil {
-		verifyTSig = true
+	// Verify the headers against the consensus rules in parallel, with the
+	// governance states synced along with the headers.
+	if engine, ok := hc.engine.(*dexcon.Dexcon); ok {
+		headers := make([]*types.Header, len(chain))
+		seals := make([]bool, len(chain))
+		for i, header := range chain {
+			headers[i] = header.Header
+			seals[i] = true
+		}
+		abort, results := engine.WithGovStateFetcher(gov, verifierCache).
+			VerifyHeaders(hc, headers, seals)
+		defer close(abort)
+		for i := range headers {
+			if err := <-results; err != nil {
+				return i, err
+			}
+		}
 	}
+
+	cache := newHeaderVerifierCache(verifierCache, gov)
 	// Iterate over the headers and ensure they all check out
 	for i, header := range chain {
 		// If the chain is terminating, stop processing blocks
@@ -499,7 +512,7 @@ func (hc *HeaderChain) ValidateDexonHeaderChain(chain []*types.HeaderWithGovStat
 			}
 		}
 
-		if err := hc.verifyDexonHeader(header.Header, gov, cache, verifyTSig); err != nil {
+		if err := hc.verifyDexonHeader(header.Header, gov, cache); err != nil {
 			return i, err
 		}
 
@@ -540,7 +553,7 @@ func (hc *HeaderChain) VerifyDexonHeader(header *types.Header,
 		return consensus.ErrUnknownAncestor
 	}
 	cache := newHeaderVerifierCache(verifierCache, gov)
-	if err := hc.verifyDexonHeader(header, gov, cache, true); err != nil {
+	if err := hc.verifyDexonHeader(header, gov, cache); err != nil {
 		return err
 	}
 
@@ -567,7 +580,7 @@ func (hc *HeaderChain) VerifyDexonHeader(header *types.Header,
 
 func (hc *HeaderChain) verifyDexonHeader(header *types.Header,
 	gov dexcon.GovernanceStateFetcher,
-	cache *headerVerifierCache, verifyTSig bool) error {
+	cache *headerVerifierCache) error {
 
 	// If the header is a banned one, straight out abort
 	if BadHashes[header.Hash()] {
@@ -586,13 +599,6 @@ func (hc *HeaderChain) verifyDexonHeader(header *types.Header,
 			header.Number.Uint64(), err)
 	}
 
-	if verifyTSig {
-		if err := hc.verifyTSig(&coreBlock, cache.verifierCache); err != nil {
-			log.Debug("verify header sig fail, number=%d, err=%v",
-				header.Number.Uint64(), err)
-		}
-	}
-
 	if coreBlock.IsEmpty() {
 		if header.Coinbase != (common.Address{}) {
 			return fmt.Errorf("coinbase should be nil for empty block")
@@ -631,34 +637,6 @@ func (hc *HeaderChain) verifyDexonHeader(header *types.Header,
 	return nil
 }
 
-func (hc *HeaderChain) verifyTSig(coreBlock *coreTypes.Block,
-	verifierCache *dexCore.TSigVerifierCache) error {
-
-	round := coreBlock.Position.Round
-	randomness := coreBlock.Randomness
-
-	if round == 0 {
-		return nil
-	}
-
-	// Verify threshold signature
-	v, ok, err := verifierCache.UpdateAndGet(round)
-	if err != nil {
-		panic(err)
-	}
-
-	if !ok {
-		panic(fmt.Errorf("DKG of round %d is not finished", round))
-	}
-
-	if !v.VerifySignature(coreBlock.Hash, coreCrypto.Signature{
-		Type:      "bls",
-		Signature: randomness}) {
-		return fmt.Errorf("signature invalid")
-	}
-	return nil
-}
-
 // InsertDexonHeaderChain attempts to insert the given header chain in to the local
 // chain, possibly creating a reorg. If an error is returned, it will return the
 // index number of the failing header as well an error describing what went wrong.
@@ -127,6 +127,42 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// CheckpointMeta is the block the chain is bootstrapped from. The blocks
+// before it are missing, except the headers at the heights of the last rounds.
+type CheckpointMeta struct {
+	Hash        common.Hash
+	Number      uint64
+	Round       uint64
+	RoundHeight uint64        // Height of the first block of the round
+	Ancestors   []common.Hash // Hashes of the blocks before it within the witness window, the parent first
+}
+
+// ReadCheckpointMeta retrieves the checkpoint the chain is bootstrapped from,
+// nil if it's bootstrapped from the genesis.
+func ReadCheckpointMeta(db DatabaseReader) *CheckpointMeta {
+	data, _ := db.Get(checkpointKey)
+	if len(data) == 0 {
+		return nil
+	}
+	meta := new(CheckpointMeta)
+	if err := rlp.DecodeBytes(data, meta); err != nil {
+		log.Error("Invalid checkpoint metadata RLP", "err", err)
+		return nil
+	}
+	return meta
+}
+
+// WriteCheckpointMeta stores the checkpoint the chain is bootstrapped from.
+func WriteCheckpointMeta(db DatabaseWriter, meta *CheckpointMeta) {
+	data, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		log.Crit("Failed to RLP encode checkpoint metadata", "err", err)
+	}
+	if err := db.Put(checkpointKey, data); err != nil {
+		log.Crit("Failed to store checkpoint metadata", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
@@ -26,11 +26,46 @@ func WriteCoreDKGPrivateKeyRLP(db DatabaseWriter, round uint64, rlp rlp.RawValue
 	return err
 }
 
+func DeleteCoreDKGPrivateKey(db DatabaseDeleter, round uint64) {
+	if err := db.Delete(coreDKGPrivateKeyKey(round)); err != nil {
+		log.Crit("Failed to delete core DKG private key", "err", err, "round", round)
+	}
+}
+
 func ReadCoreDKGPrivateKey(db DatabaseReader, round, reset uint64) *coreDKG.PrivateKey {
 	data := ReadCoreDKGPrivateKeyRLP(db, round)
 	if len(data) == 0 {
 		return nil
 	}
+	return DecodeCoreDKGPrivateKey(data, round, reset)
+}
+
+func WriteCoreDKGPrivateKey(db DatabaseWriter, round, reset uint64, pk *coreDKG.PrivateKey) error {
+	data, err := EncodeCoreDKGPrivateKey(round, reset, pk)
+	if err != nil {
+		return err
+	}
+	return WriteCoreDKGPrivateKeyRLP(db, round, data)
+}
+
+// EncodeCoreDKGPrivateKey returns the RLP encoding of a DKG private key as it
+// is stored in database.
+func EncodeCoreDKGPrivateKey(round, reset uint64, pk *coreDKG.PrivateKey) (rlp.RawValue, error) {
+	key := &dkgPrivateKey{
+		PK:    pk,
+		Reset: reset,
+	}
+	data, err := rlp.EncodeToBytes(key)
+	if err != nil {
+		log.Crit("Failed to RLP encode core DKG private key", "round", round, "err", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// DecodeCoreDKGPrivateKey decodes a DKG private key encoded by
+// EncodeCoreDKGPrivateKey, nil is returned if the reset count mismatches.
+func DecodeCoreDKGPrivateKey(data rlp.RawValue, round, reset uint64) *coreDKG.PrivateKey {
 	key := &dkgPrivateKey{
 		PK: new(coreDKG.PrivateKey),
 	}
@@ -44,15 +79,17 @@ func ReadCoreDKGPrivateKey(db DatabaseReader, round, reset uint64) *coreDKG.Priv
 	return key.PK
 }
 
-func WriteCoreDKGPrivateKey(db DatabaseWriter, round, reset uint64, pk *coreDKG.PrivateKey) error {
-	key := &dkgPrivateKey{
-		PK:    pk,
-		Reset: reset,
-	}
-	data, err := rlp.EncodeToBytes(key)
+// ReadCoreDKGSealedPrivateKey retrieves the sealed DKG private key of a round.
+func ReadCoreDKGSealedPrivateKey(db DatabaseReader, round uint64) []byte {
+	data, _ := db.Get(coreDKGSealedPrivateKeyKey(round))
+	return data
+}
+
+// WriteCoreDKGSealedPrivateKey stores the sealed DKG private key of a round.
+func WriteCoreDKGSealedPrivateKey(db DatabaseWriter, round uint64, sealed []byte) error {
+	err := db.Put(coreDKGSealedPrivateKeyKey(round), sealed)
 	if err != nil {
-		log.Crit("Failed to RLP encode core DKG private key", "round", round, "err", err)
-		return err
+		log.Crit("Failed to store sealed core DKG private key", "err", err, "round", round)
 	}
-	return WriteCoreDKGPrivateKeyRLP(db, round, data)
+	return err
 }
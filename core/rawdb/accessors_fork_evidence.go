@@ -0,0 +1,51 @@
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// ForkEvidence is the evidence of a node forking votes or blocks. Type is the
+// fine type of the governance contract, Arg1 and Arg2 are the RLP encoded
+// votes or blocks passed to its report method.
+type ForkEvidence struct {
+	Type uint64
+	Arg1 []byte
+	Arg2 []byte
+}
+
+// HasForkEvidence checks if the fork evidence of a fine record hash exists.
+func HasForkEvidence(db DatabaseReader, hash common.Hash) bool {
+	if has, err := db.Has(forkEvidenceKey(hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadForkEvidence retrieves the fork evidence of a fine record hash.
+func ReadForkEvidence(db DatabaseReader, hash common.Hash) *ForkEvidence {
+	data, _ := db.Get(forkEvidenceKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	evidence := new(ForkEvidence)
+	if err := rlp.Decode(bytes.NewReader(data), evidence); err != nil {
+		log.Error("Invalid fork evidence RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return evidence
+}
+
+// WriteForkEvidence stores the fork evidence of a fine record hash.
+func WriteForkEvidence(db DatabaseWriter, hash common.Hash, evidence *ForkEvidence) {
+	data, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		log.Crit("Failed to RLP encode fork evidence", "err", err)
+	}
+	if err := db.Put(forkEvidenceKey(hash), data); err != nil {
+		log.Crit("Failed to store fork evidence", "err", err)
+	}
+}
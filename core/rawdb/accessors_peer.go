@@ -0,0 +1,43 @@
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// PeerBan is a peer banned for misbehaving. Until is the unix time the ban
+// expires at, zero if the ban is persistent. Count is the number of times the
+// peer is banned.
+type PeerBan struct {
+	ID     string
+	Until  uint64
+	Count  uint64
+	Reason string
+}
+
+// ReadPeerBans retrieves the banned peers.
+func ReadPeerBans(db DatabaseReader) []*PeerBan {
+	data, _ := db.Get(peerBansKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var bans []*PeerBan
+	if err := rlp.Decode(bytes.NewReader(data), &bans); err != nil {
+		log.Error("Invalid peer bans RLP", "err", err)
+		return nil
+	}
+	return bans
+}
+
+// WritePeerBans stores the banned peers.
+func WritePeerBans(db DatabaseWriter, bans []*PeerBan) {
+	data, err := rlp.EncodeToBytes(bans)
+	if err != nil {
+		log.Crit("Failed to RLP encode peer bans", "err", err)
+	}
+	if err := db.Put(peerBansKey, data); err != nil {
+		log.Crit("Failed to store peer bans", "err", err)
+	}
+}
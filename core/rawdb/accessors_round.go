@@ -0,0 +1,111 @@
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// RoundMeta is the metadata of a finalised round, indexed so it's available
+// without the governance state of the round.
+type RoundMeta struct {
+	Round          uint64
+	Height         uint64 // Height of the first block of the round
+	CRS            common.Hash
+	DKGResetCount  uint64
+	NotarySet      [][]byte // Public keys of the notary set members, sorted
+	GroupPublicKey []byte   // DKG group public key, empty if there's none
+}
+
+// HasRoundMeta checks if the metadata of a round is indexed.
+func HasRoundMeta(db DatabaseReader, round uint64) bool {
+	if has, err := db.Has(roundMetaKey(round)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadRoundMeta retrieves the metadata of a round.
+func ReadRoundMeta(db DatabaseReader, round uint64) *RoundMeta {
+	data, _ := db.Get(roundMetaKey(round))
+	if len(data) == 0 {
+		return nil
+	}
+	meta := new(RoundMeta)
+	if err := rlp.Decode(bytes.NewReader(data), meta); err != nil {
+		log.Error("Invalid round metadata RLP", "round", round, "err", err)
+		return nil
+	}
+	return meta
+}
+
+// WriteRoundMeta stores the metadata of a round.
+func WriteRoundMeta(db DatabaseWriter, meta *RoundMeta) {
+	data, err := rlp.EncodeToBytes(meta)
+	if err != nil {
+		log.Crit("Failed to RLP encode round metadata", "err", err)
+	}
+	if err := db.Put(roundMetaKey(meta.Round), data); err != nil {
+		log.Crit("Failed to store round metadata", "err", err)
+	}
+}
+
+// DeleteRoundMeta removes the metadata of a round.
+func DeleteRoundMeta(db DatabaseDeleter, round uint64) {
+	if err := db.Delete(roundMetaKey(round)); err != nil {
+		log.Crit("Failed to delete round metadata", "err", err)
+	}
+}
+
+// ProposerStats is the statistics of the blocks proposed by a node in a round.
+type ProposerStats struct {
+	Proposer common.Address // Node key address of the proposer
+	Blocks   uint64
+	GasUsed  uint64
+	Reward   *big.Int
+}
+
+// RoundStats is the block proposing statistics of a round. Empty blocks are
+// delivered when no proposed block is agreed on, they have no proposer.
+type RoundStats struct {
+	Round       uint64
+	Blocks      uint64 // Number of blocks including the empty ones
+	EmptyBlocks uint64
+	Proposers   []*ProposerStats // Sorted by proposer
+}
+
+// HasRoundStats checks if the proposer statistics of a round are stored.
+func HasRoundStats(db DatabaseReader, round uint64) bool {
+	if has, err := db.Has(roundStatsKey(round)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadRoundStats retrieves the proposer statistics of a round.
+func ReadRoundStats(db DatabaseReader, round uint64) *RoundStats {
+	data, _ := db.Get(roundStatsKey(round))
+	if len(data) == 0 {
+		return nil
+	}
+	stats := new(RoundStats)
+	if err := rlp.Decode(bytes.NewReader(data), stats); err != nil {
+		log.Error("Invalid round stats RLP", "round", round, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// WriteRoundStats stores the proposer statistics of a round.
+func WriteRoundStats(db DatabaseWriter, stats *RoundStats) {
+	data, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		log.Crit("Failed to RLP encode round stats", "err", err)
+	}
+	if err := db.Put(roundStatsKey(stats.Round), data); err != nil {
+		log.Crit("Failed to store round stats", "err", err)
+	}
+}
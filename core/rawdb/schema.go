@@ -41,6 +41,9 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// checkpointKey tracks the checkpoint the chain is bootstrapped from.
+	checkpointKey = []byte("Checkpoint")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -55,10 +58,19 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
-	coreBlockPrefix           = []byte("D")
-	coreDKGPrivateKeyPrefix   = []byte("DPK")
-	coreCompactionChainTipKey = []byte("CoreChainTip")
-	coreDKGProtocolKey        = []byte("CoreDKGProtocol")
+	coreBlockPrefix               = []byte("D")
+	coreDKGPrivateKeyPrefix       = []byte("DPK")
+	coreDKGSealedPrivateKeyPrefix = []byte("DSK")
+	coreCompactionChainTipKey     = []byte("CoreChainTip")
+	coreDKGProtocolKey            = []byte("CoreDKGProtocol")
+
+	forkEvidencePrefix = []byte("F") // forkEvidencePrefix + fine record hash -> fork evidence
+
+	// peerBansKey tracks the peers banned by the protocol manager.
+	peerBansKey = []byte("PeerBans")
+
+	roundMetaPrefix  = []byte("R")  // roundMetaPrefix + round (uint64 big endian) -> round metadata
+	roundStatsPrefix = []byte("RS") // roundStatsPrefix + round (uint64 big endian) -> round proposer statistics
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -124,6 +136,21 @@ func govStateKey(hash common.Hash) []byte {
 	return append(govStatePrefix, hash.Bytes()...)
 }
 
+// forkEvidenceKey = forkEvidencePrefix + fine record hash
+func forkEvidenceKey(hash common.Hash) []byte {
+	return append(forkEvidencePrefix, hash.Bytes()...)
+}
+
+// roundMetaKey = roundMetaPrefix + round (uint64 big endian)
+func roundMetaKey(round uint64) []byte {
+	return append(roundMetaPrefix, encodeBlockNumber(round)...)
+}
+
+// roundStatsKey = roundStatsPrefix + round (uint64 big endian)
+func roundStatsKey(round uint64) []byte {
+	return append(roundStatsPrefix, encodeBlockNumber(round)...)
+}
+
 // coreBlockKey = coreBlockPrefix + hash
 func coreBlockKey(hash common.Hash) []byte {
 	return append(coreBlockPrefix, hash.Bytes()...)
@@ -137,6 +164,14 @@ func coreDKGPrivateKeyKey(round uint64) []byte {
 	return ret
 }
 
+// coreDKGSealedPrivateKeyKey = coreDKGSealedPrivateKeyPrefix + round
+func coreDKGSealedPrivateKeyKey(round uint64) []byte {
+	ret := make([]byte, len(coreDKGSealedPrivateKeyPrefix)+8)
+	copy(ret, coreDKGSealedPrivateKeyPrefix)
+	binary.LittleEndian.PutUint64(ret[len(coreDKGSealedPrivateKeyPrefix):], round)
+	return ret
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
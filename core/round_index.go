@@ -0,0 +1,177 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// roundIndexChanSize is the size of channel listening to finalised rounds.
+const roundIndexChanSize = 16
+
+var errRoundStatesMissing = errors.New("round states missing")
+
+// GetRoundMeta returns the indexed metadata of a finalised round, nil if the
+// round is not finalised or not indexed yet.
+func (bc *BlockChain) GetRoundMeta(round uint64) *rawdb.RoundMeta {
+	return rawdb.ReadRoundMeta(bc.db, round)
+}
+
+// roundIndexLoop indexes the metadata and proposer statistics of the rounds
+// as they are finalised, a round is finalised once the first block of the next
+// round is written. The rounds finalised before are backfilled on start.
+func (bc *BlockChain) roundIndexLoop() {
+	defer bc.wg.Done()
+
+	// The blocks of the rounds up to the checkpoint the chain is bootstrapped
+	// from are missing.
+	next := uint64(0)
+	if cp := bc.bootstrap; cp != nil {
+		next = cp.Round + 1
+	}
+	index := func(end uint64) {
+		for ; next < end; next++ {
+			select {
+			case <-bc.quit:
+				return
+			default:
+			}
+			if err := bc.indexRound(next); err != nil {
+				log.Warn("Failed to index round", "round", next, "err", err)
+				return
+			}
+		}
+	}
+	index(bc.CurrentBlock().Round())
+
+	for {
+		select {
+		case round := <-bc.roundIndexCh:
+			index(round)
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// indexRound stores the metadata and the proposer statistics of a finalised
+// round if they are missing.
+func (bc *BlockChain) indexRound(round uint64) error {
+	if !rawdb.HasRoundMeta(bc.db, round) {
+		meta, err := bc.roundMeta(round)
+		switch err {
+		case nil:
+			rawdb.WriteRoundMeta(bc.db, meta)
+			log.Debug("Indexed round", "round", round, "height", meta.Height)
+		case errRoundStatesMissing:
+			log.Debug("Round states missing, metadata not indexed", "round", round)
+		default:
+			return err
+		}
+	}
+	if !rawdb.HasRoundStats(bc.db, round) {
+		stats, err := bc.roundStats(round, bc.CurrentBlock())
+		if err != nil {
+			return err
+		}
+		rawdb.WriteRoundStats(bc.db, stats)
+		log.Debug("Indexed round stats", "round", round, "blocks", stats.Blocks)
+	}
+	return nil
+}
+
+// roundMeta collects the metadata of a finalised round from the governance
+// states stored at the round heights, so it's available even if the states of
+// the blocks are pruned.
+func (bc *BlockChain) roundMeta(round uint64) (*rawdb.RoundMeta, error) {
+	height := bc.gov.GetRoundHeight(round)
+	if round != 0 && height == 0 {
+		return nil, fmt.Errorf("round height not found")
+	}
+	db, err := bc.roundStateDB(round)
+	if err != nil {
+		return nil, err
+	}
+	return newRoundMeta(NewGovernance(db), round, height)
+}
+
+// roundStateDB rebuilds the governance states the metadata of a round is
+// collected from, the state at the height of the round is the head. They are
+// missing before the pivot of a fast sync or the checkpoint the chain is
+// bootstrapped from.
+func (bc *BlockChain) roundStateDB(round uint64) (*checkpointStateDB, error) {
+	from := uint64(0)
+	if round > dexCore.ConfigRoundShift {
+		from = round - dexCore.ConfigRoundShift
+	}
+	db := &checkpointStateDB{states: make(map[uint64]*state.StateDB)}
+	for r := from; r <= round; r++ {
+		height := bc.gov.GetRoundHeight(r)
+		header := bc.GetHeaderByNumber(height)
+		if header == nil {
+			return nil, errRoundStatesMissing
+		}
+		govState, err := bc.GetGovStateByHash(header.Hash())
+		if err != nil {
+			return nil, errRoundStatesMissing
+		}
+		s, err := state.NewGovStateDB(govState, vm.GovernanceContractAddress)
+		if err != nil {
+			return nil, err
+		}
+		db.states[height] = s
+		db.head = s
+	}
+	return db, nil
+}
+
+// newRoundMeta collects the metadata of the round starting at height from
+// the governance.
+func newRoundMeta(gov *Governance, round, height uint64) (*rawdb.RoundMeta, error) {
+	notarySet, err := gov.NotarySet(round)
+	if err != nil {
+		return nil, err
+	}
+	meta := &rawdb.RoundMeta{
+		Round:         round,
+		Height:        height,
+		CRS:           common.Hash(gov.CRS(round)),
+		DKGResetCount: gov.DKGResetCount(round),
+		NotarySet:     make([][]byte, 0, len(notarySet)),
+	}
+	for key := range notarySet {
+		pk, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		meta.NotarySet = append(meta.NotarySet, pk)
+	}
+	sort.Slice(meta.NotarySet, func(i, j int) bool {
+		return bytes.Compare(meta.NotarySet[i], meta.NotarySet[j]) < 0
+	})
+
+	if gov.IsDKGFinal(round) {
+		gpk, err := dkgTypes.NewGroupPublicKey(round,
+			gov.DKGMasterPublicKeys(round), gov.DKGComplaints(round),
+			coreUtils.GetDKGThreshold(gov.Configuration(round)))
+		if err != nil {
+			log.Warn("Failed to recover group public key", "round", round,
+				"err", err)
+		} else {
+			meta.GroupPublicKey = gpk.GroupPublicKey.Bytes()
+		}
+	}
+	return meta, nil
+}
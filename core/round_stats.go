@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// GetRoundStats returns the proposer statistics of a round. The statistics of
+// the current round cover the blocks up to the head, and are computed on each
+// call like the ones of a finalised round not indexed yet.
+func (bc *BlockChain) GetRoundStats(round uint64) (*rawdb.RoundStats, error) {
+	if stats := rawdb.ReadRoundStats(bc.db, round); stats != nil {
+		return stats, nil
+	}
+	head := bc.CurrentBlock()
+	if round > head.Round() {
+		return nil, fmt.Errorf("round %d not started", round)
+	}
+	return bc.roundStats(round, head)
+}
+
+// roundStats computes the proposer statistics of a round with the given
+// head.
+func (bc *BlockChain) roundStats(round uint64,
+	head *types.Block) (*rawdb.RoundStats, error) {
+	from := bc.gov.GetRoundHeight(round)
+	if round != 0 && from == 0 {
+		return nil, fmt.Errorf("round height not found")
+	}
+	to := head.NumberU64()
+	if round < head.Round() {
+		next := bc.gov.GetRoundHeight(round + 1)
+		if next == 0 {
+			return nil, fmt.Errorf("round height not found")
+		}
+		to = next - 1
+	}
+	return ComputeRoundStats(bc.db, round, from, to)
+}
+
+// ComputeRoundStats derives the proposer statistics of a round from the
+// DexconMeta of the canonical blocks between from and to, both inclusive.
+func ComputeRoundStats(db rawdb.DatabaseReader, round, from,
+	to uint64) (*rawdb.RoundStats, error) {
+	// The genesis block is not proposed.
+	if from == 0 {
+		from = 1
+	}
+	stats := &rawdb.RoundStats{Round: round}
+	proposers := make(map[common.Address]*rawdb.ProposerStats)
+	for number := from; number <= to; number++ {
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		if header == nil {
+			return nil, fmt.Errorf("header %d not found", number)
+		}
+		var block coreTypes.Block
+		if err := rlp.DecodeBytes(header.DexconMeta, &block); err != nil {
+			return nil, fmt.Errorf("header %d: %v", number, err)
+		}
+		stats.Blocks++
+		if block.IsEmpty() {
+			stats.EmptyBlocks++
+			continue
+		}
+		addr := vm.IdToAddress(block.ProposerID)
+		proposer, ok := proposers[addr]
+		if !ok {
+			proposer = &rawdb.ProposerStats{Proposer: addr, Reward: new(big.Int)}
+			proposers[addr] = proposer
+			stats.Proposers = append(stats.Proposers, proposer)
+		}
+		proposer.Blocks++
+		proposer.GasUsed += header.GasUsed
+		if header.Reward != nil {
+			proposer.Reward.Add(proposer.Reward, header.Reward)
+		}
+	}
+	sort.Slice(stats.Proposers, func(i, j int) bool {
+		return bytes.Compare(stats.Proposers[i].Proposer[:],
+			stats.Proposers[j].Proposer[:]) < 0
+	})
+	return stats, nil
+}
@@ -1,8 +1,15 @@
 package state
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/dexon-foundation/dexon/trie"
 )
 
@@ -29,3 +36,160 @@ func GetGovState(statedb *StateDB, header *types.Header,
 	}
 	return govState, nil
 }
+
+// GetGovStorageTrie returns the governance contract's storage trie keyed by
+// the hashed keys, so the slots can be proved as they are stored.
+func GetGovStorageTrie(statedb *StateDB, addr common.Address) (*trie.Trie, error) {
+	t := statedb.StorageTrie(addr)
+	if t == nil {
+		return trie.New(common.Hash{}, statedb.Database().TrieDB())
+	}
+	return trie.New(t.Hash(), statedb.Database().TrieDB())
+}
+
+// NewGovStorageDatabase rebuilds the governance contract's storage of the
+// governance state in a memory trie database, and returns the storage root.
+func NewGovStorageDatabase(govState *types.GovState) (common.Hash,
+	*trie.Database, error) {
+	triedb := trie.NewDatabase(ethdb.NewMemDatabase())
+	t, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	for _, kv := range govState.Storage {
+		if err := t.TryUpdate(kv[0], kv[1]); err != nil {
+			return common.Hash{}, nil, err
+		}
+	}
+	root, err := t.Commit(nil)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return root, triedb, nil
+}
+
+// NewGovStateDB rebuilds the state of the governance contract of the
+// governance state in memory. The account is verified against the state root
+// with the proof, and the storage against the storage root of the account.
+func NewGovStateDB(govState *types.GovState, addr common.Address) (*StateDB, error) {
+	db := ethdb.NewMemDatabase()
+	for _, node := range govState.Proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	data, _, err := trie.VerifyProof(govState.Root, crypto.Keccak256(addr.Bytes()), db)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("account %x not found", addr)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(data, &account); err != nil {
+		return nil, err
+	}
+
+	triedb := trie.NewDatabase(db)
+	t, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range govState.Storage {
+		if err := t.TryUpdate(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+	}
+	root, err := t.Commit(nil)
+	if err != nil {
+		return nil, err
+	}
+	if root != account.Root {
+		return nil, fmt.Errorf("storage root mismatch: have %x, want %x",
+			root, account.Root)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, err
+	}
+	return New(govState.Root, NewDatabase(db))
+}
+
+// GetGovStateSlice extracts at most amount slots of the governance contract's
+// storage starting from the hashed key origin, with the merkle proofs of the
+// slots against the storage root. The slots changed from the base storage are
+// extracted if base is not nil, the deleted slots have empty values.
+func GetGovStateSlice(header *types.Header, proof [][]byte, storage,
+	base *trie.Trie, origin []byte, amount int) (*types.GovStateSlice, error) {
+	var keys [][]byte
+	if base == nil {
+		keys = leafKeys(storage.NodeIterator(origin), amount+1)
+	} else {
+		// The first amount+1 changed keys are within the first amount+1
+		// keys of both differences.
+		changed, _ := trie.NewDifferenceIterator(
+			base.NodeIterator(origin), storage.NodeIterator(origin))
+		deleted, _ := trie.NewDifferenceIterator(
+			storage.NodeIterator(origin), base.NodeIterator(origin))
+		keys = append(leafKeys(changed, amount+1), leafKeys(deleted, amount+1)...)
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+		unique := keys[:0]
+		for i, key := range keys {
+			if i == 0 || !bytes.Equal(key, keys[i-1]) {
+				unique = append(unique, key)
+			}
+		}
+		keys = unique
+	}
+
+	slice := &types.GovStateSlice{
+		BlockHash: header.Hash(),
+		Number:    header.Number,
+		Root:      header.Root,
+		Proof:     proof,
+	}
+	if len(keys) > amount {
+		slice.Next = keys[amount]
+		keys = keys[:amount]
+	}
+	nodes := newProofSet()
+	for _, key := range keys {
+		value, err := storage.TryGet(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := storage.Prove(key, 0, nodes); err != nil {
+			return nil, err
+		}
+		slice.Storage = append(slice.Storage, [2][]byte{key, value})
+	}
+	slice.StorageProof = nodes.list
+	return slice, nil
+}
+
+// leafKeys returns the keys of at most n leaves of the node iterator.
+func leafKeys(it trie.NodeIterator, n int) [][]byte {
+	var keys [][]byte
+	for iter := trie.NewIterator(it); len(keys) < n && iter.Next(); {
+		keys = append(keys, common.CopyBytes(iter.Key))
+	}
+	return keys
+}
+
+// proofSet collects the distinct nodes of merkle proofs.
+type proofSet struct {
+	nodes map[string]struct{}
+	list  [][]byte
+}
+
+func newProofSet() *proofSet {
+	return &proofSet{nodes: make(map[string]struct{})}
+}
+
+func (s *proofSet) Put(key []byte, value []byte) error {
+	if _, ok := s.nodes[string(key)]; ok {
+		return nil
+	}
+	s.nodes[string(key)] = struct{}{}
+	s.list = append(s.list, common.CopyBytes(value))
+	return nil
+}
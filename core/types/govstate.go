@@ -18,3 +18,18 @@ type HeaderWithGovState struct {
 	*Header
 	GovState *GovState `rlp:"nil"`
 }
+
+// GovStateSlice is a part of the governance contract's storage at a block.
+// The slots are either a range of the storage, or the slots changed from the
+// storage at the base block, and are proved against the storage root of the
+// account in Proof.
+type GovStateSlice struct {
+	BlockHash    common.Hash
+	Number       *big.Int
+	Root         common.Hash
+	Base         common.Hash // Block hash of the base state, zero for a range
+	Proof        [][]byte    // Merkle proof of the governance contract account
+	Storage      [][2][]byte // Hashed keys and values, deleted slots have empty values
+	StorageProof [][]byte    // Merkle proof nodes of the slots
+	Next         []byte      // Key the next slice starts from, empty for the last slice
+}
@@ -57,6 +57,7 @@ type OracleContractABI struct {
 	Name2Method map[string]abi.Method
 	Sig2Method  map[string]abi.Method
 	Events      map[string]abi.Event
+	Topic2Event map[common.Hash]abi.Event
 }
 
 // NewOracleContractABI parse the ABI.
@@ -75,8 +76,10 @@ func NewOracleContractABI(abiDefinition string) *OracleContractABI {
 	}
 
 	events := make(map[string]abi.Event)
+	topic2Event := make(map[common.Hash]abi.Event)
 	for _, event := range abiObject.Events {
 		events[event.Name] = event
+		topic2Event[event.Id()] = event
 	}
 
 	return &OracleContractABI{
@@ -84,5 +87,6 @@ func NewOracleContractABI(abiDefinition string) *OracleContractABI {
 		Name2Method: name2Method,
 		Sig2Method:  sig2Method,
 		Events:      events,
+		Topic2Event: topic2Event,
 	}
 }
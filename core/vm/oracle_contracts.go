@@ -57,6 +57,14 @@ const (
 
 const GovernanceActionGasCost = 200000
 
+// CommissionRateBase is the denominator of node commission rates, i.e. rates
+// are expressed in basis points.
+const CommissionRateBase = 10000
+
+// rewardsPerShareBase is the denominator of the rewards accumulated per
+// delegation share.
+const rewardsPerShareBase = 1000000000000000000
+
 // Storage position enums.
 const (
 	roundHeightLoc = iota
@@ -97,6 +105,13 @@ const (
 	minBlockIntervalLoc
 	fineValuesLoc
 	finedRecordsLoc
+	delegatorsLoc
+	delegatorsOffsetLoc
+	totalDelegatedLoc
+	commissionRatesLoc
+	pendingRewardsLoc
+	totalDelegationSharesLoc
+	rewardsPerShareLoc
 )
 
 func publicKeyToNodeKeyAddress(pkBytes []byte) (common.Address, error) {
@@ -943,6 +958,222 @@ func (s *GovernanceState) SetFineRecords(recordHash Bytes32, status bool) {
 	s.setStateBigInt(loc, big.NewInt(value))
 }
 
+// struct Delegator {
+//     address owner;
+//     uint256 shares;
+//     uint256 undelegated;
+//     uint256 undelegatedAt;
+//     uint256 rewardDebt;
+// }
+//
+// mapping(address => Delegator[]) public delegators;
+
+// delegatorInfo is a delegation to a node. The delegation is held in shares of
+// the total delegated value of the node, so slashing the delegators only
+// lowers the total delegated value. RewardDebt is the part of the rewards
+// accumulated per share of the node already settled to the delegator.
+type delegatorInfo struct {
+	Owner         common.Address
+	Shares        *big.Int
+	Undelegated   *big.Int
+	UndelegatedAt *big.Int
+	RewardDebt    *big.Int
+}
+
+const delegatorStructSize = 5
+
+func (s *GovernanceState) LenDelegators(nodeAddr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(delegatorsLoc), nodeAddr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) Delegator(nodeAddr common.Address, index *big.Int) *delegatorInfo {
+	delegator := new(delegatorInfo)
+
+	arrayBaseLoc := s.getSlotLoc(s.getMapLoc(big.NewInt(delegatorsLoc), nodeAddr.Bytes()))
+	elementBaseLoc := new(big.Int).Add(arrayBaseLoc,
+		new(big.Int).Mul(index, big.NewInt(delegatorStructSize)))
+
+	// Owner.
+	loc := elementBaseLoc
+	delegator.Owner = common.BytesToAddress(s.getState(common.BigToHash(loc)).Bytes())
+
+	// Shares.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(1))
+	delegator.Shares = s.getStateBigInt(loc)
+
+	// Undelegated.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(2))
+	delegator.Undelegated = s.getStateBigInt(loc)
+
+	// UndelegatedAt.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(3))
+	delegator.UndelegatedAt = s.getStateBigInt(loc)
+
+	// RewardDebt.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(4))
+	delegator.RewardDebt = s.getStateBigInt(loc)
+
+	return delegator
+}
+func (s *GovernanceState) PushDelegator(nodeAddr common.Address, d *delegatorInfo) {
+	// Increase length by 1.
+	arrayLength := s.LenDelegators(nodeAddr)
+	loc := s.getMapLoc(big.NewInt(delegatorsLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(arrayLength, big.NewInt(1)))
+
+	s.UpdateDelegator(nodeAddr, arrayLength, d)
+}
+func (s *GovernanceState) UpdateDelegator(nodeAddr common.Address, index *big.Int, d *delegatorInfo) {
+	arrayBaseLoc := s.getSlotLoc(s.getMapLoc(big.NewInt(delegatorsLoc), nodeAddr.Bytes()))
+	elementBaseLoc := new(big.Int).Add(arrayBaseLoc,
+		new(big.Int).Mul(index, big.NewInt(delegatorStructSize)))
+
+	// Owner.
+	loc := elementBaseLoc
+	s.setState(common.BigToHash(loc), d.Owner.Hash())
+
+	// Shares.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(1))
+	s.setStateBigInt(loc, d.Shares)
+
+	// Undelegated.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(2))
+	s.setStateBigInt(loc, d.Undelegated)
+
+	// UndelegatedAt.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(3))
+	s.setStateBigInt(loc, d.UndelegatedAt)
+
+	// RewardDebt.
+	loc = new(big.Int).Add(elementBaseLoc, big.NewInt(4))
+	s.setStateBigInt(loc, d.RewardDebt)
+}
+func (s *GovernanceState) PopLastDelegator(nodeAddr common.Address) {
+	// Decrease length by 1.
+	arrayLength := s.LenDelegators(nodeAddr)
+	newArrayLength := new(big.Int).Sub(arrayLength, big.NewInt(1))
+	loc := s.getMapLoc(big.NewInt(delegatorsLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, newArrayLength)
+
+	s.UpdateDelegator(nodeAddr, newArrayLength, &delegatorInfo{
+		Shares:        big.NewInt(0),
+		Undelegated:   big.NewInt(0),
+		UndelegatedAt: big.NewInt(0),
+		RewardDebt:    big.NewInt(0),
+	})
+}
+func (s *GovernanceState) Delegators(nodeAddr common.Address) []*delegatorInfo {
+	var delegators []*delegatorInfo
+	for i := int64(0); i < int64(s.LenDelegators(nodeAddr).Uint64()); i++ {
+		delegators = append(delegators, s.Delegator(nodeAddr, big.NewInt(i)))
+	}
+	return delegators
+}
+
+// mapping(address => mapping(address => uint256)) delegatorsOffset;
+func (s *GovernanceState) DelegatorsOffset(nodeAddr, delegatorAddr common.Address) *big.Int {
+	loc := s.getMapLoc(s.getMapLoc(big.NewInt(delegatorsOffsetLoc), nodeAddr.Bytes()), delegatorAddr.Bytes())
+	return new(big.Int).Sub(s.getStateBigInt(loc), big.NewInt(1))
+}
+func (s *GovernanceState) PutDelegatorsOffset(nodeAddr, delegatorAddr common.Address, offset *big.Int) {
+	loc := s.getMapLoc(s.getMapLoc(big.NewInt(delegatorsOffsetLoc), nodeAddr.Bytes()), delegatorAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(offset, big.NewInt(1)))
+}
+func (s *GovernanceState) DeleteDelegatorsOffset(nodeAddr, delegatorAddr common.Address) {
+	loc := s.getMapLoc(s.getMapLoc(big.NewInt(delegatorsOffsetLoc), nodeAddr.Bytes()), delegatorAddr.Bytes())
+	s.setStateBigInt(loc, big.NewInt(0))
+}
+
+// mapping(address => uint256) public totalDelegated;
+func (s *GovernanceState) TotalDelegated(nodeAddr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(totalDelegatedLoc), nodeAddr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) IncTotalDelegated(nodeAddr common.Address, amount *big.Int) {
+	loc := s.getMapLoc(big.NewInt(totalDelegatedLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(s.getStateBigInt(loc), amount))
+}
+func (s *GovernanceState) DecTotalDelegated(nodeAddr common.Address, amount *big.Int) {
+	loc := s.getMapLoc(big.NewInt(totalDelegatedLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Sub(s.getStateBigInt(loc), amount))
+}
+
+// mapping(address => uint256) public totalDelegationShares;
+func (s *GovernanceState) TotalDelegationShares(nodeAddr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(totalDelegationSharesLoc), nodeAddr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) IncTotalDelegationShares(nodeAddr common.Address, shares *big.Int) {
+	loc := s.getMapLoc(big.NewInt(totalDelegationSharesLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(s.getStateBigInt(loc), shares))
+}
+func (s *GovernanceState) DecTotalDelegationShares(nodeAddr common.Address, shares *big.Int) {
+	loc := s.getMapLoc(big.NewInt(totalDelegationSharesLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Sub(s.getStateBigInt(loc), shares))
+}
+
+// DelegationValue returns the value of the delegation shares to the node.
+func (s *GovernanceState) DelegationValue(nodeAddr common.Address, shares *big.Int) *big.Int {
+	totalShares := s.TotalDelegationShares(nodeAddr)
+	if totalShares.Cmp(big.NewInt(0)) == 0 {
+		return big.NewInt(0)
+	}
+	value := new(big.Int).Mul(shares, s.TotalDelegated(nodeAddr))
+	return value.Div(value, totalShares)
+}
+
+// mapping(address => uint256) public rewardsPerShare;
+func (s *GovernanceState) RewardsPerShare(nodeAddr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(rewardsPerShareLoc), nodeAddr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) IncRewardsPerShare(nodeAddr common.Address, amount *big.Int) {
+	loc := s.getMapLoc(big.NewInt(rewardsPerShareLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(s.getStateBigInt(loc), amount))
+}
+
+// RewardDebt returns the rewards accumulated by the delegation shares to the
+// node since it started taking delegation.
+func (s *GovernanceState) RewardDebt(nodeAddr common.Address, shares *big.Int) *big.Int {
+	debt := new(big.Int).Mul(shares, s.RewardsPerShare(nodeAddr))
+	return debt.Div(debt, big.NewInt(rewardsPerShareBase))
+}
+
+// SettleDelegatorRewards moves the rewards accumulated by the delegation since
+// it was last settled to the pending rewards of the delegator. It should be
+// called before the shares of the delegation change.
+func (s *GovernanceState) SettleDelegatorRewards(nodeAddr common.Address, d *delegatorInfo) {
+	debt := s.RewardDebt(nodeAddr, d.Shares)
+	if pending := new(big.Int).Sub(debt, d.RewardDebt); pending.Cmp(big.NewInt(0)) > 0 {
+		s.IncPendingRewards(d.Owner, pending)
+	}
+	d.RewardDebt = debt
+}
+
+// mapping(address => uint256) public commissionRates;
+func (s *GovernanceState) CommissionRate(nodeAddr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(commissionRatesLoc), nodeAddr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) SetCommissionRate(nodeAddr common.Address, rate *big.Int) {
+	loc := s.getMapLoc(big.NewInt(commissionRatesLoc), nodeAddr.Bytes())
+	s.setStateBigInt(loc, rate)
+}
+
+// mapping(address => uint256) public pendingRewards;
+func (s *GovernanceState) PendingRewards(addr common.Address) *big.Int {
+	loc := s.getMapLoc(big.NewInt(pendingRewardsLoc), addr.Bytes())
+	return s.getStateBigInt(loc)
+}
+func (s *GovernanceState) IncPendingRewards(addr common.Address, amount *big.Int) {
+	loc := s.getMapLoc(big.NewInt(pendingRewardsLoc), addr.Bytes())
+	s.setStateBigInt(loc, new(big.Int).Add(s.getStateBigInt(loc), amount))
+}
+func (s *GovernanceState) ResetPendingRewards(addr common.Address) {
+	loc := s.getMapLoc(big.NewInt(pendingRewardsLoc), addr.Bytes())
+	s.setStateBigInt(loc, big.NewInt(0))
+}
+
 // Initialize initializes governance contract state.
 func (s *GovernanceState) Initialize(config *params.DexconConfig, totalSupply *big.Int) {
 	if config.NextHalvingSupply.Cmp(totalSupply) <= 0 {
@@ -1019,6 +1250,42 @@ func (s *GovernanceState) Disqualify(n *nodeInfo) error {
 	return nil
 }
 
+// DistributeReward splits the block reward of a node between its owner and
+// its delegators. The node owner takes the commission and the rest is shared
+// in proportion to the stake. The share of the delegators is accumulated per
+// delegation share and settled to each delegator on claim or when its shares
+// change. The reward itself should be held by the governance contract until
+// claimed.
+func (s *GovernanceState) DistributeReward(nodeAddr common.Address, reward *big.Int) {
+	offset := s.NodesOffsetByAddress(nodeAddr)
+	if offset.Cmp(big.NewInt(0)) < 0 {
+		s.IncPendingRewards(nodeAddr, reward)
+		return
+	}
+	node := s.Node(offset)
+	totalShares := s.TotalDelegationShares(nodeAddr)
+	if node.Staked.Cmp(big.NewInt(0)) == 0 || totalShares.Cmp(big.NewInt(0)) == 0 {
+		s.IncPendingRewards(nodeAddr, reward)
+		return
+	}
+
+	commission := new(big.Int).Mul(reward, s.CommissionRate(nodeAddr))
+	commission.Div(commission, big.NewInt(CommissionRateBase))
+	shared := new(big.Int).Sub(reward, commission)
+
+	delegated := new(big.Int).Mul(shared, s.TotalDelegated(nodeAddr))
+	delegated.Div(delegated, node.Staked)
+	perShare := new(big.Int).Mul(delegated, big.NewInt(rewardsPerShareBase))
+	perShare.Div(perShare, totalShares)
+	s.IncRewardsPerShare(nodeAddr, perShare)
+
+	distributed := new(big.Int).Mul(perShare, totalShares)
+	distributed.Div(distributed, big.NewInt(rewardsPerShareBase))
+
+	// Node owner takes the commission, its own share and the rounding error.
+	s.IncPendingRewards(nodeAddr, new(big.Int).Sub(reward, distributed))
+}
+
 const decimalMultiplier = 100000000.0
 
 // Configuration returns the current configuration.
@@ -1237,6 +1504,60 @@ func (s *GovernanceState) emitFinePaid(nodeAddr common.Address, amount *big.Int)
 	})
 }
 
+// event Delegated(address indexed NodeAddress, address indexed DelegatorAddress, uint256 Amount);
+func (s *GovernanceState) emitDelegated(nodeAddr, delegatorAddr common.Address, amount *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["Delegated"].Id(), nodeAddr.Hash(), delegatorAddr.Hash()},
+		Data:    common.BigToHash(amount).Bytes(),
+	})
+}
+
+// event Undelegated(address indexed NodeAddress, address indexed DelegatorAddress, uint256 Amount);
+func (s *GovernanceState) emitUndelegated(nodeAddr, delegatorAddr common.Address, amount *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["Undelegated"].Id(), nodeAddr.Hash(), delegatorAddr.Hash()},
+		Data:    common.BigToHash(amount).Bytes(),
+	})
+}
+
+// event DelegationWithdrawn(address indexed NodeAddress, address indexed DelegatorAddress, uint256 Amount);
+func (s *GovernanceState) emitDelegationWithdrawn(nodeAddr, delegatorAddr common.Address, amount *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["DelegationWithdrawn"].Id(), nodeAddr.Hash(), delegatorAddr.Hash()},
+		Data:    common.BigToHash(amount).Bytes(),
+	})
+}
+
+// event DelegatorsFined(address indexed NodeAddress, uint256 Amount);
+func (s *GovernanceState) emitDelegatorsFined(nodeAddr common.Address, amount *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["DelegatorsFined"].Id(), nodeAddr.Hash()},
+		Data:    common.BigToHash(amount).Bytes(),
+	})
+}
+
+// event CommissionRateChanged(address indexed NodeAddress, uint256 Rate);
+func (s *GovernanceState) emitCommissionRateChanged(nodeAddr common.Address, rate *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["CommissionRateChanged"].Id(), nodeAddr.Hash()},
+		Data:    common.BigToHash(rate).Bytes(),
+	})
+}
+
+// event RewardsClaimed(address indexed Owner, uint256 Amount);
+func (s *GovernanceState) emitRewardsClaimed(owner common.Address, amount *big.Int) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{GovernanceABI.Events["RewardsClaimed"].Id(), owner.Hash()},
+		Data:    common.BigToHash(amount).Bytes(),
+	})
+}
+
 // event DKGReset(uint256 indexed Round, uint256 BlockHeight);
 func (s *GovernanceState) emitDKGReset(round *big.Int, blockHeight *big.Int) {
 	s.StateDB.AddLog(&types.Log{
@@ -1829,6 +2150,14 @@ func (g *GovernanceContract) unstake(amount *big.Int) ([]byte, error) {
 		return nil, errExecutionReverted
 	}
 
+	// Delegated fund can only be taken back by its delegators.
+	if g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+		ownStaked := new(big.Int).Sub(node.Staked, g.state.TotalDelegated(caller))
+		if ownStaked.Cmp(amount) < 0 {
+			return nil, errExecutionReverted
+		}
+	}
+
 	node.Staked = new(big.Int).Sub(node.Staked, amount)
 	node.Unstaked = amount
 	node.UnstakedAt = g.evm.Time
@@ -1906,6 +2235,263 @@ func (g *GovernanceContract) withdrawable() bool {
 	return g.evm.Time.Cmp(unlockTime) > 0
 }
 
+func (g *GovernanceContract) delegate(nodeAddr common.Address) ([]byte, error) {
+	if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+		return nil, errExecutionReverted
+	}
+	caller := g.contract.Caller()
+	value := g.contract.Value()
+
+	if big.NewInt(0).Cmp(value) == 0 {
+		return nil, errExecutionReverted
+	}
+
+	// Node owner should use stake instead.
+	if caller == nodeAddr {
+		return nil, errExecutionReverted
+	}
+
+	offset := g.state.NodesOffsetByAddress(nodeAddr)
+	if offset.Cmp(big.NewInt(0)) < 0 {
+		return nil, errExecutionReverted
+	}
+
+	node := g.state.Node(offset)
+	if node.Fined.Cmp(big.NewInt(0)) > 0 {
+		return nil, errExecutionReverted
+	}
+
+	// Shares are issued at the current value of the delegation, which is
+	// lowered by slashing. A node whose delegation is slashed to zero can not
+	// take new delegation since the existing shares are worthless.
+	shares := new(big.Int).Set(value)
+	totalShares := g.state.TotalDelegationShares(nodeAddr)
+	if totalShares.Cmp(big.NewInt(0)) > 0 {
+		totalDelegated := g.state.TotalDelegated(nodeAddr)
+		if totalDelegated.Cmp(big.NewInt(0)) == 0 {
+			return nil, errExecutionReverted
+		}
+		shares.Mul(value, totalShares)
+		shares.Div(shares, totalDelegated)
+		if shares.Cmp(big.NewInt(0)) == 0 {
+			return nil, errExecutionReverted
+		}
+	}
+
+	delegatorOffset := g.state.DelegatorsOffset(nodeAddr, caller)
+	if delegatorOffset.Cmp(big.NewInt(0)) < 0 {
+		delegatorOffset = g.state.LenDelegators(nodeAddr)
+		g.state.PushDelegator(nodeAddr, &delegatorInfo{
+			Owner:         caller,
+			Shares:        shares,
+			Undelegated:   big.NewInt(0),
+			UndelegatedAt: big.NewInt(0),
+			RewardDebt:    g.state.RewardDebt(nodeAddr, shares),
+		})
+		g.state.PutDelegatorsOffset(nodeAddr, caller, delegatorOffset)
+	} else {
+		delegator := g.state.Delegator(nodeAddr, delegatorOffset)
+		g.state.SettleDelegatorRewards(nodeAddr, delegator)
+		delegator.Shares = new(big.Int).Add(delegator.Shares, shares)
+		delegator.RewardDebt = g.state.RewardDebt(nodeAddr, delegator.Shares)
+		g.state.UpdateDelegator(nodeAddr, delegatorOffset, delegator)
+	}
+	g.state.IncTotalDelegationShares(nodeAddr, shares)
+
+	node.Staked = new(big.Int).Add(node.Staked, value)
+	g.state.UpdateNode(offset, node)
+
+	g.state.IncTotalDelegated(nodeAddr, value)
+	g.state.IncTotalStaked(value)
+	g.state.emitDelegated(nodeAddr, caller, value)
+
+	return g.useGas(GovernanceActionGasCost)
+}
+
+func (g *GovernanceContract) undelegate(nodeAddr common.Address, amount *big.Int) ([]byte, error) {
+	if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+		return nil, errExecutionReverted
+	}
+	caller := g.contract.Caller()
+
+	offset := g.state.NodesOffsetByAddress(nodeAddr)
+	if offset.Cmp(big.NewInt(0)) < 0 {
+		return nil, errExecutionReverted
+	}
+
+	node := g.state.Node(offset)
+
+	// Can not undelegate if the node has unpaid fine.
+	if node.Fined.Cmp(big.NewInt(0)) > 0 {
+		return nil, errExecutionReverted
+	}
+
+	delegatorOffset := g.state.DelegatorsOffset(nodeAddr, caller)
+	if delegatorOffset.Cmp(big.NewInt(0)) < 0 {
+		return nil, errExecutionReverted
+	}
+
+	delegator := g.state.Delegator(nodeAddr, delegatorOffset)
+
+	// Can not undelegate if there are unwithdrawn delegation.
+	if delegator.Undelegated.Cmp(big.NewInt(0)) > 0 {
+		return nil, errExecutionReverted
+	}
+	value := g.state.DelegationValue(nodeAddr, delegator.Shares)
+	if amount.Cmp(big.NewInt(0)) <= 0 || value.Cmp(amount) < 0 {
+		return nil, errExecutionReverted
+	}
+
+	// Burn the shares worth the amount, rounded up in favor of the remaining
+	// delegators.
+	shares := new(big.Int).Set(delegator.Shares)
+	if amount.Cmp(value) < 0 {
+		totalDelegated := g.state.TotalDelegated(nodeAddr)
+		shares.Mul(amount, g.state.TotalDelegationShares(nodeAddr))
+		shares.Add(shares, new(big.Int).Sub(totalDelegated, big.NewInt(1)))
+		shares.Div(shares, totalDelegated)
+		if shares.Cmp(delegator.Shares) > 0 {
+			return nil, errExecutionReverted
+		}
+	}
+
+	g.state.SettleDelegatorRewards(nodeAddr, delegator)
+	delegator.Shares = new(big.Int).Sub(delegator.Shares, shares)
+	delegator.RewardDebt = g.state.RewardDebt(nodeAddr, delegator.Shares)
+	delegator.Undelegated = amount
+	delegator.UndelegatedAt = g.evm.Time
+	g.state.UpdateDelegator(nodeAddr, delegatorOffset, delegator)
+	g.state.DecTotalDelegationShares(nodeAddr, shares)
+
+	node.Staked = new(big.Int).Sub(node.Staked, amount)
+	g.state.UpdateNode(offset, node)
+
+	g.state.DecTotalDelegated(nodeAddr, amount)
+	g.state.DecTotalStaked(amount)
+	g.state.emitUndelegated(nodeAddr, caller, amount)
+
+	return g.useGas(GovernanceActionGasCost)
+}
+
+func (g *GovernanceContract) withdrawDelegation(nodeAddr common.Address) ([]byte, error) {
+	if !g.delegationWithdrawable(nodeAddr) {
+		return nil, errExecutionReverted
+	}
+	caller := g.contract.Caller()
+
+	delegatorOffset := g.state.DelegatorsOffset(nodeAddr, caller)
+	delegator := g.state.Delegator(nodeAddr, delegatorOffset)
+
+	amount := delegator.Undelegated
+	delegator.Undelegated = big.NewInt(0)
+	delegator.UndelegatedAt = big.NewInt(0)
+	g.state.UpdateDelegator(nodeAddr, delegatorOffset, delegator)
+
+	if delegator.Shares.Cmp(big.NewInt(0)) == 0 {
+		length := g.state.LenDelegators(nodeAddr)
+		lastIndex := new(big.Int).Sub(length, big.NewInt(1))
+
+		// Delete the delegator.
+		if delegatorOffset.Cmp(lastIndex) != 0 {
+			lastDelegator := g.state.Delegator(nodeAddr, lastIndex)
+			g.state.UpdateDelegator(nodeAddr, delegatorOffset, lastDelegator)
+			g.state.PutDelegatorsOffset(nodeAddr, lastDelegator.Owner, delegatorOffset)
+		}
+		g.state.DeleteDelegatorsOffset(nodeAddr, caller)
+		g.state.PopLastDelegator(nodeAddr)
+	}
+
+	// Return the delegated fund.
+	if !g.transfer(GovernanceContractAddress, caller, amount) {
+		return nil, errExecutionReverted
+	}
+	g.state.emitDelegationWithdrawn(nodeAddr, caller, amount)
+
+	return g.useGas(GovernanceActionGasCost)
+}
+
+func (g *GovernanceContract) delegationWithdrawable(nodeAddr common.Address) bool {
+	if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+		return false
+	}
+	caller := g.contract.Caller()
+
+	// The node might have been removed, delegators are still able to withdraw
+	// their fund in that case.
+	offset := g.state.NodesOffsetByAddress(nodeAddr)
+	if offset.Cmp(big.NewInt(0)) >= 0 {
+		// Can not withdraw if there are unpaied fine.
+		node := g.state.Node(offset)
+		if node.Fined.Cmp(big.NewInt(0)) > 0 {
+			return false
+		}
+	}
+
+	delegatorOffset := g.state.DelegatorsOffset(nodeAddr, caller)
+	if delegatorOffset.Cmp(big.NewInt(0)) < 0 {
+		return false
+	}
+
+	delegator := g.state.Delegator(nodeAddr, delegatorOffset)
+
+	// Can not withdraw if there are no pending withdrawal.
+	if delegator.Undelegated.Cmp(big.NewInt(0)) == 0 {
+		return false
+	}
+
+	unlockTime := new(big.Int).Add(delegator.UndelegatedAt, g.state.LockupPeriod())
+	return g.evm.Time.Cmp(unlockTime) > 0
+}
+
+func (g *GovernanceContract) setCommissionRate(rate *big.Int) ([]byte, error) {
+	if !g.evm.ChainConfig().IsRewardSplit(g.evm.BlockNumber) {
+		return nil, errExecutionReverted
+	}
+	caller := g.contract.Caller()
+
+	offset := g.state.NodesOffsetByAddress(caller)
+	if offset.Cmp(big.NewInt(0)) < 0 {
+		return nil, errExecutionReverted
+	}
+
+	if rate.Cmp(big.NewInt(0)) < 0 || rate.Cmp(big.NewInt(CommissionRateBase)) > 0 {
+		return nil, errExecutionReverted
+	}
+
+	g.state.SetCommissionRate(caller, rate)
+	g.state.emitCommissionRateChanged(caller, rate)
+
+	return g.useGas(GovernanceActionGasCost)
+}
+
+func (g *GovernanceContract) claimRewards(nodeAddr common.Address) ([]byte, error) {
+	if !g.evm.ChainConfig().IsRewardSplit(g.evm.BlockNumber) {
+		return nil, errExecutionReverted
+	}
+	caller := g.contract.Caller()
+
+	// Settle the rewards of the delegation to the node, if any.
+	delegatorOffset := g.state.DelegatorsOffset(nodeAddr, caller)
+	if delegatorOffset.Cmp(big.NewInt(0)) >= 0 {
+		delegator := g.state.Delegator(nodeAddr, delegatorOffset)
+		g.state.SettleDelegatorRewards(nodeAddr, delegator)
+		g.state.UpdateDelegator(nodeAddr, delegatorOffset, delegator)
+	}
+
+	amount := g.state.PendingRewards(caller)
+	if amount.Cmp(big.NewInt(0)) == 0 {
+		return nil, errExecutionReverted
+	}
+	g.state.ResetPendingRewards(caller)
+
+	if !g.transfer(GovernanceContractAddress, caller, amount) {
+		return nil, errExecutionReverted
+	}
+	g.state.emitRewardsClaimed(caller, amount)
+
+	return g.useGas(GovernanceActionGasCost)
+}
+
 func (g *GovernanceContract) payFine(nodeAddr common.Address) ([]byte, error) {
 	nodeOffset := g.state.NodesOffsetByAddress(nodeAddr)
 	if nodeOffset.Cmp(big.NewInt(0)) < 0 {
@@ -1981,10 +2567,17 @@ func (s sortBytes) Len() int {
 	return len(s)
 }
 
-func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payloads ...[]byte) error {
-	sort.Sort(sortBytes(payloads))
+// FineRecordHash returns the hash recording a fine charged with the payloads,
+// the same payloads are only fined once.
+func FineRecordHash(payloads ...[]byte) Bytes32 {
+	sorted := make(sortBytes, len(payloads))
+	copy(sorted, payloads)
+	sort.Sort(sorted)
+	return Bytes32(crypto.Keccak256Hash(sorted...))
+}
 
-	hash := Bytes32(crypto.Keccak256Hash(payloads...))
+func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payloads ...[]byte) error {
+	hash := FineRecordHash(payloads...)
 	if g.state.FineRecords(hash) {
 		return errors.New("already fined")
 	}
@@ -1995,8 +2588,14 @@ func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payl
 		return errExecutionReverted
 	}
 
-	// Set fined value.
 	node := g.state.Node(nodeOffset)
+
+	// Delegators take their share of the fine, slashed from their delegation.
+	if g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+		amount = g.slashDelegators(nodeAddr, node, amount)
+	}
+
+	// Set fined value.
 	node.Fined = new(big.Int).Add(node.Fined, amount)
 	g.state.UpdateNode(nodeOffset, node)
 
@@ -2005,6 +2604,39 @@ func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payl
 	return nil
 }
 
+// slashDelegators slashes the delegators of the node in proportion to their
+// share of the stake and returns the part of the fine left to the node owner.
+// Only the total delegated value is lowered, each delegation loses the same
+// ratio of its value through its shares.
+func (g *GovernanceContract) slashDelegators(
+	nodeAddr common.Address, node *nodeInfo, amount *big.Int) *big.Int {
+	totalDelegated := g.state.TotalDelegated(nodeAddr)
+	if node.Staked.Cmp(big.NewInt(0)) == 0 ||
+		totalDelegated.Cmp(big.NewInt(0)) == 0 {
+		return amount
+	}
+
+	slashed := new(big.Int).Mul(amount, totalDelegated)
+	slashed.Div(slashed, node.Staked)
+	if slashed.Cmp(totalDelegated) > 0 {
+		slashed = new(big.Int).Set(totalDelegated)
+	}
+	if slashed.Cmp(big.NewInt(0)) == 0 {
+		return amount
+	}
+
+	node.Staked = new(big.Int).Sub(node.Staked, slashed)
+	g.state.DecTotalDelegated(nodeAddr, slashed)
+	g.state.DecTotalStaked(slashed)
+	g.state.emitDelegatorsFined(nodeAddr, slashed)
+
+	// Pay the slashed fund to governance owner.
+	g.evm.StateDB.SubBalance(GovernanceContractAddress, slashed)
+	g.evm.StateDB.AddBalance(g.state.Owner(), slashed)
+
+	return new(big.Int).Sub(amount, slashed)
+}
+
 func (g *GovernanceContract) report(reportType *big.Int, arg1, arg2 []byte) ([]byte, error) {
 	typeEnum := FineType(reportType.Uint64())
 	var reportedNodeID coreTypes.NodeID
@@ -2288,6 +2920,12 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return g.register(args.PublicKey, args.Name, args.Email, args.Location, args.Url)
+	case "setCommissionRate":
+		rate := new(big.Int)
+		if err := method.Inputs.Unpack(&rate, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		return g.setCommissionRate(rate)
 	case "stake":
 		return g.stake()
 	case "transferOwnership":
@@ -2311,6 +2949,53 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return g.transferNodeOwnershipByFoundation(args.OldOwner, args.NewOwner)
+	case "claimRewards":
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		return g.claimRewards(address)
+	case "delegate":
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		return g.delegate(address)
+	case "delegationWithdrawable":
+		if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.delegationWithdrawable(address))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
+	case "delegatorsLength":
+		if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.LenDelegators(address))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
+	case "undelegate":
+		args := struct {
+			NodeAddress common.Address
+			Amount      *big.Int
+		}{}
+		if err := method.Inputs.Unpack(&args, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		return g.undelegate(args.NodeAddress, args.Amount)
 	case "unstake":
 		amount := new(big.Int)
 		if err := method.Inputs.Unpack(&amount, arguments); err != nil {
@@ -2325,6 +3010,12 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 		return g.updateConfiguration(&cfg)
 	case "withdraw":
 		return g.withdraw()
+	case "withdrawDelegation":
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		return g.withdrawDelegation(address)
 	case "withdrawable":
 		res, err := method.Outputs.Pack(g.withdrawable())
 		if err != nil {
@@ -2342,6 +3033,19 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return res, nil
+	case "commissionRates":
+		if !g.evm.ChainConfig().IsRewardSplit(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.CommissionRate(address))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
 	case "crs":
 		res, err := method.Outputs.Pack(g.state.CRS())
 		if err != nil {
@@ -2354,6 +3058,41 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return res, nil
+	case "delegators":
+		if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		args := struct {
+			NodeAddress common.Address
+			Index       *big.Int
+		}{}
+		if err := method.Inputs.Unpack(&args, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		delegator := g.state.Delegator(args.NodeAddress, args.Index)
+		res, err := method.Outputs.Pack(delegator.Owner,
+			g.state.DelegationValue(args.NodeAddress, delegator.Shares),
+			delegator.Undelegated, delegator.UndelegatedAt)
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
+	case "delegatorsOffset":
+		if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		args := struct {
+			NodeAddress      common.Address
+			DelegatorAddress common.Address
+		}{}
+		if err := method.Inputs.Unpack(&args, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.DelegatorsOffset(args.NodeAddress, args.DelegatorAddress))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
 	case "dkgComplaints":
 		offset := new(big.Int)
 		if err := method.Inputs.Unpack(&offset, arguments); err != nil {
@@ -2612,6 +3351,19 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return res, nil
+	case "pendingRewards":
+		if !g.evm.ChainConfig().IsRewardSplit(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.PendingRewards(address))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
 	case "replaceNodePublicKey":
 		var pk []byte
 		if err := method.Inputs.Unpack(&pk, arguments); err != nil {
@@ -2634,6 +3386,19 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			return nil, errExecutionReverted
 		}
 		return res, nil
+	case "totalDelegated":
+		if !g.evm.ChainConfig().IsDelegation(g.evm.BlockNumber) {
+			return nil, errExecutionReverted
+		}
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, errExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.TotalDelegated(address))
+		if err != nil {
+			return nil, errExecutionReverted
+		}
+		return res, nil
 	case "totalStaked":
 		res, err := method.Outputs.Pack(g.state.TotalStaked())
 		if err != nil {
@@ -2678,6 +3443,11 @@ func (g *GovernanceContract) transferNodeOwnership(newOwner common.Address) ([]b
 		return nil, errExecutionReverted
 	}
 
+	// Delegations are bound to the owner address.
+	if g.state.LenDelegators(caller).Cmp(big.NewInt(0)) > 0 {
+		return nil, errExecutionReverted
+	}
+
 	node := g.state.Node(offset)
 	g.state.DeleteNodeOffsets(node)
 
@@ -2710,6 +3480,11 @@ func (g *GovernanceContract) transferNodeOwnershipByFoundation(oldOwner, newOwne
 		return nil, errExecutionReverted
 	}
 
+	// Delegations are bound to the owner address.
+	if g.state.LenDelegators(oldOwner).Cmp(big.NewInt(0)) > 0 {
+		return nil, errExecutionReverted
+	}
+
 	node := g.state.Node(offset)
 	g.state.DeleteNodeOffsets(node)
 
@@ -2832,8 +3607,6 @@ func PackAddDKGSuccess(final *dkgTypes.Success) ([]byte, error) {
 }
 
 func PackReportForkVote(vote1, vote2 *coreTypes.Vote) ([]byte, error) {
-	method := GovernanceABI.Name2Method["report"]
-
 	vote1Bytes, err := rlp.EncodeToBytes(vote1)
 	if err != nil {
 		return nil, err
@@ -2843,18 +3616,10 @@ func PackReportForkVote(vote1, vote2 *coreTypes.Vote) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	res, err := method.Inputs.Pack(big.NewInt(FineTypeForkVote), vote1Bytes, vote2Bytes)
-	if err != nil {
-		return nil, err
-	}
-	data := append(method.Id(), res...)
-	return data, nil
+	return PackReport(FineTypeForkVote, vote1Bytes, vote2Bytes)
 }
 
 func PackReportForkBlock(block1, block2 *coreTypes.Block) ([]byte, error) {
-	method := GovernanceABI.Name2Method["report"]
-
 	block1Bytes, err := rlp.EncodeToBytes(block1)
 	if err != nil {
 		return nil, err
@@ -2864,8 +3629,14 @@ func PackReportForkBlock(block1, block2 *coreTypes.Block) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return PackReport(FineTypeForkBlock, block1Bytes, block2Bytes)
+}
 
-	res, err := method.Inputs.Pack(big.NewInt(FineTypeForkBlock), block1Bytes, block2Bytes)
+// PackReport packs a report of the fine type with the RLP encoded arguments.
+func PackReport(fineType FineType, arg1, arg2 []byte) ([]byte, error) {
+	method := GovernanceABI.Name2Method["report"]
+
+	res, err := method.Inputs.Pack(new(big.Int).SetUint64(uint64(fineType)), arg1, arg2)
 	if err != nil {
 		return nil, err
 	}
@@ -2882,3 +3653,24 @@ func PackResetDKG(newSignedCRS []byte) ([]byte, error) {
 	data := append(method.Id(), res...)
 	return data, nil
 }
+
+func PackUpdateConfiguration(cfg *params.DexconConfig) ([]byte, error) {
+	method := GovernanceABI.Name2Method["updateConfiguration"]
+	res, err := method.Inputs.Pack(
+		cfg.MinStake,
+		new(big.Int).SetUint64(cfg.LockupPeriod),
+		cfg.MinGasPrice,
+		new(big.Int).SetUint64(cfg.BlockGasLimit),
+		new(big.Int).SetUint64(cfg.LambdaBA),
+		new(big.Int).SetUint64(cfg.LambdaDKG),
+		big.NewInt(int64(cfg.NotaryParamAlpha*decimalMultiplier)),
+		big.NewInt(int64(cfg.NotaryParamBeta*decimalMultiplier)),
+		new(big.Int).SetUint64(cfg.RoundLength),
+		new(big.Int).SetUint64(cfg.MinBlockInterval),
+		cfg.FineValues)
+	if err != nil {
+		return nil, err
+	}
+	data := append(method.Id(), res...)
+	return data, nil
+}
@@ -622,6 +622,373 @@ func (g *OracleContractsTestSuite) TestFine() {
 	g.Require().Equal(1, len(g.s.QualifiedNodes()))
 }
 
+func (g *OracleContractsTestSuite) TestDelegation() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+
+	// Register with some stake.
+	ownerStaked := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, ownerStaked)
+	g.Require().NoError(err)
+	g.Require().Equal(0, len(g.s.QualifiedNodes()))
+
+	// Delegate to a non-existing node should fail.
+	_, delegatorAddr := newPrefundAccount(g.stateDB)
+	_, nonNodeAddr := newPrefundAccount(g.stateDB)
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err = GovernanceABI.ABI.Pack("delegate", nonNodeAddr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, amount)
+	g.Require().Error(err)
+
+	// Node owner can not delegate to itself.
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, amount)
+	g.Require().Error(err)
+
+	// Delegate to qualify the node.
+	balanceBeforeDelegate := g.stateDB.GetBalance(delegatorAddr)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, amount)
+	g.Require().NoError(err)
+	g.Require().Equal(1, len(g.s.QualifiedNodes()))
+	g.Require().Equal(new(big.Int).Sub(balanceBeforeDelegate, amount), g.stateDB.GetBalance(delegatorAddr))
+	g.Require().Equal(1, int(g.s.LenDelegators(addr).Uint64()))
+	g.Require().Equal(0, int(g.s.DelegatorsOffset(addr, delegatorAddr).Int64()))
+	g.Require().Equal(amount.String(), g.s.TotalDelegated(addr).String())
+	g.Require().Equal(new(big.Int).Add(ownerStaked, amount).String(), g.s.TotalStaked().String())
+
+	// Delegate again adds to the same delegator.
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, amount)
+	g.Require().NoError(err)
+	g.Require().Equal(1, int(g.s.LenDelegators(addr).Uint64()))
+	delegated := new(big.Int).Mul(amount, big.NewInt(2))
+	g.Require().Equal(delegated.String(),
+		g.s.DelegationValue(addr, g.s.Delegator(addr, big.NewInt(0)).Shares).String())
+	g.Require().Equal(new(big.Int).Add(ownerStaked, delegated).String(),
+		g.s.Node(big.NewInt(0)).Staked.String())
+
+	// Owner can not unstake the delegated fund.
+	input, err = GovernanceABI.ABI.Pack("unstake", new(big.Int).Add(ownerStaked, big.NewInt(1)))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Node ownership can not be transfered while having delegators.
+	_, newOwner := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("transferNodeOwnership", newOwner)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Undelegate more than delegated should fail.
+	input, err = GovernanceABI.ABI.Pack("undelegate", addr, new(big.Int).Add(delegated, big.NewInt(1)))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Undelegate.
+	input, err = GovernanceABI.ABI.Pack("undelegate", addr, amount)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(amount.String(), g.s.TotalDelegated(addr).String())
+	g.Require().Equal(new(big.Int).Add(ownerStaked, amount).String(), g.s.TotalStaked().String())
+
+	// Undelegate again before withdrawal should fail.
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Withdraw immediately should fail.
+	var ok bool
+	input, err = GovernanceABI.ABI.Pack("delegationWithdrawable", addr)
+	g.Require().NoError(err)
+	output, err := g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	GovernanceABI.ABI.Unpack(&ok, "delegationWithdrawable", output)
+	g.Require().False(ok)
+	input, err = GovernanceABI.ABI.Pack("withdrawDelegation", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Wait for lockup time than withdraw.
+	time.Sleep(time.Second * 2)
+	balanceBeforeWithdraw := g.stateDB.GetBalance(delegatorAddr)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(new(big.Int).Add(balanceBeforeWithdraw, amount), g.stateDB.GetBalance(delegatorAddr))
+	g.Require().Equal(1, int(g.s.LenDelegators(addr).Uint64()))
+
+	// Undelegate all and withdraw to remove the delegator.
+	input, err = GovernanceABI.ABI.Pack("undelegate", addr, amount)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	time.Sleep(time.Second * 2)
+	input, err = GovernanceABI.ABI.Pack("withdrawDelegation", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(0, int(g.s.LenDelegators(addr).Uint64()))
+	g.Require().True(g.s.DelegatorsOffset(addr, delegatorAddr).Cmp(big.NewInt(0)) < 0)
+	g.Require().Equal(ownerStaked.String(), g.s.TotalStaked().String())
+}
+
+func (g *OracleContractsTestSuite) TestDelegatorFined() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+
+	// Register with some stake.
+	ownerStaked := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, ownerStaked)
+	g.Require().NoError(err)
+
+	// Two delegators holding 1/4 and 1/2 of the total stake.
+	_, delegator1 := newPrefundAccount(g.stateDB)
+	_, delegator2 := newPrefundAccount(g.stateDB)
+	amount1 := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(2.5e5))
+	amount2 := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(7.5e5))
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegator1, input, amount1)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegator2, input, amount2)
+	g.Require().NoError(err)
+
+	totalStaked := new(big.Int).Add(ownerStaked, new(big.Int).Add(amount1, amount2))
+	g.Require().Equal(totalStaked.String(), g.s.TotalStaked().String())
+
+	// Fine the node.
+	gov := &GovernanceContract{
+		evm:   NewEVM(g.context, g.stateDB, params.TestChainConfig, Config{}),
+		state: *g.s,
+	}
+	ownerBalance := g.stateDB.GetBalance(g.config.Owner)
+	fine := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e4))
+	g.Require().NoError(gov.fine(addr, fine, []byte("fine")))
+
+	// Delegators take 2/3 of the fine, each loses the same ratio of its value.
+	delegated := new(big.Int).Add(amount1, amount2)
+	slashed := new(big.Int).Div(new(big.Int).Mul(fine, big.NewInt(2)), big.NewInt(3))
+	remaining := new(big.Int).Sub(delegated, slashed)
+	for _, d := range []struct {
+		addr   common.Address
+		amount *big.Int
+	}{{delegator1, amount1}, {delegator2, amount2}} {
+		value := new(big.Int).Mul(d.amount, remaining)
+		value.Div(value, delegated)
+		shares := g.s.Delegator(addr, g.s.DelegatorsOffset(addr, d.addr)).Shares
+		g.Require().Equal(d.amount.String(), shares.String())
+		g.Require().Equal(value.String(), g.s.DelegationValue(addr, shares).String())
+	}
+	g.Require().Equal(remaining.String(), g.s.TotalDelegated(addr).String())
+	g.Require().Equal(new(big.Int).Sub(totalStaked, slashed).String(), g.s.TotalStaked().String())
+	g.Require().Equal(new(big.Int).Add(ownerBalance, slashed), g.stateDB.GetBalance(g.config.Owner))
+
+	// The rest of the fine goes to the node owner.
+	node := g.s.Node(g.s.NodesOffsetByAddress(addr))
+	g.Require().Equal(new(big.Int).Sub(fine, slashed).String(), node.Fined.String())
+	g.Require().Equal(new(big.Int).Sub(totalStaked, slashed).String(), node.Staked.String())
+
+	// Can not undelegate before fines are paid.
+	input, err = GovernanceABI.ABI.Pack("undelegate", addr, big.NewInt(1))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegator1, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// New delegation is issued shares at the slashed value.
+	input, err = GovernanceABI.ABI.Pack("payFine", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, node.Fined)
+	g.Require().NoError(err)
+	_, delegator3 := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegator3, input, amount1)
+	g.Require().NoError(err)
+	shares := new(big.Int).Mul(amount1, delegated)
+	shares.Div(shares, remaining)
+	g.Require().Equal(shares.String(),
+		g.s.Delegator(addr, g.s.DelegatorsOffset(addr, delegator3)).Shares.String())
+}
+
+func (g *OracleContractsTestSuite) TestDelegationBeforeFork() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, amount)
+	g.Require().NoError(err)
+
+	config := *params.TestChainConfig
+	config.DelegationBlock = big.NewInt(1)
+	g.context.Time = big.NewInt(time.Now().UnixNano() / 1000000)
+	evm := NewEVM(g.context, g.stateDB, &config, Config{IsBlockProposer: true})
+
+	_, delegatorAddr := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, _, err = evm.Call(AccountRef(delegatorAddr), GovernanceContractAddress, input, 10000000, amount)
+	g.Require().Error(err)
+	g.Require().Equal(0, int(g.s.LenDelegators(addr).Uint64()))
+
+	// Delegation getters are not available either.
+	for _, args := range [][]interface{}{
+		{"delegationWithdrawable", addr},
+		{"delegatorsLength", addr},
+		{"delegators", addr, big.NewInt(0)},
+		{"delegatorsOffset", addr, delegatorAddr},
+		{"totalDelegated", addr},
+	} {
+		input, err = GovernanceABI.ABI.Pack(args[0].(string), args[1:]...)
+		g.Require().NoError(err)
+		_, _, err = evm.Call(AccountRef(delegatorAddr), GovernanceContractAddress, input, 10000000, big.NewInt(0))
+		g.Require().Error(err, args[0])
+	}
+}
+
+func (g *OracleContractsTestSuite) TestRewardDistribution() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+
+	// Register with some stake.
+	ownerStaked := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, ownerStaked)
+	g.Require().NoError(err)
+
+	// Delegate the same amount as owner.
+	_, delegatorAddr := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, ownerStaked)
+	g.Require().NoError(err)
+
+	// Only node owner can set commission rate.
+	input, err = GovernanceABI.ABI.Pack("setCommissionRate", big.NewInt(1000))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Commission rate can not exceed 100%.
+	input, err = GovernanceABI.ABI.Pack("setCommissionRate", big.NewInt(CommissionRateBase+1))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Set 10% commission rate.
+	input, err = GovernanceABI.ABI.Pack("setCommissionRate", big.NewInt(1000))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(big.NewInt(1000).String(), g.s.CommissionRate(addr).String())
+
+	// Nothing to claim yet.
+	input, err = GovernanceABI.ABI.Pack("claimRewards", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// Distribute reward, delegator takes 45% and the owner takes the rest.
+	// The reward of the delegator is settled on claim.
+	reward := big.NewInt(1e18)
+	g.stateDB.AddBalance(GovernanceContractAddress, reward)
+	g.s.DistributeReward(addr, reward)
+	delegatorReward := new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(45)), big.NewInt(100))
+	ownerReward := new(big.Int).Sub(reward, delegatorReward)
+	g.Require().Equal(0, g.s.PendingRewards(delegatorAddr).Cmp(big.NewInt(0)))
+	g.Require().Equal(ownerReward.String(), g.s.PendingRewards(addr).String())
+
+	var pending *big.Int
+	input, err = GovernanceABI.ABI.Pack("pendingRewards", addr)
+	g.Require().NoError(err)
+	output, err := g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().NoError(GovernanceABI.ABI.Unpack(&pending, "pendingRewards", output))
+	g.Require().Equal(ownerReward.String(), pending.String())
+
+	// Claim rewards.
+	balanceBeforeClaim := g.stateDB.GetBalance(delegatorAddr)
+	input, err = GovernanceABI.ABI.Pack("claimRewards", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(new(big.Int).Add(balanceBeforeClaim, delegatorReward), g.stateDB.GetBalance(delegatorAddr))
+	g.Require().Equal(0, g.s.PendingRewards(delegatorAddr).Cmp(big.NewInt(0)))
+
+	// Claim again should fail.
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	balanceBeforeClaim = g.stateDB.GetBalance(addr)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(new(big.Int).Add(balanceBeforeClaim, ownerReward), g.stateDB.GetBalance(addr))
+
+	// A delegator joining later takes no part of the earlier rewards, delegators
+	// now take 60% of the reward and the rewards are settled on undelegate.
+	_, delegatorAddr2 := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("delegate", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr2, input, ownerStaked)
+	g.Require().NoError(err)
+	g.stateDB.AddBalance(GovernanceContractAddress, reward)
+	g.s.DistributeReward(addr, reward)
+
+	input, err = GovernanceABI.ABI.Pack("undelegate", addr, big.NewInt(1))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr2, input, big.NewInt(0))
+	g.Require().NoError(err)
+	delegatorReward = new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(3)), big.NewInt(10))
+	g.Require().Equal(delegatorReward.String(), g.s.PendingRewards(delegatorAddr2).String())
+	g.Require().Equal(0, g.s.PendingRewards(delegatorAddr).Cmp(big.NewInt(0)))
+	g.Require().Equal(new(big.Int).Sub(reward, new(big.Int).Mul(delegatorReward, big.NewInt(2))).String(),
+		g.s.PendingRewards(addr).String())
+
+	balanceBeforeClaim = g.stateDB.GetBalance(delegatorAddr)
+	input, err = GovernanceABI.ABI.Pack("claimRewards", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, delegatorAddr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(new(big.Int).Add(balanceBeforeClaim, delegatorReward), g.stateDB.GetBalance(delegatorAddr))
+}
+
+func (g *OracleContractsTestSuite) TestRewardSplitBeforeFork() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(5e5))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, amount)
+	g.Require().NoError(err)
+
+	config := *params.TestChainConfig
+	config.RewardSplitBlock = big.NewInt(1)
+	g.context.Time = big.NewInt(time.Now().UnixNano() / 1000000)
+	evm := NewEVM(g.context, g.stateDB, &config, Config{IsBlockProposer: true})
+
+	for _, args := range [][]interface{}{
+		{"setCommissionRate", big.NewInt(1000)},
+		{"claimRewards", addr},
+		{"commissionRates", addr},
+		{"pendingRewards", addr},
+	} {
+		input, err = GovernanceABI.ABI.Pack(args[0].(string), args[1:]...)
+		g.Require().NoError(err)
+		_, _, err = evm.Call(AccountRef(addr), GovernanceContractAddress, input, 10000000, big.NewInt(0))
+		g.Require().Error(err, args[0])
+	}
+}
+
 func (g *OracleContractsTestSuite) TestUpdateConfiguration() {
 	_, addr := newPrefundAccount(g.stateDB)
 
@@ -648,6 +1015,30 @@ func (g *OracleContractsTestSuite) TestUpdateConfiguration() {
 	g.Require().NoError(err)
 }
 
+func (g *OracleContractsTestSuite) TestPackUpdateConfiguration() {
+	cfg := g.s.Configuration()
+	cfg.LockupPeriod = 2000
+	cfg.NotaryParamAlpha = 70.5
+	cfg.NotaryParamBeta = 264
+	cfg.RoundLength = 1200
+	cfg.FineValues = []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+
+	input, err := PackUpdateConfiguration(cfg)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NoError(err)
+
+	updated := g.s.Configuration()
+	g.Require().Equal(cfg.LockupPeriod, updated.LockupPeriod)
+	g.Require().Equal(cfg.NotaryParamAlpha, updated.NotaryParamAlpha)
+	g.Require().Equal(cfg.NotaryParamBeta, updated.NotaryParamBeta)
+	g.Require().Equal(cfg.RoundLength, updated.RoundLength)
+	g.Require().Equal(cfg.MinStake.String(), updated.MinStake.String())
+	g.Require().Equal(cfg.MinGasPrice.String(), updated.MinGasPrice.String())
+	g.Require().Equal(cfg.BlockGasLimit, updated.BlockGasLimit)
+	g.Require().Len(updated.FineValues, 3)
+}
+
 func (g *OracleContractsTestSuite) TestConfigurationReading() {
 	_, addr := newPrefundAccount(g.stateDB)
 
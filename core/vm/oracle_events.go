@@ -0,0 +1,219 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+var (
+	errNotGovernanceEvent = errors.New("not a governance event")
+	errUnknownEvent       = errors.New("unknown governance event")
+)
+
+// GovernanceReport is the evidence carried by a Reported event.
+type GovernanceReport struct {
+	Type   hexutil.Uint64     `json:"type"`
+	Arg1   hexutil.Bytes      `json:"arg1"`
+	Arg2   hexutil.Bytes      `json:"arg2"`
+	Votes  []*coreTypes.Vote  `json:"votes,omitempty"`
+	Blocks []*coreTypes.Block `json:"blocks,omitempty"`
+}
+
+// GovernanceEvent is a decoded log emitted by the governance contract. Only
+// the fields carried by the event type are set.
+type GovernanceEvent struct {
+	Type string `json:"type"`
+
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	Index       hexutil.Uint   `json:"logIndex"`
+	Removed     bool           `json:"removed"`
+
+	NodeAddress      *common.Address   `json:"nodeAddress,omitempty"`
+	NewOwnerAddress  *common.Address   `json:"newOwnerAddress,omitempty"`
+	DelegatorAddress *common.Address   `json:"delegatorAddress,omitempty"`
+	Round            *hexutil.Big      `json:"round,omitempty"`
+	Amount           *hexutil.Big      `json:"amount,omitempty"`
+	Rate             *hexutil.Big      `json:"rate,omitempty"`
+	BlockHeight      *hexutil.Big      `json:"blockHeight,omitempty"`
+	CRS              *common.Hash      `json:"crs,omitempty"`
+	PublicKey        hexutil.Bytes     `json:"publicKey,omitempty"`
+	Report           *GovernanceReport `json:"report,omitempty"`
+}
+
+func (e *GovernanceEvent) setIndexed(name string, topic common.Hash) error {
+	switch name {
+	case "NodeAddress", "Owner":
+		addr := common.BytesToAddress(topic.Bytes())
+		e.NodeAddress = &addr
+	case "NewOwnerAddress":
+		addr := common.BytesToAddress(topic.Bytes())
+		e.NewOwnerAddress = &addr
+	case "DelegatorAddress":
+		addr := common.BytesToAddress(topic.Bytes())
+		e.DelegatorAddress = &addr
+	case "Round":
+		e.Round = (*hexutil.Big)(topic.Big())
+	default:
+		return fmt.Errorf("unknown indexed argument %s of %s", name, e.Type)
+	}
+	return nil
+}
+
+func (e *GovernanceEvent) setValue(name string, value interface{}) error {
+	var ok bool
+	switch name {
+	case "Amount":
+		var v *big.Int
+		v, ok = value.(*big.Int)
+		e.Amount = (*hexutil.Big)(v)
+	case "Rate":
+		var v *big.Int
+		v, ok = value.(*big.Int)
+		e.Rate = (*hexutil.Big)(v)
+	case "BlockHeight":
+		var v *big.Int
+		v, ok = value.(*big.Int)
+		e.BlockHeight = (*hexutil.Big)(v)
+	case "CRS":
+		var v [32]byte
+		v, ok = value.([32]byte)
+		crs := common.Hash(v)
+		e.CRS = &crs
+	case "PublicKey":
+		e.PublicKey, ok = value.([]byte)
+	case "Type":
+		var v *big.Int
+		if v, ok = value.(*big.Int); ok {
+			e.Report.Type = hexutil.Uint64(v.Uint64())
+		}
+	case "Arg1":
+		e.Report.Arg1, ok = value.([]byte)
+	case "Arg2":
+		e.Report.Arg2, ok = value.([]byte)
+	default:
+		return fmt.Errorf("unknown argument %s of %s", name, e.Type)
+	}
+	if !ok {
+		return fmt.Errorf("invalid argument %s of %s", name, e.Type)
+	}
+	return nil
+}
+
+// decodeEvidence decodes the fork votes or blocks of a report.
+func (r *GovernanceReport) decodeEvidence() error {
+	switch r.Type {
+	case FineTypeForkVote:
+		for _, arg := range [][]byte{r.Arg1, r.Arg2} {
+			vote := new(coreTypes.Vote)
+			if err := rlp.DecodeBytes(arg, vote); err != nil {
+				return err
+			}
+			r.Votes = append(r.Votes, vote)
+		}
+	case FineTypeForkBlock:
+		for _, arg := range [][]byte{r.Arg1, r.Arg2} {
+			block := new(coreTypes.Block)
+			if err := rlp.DecodeBytes(arg, block); err != nil {
+				return err
+			}
+			r.Blocks = append(r.Blocks, block)
+		}
+	}
+	return nil
+}
+
+// DecodeGovernanceEvent decodes a log emitted by the governance contract.
+func DecodeGovernanceEvent(log *types.Log) (*GovernanceEvent, error) {
+	if log.Address != GovernanceContractAddress || len(log.Topics) == 0 {
+		return nil, errNotGovernanceEvent
+	}
+	event, ok := GovernanceABI.Topic2Event[log.Topics[0]]
+	if !ok {
+		return nil, errUnknownEvent
+	}
+
+	e := &GovernanceEvent{
+		Type:        event.Name,
+		BlockNumber: hexutil.Uint64(log.BlockNumber),
+		BlockHash:   log.BlockHash,
+		TxHash:      log.TxHash,
+		TxIndex:     hexutil.Uint(log.TxIndex),
+		Index:       hexutil.Uint(log.Index),
+		Removed:     log.Removed,
+	}
+	if event.Name == "Reported" {
+		e.Report = &GovernanceReport{}
+	}
+
+	topics := log.Topics[1:]
+	for _, input := range event.Inputs {
+		if !input.Indexed {
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("missing indexed argument %s of %s",
+				input.Name, event.Name)
+		}
+		if err := e.setIndexed(input.Name, topics[0]); err != nil {
+			return nil, err
+		}
+		topics = topics[1:]
+	}
+
+	nonIndexed := event.Inputs.NonIndexed()
+	var values []interface{}
+	switch event.Name {
+	case "NodePublicKeyReplaced":
+		// The public key is emitted as is instead of ABI encoded.
+		values = []interface{}{common.CopyBytes(log.Data)}
+	default:
+		if len(nonIndexed) == 0 {
+			break
+		}
+		var err error
+		values, err = nonIndexed.UnpackValues(log.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, input := range nonIndexed {
+		if err := e.setValue(input.Name, values[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Report != nil {
+		if err := e.Report.decodeEvidence(); err != nil {
+			return nil, fmt.Errorf("invalid report evidence: %v", err)
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func TestDecodeGovernanceEvent(t *testing.T) {
+	statedb, err := state.New(common.Hash{},
+		state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	s := &GovernanceState{StateDB: statedb}
+
+	nodeAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	delegator := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	crs := common.HexToHash("0x1234")
+	pk := []byte{4, 1, 2, 3}
+
+	vote1 := coreTypes.NewVote(coreTypes.VoteCom, coreCommon.NewRandomHash(), 1)
+	vote2 := coreTypes.NewVote(coreTypes.VoteCom, coreCommon.NewRandomHash(), 1)
+	arg1, err := rlp.EncodeToBytes(vote1)
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+	arg2, err := rlp.EncodeToBytes(vote2)
+	if err != nil {
+		t.Fatalf("failed to encode vote: %v", err)
+	}
+
+	s.emitStaked(nodeAddr, big.NewInt(100))
+	s.emitDelegated(nodeAddr, delegator, big.NewInt(5))
+	s.emitCRSProposed(big.NewInt(3), crs)
+	s.emitNodePublicKeyReplaced(nodeAddr, pk)
+	s.emitDKGReset(big.NewInt(2), big.NewInt(1000))
+	s.emitConfigurationChangedEvent()
+	s.emitReported(nodeAddr, big.NewInt(FineTypeForkVote), arg1, arg2)
+
+	logs := statedb.Logs()
+	if len(logs) != 7 {
+		t.Fatalf("log count mismatch: have %d, want 7", len(logs))
+	}
+	events := make([]*GovernanceEvent, len(logs))
+	for i, log := range logs {
+		events[i], err = DecodeGovernanceEvent(log)
+		if err != nil {
+			t.Fatalf("failed to decode log %d: %v", i, err)
+		}
+	}
+
+	if e := events[0]; e.Type != "Staked" || *e.NodeAddress != nodeAddr ||
+		e.Amount.ToInt().Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("Staked mismatch: %+v", e)
+	}
+	if e := events[1]; e.Type != "Delegated" || *e.NodeAddress != nodeAddr ||
+		*e.DelegatorAddress != delegator || e.Amount.ToInt().Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Delegated mismatch: %+v", e)
+	}
+	if e := events[2]; e.Type != "CRSProposed" ||
+		e.Round.ToInt().Cmp(big.NewInt(3)) != 0 || *e.CRS != crs {
+		t.Errorf("CRSProposed mismatch: %+v", e)
+	}
+	if e := events[3]; e.Type != "NodePublicKeyReplaced" ||
+		!bytes.Equal(e.PublicKey, pk) {
+		t.Errorf("NodePublicKeyReplaced mismatch: %+v", e)
+	}
+	if e := events[4]; e.Type != "DKGReset" ||
+		e.Round.ToInt().Cmp(big.NewInt(2)) != 0 ||
+		e.BlockHeight.ToInt().Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("DKGReset mismatch: %+v", e)
+	}
+	if e := events[5]; e.Type != "ConfigurationChanged" {
+		t.Errorf("ConfigurationChanged mismatch: %+v", e)
+	}
+	e := events[6]
+	if e.Type != "Reported" || *e.NodeAddress != nodeAddr || e.Report == nil {
+		t.Fatalf("Reported mismatch: %+v", e)
+	}
+	if e.Report.Type != FineTypeForkVote || len(e.Report.Votes) != 2 ||
+		e.Report.Votes[0].BlockHash != vote1.BlockHash ||
+		e.Report.Votes[1].BlockHash != vote2.BlockHash {
+		t.Errorf("report mismatch: %+v", e.Report)
+	}
+
+	if _, err := DecodeGovernanceEvent(&types.Log{
+		Address: common.Address{1},
+		Topics:  logs[0].Topics,
+	}); err != errNotGovernanceEvent {
+		t.Errorf("expect errNotGovernanceEvent, have %v", err)
+	}
+	if _, err := DecodeGovernanceEvent(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics:  []common.Hash{{1}},
+	}); err != errUnknownEvent {
+		t.Errorf("expect errUnknownEvent, have %v", err)
+	}
+}
@@ -139,6 +139,16 @@ func (api *PrivateAdminAPI) IsProposing() bool {
 	return api.dex.IsProposing()
 }
 
+// PeerScores returns the reputation of the scored and banned peers.
+func (api *PrivateAdminAPI) PeerScores() []*PeerScore {
+	return api.dex.protocolManager.reputation.peerScores()
+}
+
+// Unban lifts the ban of a peer, and reports whether the peer was banned.
+func (api *PrivateAdminAPI) Unban(id string) bool {
+	return api.dex.protocolManager.reputation.unban(id)
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
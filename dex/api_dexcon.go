@@ -0,0 +1,385 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+var (
+	errRoundNotReady    = errors.New("round not ready")
+	errNoDexconMeta     = errors.New("block has no dexcon meta")
+	errBlockNotFound    = errors.New("block not found")
+	errDKGRoundNotReady = errors.New("DKG of round not started")
+)
+
+// RPCNodeInfo is the registered information of a node.
+type RPCNodeInfo struct {
+	PublicKey      hexutil.Bytes  `json:"publicKey"`
+	NodeKeyAddress common.Address `json:"nodeKeyAddress"`
+	Owner          common.Address `json:"owner"`
+	Name           string         `json:"name"`
+	Email          string         `json:"email"`
+	Location       string         `json:"location"`
+	Url            string         `json:"url"`
+	Staked         *hexutil.Big   `json:"staked"`
+	Fined          *hexutil.Big   `json:"fined"`
+}
+
+// RPCDKGStatus is the DKG status of a round.
+type RPCDKGStatus struct {
+	Round            hexutil.Uint64 `json:"round"`
+	ResetCount       hexutil.Uint64 `json:"resetCount"`
+	MasterPublicKeys hexutil.Uint   `json:"masterPublicKeys"`
+	Complaints       hexutil.Uint   `json:"complaints"`
+	MPKReady         bool           `json:"mpkReady"`
+	Final            bool           `json:"final"`
+	Success          bool           `json:"success"`
+}
+
+// RPCRoundMeta is the indexed metadata of a finalised round.
+type RPCRoundMeta struct {
+	Round          hexutil.Uint64  `json:"round"`
+	Height         hexutil.Uint64  `json:"height"`
+	CRS            common.Hash     `json:"crs"`
+	DKGResetCount  hexutil.Uint64  `json:"dkgResetCount"`
+	NotarySet      []hexutil.Bytes `json:"notarySet"`
+	GroupPublicKey hexutil.Bytes   `json:"groupPublicKey"`
+}
+
+// RPCProposerStats is the statistics of the blocks proposed by a node in a
+// round.
+type RPCProposerStats struct {
+	Proposer common.Address `json:"proposer"`
+	Blocks   hexutil.Uint64 `json:"blocks"`
+	GasUsed  hexutil.Uint64 `json:"gasUsed"`
+	Reward   *hexutil.Big   `json:"reward"`
+}
+
+// RPCRoundStats is the proposer statistics of a round.
+type RPCRoundStats struct {
+	Round       hexutil.Uint64      `json:"round"`
+	Blocks      hexutil.Uint64      `json:"blocks"`
+	EmptyBlocks hexutil.Uint64      `json:"emptyBlocks"`
+	Proposers   []*RPCProposerStats `json:"proposers"`
+}
+
+// RPCWitness is the witness of a consensus block.
+type RPCWitness struct {
+	Height hexutil.Uint64 `json:"height"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+// RPCDexconMeta is the consensus block stored in a block header.
+type RPCDexconMeta struct {
+	ProposerID  common.Hash    `json:"proposerID"`
+	Proposer    common.Address `json:"proposer"`
+	ParentHash  common.Hash    `json:"parentHash"`
+	Hash        common.Hash    `json:"hash"`
+	Round       hexutil.Uint64 `json:"round"`
+	Height      hexutil.Uint64 `json:"height"`
+	Timestamp   time.Time      `json:"timestamp"`
+	PayloadHash common.Hash    `json:"payloadHash"`
+	Witness     RPCWitness     `json:"witness"`
+	Randomness  hexutil.Bytes  `json:"randomness"`
+	Signature   hexutil.Bytes  `json:"signature"`
+}
+
+func newRPCDexconMeta(header *types.Header) (*RPCDexconMeta, error) {
+	if len(header.DexconMeta) == 0 {
+		return nil, errNoDexconMeta
+	}
+	var block coreTypes.Block
+	if err := rlp.DecodeBytes(header.DexconMeta, &block); err != nil {
+		return nil, err
+	}
+	return &RPCDexconMeta{
+		ProposerID:  common.Hash(block.ProposerID.Hash),
+		Proposer:    vm.IdToAddress(block.ProposerID),
+		ParentHash:  common.Hash(block.ParentHash),
+		Hash:        common.Hash(block.Hash),
+		Round:       hexutil.Uint64(block.Position.Round),
+		Height:      hexutil.Uint64(block.Position.Height),
+		Timestamp:   block.Timestamp,
+		PayloadHash: common.Hash(block.PayloadHash),
+		Witness: RPCWitness{
+			Height: hexutil.Uint64(block.Witness.Height),
+			Data:   block.Witness.Data,
+		},
+		// The randomness of the delivered block is stored in the header.
+		Randomness: header.Randomness,
+		Signature:  block.Signature.Signature,
+	}, nil
+}
+
+// PublicDexconAPI provides the consensus state of DEXON.
+type PublicDexconAPI struct {
+	chain *core.BlockChain
+	gov   *core.Governance
+}
+
+// NewPublicDexconAPI creates a new DEXON consensus API.
+func NewPublicDexconAPI(dex *Dexon) *PublicDexconAPI {
+	return &PublicDexconAPI{
+		chain: dex.BlockChain(),
+		gov:   dex.governance.Governance,
+	}
+}
+
+func (api *PublicDexconAPI) currentRound() uint64 {
+	return api.chain.CurrentBlock().Round()
+}
+
+// checkConfigRound returns error if the configuration of round is not
+// available yet.
+func (api *PublicDexconAPI) checkConfigRound(round uint64) error {
+	if round > api.currentRound()+dexCore.ConfigRoundShift {
+		return errRoundNotReady
+	}
+	return nil
+}
+
+// checkRoundState returns error if the governance state at the height of
+// round is not available, e.g. pruned, which the governance helpers expect
+// to exist.
+func (api *PublicDexconAPI) checkRoundState(round uint64) error {
+	if _, err := api.chain.State(); err != nil {
+		return err
+	}
+	height := api.gov.GetRoundHeight(round)
+	if round != 0 && height == 0 {
+		return errRoundNotReady
+	}
+	header := api.chain.GetHeaderByNumber(height)
+	if header == nil {
+		return errRoundNotReady
+	}
+	_, err := api.chain.StateAt(header.Root)
+	return err
+}
+
+// checkConfigState returns error if the governance state holding the
+// configuration of round is not available.
+func (api *PublicDexconAPI) checkConfigState(round uint64) error {
+	if round < dexCore.ConfigRoundShift {
+		return api.checkRoundState(0)
+	}
+	return api.checkRoundState(round - dexCore.ConfigRoundShift)
+}
+
+// checkCRSState returns error if the governance state holding the CRS of
+// round is not available.
+func (api *PublicDexconAPI) checkCRSState(round uint64) error {
+	if round <= dexCore.DKGDelayRound {
+		return api.checkRoundState(0)
+	}
+	if round >= api.gov.CRSRound() {
+		// Read from the head state.
+		return nil
+	}
+	return api.checkRoundState(round)
+}
+
+// Round returns the round of the current block.
+func (api *PublicDexconAPI) Round() hexutil.Uint64 {
+	return hexutil.Uint64(api.currentRound())
+}
+
+// GetRoundHeight returns the height of the first block in round.
+func (api *PublicDexconAPI) GetRoundHeight(round hexutil.Uint64) (hexutil.Uint64, error) {
+	height, ok := api.chain.GetRoundHeight(uint64(round))
+	if !ok {
+		return 0, errRoundNotReady
+	}
+	return hexutil.Uint64(height), nil
+}
+
+// GetNotarySet returns the nodes in the notary set of round.
+func (api *PublicDexconAPI) GetNotarySet(round hexutil.Uint64) ([]*RPCNodeInfo, error) {
+	if err := api.checkConfigRound(uint64(round)); err != nil {
+		return nil, err
+	}
+	if err := api.checkConfigState(uint64(round)); err != nil {
+		return nil, err
+	}
+	if err := api.checkCRSState(uint64(round)); err != nil {
+		return nil, err
+	}
+	notarySet, err := api.gov.NotarySet(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*RPCNodeInfo, 0, len(notarySet))
+	for _, node := range api.gov.GetStateForConfigAtRound(uint64(round)).Nodes() {
+		if _, exist := notarySet[hex.EncodeToString(node.PublicKey)]; !exist {
+			continue
+		}
+		info := &RPCNodeInfo{
+			PublicKey: node.PublicKey,
+			Owner:     node.Owner,
+			Name:      node.Name,
+			Email:     node.Email,
+			Location:  node.Location,
+			Url:       node.Url,
+			Staked:    (*hexutil.Big)(node.Staked),
+			Fined:     (*hexutil.Big)(node.Fined),
+		}
+		if pub, err := crypto.UnmarshalPubkey(node.PublicKey); err == nil {
+			info.NodeKeyAddress = crypto.PubkeyToAddress(*pub)
+		}
+		nodes = append(nodes, info)
+	}
+	return nodes, nil
+}
+
+// GetCRS returns the CRS of round.
+func (api *PublicDexconAPI) GetCRS(round hexutil.Uint64) (common.Hash, error) {
+	// CRS of the first rounds are derived from the genesis CRS.
+	if uint64(round) > dexCore.DKGDelayRound && uint64(round) > api.gov.CRSRound() {
+		return common.Hash{}, errRoundNotReady
+	}
+	if err := api.checkCRSState(uint64(round)); err != nil {
+		return common.Hash{}, err
+	}
+	return common.Hash(api.gov.CRS(uint64(round))), nil
+}
+
+// GetDKGStatus returns the DKG status of round.
+func (api *PublicDexconAPI) GetDKGStatus(round hexutil.Uint64) (*RPCDKGStatus, error) {
+	r := uint64(round)
+	if _, err := api.chain.State(); err != nil {
+		return nil, err
+	}
+	dkgRound := api.gov.GetHeadState().DKGRound().Uint64()
+	if r > dkgRound {
+		return nil, errDKGRoundNotReady
+	}
+	if r < dkgRound {
+		if err := api.checkRoundState(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := api.checkConfigState(r); err != nil {
+		return nil, err
+	}
+	return &RPCDKGStatus{
+		Round:            round,
+		ResetCount:       hexutil.Uint64(api.gov.DKGResetCount(r)),
+		MasterPublicKeys: hexutil.Uint(len(api.gov.DKGMasterPublicKeys(r))),
+		Complaints:       hexutil.Uint(len(api.gov.DKGComplaints(r))),
+		MPKReady:         api.gov.IsDKGMPKReady(r),
+		Final:            api.gov.IsDKGFinal(r),
+		Success:          api.gov.IsDKGSuccess(r),
+	}, nil
+}
+
+// GetDKGResetCount returns the number of DKG resets of round.
+func (api *PublicDexconAPI) GetDKGResetCount(round hexutil.Uint64) (hexutil.Uint64, error) {
+	if _, err := api.chain.State(); err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(api.gov.DKGResetCount(uint64(round))), nil
+}
+
+// GetRoundMeta returns the metadata of a finalised round, which is available
+// even if the governance state of the round is pruned.
+func (api *PublicDexconAPI) GetRoundMeta(round hexutil.Uint64) (*RPCRoundMeta, error) {
+	meta := api.chain.GetRoundMeta(uint64(round))
+	if meta == nil {
+		return nil, errRoundNotReady
+	}
+	notarySet := make([]hexutil.Bytes, len(meta.NotarySet))
+	for i, key := range meta.NotarySet {
+		notarySet[i] = key
+	}
+	return &RPCRoundMeta{
+		Round:          hexutil.Uint64(meta.Round),
+		Height:         hexutil.Uint64(meta.Height),
+		CRS:            meta.CRS,
+		DKGResetCount:  hexutil.Uint64(meta.DKGResetCount),
+		NotarySet:      notarySet,
+		GroupPublicKey: meta.GroupPublicKey,
+	}, nil
+}
+
+// GetRoundStats returns the proposer statistics of a round, the statistics of
+// the current round cover the blocks up to the head.
+func (api *PublicDexconAPI) GetRoundStats(round hexutil.Uint64) (*RPCRoundStats, error) {
+	if uint64(round) > api.chain.CurrentBlock().Round() {
+		return nil, errRoundNotReady
+	}
+	stats, err := api.chain.GetRoundStats(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	proposers := make([]*RPCProposerStats, len(stats.Proposers))
+	for i, proposer := range stats.Proposers {
+		proposers[i] = &RPCProposerStats{
+			Proposer: proposer.Proposer,
+			Blocks:   hexutil.Uint64(proposer.Blocks),
+			GasUsed:  hexutil.Uint64(proposer.GasUsed),
+			Reward:   (*hexutil.Big)(proposer.Reward),
+		}
+	}
+	return &RPCRoundStats{
+		Round:       hexutil.Uint64(stats.Round),
+		Blocks:      hexutil.Uint64(stats.Blocks),
+		EmptyBlocks: hexutil.Uint64(stats.EmptyBlocks),
+		Proposers:   proposers,
+	}, nil
+}
+
+// GetDexconMetaByNumber returns the decoded consensus block of the block.
+func (api *PublicDexconAPI) GetDexconMetaByNumber(
+	ctx context.Context, blockNr rpc.BlockNumber) (*RPCDexconMeta, error) {
+	var header *types.Header
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(blockNr))
+	}
+	if header == nil {
+		return nil, errBlockNotFound
+	}
+	return newRPCDexconMeta(header)
+}
+
+// GetDexconMetaByHash returns the decoded consensus block of the block.
+func (api *PublicDexconAPI) GetDexconMetaByHash(
+	ctx context.Context, hash common.Hash) (*RPCDexconMeta, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errBlockNotFound
+	}
+	return newRPCDexconMeta(header)
+}
@@ -0,0 +1,406 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+func TestPublicDexconAPI(t *testing.T) {
+	masterKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, _, err := newDexon(masterKey, 0)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	api := NewPublicDexconAPI(dex)
+
+	if round := api.Round(); round != 0 {
+		t.Errorf("round mismatch: have %d, want 0", round)
+	}
+	if height, err := api.GetRoundHeight(0); err != nil || height != 0 {
+		t.Errorf("round height mismatch: have %d %v, want 0", height, err)
+	}
+	if _, err := api.GetRoundHeight(10); err != errRoundNotReady {
+		t.Errorf("expect errRoundNotReady, have %v", err)
+	}
+
+	nodes, err := api.GetNotarySet(0)
+	if err != nil {
+		t.Fatalf("Get notary set fail: %v", err)
+	}
+	notarySet, err := dex.governance.NotarySet(0)
+	if err != nil {
+		t.Fatalf("Get notary set fail: %v", err)
+	}
+	if len(nodes) == 0 || len(nodes) != len(notarySet) {
+		t.Errorf("notary set size mismatch: have %d, want %d",
+			len(nodes), len(notarySet))
+	}
+	for _, node := range nodes {
+		pub, err := crypto.UnmarshalPubkey(node.PublicKey)
+		if err != nil {
+			t.Fatalf("Invalid public key: %v", err)
+		}
+		if node.NodeKeyAddress != crypto.PubkeyToAddress(*pub) {
+			t.Errorf("node key address mismatch")
+		}
+	}
+	if _, err := api.GetNotarySet(10); err != errRoundNotReady {
+		t.Errorf("expect errRoundNotReady, have %v", err)
+	}
+
+	crs, err := api.GetCRS(0)
+	if err != nil {
+		t.Fatalf("Get CRS fail: %v", err)
+	}
+	if crs != common.Hash(dex.governance.CRS(0)) {
+		t.Errorf("CRS mismatch")
+	}
+	if _, err := api.GetCRS(10); err != errRoundNotReady {
+		t.Errorf("expect errRoundNotReady, have %v", err)
+	}
+
+	status, err := api.GetDKGStatus(0)
+	if err != nil {
+		t.Fatalf("Get DKG status fail: %v", err)
+	}
+	if status.ResetCount != 0 || status.MasterPublicKeys != 0 || status.Final {
+		t.Errorf("unexpected DKG status: %+v", status)
+	}
+	if _, err := api.GetDKGStatus(10); err != errDKGRoundNotReady {
+		t.Errorf("expect errDKGRoundNotReady, have %v", err)
+	}
+
+	// Genesis block carries no consensus block.
+	if _, err := api.GetDexconMetaByNumber(context.Background(),
+		rpc.LatestBlockNumber); err != errNoDexconMeta {
+		t.Errorf("expect errNoDexconMeta, have %v", err)
+	}
+	if _, err := api.GetDexconMetaByNumber(context.Background(), 10); err != errBlockNotFound {
+		t.Errorf("expect errBlockNotFound, have %v", err)
+	}
+}
+
+func TestNewRPCDexconMeta(t *testing.T) {
+	block := &coreTypes.Block{
+		ProposerID: coreTypes.NodeID{Hash: coreCommon.NewRandomHash()},
+		ParentHash: coreCommon.NewRandomHash(),
+		Hash:       coreCommon.NewRandomHash(),
+		Position:   coreTypes.Position{Round: 3, Height: 100},
+		Timestamp:  time.Now().UTC(),
+		Witness:    coreTypes.Witness{Height: 99, Data: []byte{1, 2, 3}},
+	}
+	meta, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatalf("Encode block fail: %v", err)
+	}
+	header := &types.Header{DexconMeta: meta, Randomness: []byte{4, 5, 6}}
+
+	m, err := newRPCDexconMeta(header)
+	if err != nil {
+		t.Fatalf("Decode dexcon meta fail: %v", err)
+	}
+	if m.Hash != common.Hash(block.Hash) ||
+		m.ParentHash != common.Hash(block.ParentHash) ||
+		m.ProposerID != common.Hash(block.ProposerID.Hash) ||
+		m.Proposer != vm.IdToAddress(block.ProposerID) {
+		t.Errorf("block mismatch: %+v", m)
+	}
+	if m.Round != 3 || m.Height != 100 || !m.Timestamp.Equal(block.Timestamp) {
+		t.Errorf("position mismatch: %+v", m)
+	}
+	if m.Witness.Height != 99 || !bytes.Equal(m.Witness.Data, block.Witness.Data) ||
+		!bytes.Equal(m.Randomness, header.Randomness) {
+		t.Errorf("witness or randomness mismatch: %+v", m)
+	}
+
+	header.DexconMeta = []byte{1}
+	if _, err := newRPCDexconMeta(header); err == nil {
+		t.Errorf("expect error for malformed dexcon meta")
+	}
+}
+
+func TestGetRoundMeta(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, common.Address{1}, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	api := NewPublicDexconAPI(dex)
+
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 3 {
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+
+	// Rounds are indexed in background once finalised.
+	waitRoundMeta := func(chain *core.BlockChain, round uint64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for chain.GetRoundMeta(round) == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("round %d not indexed", round)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	nodePK := crypto.FromECDSAPub(&nodeKey.PublicKey)
+	for round := uint64(0); round < 3; round++ {
+		waitRoundMeta(dex.blockchain, round)
+		meta, err := api.GetRoundMeta(hexutil.Uint64(round))
+		if err != nil {
+			t.Fatalf("Get round meta fail: %v", err)
+		}
+		height, _ := dex.blockchain.GetRoundHeight(round)
+		if uint64(meta.Height) != height {
+			t.Errorf("round %d height mismatch: have %d, want %d",
+				round, meta.Height, height)
+		}
+		if meta.CRS != common.Hash(dex.governance.CRS(round)) {
+			t.Errorf("round %d CRS mismatch", round)
+		}
+		if len(meta.NotarySet) != 1 || !bytes.Equal(meta.NotarySet[0], nodePK) {
+			t.Errorf("round %d notary set mismatch: %v", round, meta.NotarySet)
+		}
+		if dex.governance.IsDKGFinal(round) != (len(meta.GroupPublicKey) > 0) {
+			t.Errorf("round %d group public key mismatch", round)
+		}
+	}
+	if _, err := api.GetRoundMeta(3); err != errRoundNotReady {
+		t.Errorf("expect errRoundNotReady, have %v", err)
+	}
+
+	// Missing rounds are backfilled on start.
+	rawdb.DeleteRoundMeta(dex.chainDb, 1)
+	if dex.blockchain.GetRoundMeta(1) != nil {
+		t.Fatalf("round meta not deleted")
+	}
+	chain, err := core.NewBlockChain(dex.chainDb, nil, dex.chainConfig,
+		dexcon.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("New blockchain fail: %v", err)
+	}
+	defer chain.Stop()
+	waitRoundMeta(chain, 1)
+
+	// The metadata is collected from the governance states stored at the
+	// round heights, so the rounds are backfilled with the states pruned.
+	var roots []common.Hash
+	for round := uint64(1); round < 3; round++ {
+		rawdb.DeleteRoundMeta(dex.chainDb, round)
+		height, _ := dex.blockchain.GetRoundHeight(round)
+		root := dex.blockchain.GetHeaderByNumber(height).Root
+		if err := dex.chainDb.Delete(root.Bytes()); err != nil {
+			t.Fatalf("Delete state fail: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	pruned, err := core.NewBlockChain(dex.chainDb, nil, dex.chainConfig,
+		dexcon.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("New blockchain fail: %v", err)
+	}
+	defer pruned.Stop()
+	for _, root := range roots {
+		if pruned.HasState(root) {
+			t.Fatalf("state %x not pruned", root)
+		}
+	}
+	waitRoundMeta(pruned, 1)
+	waitRoundMeta(pruned, 2)
+}
+
+func TestPublicDexconAPIPrunedState(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, common.Address{1}, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 4 {
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+
+	// Prune the states at the heights of round 1 and 2.
+	for round := uint64(1); round < 3; round++ {
+		height, _ := dex.blockchain.GetRoundHeight(round)
+		root := dex.blockchain.GetHeaderByNumber(height).Root
+		if err := dex.chainDb.Delete(root.Bytes()); err != nil {
+			t.Fatalf("Delete state fail: %v", err)
+		}
+	}
+	chain, err := core.NewBlockChain(dex.chainDb, nil, dex.chainConfig,
+		dexcon.New(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("New blockchain fail: %v", err)
+	}
+	defer chain.Stop()
+	api := &PublicDexconAPI{
+		chain: chain,
+		gov:   core.NewGovernance(core.NewGovernanceStateDB(chain)),
+	}
+
+	// The CRS of round 2 and the configurations of round 3 and 4 are read
+	// from the pruned states.
+	for round := uint64(2); round <= 4; round++ {
+		if _, err := api.GetNotarySet(hexutil.Uint64(round)); err == nil {
+			t.Errorf("expect error for notary set of round %d", round)
+		}
+	}
+	if _, err := api.GetCRS(2); err == nil {
+		t.Errorf("expect error for CRS of round 2")
+	}
+	for round := uint64(2); round <= 3; round++ {
+		if _, err := api.GetDKGStatus(hexutil.Uint64(round)); err == nil {
+			t.Errorf("expect error for DKG status of round %d", round)
+		}
+	}
+
+	// Rounds with states available are still served.
+	if _, err := api.GetNotarySet(1); err != nil {
+		t.Errorf("Get notary set fail: %v", err)
+	}
+	if _, err := api.GetCRS(3); err != nil {
+		t.Errorf("Get CRS fail: %v", err)
+	}
+	if _, err := api.GetDKGStatus(0); err != nil {
+		t.Errorf("Get DKG status fail: %v", err)
+	}
+	if _, err := api.GetDKGResetCount(2); err != nil {
+		t.Errorf("Get DKG reset count fail: %v", err)
+	}
+}
+
+func TestGetRoundStats(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, common.Address{1}, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	api := NewPublicDexconAPI(dex)
+
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 2 ||
+		dex.blockchain.CurrentBlock().NumberU64()%5 != 2 {
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+
+	// Round 0 and 1 are indexed once finalised, round 2 is computed.
+	deadline := time.Now().Add(5 * time.Second)
+	for !rawdb.HasRoundStats(dex.chainDb, 1) {
+		if time.Now().After(deadline) {
+			t.Fatalf("round stats not indexed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rawdb.HasRoundStats(dex.chainDb, 2) {
+		t.Errorf("stats of current round are indexed")
+	}
+
+	proposer := crypto.PubkeyToAddress(nodeKey.PublicKey)
+	head := dex.blockchain.CurrentBlock().NumberU64()
+	for round := uint64(0); round <= 2; round++ {
+		stats, err := api.GetRoundStats(hexutil.Uint64(round))
+		if err != nil {
+			t.Fatalf("Get round stats fail: %v", err)
+		}
+		from, _ := dex.blockchain.GetRoundHeight(round)
+		to := head
+		if next, ok := dex.blockchain.GetRoundHeight(round + 1); ok {
+			to = next - 1
+		}
+		if from == 0 {
+			from = 1
+		}
+		var (
+			gasUsed uint64
+			reward  = new(big.Int)
+		)
+		for number := from; number <= to; number++ {
+			header := dex.blockchain.GetHeaderByNumber(number)
+			gasUsed += header.GasUsed
+			reward.Add(reward, header.Reward)
+		}
+
+		blocks := to - from + 1
+		if uint64(stats.Blocks) != blocks || stats.EmptyBlocks != 0 {
+			t.Errorf("round %d blocks mismatch: have %d (%d empty), want %d",
+				round, stats.Blocks, stats.EmptyBlocks, blocks)
+		}
+		if len(stats.Proposers) != 1 {
+			t.Fatalf("round %d proposers mismatch: %v", round, stats.Proposers)
+		}
+		s := stats.Proposers[0]
+		if s.Proposer != proposer || uint64(s.Blocks) != blocks {
+			t.Errorf("round %d proposer mismatch: have %s %d, want %s %d",
+				round, s.Proposer.Hex(), s.Blocks, proposer.Hex(), blocks)
+		}
+		if uint64(s.GasUsed) != gasUsed || s.Reward.ToInt().Cmp(reward) != 0 {
+			t.Errorf("round %d usage mismatch: have %d %v, want %d %v",
+				round, s.GasUsed, s.Reward, gasUsed, reward)
+		}
+	}
+	if _, err := api.GetRoundStats(3); err != errRoundNotReady {
+		t.Errorf("expect errRoundNotReady, have %v", err)
+	}
+}
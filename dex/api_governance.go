@@ -0,0 +1,153 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"fmt"
+
+	dexon "github.com/dexon-foundation/dexon"
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/eth/filters"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+// GovernanceEventFilter selects governance events by event type and node
+// address. Empty fields match everything.
+type GovernanceEventFilter struct {
+	Types         []string         `json:"types"`
+	NodeAddresses []common.Address `json:"nodeAddresses"`
+}
+
+// topics converts the filter to log filter topics.
+func (f *GovernanceEventFilter) topics() ([][]common.Hash, error) {
+	topics := [][]common.Hash{nil}
+	for _, name := range f.Types {
+		event, ok := vm.GovernanceABI.Events[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown governance event type %q", name)
+		}
+		topics[0] = append(topics[0], event.Id())
+	}
+	if len(f.NodeAddresses) > 0 {
+		var nodes []common.Hash
+		for _, addr := range f.NodeAddresses {
+			nodes = append(nodes, addr.Hash())
+		}
+		topics = append(topics, nodes)
+	}
+	return topics, nil
+}
+
+// decodeGovernanceEvents decodes governance logs, logs that failed to decode
+// are skipped.
+func decodeGovernanceEvents(logs []*types.Log) []*vm.GovernanceEvent {
+	events := make([]*vm.GovernanceEvent, 0, len(logs))
+	for _, l := range logs {
+		event, err := vm.DecodeGovernanceEvent(l)
+		if err != nil {
+			log.Warn("Failed to decode governance event",
+				"block", l.BlockNumber, "tx", l.TxHash, "err", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// PublicGovernanceAPI provides decoded governance contract events.
+type PublicGovernanceAPI struct {
+	backend filters.Backend
+	events  *filters.EventSystem
+}
+
+// NewPublicGovernanceAPI creates a new governance event API.
+func NewPublicGovernanceAPI(backend filters.Backend) *PublicGovernanceAPI {
+	return &PublicGovernanceAPI{
+		backend: backend,
+		events:  filters.NewEventSystem(backend.EventMux(), backend, false),
+	}
+}
+
+// GovernanceEvents creates a subscription that fires for governance events
+// matching the filter. Events removed by reorg are sent again with the
+// removed flag set.
+func (api *PublicGovernanceAPI) GovernanceEvents(
+	ctx context.Context, filter *GovernanceEventFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if filter == nil {
+		filter = &GovernanceEventFilter{}
+	}
+	topics, err := filter.topics()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rpcSub      = notifier.CreateSubscription()
+		matchedLogs = make(chan []*types.Log)
+	)
+	logsSub, err := api.events.SubscribeLogs(dexon.FilterQuery{
+		Addresses: []common.Address{vm.GovernanceContractAddress},
+		Topics:    topics,
+	}, matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case logs := <-matchedLogs:
+				for _, event := range decodeGovernanceEvents(logs) {
+					notifier.Notify(rpcSub.ID, event)
+				}
+			case <-rpcSub.Err():
+				logsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				logsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// GetGovernanceEvents returns governance events of the given types within
+// the block range, all types are returned if types is empty.
+func (api *PublicGovernanceAPI) GetGovernanceEvents(ctx context.Context,
+	fromBlock, toBlock rpc.BlockNumber, types []string) ([]*vm.GovernanceEvent, error) {
+	topics, err := (&GovernanceEventFilter{Types: types}).topics()
+	if err != nil {
+		return nil, err
+	}
+	filter := filters.NewRangeFilter(api.backend, fromBlock.Int64(),
+		toBlock.Int64(), []common.Address{vm.GovernanceContractAddress}, topics)
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeGovernanceEvents(logs), nil
+}
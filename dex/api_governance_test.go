@@ -0,0 +1,78 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+)
+
+func TestGovernanceEventFilter(t *testing.T) {
+	node := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	filter := &GovernanceEventFilter{
+		Types:         []string{"Staked", "Fined"},
+		NodeAddresses: []common.Address{node},
+	}
+	topics, err := filter.topics()
+	if err != nil {
+		t.Fatalf("failed to convert filter: %v", err)
+	}
+	if len(topics) != 2 || len(topics[0]) != 2 || len(topics[1]) != 1 {
+		t.Fatalf("topics mismatch: %v", topics)
+	}
+	if topics[0][0] != vm.GovernanceABI.Events["Staked"].Id() ||
+		topics[0][1] != vm.GovernanceABI.Events["Fined"].Id() ||
+		topics[1][0] != node.Hash() {
+		t.Errorf("topics mismatch: %v", topics)
+	}
+
+	topics, err = (&GovernanceEventFilter{}).topics()
+	if err != nil {
+		t.Fatalf("failed to convert filter: %v", err)
+	}
+	if len(topics) != 1 || topics[0] != nil {
+		t.Errorf("empty filter should match all events: %v", topics)
+	}
+
+	filter = &GovernanceEventFilter{Types: []string{"Unknown"}}
+	if _, err := filter.topics(); err == nil {
+		t.Errorf("expect error for unknown event type")
+	}
+
+	// Logs failed to decode are skipped.
+	logs := []*types.Log{
+		{
+			Address: vm.GovernanceContractAddress,
+			Topics: []common.Hash{
+				vm.GovernanceABI.Events["NodeAdded"].Id(), node.Hash()},
+			BlockNumber: 10,
+		},
+		{
+			Address: vm.GovernanceContractAddress,
+			Topics:  []common.Hash{vm.GovernanceABI.Events["Staked"].Id()},
+		},
+	}
+	events := decodeGovernanceEvents(logs)
+	if len(events) != 1 || events[0].Type != "NodeAdded" ||
+		*events[0].NodeAddress != node || events[0].BlockNumber != 10 {
+		t.Errorf("events mismatch: %+v", events)
+	}
+}
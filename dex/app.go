@@ -34,6 +34,7 @@ import (
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/event"
 	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/params"
 	"github.com/dexon-foundation/dexon/rlp"
 )
 
@@ -56,6 +57,10 @@ type DexconApp struct {
 	addressCounter  map[common.Address]uint64
 	undeliveredNum  uint64
 	deliveredHeight uint64
+
+	// randomnessLatency is the time the last delivered block waited for its
+	// randomness after being confirmed.
+	randomnessLatency time.Duration
 }
 
 func NewDexconApp(txPool *core.TxPool, blockchain *core.BlockChain, gov *DexconGovernance,
@@ -185,20 +190,9 @@ func (d *DexconApp) preparePayload(ctx context.Context, position coreTypes.Posit
 	blockGasUsed := new(big.Int)
 	allTxs := make([]*types.Transaction, 0, 10000)
 
-addressMap:
+	// Drop the transactions already included in undelivered blocks.
+	balances := make(map[common.Address]*big.Int, len(txsMap))
 	for address, txs := range txsMap {
-		select {
-		case <-ctx.Done():
-			break addressMap
-		default:
-		}
-
-		balance := state.GetBalance(address)
-		cost, exist := d.addressCost[address]
-		if exist {
-			balance = new(big.Int).Sub(balance, cost)
-		}
-
 		var expectNonce uint64
 		lastConfirmedNonce, exist := d.addressNonce[address]
 		if !exist {
@@ -208,43 +202,98 @@ addressMap:
 		}
 
 		if len(txs) == 0 {
+			delete(txsMap, address)
 			continue
 		}
 
+		// Warning: the pending tx will also affect by syncing, so startIndex maybe negative
 		firstNonce := txs[0].Nonce()
 		startIndex := int(expectNonce - firstNonce)
+		if startIndex < 0 || startIndex >= len(txs) {
+			delete(txsMap, address)
+			continue
+		}
+		txsMap[address] = txs[startIndex:]
 
-		// Warning: the pending tx will also affect by syncing, so startIndex maybe negative
-		for i := startIndex; i >= 0 && i < len(txs); i++ {
-			tx := txs[i]
-			if minGasPrice.Cmp(tx.GasPrice()) > 0 {
-				log.Error("Invalid gas price minGas(%v) > get(%v)", minGasPrice, tx.GasPrice())
-				break
-			}
+		balance := state.GetBalance(address)
+		cost, exist := d.addressCost[address]
+		if exist {
+			balance = new(big.Int).Sub(balance, cost)
+		}
+		balances[address] = balance
+	}
 
-			intrGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, true)
-			if err != nil {
-				log.Error("Failed to calculate intrinsic gas", "error", err)
-				return nil, fmt.Errorf("calculate intrinsic gas error: %v", err)
-			}
-			if tx.Gas() < intrGas {
-				log.Error("Intrinsic gas too low", "txHash", tx.Hash().String())
-				break
-			}
+	signer := types.MakeSigner(d.blockchain.Config(),
+		new(big.Int).SetUint64(position.Height))
+	txsByPrice := types.NewTransactionsByPriceAndNonce(signer, txsMap)
+	senderTxsCount := make(map[common.Address]int)
+
+txLoop:
+	for {
+		select {
+		case <-ctx.Done():
+			break txLoop
+		default:
+		}
 
-			balance = new(big.Int).Sub(balance, tx.Cost())
-			if balance.Cmp(big.NewInt(0)) < 0 {
-				log.Warn("Insufficient funds for gas * price + value", "txHash", tx.Hash().String())
+		tx := txsByPrice.Peek()
+		if tx == nil {
+			break
+		}
+		address, err := types.Sender(signer, tx)
+		if err != nil {
+			log.Error("Failed to get tx sender", "txHash", tx.Hash().String(), "error", err)
+			txsByPrice.Pop()
+			continue
+		}
+
+		if minGasPrice.Cmp(tx.GasPrice()) > 0 {
+			log.Error("Invalid gas price minGas(%v) > get(%v)", minGasPrice, tx.GasPrice())
+			txsByPrice.Pop()
+			continue
+		}
+
+		intrGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, true)
+		if err != nil {
+			log.Error("Failed to calculate intrinsic gas", "error", err)
+			return nil, fmt.Errorf("calculate intrinsic gas error: %v", err)
+		}
+		if tx.Gas() < intrGas {
+			log.Error("Intrinsic gas too low", "txHash", tx.Hash().String())
+			txsByPrice.Pop()
+			continue
+		}
+
+		balance := new(big.Int).Sub(balances[address], tx.Cost())
+		if balance.Cmp(big.NewInt(0)) < 0 {
+			log.Warn("Insufficient funds for gas * price + value", "txHash", tx.Hash().String())
+			txsByPrice.Pop()
+			continue
+		}
+
+		gasUsed := new(big.Int).Add(blockGasUsed, new(big.Int).SetUint64(tx.Gas()))
+		if gasUsed.Cmp(blockGasLimit) > 0 {
+			// Stop if there is no room for even the simplest transaction,
+			// otherwise skip this sender and try the others.
+			if new(big.Int).Sub(blockGasLimit, blockGasUsed).Cmp(
+				new(big.Int).SetUint64(params.TxGas)) < 0 {
 				break
 			}
+			txsByPrice.Pop()
+			continue
+		}
 
-			blockGasUsed = new(big.Int).Add(blockGasUsed, big.NewInt(int64(tx.Gas())))
-			if blockGasUsed.Cmp(blockGasLimit) > 0 {
-				break addressMap
-			}
+		blockGasUsed = gasUsed
+		balances[address] = balance
+		allTxs = append(allTxs, tx)
 
-			allTxs = append(allTxs, tx)
+		senderTxsCount[address]++
+		if d.config.PayloadTxsPerSender > 0 &&
+			senderTxsCount[address] >= d.config.PayloadTxsPerSender {
+			txsByPrice.Pop()
+			continue
 		}
+		txsByPrice.Shift()
 	}
 
 	return rlp.EncodeToBytes(&allTxs)
@@ -469,6 +518,10 @@ func (d *DexconApp) BlockDelivered(
 		}
 	}
 
+	latency := time.Since(d.confirmedBlocks[blockHash].confirmedAt)
+	propBlockRandomnessLatency.Update(latency.Nanoseconds() / 1000)
+	d.randomnessLatency = latency
+
 	d.removeConfirmedBlock(blockHash)
 	d.deliveredHeight = block.Position.Height
 
@@ -494,9 +547,10 @@ type addressInfo struct {
 }
 
 type blockInfo struct {
-	addresses map[common.Address]*addressInfo
-	block     *coreTypes.Block
-	txs       types.Transactions
+	addresses   map[common.Address]*addressInfo
+	block       *coreTypes.Block
+	txs         types.Transactions
+	confirmedAt time.Time
 }
 
 func (d *DexconApp) addConfirmedBlock(block *coreTypes.Block) error {
@@ -544,9 +598,10 @@ func (d *DexconApp) addConfirmedBlock(block *coreTypes.Block) error {
 	}
 
 	d.confirmedBlocks[block.Hash] = &blockInfo{
-		addresses: addressMap,
-		block:     block,
-		txs:       transactions,
+		addresses:   addressMap,
+		block:       block,
+		txs:         transactions,
+		confirmedAt: time.Now(),
 	}
 
 	d.undeliveredNum++
@@ -578,6 +633,23 @@ func (d *DexconApp) getConfirmedBlockByHash(hash coreCommon.Hash) (*coreTypes.Bl
 	return info.block, info.txs
 }
 
+// finalizedHeight returns the height of the last block confirmed by the
+// consensus core. The blocks above the delivered height are waiting for their
+// randomness to be executed.
+func (d *DexconApp) finalizedHeight() uint64 {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+	return d.deliveredHeight + d.undeliveredNum
+}
+
+// lastRandomnessLatency returns the time the last delivered block waited for
+// its randomness.
+func (d *DexconApp) lastRandomnessLatency() time.Duration {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+	return d.randomnessLatency
+}
+
 func (d *DexconApp) SubscribeNewFinalizedBlockEvent(
 	ch chan<- core.NewFinalizedBlockEvent) event.Subscription {
 	return d.scope.Track(d.finalizedBlockFeed.Subscribe(ch))
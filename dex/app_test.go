@@ -1,6 +1,7 @@
 package dex
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
@@ -2343,6 +2344,7 @@ func newDexon(masterKey *ecdsa.PrivateKey, accountNum int) (*Dexon, []*ecdsa.Pri
 	}
 
 	txPoolConfig := core.DefaultTxPoolConfig
+	txPoolConfig.Journal = ""
 	dex.txPool = core.NewTxPool(txPoolConfig, chainConfig, dex.blockchain)
 
 	dex.APIBackend = &DexAPIBackend{dex, nil}
@@ -2352,3 +2354,149 @@ func newDexon(masterKey *ecdsa.PrivateKey, accountNum int) (*Dexon, []*ecdsa.Pri
 
 	return dex, accounts, nil
 }
+
+func TestPreparePayloadPriority(t *testing.T) {
+	masterKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+
+	dex, keys, err := newDexon(masterKey, 3)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+
+	// Sender i pays (i+1) * minGasPrice for each of its transactions.
+	minGasPrice := dex.app.gov.GetHeadState().MinGasPrice()
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	var txs []*types.Transaction
+	for i, key := range keys {
+		gasPrice := new(big.Int).Mul(minGasPrice, big.NewInt(int64(i+1)))
+		for nonce := uint64(0); nonce < 5; nonce++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{},
+				big.NewInt(1), 21000, gasPrice, nil), signer, key)
+			if err != nil {
+				t.Fatalf("Sign tx fail: %v", err)
+			}
+			txs = append(txs, tx)
+		}
+	}
+	for _, err := range dex.txPool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("Add tx fail: %v", err)
+		}
+	}
+
+	checkPayload := func(expectSenders []int) {
+		payload, err := dex.app.preparePayload(context.Background(), coreTypes.Position{Height: 1})
+		if err != nil {
+			t.Fatalf("Prepare payload fail: %v", err)
+		}
+		var payloadTxs types.Transactions
+		if err := rlp.DecodeBytes(payload, &payloadTxs); err != nil {
+			t.Fatalf("Decode payload fail: %v", err)
+		}
+		if len(payloadTxs) != len(expectSenders) {
+			t.Fatalf("Unexpected payload size: expect %d, get %d", len(expectSenders), len(payloadTxs))
+		}
+		for i, tx := range payloadTxs {
+			sender, err := types.Sender(signer, tx)
+			if err != nil {
+				t.Fatalf("Get sender fail: %v", err)
+			}
+			if expect := crypto.PubkeyToAddress(keys[expectSenders[i]].PublicKey); sender != expect {
+				t.Fatalf("Unexpected sender of tx %d: expect %s, get %s", i, expect.String(), sender.String())
+			}
+		}
+	}
+
+	// Transactions are ordered by gas price across senders.
+	checkPayload([]int{2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0})
+
+	// Each sender gets limited slots in a block.
+	dex.app.config.PayloadTxsPerSender = 2
+	checkPayload([]int{2, 2, 1, 1, 0, 0})
+}
+
+func newBenchmarkPreparePayloadDexon(b *testing.B) *Dexon {
+	const (
+		senders      = 1000
+		txsPerSender = 100
+	)
+
+	masterKey, err := crypto.GenerateKey()
+	if err != nil {
+		b.Fatalf("Generate key fail: %v", err)
+	}
+
+	dex, keys, err := newDexon(masterKey, senders)
+	if err != nil {
+		b.Fatalf("New dexon fail: %v", err)
+	}
+
+	txPoolConfig := core.DefaultTxPoolConfig
+	txPoolConfig.Journal = ""
+	txPoolConfig.AccountSlots = txsPerSender
+	txPoolConfig.GlobalSlots = senders * txsPerSender
+	dex.txPool.Stop()
+	dex.txPool = core.NewTxPool(txPoolConfig, dex.chainConfig, dex.blockchain)
+	dex.app.txPool = dex.txPool
+
+	minGasPrice := dex.app.gov.GetHeadState().MinGasPrice()
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	txs := make([]*types.Transaction, 0, senders*txsPerSender)
+	for _, key := range keys {
+		gasPrice := new(big.Int).Mul(minGasPrice, big.NewInt(rand.Int63n(100)+1))
+		for nonce := uint64(0); nonce < txsPerSender; nonce++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{},
+				big.NewInt(1), 21000, gasPrice, nil), signer, key)
+			if err != nil {
+				b.Fatalf("Sign tx fail: %v", err)
+			}
+			txs = append(txs, tx)
+		}
+	}
+	for _, err := range dex.txPool.AddRemotes(txs) {
+		if err != nil {
+			b.Fatalf("Add tx fail: %v", err)
+		}
+	}
+	return dex
+}
+
+func BenchmarkPreparePayload(b *testing.B) {
+	dex := newBenchmarkPreparePayloadDexon(b)
+	defer dex.txPool.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dex.app.preparePayload(
+			context.Background(), coreTypes.Position{Height: 1}); err != nil {
+			b.Fatalf("Prepare payload fail: %v", err)
+		}
+	}
+}
+
+// BenchmarkPreparePayloadSoftLimit runs preparePayload under the same 100ms
+// soft limit used by PreparePayload.
+func BenchmarkPreparePayloadSoftLimit(b *testing.B) {
+	dex := newBenchmarkPreparePayloadDexon(b)
+	defer dex.txPool.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		payload, err := dex.app.preparePayload(ctx, coreTypes.Position{Height: 1})
+		cancel()
+		if err != nil {
+			b.Fatalf("Prepare payload fail: %v", err)
+		}
+		var payloadTxs types.Transactions
+		if err := rlp.DecodeBytes(payload, &payloadTxs); err != nil {
+			b.Fatalf("Decode payload fail: %v", err)
+		}
+		if len(payloadTxs) == 0 {
+			b.Fatalf("Empty payload")
+		}
+	}
+}
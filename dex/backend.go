@@ -18,6 +18,8 @@
 package dex
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -29,6 +31,7 @@ import (
 	"github.com/dexon-foundation/dexon/core/bloombits"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/dex/downloader"
 	"github.com/dexon-foundation/dexon/eth/filters"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
@@ -43,6 +46,13 @@ import (
 	"github.com/dexon-foundation/dexon/rpc"
 )
 
+// LesServer serves the light clients of the full node.
+type LesServer interface {
+	Start(srvr *p2p.Server)
+	Stop()
+	Protocols() []p2p.Protocol
+}
+
 // Dexon implements the DEXON fullnode service.
 type Dexon struct {
 	config      *Config
@@ -73,7 +83,9 @@ type Dexon struct {
 	governance *DexconGovernance
 	network    *DexconNetwork
 
-	bp *blockProposer
+	bp proposer
+
+	lesServer LesServer
 
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
@@ -82,11 +94,21 @@ type Dexon struct {
 }
 
 func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
+	if len(config.SentryFor) > 0 && (config.BlockProposerEnabled ||
+		len(config.Sentries) > 0) {
+		return nil, errors.New("sentry can't be a validator")
+	}
 	// Consensus.
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	if err != nil {
 		return nil, err
 	}
+	if config.Dev {
+		// The node is the only node of the developer chain, which is known
+		// only after the node key is loaded.
+		config.Genesis = core.DexconDeveloperGenesisBlock(config.DevFaucet,
+			&config.PrivateKey.PublicKey)
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb,
 		config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
@@ -94,6 +116,13 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	if config.Checkpoint != nil {
+		if err := core.SetupCheckpoint(chainDb, config.Checkpoint,
+			config.CheckpointSigners, config.CheckpointThreshold); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint: %v", err)
+		}
+	}
+
 	if !config.SkipBcVersionCheck {
 		bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 		if bcVersion != nil && *bcVersion != core.BlockChainVersion {
@@ -137,11 +166,19 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 	dex.bloomIndexer.Start(dex.blockchain)
 
 	if config.Indexer.Enable {
-		dex.indexer = indexer.NewIndexerFromConfig(
+		if config.Indexer.DataDir == "" {
+			config.Indexer.DataDir = ctx.ResolvePath("indexer")
+		}
+		dex.indexer, err = indexer.NewIndexerFromConfig(
 			indexer.NewROBlockChain(dex.blockchain),
 			config.Indexer,
 		)
-		dex.indexer.Start()
+		if err != nil {
+			return nil, err
+		}
+		if err := dex.indexer.Start(); err != nil {
+			return nil, err
+		}
 	}
 
 	if config.TxPool.Journal != "" {
@@ -167,7 +204,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 	log.Info("Consensus DMoment", "dMoment", dMoment)
 
 	// Force starting with full sync mode if this node is a bootstrap proposer.
-	if config.BlockProposerEnabled && dMoment.After(time.Now()) {
+	if config.BlockProposerEnabled && (config.Dev || dMoment.After(time.Now())) {
 		config.SyncMode = downloader.FullSync
 	}
 
@@ -178,11 +215,32 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 		return nil, err
 	}
 
+	pm.forkWatcher = newForkWatcher(chainDb, dex.governance, config.ForkReporterKey)
+	pm.sentries, pm.sentryFor = config.Sentries, config.SentryFor
+	if config.ConsensusJournal != "" {
+		pm.journal, err = newCoreJournal(ctx.ResolvePath(config.ConsensusJournal))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	dex.protocolManager = pm
 	dex.network = NewDexconNetwork(pm)
 
-	recovery := NewRecovery(chainConfig.Recovery, config.RecoveryNetworkRPC,
-		dex.governance, config.PrivateKey)
+	if config.Dev {
+		dex.bp = newDevProposer(dex, config.DevPeriod)
+		return dex, nil
+	}
+	if config.ReplayJournal != "" {
+		dex.bp = newReplayProposer(dex, config.ReplayJournal, dMoment)
+		return dex, nil
+	}
+
+	recoveryBackend, err := newRecoveryBackend(config, chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	recovery := NewRecovery(dex.governance, config.PrivateKey, recoveryBackend)
 	watchCat := syncer.NewWatchCat(recovery, dex.governance, 10*time.Second,
 		time.Duration(chainConfig.Recovery.Timeout)*time.Second, log.Root())
 
@@ -190,8 +248,30 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 	return dex, nil
 }
 
+func newRecoveryBackend(config *Config,
+	chainConfig *params.ChainConfig) (RecoveryBackend, error) {
+	if config.RecoveryVoteDir != "" {
+		return NewFileRecoveryBackend(config.RecoveryVoteDir,
+			chainConfig.ChainID, config.PrivateKey), nil
+	}
+	client, err := DialRecoveryRPCClient(config.RecoveryNetworkRPC)
+	if err != nil {
+		return nil, err
+	}
+	return NewContractRecoveryBackend(client, chainConfig.Recovery.Contract,
+		config.PrivateKey), nil
+}
+
 func (s *Dexon) Protocols() []p2p.Protocol {
-	return s.protocolManager.SubProtocols
+	if s.lesServer == nil {
+		return s.protocolManager.SubProtocols
+	}
+	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+}
+
+// AddLesServer sets the light server serving the light clients.
+func (s *Dexon) AddLesServer(ls LesServer) {
+	s.lesServer = ls
 }
 
 func (s *Dexon) APIs() []rpc.API {
@@ -212,6 +292,16 @@ func (s *Dexon) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
 			Public:    true,
+		}, {
+			Namespace: "dex",
+			Version:   "1.0",
+			Service:   NewPublicGovernanceAPI(s.APIBackend),
+			Public:    true,
+		}, {
+			Namespace: "dexcon",
+			Version:   "1.0",
+			Service:   NewPublicDexconAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -251,6 +341,9 @@ func (s *Dexon) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(srvr, maxPeers)
+	if s.lesServer != nil {
+		s.lesServer.Start(srvr)
+	}
 
 	if s.config.BlockProposerEnabled {
 		go func() {
@@ -272,13 +365,16 @@ func (s *Dexon) Start(srvr *p2p.Server) error {
 }
 
 func (s *Dexon) Stop() error {
+	s.bp.Stop()
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
+	if s.lesServer != nil {
+		s.lesServer.Stop()
+	}
 	s.txPool.Stop()
 	s.eventMux.Stop()
-	s.bp.Stop()
 	s.app.Stop()
 	if s.indexer != nil {
 		s.indexer.Stop()
@@ -296,6 +392,43 @@ func (s *Dexon) IsProposing() bool {
 	return s.bp.IsProposing()
 }
 
+// StartProposing starts the block proposer, the consensus core is synced
+// from the local chain before it starts proposing.
+func (s *Dexon) StartProposing() error {
+	if !s.config.BlockProposerEnabled {
+		return errors.New("block proposer is not enabled")
+	}
+	return s.bp.Start()
+}
+
+// StopProposing stops the block proposer and the consensus core it runs.
+func (s *Dexon) StopProposing() {
+	s.bp.Stop()
+}
+
+// IsNotary returns whether the node is in the notary set of round.
+func (s *Dexon) IsNotary(round uint64) bool {
+	notarySet, err := s.governance.NotarySet(round)
+	if err != nil {
+		return false
+	}
+	_, exist := notarySet[hex.EncodeToString(
+		crypto.FromECDSAPub(&s.config.PrivateKey.PublicKey))]
+	return exist
+}
+
+// FinalizedHeight returns the height of the last block confirmed by the
+// consensus core, which is at or above the height of the current block.
+func (s *Dexon) FinalizedHeight() uint64 {
+	return s.app.finalizedHeight()
+}
+
+// RandomnessLatency returns the time the last delivered block waited for its
+// randomness after being confirmed.
+func (s *Dexon) RandomnessLatency() time.Duration {
+	return s.app.lastRandomnessLatency()
+}
+
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Database, error) {
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
 	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
 	"github.com/dexon-foundation/dexon-consensus/core/syncer"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
@@ -22,6 +23,14 @@ var (
 	forceSyncTimeout = 20 * time.Second
 )
 
+// proposer proposes the blocks of the node.
+type proposer interface {
+	Start() error
+	Stop()
+	IsCoreSyncing() bool
+	IsProposing() bool
+}
+
 type blockProposer struct {
 	mu        sync.Mutex
 	running   int32
@@ -86,6 +95,7 @@ func (b *blockProposer) run(c *dexCore.Consensus) {
 	atomic.StoreInt32(&b.proposing, 1)
 	<-b.stopCh
 	log.Debug("Block proposer receive stop signal")
+	c.Stop()
 }
 
 func (b *blockProposer) Stop() {
@@ -110,11 +120,49 @@ func (b *blockProposer) IsProposing() bool {
 	return atomic.LoadInt32(&b.proposing) == 1
 }
 
+// newCoreDatabase creates the consensus core database with DKG private keys
+// sealed. Plaintext DKG private keys written before are sealed here.
+func newCoreDatabase(dex *Dexon) *db.DB {
+	sealKey := dex.config.DKGSealKey
+	if sealKey == nil {
+		sealKey = dex.config.PrivateKey
+	}
+	d := db.NewSealedDatabase(dex.chainDb, db.DeriveDKGSealKey(sealKey))
+
+	// DKG private key of next round might be prepared already.
+	count, err := d.MigrateDKGPrivateKeys(dex.governance.Round() + 1)
+	if err != nil {
+		log.Error("Failed to seal DKG private keys", "err", err)
+	} else if count > 0 {
+		log.Info("Sealed plaintext DKG private keys", "count", count)
+	}
+	return d
+}
+
 func (b *blockProposer) initConsensus() *dexCore.Consensus {
-	db := db.NewDatabase(b.dex.chainDb)
+	db := newCoreDatabase(b.dex)
 	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
 	return dexCore.NewConsensus(b.dMoment,
-		b.dex.app, b.dex.governance, db, b.dex.network, privkey, log.Root())
+		b.dex.app, b.governance(), db, b.network(privkey), privkey, log.Root())
+}
+
+// network returns the network used by the consensus core, wrapped to
+// misbehave if a byzantine mode is configured.
+func (b *blockProposer) network(privkey coreCrypto.PrivateKey) dexCore.Network {
+	if b.dex.config.Byzantine == 0 {
+		return b.dex.network
+	}
+	return newByzantineNetwork(b.dex.network, b.dex.config.Byzantine,
+		b.dex.config.ByzantineSilentRound, privkey)
+}
+
+// governance returns the governance used by the consensus core, wrapped to
+// misbehave if a byzantine mode is configured.
+func (b *blockProposer) governance() dexCore.Governance {
+	if b.dex.config.Byzantine == 0 {
+		return b.dex.governance
+	}
+	return &byzantineGovernance{b.dex.governance, b.dex.config.Byzantine}
 }
 
 func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
@@ -123,10 +171,10 @@ func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
 
 	cb := b.dex.blockchain.CurrentBlock()
 
-	db := db.NewDatabase(b.dex.chainDb)
+	db := newCoreDatabase(b.dex)
 	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
 	consensusSync := syncer.NewConsensus(cb.NumberU64(), b.dMoment, b.dex.app,
-		b.dex.governance, db, b.dex.network, privkey, log.Root())
+		b.governance(), db, b.network(privkey), privkey, log.Root())
 
 	// Start the watchCat.
 	b.watchCat.Start()
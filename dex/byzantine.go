@@ -0,0 +1,227 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// ByzantineMode makes the block proposer misbehave, so that the reports and
+// fines of the governance contract can be tested end to end. It's for
+// testing only and must never be set on a real network.
+type ByzantineMode uint
+
+const (
+	// ByzantineForkVote sends a conflicting vote along with a vote of each
+	// round.
+	ByzantineForkVote ByzantineMode = 1 << iota
+
+	// ByzantineForkBlock sends a conflicting block along with a block of each
+	// round.
+	ByzantineForkBlock
+
+	// ByzantineWithholdDKGShare never sends the DKG private shares.
+	ByzantineWithholdDKGShare
+
+	// ByzantineWithholdDKGComplaint never proposes the DKG complaints.
+	ByzantineWithholdDKGComplaint
+
+	// ByzantineSilent drops all consensus messages of ByzantineSilentRound.
+	ByzantineSilent
+)
+
+// byzantineNetwork applies the byzantine mode to the messages sent by the
+// consensus core.
+//
+// Forks are sent once a round. A node receiving both messages drops the peer
+// it got them from, which may be an honest peer relaying pulled votes, so
+// forking every message would leave the network without connections.
+type byzantineNetwork struct {
+	dexCore.Network
+
+	mode        ByzantineMode
+	silentRound uint64
+	signer      *coreUtils.Signer
+
+	lock          sync.Mutex
+	nextForkVote  uint64 // Next round to send a fork vote
+	nextForkBlock uint64 // Next round to send a fork block
+}
+
+func newByzantineNetwork(network dexCore.Network, mode ByzantineMode,
+	silentRound uint64, prvKey coreCrypto.PrivateKey) *byzantineNetwork {
+	return &byzantineNetwork{
+		Network:     network,
+		mode:        mode,
+		silentRound: silentRound,
+		signer:      coreUtils.NewSigner(prvKey),
+	}
+}
+
+func (n *byzantineNetwork) silent(round uint64) bool {
+	return n.mode&ByzantineSilent != 0 && round == n.silentRound
+}
+
+// PullVotes tries to pull votes from the DEXON network.
+func (n *byzantineNetwork) PullVotes(pos coreTypes.Position) {
+	if n.silent(pos.Round) {
+		return
+	}
+	n.Network.PullVotes(pos)
+}
+
+// BroadcastVote broadcasts vote to all nodes in DEXON network.
+func (n *byzantineNetwork) BroadcastVote(vote *coreTypes.Vote) {
+	if n.silent(vote.Position.Round) {
+		return
+	}
+	n.Network.BroadcastVote(vote)
+	// Commit votes carry a partial signature which can't be forged.
+	if n.mode&ByzantineForkVote == 0 ||
+		vote.Type == coreTypes.VoteCom || vote.Type == coreTypes.VoteFastCom {
+		return
+	}
+	if !n.fork(&n.nextForkVote, vote.Position.Round) {
+		return
+	}
+	fork, err := forkVote(n.signer, vote)
+	if err != nil {
+		log.Error("Failed to fork vote", "vote", vote, "err", err)
+		return
+	}
+	log.Debug("Byzantine fork vote", "vote", vote, "fork", fork)
+	n.Network.BroadcastVote(fork)
+}
+
+// BroadcastBlock broadcasts block to all nodes in DEXON network.
+func (n *byzantineNetwork) BroadcastBlock(block *coreTypes.Block) {
+	if n.silent(block.Position.Round) {
+		return
+	}
+	n.Network.BroadcastBlock(block)
+	if n.mode&ByzantineForkBlock == 0 || block.IsFinalized() ||
+		!n.fork(&n.nextForkBlock, block.Position.Round) {
+		return
+	}
+	fork, err := forkBlock(n.signer, block)
+	if err != nil {
+		log.Error("Failed to fork block", "block", block, "err", err)
+		return
+	}
+	log.Debug("Byzantine fork block", "block", block, "fork", fork)
+	n.Network.BroadcastBlock(fork)
+}
+
+// SendDKGPrivateShare sends PrivateShare to a DKG participant.
+func (n *byzantineNetwork) SendDKGPrivateShare(
+	pub coreCrypto.PublicKey, prvShare *dkgTypes.PrivateShare) {
+	if n.silent(prvShare.Round) || n.mode&ByzantineWithholdDKGShare != 0 {
+		return
+	}
+	n.Network.SendDKGPrivateShare(pub, prvShare)
+}
+
+// BroadcastDKGPrivateShare broadcasts PrivateShare to all DKG participants.
+func (n *byzantineNetwork) BroadcastDKGPrivateShare(
+	prvShare *dkgTypes.PrivateShare) {
+	if n.silent(prvShare.Round) || n.mode&ByzantineWithholdDKGShare != 0 {
+		return
+	}
+	n.Network.BroadcastDKGPrivateShare(prvShare)
+}
+
+// BroadcastDKGPartialSignature broadcasts partialSignature to all
+// DKG participants.
+func (n *byzantineNetwork) BroadcastDKGPartialSignature(
+	psig *dkgTypes.PartialSignature) {
+	if n.silent(psig.Round) {
+		return
+	}
+	n.Network.BroadcastDKGPartialSignature(psig)
+}
+
+// BroadcastAgreementResult broadcasts rand request to DKG set.
+func (n *byzantineNetwork) BroadcastAgreementResult(
+	result *coreTypes.AgreementResult) {
+	if n.silent(result.Position.Round) {
+		return
+	}
+	n.Network.BroadcastAgreementResult(result)
+}
+
+// fork reports whether a fork should be sent in the round, next is the next
+// round to fork which is advanced if so.
+func (n *byzantineNetwork) fork(next *uint64, round uint64) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if round < *next {
+		return false
+	}
+	*next = round + 1
+	return true
+}
+
+// forkVote returns a vote conflicting with the given one.
+func forkVote(signer *coreUtils.Signer, vote *coreTypes.Vote) (
+	*coreTypes.Vote, error) {
+	fork := vote.Clone()
+	fork.BlockHash = coreCrypto.Keccak256Hash(vote.BlockHash[:])
+	if err := signer.SignVote(fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// forkBlock returns a block at the same position of the given one with a
+// different hash.
+func forkBlock(signer *coreUtils.Signer, block *coreTypes.Block) (
+	*coreTypes.Block, error) {
+	fork := block.Clone()
+	fork.Timestamp = fork.Timestamp.Add(time.Millisecond)
+	if err := signer.SignBlock(fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// byzantineGovernance applies the byzantine mode to the governance
+// transactions sent by the consensus core.
+type byzantineGovernance struct {
+	*DexconGovernance
+
+	mode ByzantineMode
+}
+
+// AddDKGComplaint adds a DKGComplaint.
+func (g *byzantineGovernance) AddDKGComplaint(complaint *dkgTypes.Complaint) {
+	if g.mode&ByzantineWithholdDKGComplaint != 0 {
+		log.Debug("Byzantine withhold DKG complaint", "complaint", complaint)
+		return
+	}
+	g.DexconGovernance.AddDKGComplaint(complaint)
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+// recordNetwork records the messages sent by the consensus core.
+type recordNetwork struct {
+	dexCore.Network
+
+	votes  []*coreTypes.Vote
+	blocks []*coreTypes.Block
+	shares []*dkgTypes.PrivateShare
+	psigs  []*dkgTypes.PartialSignature
+}
+
+func (n *recordNetwork) BroadcastVote(vote *coreTypes.Vote) {
+	n.votes = append(n.votes, vote)
+}
+
+func (n *recordNetwork) BroadcastBlock(block *coreTypes.Block) {
+	n.blocks = append(n.blocks, block)
+}
+
+func (n *recordNetwork) SendDKGPrivateShare(
+	pub coreCrypto.PublicKey, prvShare *dkgTypes.PrivateShare) {
+	n.shares = append(n.shares, prvShare)
+}
+
+func (n *recordNetwork) BroadcastDKGPrivateShare(
+	prvShare *dkgTypes.PrivateShare) {
+	n.shares = append(n.shares, prvShare)
+}
+
+func (n *recordNetwork) BroadcastDKGPartialSignature(
+	psig *dkgTypes.PartialSignature) {
+	n.psigs = append(n.psigs, psig)
+}
+
+func newTestByzantineNetwork(t *testing.T, mode ByzantineMode,
+	silentRound uint64) (*byzantineNetwork, *recordNetwork, *coreUtils.Signer) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	prvKey := coreEcdsa.NewPrivateKeyFromECDSA(key)
+	record := &recordNetwork{}
+	return newByzantineNetwork(record, mode, silentRound, prvKey), record,
+		coreUtils.NewSigner(prvKey)
+}
+
+func TestByzantineForkVote(t *testing.T) {
+	network, record, signer := newTestByzantineNetwork(t, ByzantineForkVote, 0)
+
+	broadcast := func(voteType coreTypes.VoteType, round uint64) {
+		vote := coreTypes.NewVote(voteType, coreCommon.NewRandomHash(), 2)
+		vote.Position = coreTypes.Position{Round: round, Height: 10}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("Sign vote fail: %v", err)
+		}
+		network.BroadcastVote(vote)
+	}
+
+	broadcast(coreTypes.VotePreCom, 1)
+	if len(record.votes) != 2 {
+		t.Fatalf("votes mismatch: have %d, want 2", len(record.votes))
+	}
+	fork, err := coreUtils.NeedPenaltyForkVote(record.votes[0], record.votes[1])
+	if err != nil {
+		t.Fatalf("Check fork vote fail: %v", err)
+	}
+	if !fork {
+		t.Error("votes are not fork votes")
+	}
+
+	// A vote is forked once a round.
+	broadcast(coreTypes.VotePreCom, 1)
+	if len(record.votes) != 3 {
+		t.Errorf("votes mismatch: have %d, want 3", len(record.votes))
+	}
+	// Commit votes carry a partial signature which can't be forged.
+	broadcast(coreTypes.VoteCom, 2)
+	if len(record.votes) != 4 {
+		t.Errorf("votes mismatch: have %d, want 4", len(record.votes))
+	}
+	broadcast(coreTypes.VoteFast, 2)
+	if len(record.votes) != 6 {
+		t.Errorf("votes mismatch: have %d, want 6", len(record.votes))
+	}
+}
+
+func TestByzantineForkBlock(t *testing.T) {
+	network, record, signer := newTestByzantineNetwork(t, ByzantineForkBlock, 0)
+
+	newBlock := func(round uint64) *coreTypes.Block {
+		block := &coreTypes.Block{
+			Position:  coreTypes.Position{Round: round, Height: 10},
+			Timestamp: time.Now().UTC(),
+		}
+		if err := signer.SignBlock(block); err != nil {
+			t.Fatalf("Sign block fail: %v", err)
+		}
+		return block
+	}
+
+	network.BroadcastBlock(newBlock(1))
+	if len(record.blocks) != 2 {
+		t.Fatalf("blocks mismatch: have %d, want 2", len(record.blocks))
+	}
+	fork, err := coreUtils.NeedPenaltyForkBlock(record.blocks[0],
+		record.blocks[1])
+	if err != nil {
+		t.Fatalf("Check fork block fail: %v", err)
+	}
+	if !fork {
+		t.Error("blocks are not fork blocks")
+	}
+
+	// A block is forked once a round, and finalized blocks are relayed as is.
+	network.BroadcastBlock(newBlock(1))
+	if len(record.blocks) != 3 {
+		t.Errorf("blocks mismatch: have %d, want 3", len(record.blocks))
+	}
+	block := newBlock(2)
+	block.Randomness = []byte{1}
+	network.BroadcastBlock(block)
+	if len(record.blocks) != 4 {
+		t.Errorf("blocks mismatch: have %d, want 4", len(record.blocks))
+	}
+	network.BroadcastBlock(newBlock(2))
+	if len(record.blocks) != 6 {
+		t.Errorf("blocks mismatch: have %d, want 6", len(record.blocks))
+	}
+}
+
+func TestByzantineWithholdDKGShare(t *testing.T) {
+	network, record, _ := newTestByzantineNetwork(t,
+		ByzantineWithholdDKGShare, 0)
+
+	network.SendDKGPrivateShare(nil, &dkgTypes.PrivateShare{Round: 1})
+	network.BroadcastDKGPrivateShare(&dkgTypes.PrivateShare{Round: 1})
+	if len(record.shares) != 0 {
+		t.Errorf("shares mismatch: have %d, want 0", len(record.shares))
+	}
+	network.BroadcastDKGPartialSignature(&dkgTypes.PartialSignature{Round: 1})
+	if len(record.psigs) != 1 {
+		t.Errorf("partial signatures mismatch: have %d, want 1",
+			len(record.psigs))
+	}
+}
+
+func TestByzantineSilent(t *testing.T) {
+	network, record, _ := newTestByzantineNetwork(t, ByzantineSilent, 2)
+
+	for round := uint64(1); round <= 3; round++ {
+		pos := coreTypes.Position{Round: round}
+		vote := coreTypes.NewVote(coreTypes.VoteInit, coreCommon.Hash{}, 0)
+		vote.Position = pos
+		network.BroadcastVote(vote)
+		network.BroadcastBlock(&coreTypes.Block{Position: pos})
+		network.BroadcastDKGPrivateShare(&dkgTypes.PrivateShare{Round: round})
+		network.BroadcastDKGPartialSignature(
+			&dkgTypes.PartialSignature{Round: round})
+	}
+	if len(record.votes) != 2 || len(record.blocks) != 2 ||
+		len(record.shares) != 2 || len(record.psigs) != 2 {
+		t.Errorf("messages of silent round sent: votes %d, blocks %d, "+
+			"shares %d, psigs %d", len(record.votes), len(record.blocks),
+			len(record.shares), len(record.psigs))
+	}
+	for _, vote := range record.votes {
+		if vote.Position.Round == 2 {
+			t.Error("vote of silent round sent")
+		}
+	}
+}
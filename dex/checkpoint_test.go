@@ -0,0 +1,221 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func TestCheckpoint(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	faucet := common.Address{1}
+	dex, err := newDevDexon(nodeKey, faucet, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 5 ||
+		dex.blockchain.CurrentBlock().NumberU64()%5 != 3 {
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+	head := dex.blockchain.CurrentBlock()
+	genesis := dex.blockchain.Genesis().Hash()
+
+	cp, err := dex.blockchain.MakeCheckpoint(head.NumberU64())
+	if err != nil {
+		t.Fatalf("Make checkpoint fail: %v", err)
+	}
+	if len(cp.RoundStates) != 4 || cp.RoundStates[3].Round != head.Round() {
+		t.Fatalf("round states mismatch: have %d", len(cp.RoundStates))
+	}
+	if len(cp.Rounds) == 0 || cp.Rounds[0].Round != head.Round() ||
+		len(cp.Rounds[0].NotarySet) != 1 {
+		t.Fatalf("rounds mismatch: %+v", cp.Rounds)
+	}
+
+	// The checkpoint must be signed by enough trusted signers.
+	signer, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	signers := []common.Address{
+		crypto.PubkeyToAddress(signer.PublicKey),
+		crypto.PubkeyToAddress(other.PublicKey),
+	}
+	if err := core.VerifyCheckpoint(cp, genesis, signers, 1); err == nil {
+		t.Errorf("unsigned checkpoint verified")
+	}
+	if err := cp.Sign(signer); err != nil {
+		t.Fatalf("Sign checkpoint fail: %v", err)
+	}
+	if err := core.VerifyCheckpoint(cp, genesis, signers, 1); err != nil {
+		t.Errorf("Verify checkpoint fail: %v", err)
+	}
+	if err := core.VerifyCheckpoint(cp, genesis, signers, 0); err == nil {
+		t.Errorf("checkpoint verified without all signers")
+	}
+	if err := core.VerifyCheckpoint(cp, common.Hash{1}, signers, 1); err == nil {
+		t.Errorf("checkpoint of another chain verified")
+	}
+
+	// The content is verified against the block.
+	decode := func() *core.Checkpoint {
+		data, err := rlp.EncodeToBytes(cp)
+		if err != nil {
+			t.Fatalf("Encode checkpoint fail: %v", err)
+		}
+		decoded := new(core.Checkpoint)
+		if err := rlp.DecodeBytes(data, decoded); err != nil {
+			t.Fatalf("Decode checkpoint fail: %v", err)
+		}
+		return decoded
+	}
+	tampered := decode()
+	tampered.Rounds[0].DKGResetCount++
+	if err := core.VerifyCheckpoint(tampered, genesis, signers, 1); err == nil {
+		t.Errorf("tampered round verified")
+	}
+	params.TrustedBootstrapCheckpoints[genesis] = &params.BootstrapCheckpoint{
+		Number: head.NumberU64(),
+		Hash:   tampered.Hash(),
+	}
+	if err := core.VerifyCheckpoint(tampered, genesis, nil, 0); err == nil {
+		t.Errorf("trusted checkpoint with tampered round verified")
+	}
+	tampered = decode()
+	tampered.Ancestors[1] = common.Hash{1}
+	if err := core.VerifyCheckpoint(tampered, genesis, signers, 1); err == nil {
+		t.Errorf("checkpoint with tampered ancestors verified")
+	}
+	tampered.Ancestors = tampered.Ancestors[1:]
+	params.TrustedBootstrapCheckpoints[genesis].Hash = tampered.Hash()
+	if err := core.VerifyCheckpoint(tampered, genesis, nil, 0); err == nil {
+		t.Errorf("trusted checkpoint with unlinked ancestors verified")
+	}
+	tampered = decode()
+	tampered.Receipts = append(tampered.Receipts,
+		&types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful})
+	params.TrustedBootstrapCheckpoints[genesis].Hash = tampered.Hash()
+	if err := core.VerifyCheckpoint(tampered, genesis, nil, 0); err == nil {
+		t.Errorf("trusted checkpoint with tampered receipts verified")
+	}
+	if err := core.VerifyCheckpoint(decode(), genesis, nil, 0); err != nil {
+		t.Errorf("Verify trusted checkpoint fail: %v", err)
+	}
+	delete(params.TrustedBootstrapCheckpoints, genesis)
+
+	// A new node is bootstrapped from the checkpoint.
+	db := ethdb.NewMemDatabase()
+	gspec := core.DexconDeveloperGenesisBlock(faucet, &nodeKey.PublicKey)
+	gspec.Config.Dexcon.RoundLength = 5
+	chainConfig, _, err := core.SetupGenesisBlock(db, gspec)
+	if err != nil {
+		t.Fatalf("Setup genesis fail: %v", err)
+	}
+	if err := core.SetupCheckpoint(db, &core.Checkpoint{}, signers, 0); err == nil {
+		t.Fatalf("checkpoint without block set up")
+	}
+	if err := core.SetupCheckpoint(db, decode(), signers[1:], 0); err == nil {
+		t.Fatalf("untrusted checkpoint set up")
+	}
+	if err := core.SetupCheckpoint(db, decode(), signers[:1], 0); err != nil {
+		t.Fatalf("Setup checkpoint fail: %v", err)
+	}
+	if err := core.SetupCheckpoint(db, decode(), nil, 0); err != nil {
+		t.Fatalf("Setup checkpoint again fail: %v", err)
+	}
+	chain, err := core.NewBlockChain(db, nil, chainConfig, dexcon.New(),
+		vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("New blockchain fail: %v", err)
+	}
+	defer chain.Stop()
+
+	if hash := chain.CurrentHeader().Hash(); hash != head.Hash() {
+		t.Errorf("head header mismatch: have %x, want %x", hash, head.Hash())
+	}
+	if hash := chain.CurrentFastBlock().Hash(); hash != head.Hash() {
+		t.Errorf("head fast block mismatch: have %x, want %x", hash, head.Hash())
+	}
+	if !chain.HasFastBlock(head.Hash(), head.NumberU64()) {
+		t.Errorf("checkpoint block not complete")
+	}
+	if number := chain.CurrentBlock().NumberU64(); number != 0 {
+		t.Errorf("head block mismatch: have %d, want 0", number)
+	}
+	if meta := chain.Checkpoint(); meta == nil || meta.Hash != head.Hash() ||
+		meta.Round != head.Round() ||
+		meta.RoundHeight != cp.RoundStates[3].Number.Uint64() {
+		t.Errorf("checkpoint meta mismatch: %+v", meta)
+	}
+	for _, roundState := range cp.RoundStates {
+		number := roundState.Number.Uint64()
+		if _, err := chain.GetGovStateByNumber(number); err != nil {
+			t.Errorf("gov state of round %d not found: %v", roundState.Round, err)
+		}
+	}
+	// The witnessed blocks before the checkpoint are missing, only the ones
+	// within the witness window are accepted and checked against the hashes
+	// kept with the checkpoint.
+	if meta := chain.Checkpoint(); len(meta.Ancestors) != len(cp.Ancestors) {
+		t.Errorf("checkpoint ancestors mismatch: have %d, want %d",
+			len(meta.Ancestors), len(cp.Ancestors))
+	}
+	if err := chain.Validator().ValidateWitnessData(head.NumberU64()-1,
+		head.ParentHash()); err != nil {
+		t.Errorf("Validate witness before checkpoint fail: %v", err)
+	}
+	if err := chain.Validator().ValidateWitnessData(head.NumberU64()-1,
+		common.Hash{}); err == nil {
+		t.Errorf("mismatched witness of checkpoint parent validated")
+	}
+	for number := head.NumberU64() - 2; number > 0; number-- {
+		if chain.GetHeaderByNumber(number) != nil {
+			continue
+		}
+		want := dex.blockchain.GetHeaderByNumber(number).Hash()
+		if err := chain.Validator().ValidateWitnessData(number, want); err != nil {
+			t.Errorf("Validate witness before checkpoint fail: %v", err)
+		}
+		if err := chain.Validator().ValidateWitnessData(number,
+			common.Hash{1}); err == nil {
+			t.Errorf("mismatched witness before checkpoint validated")
+		}
+		break
+	}
+	if err := chain.Validator().ValidateWitnessData(head.NumberU64(),
+		common.Hash{}); err == nil {
+		t.Errorf("mismatched witness validated")
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/dexon-foundation/dexon/dex/downloader"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
 	"github.com/dexon-foundation/dexon/indexer"
+	"github.com/dexon-foundation/dexon/p2p/enode"
 	"github.com/dexon-foundation/dexon/params"
 )
 
@@ -77,6 +78,14 @@ type Config struct {
 	// PrivateKey, also represents the node identity.
 	PrivateKey *ecdsa.PrivateKey `toml:",omitempty"`
 
+	// DKGSealKey is used to seal DKG private keys at rest, PrivateKey is used
+	// if it's nil.
+	DKGSealKey *ecdsa.PrivateKey `toml:"-"`
+
+	// ForkReporterKey signs the reports of the forks observed by the node,
+	// the fork evidence is only persisted if it's nil.
+	ForkReporterKey *ecdsa.PrivateKey `toml:"-"`
+
 	// Protocol options
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
@@ -85,6 +94,20 @@ type Config struct {
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Checkpoint to bootstrap the chain from if it's empty. It's trusted if
+	// it's the trusted checkpoint of the network, or signed by at least
+	// CheckpointThreshold of CheckpointSigners (all of them if zero).
+	Checkpoint          *core.Checkpoint `toml:"-"`
+	CheckpointSigners   []common.Address `toml:",omitempty"`
+	CheckpointThreshold int              `toml:",omitempty"`
+
+	// Sentries relay the consensus messages of the node, which is connected
+	// to them only while they join the notary set connections on its behalf.
+	// SentryFor are the validators the node is a sentry of, it must not be a
+	// block proposer itself.
+	Sentries  []*enode.Node `toml:",omitempty"`
+	SentryFor []*enode.Node `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
@@ -109,6 +132,7 @@ type Config struct {
 
 	// BlockProposer options
 	BlockProposerEnabled bool
+	PayloadTxsPerSender  int // Maximum number of transactions from a sender in a block payload (0 = unlimited)
 
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
@@ -133,4 +157,29 @@ type Config struct {
 
 	// Recovery network RPC
 	RecoveryNetworkRPC string
+
+	// RecoveryVoteDir is the directory to exchange signed recovery votes,
+	// votes are sent to the recovery contract on RecoveryNetworkRPC if it's
+	// empty.
+	RecoveryVoteDir string
+
+	// ConsensusJournal is the directory to record the received consensus
+	// messages to for post-mortem debugging, disabled if empty.
+	ConsensusJournal string `toml:",omitempty"`
+
+	// ReplayJournal is a consensus journal to replay, the block proposer is
+	// fed the journaled messages instead of the ones from the network.
+	ReplayJournal string `toml:"-"`
+
+	// Developer mode options. The node runs a single node chain funding
+	// DevFaucet, blocks are proposed every DevPeriod or on transaction
+	// arrival if DevPeriod is zero.
+	Dev       bool           `toml:"-"`
+	DevFaucet common.Address `toml:"-"`
+	DevPeriod time.Duration  `toml:"-"`
+
+	// Byzantine makes the block proposer misbehave, ByzantineSilentRound is
+	// the round dropped by ByzantineSilent. For testing only.
+	Byzantine            ByzantineMode `toml:"-"`
+	ByzantineSilentRound uint64        `toml:"-"`
 }
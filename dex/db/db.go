@@ -18,6 +18,9 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+
 	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
 	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
@@ -26,15 +29,26 @@ import (
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
 )
 
 // DB implement dexon-consensus BlockDatabase interface.
 type DB struct {
 	db ethdb.Database
+
+	// sealKey is used to seal DKG private keys at rest, DKG private keys are
+	// stored in plaintext if it's nil.
+	sealKey []byte
 }
 
 func NewDatabase(db ethdb.Database) *DB {
-	return &DB{db}
+	return &DB{db: db}
+}
+
+// NewSealedDatabase creates a database which seals DKG private keys with
+// sealKey. Existing plaintext DKG private keys are sealed when accessed.
+func NewSealedDatabase(db ethdb.Database, sealKey []byte) *DB {
+	return &DB{db: db, sealKey: sealKey}
 }
 
 func (d *DB) HasBlock(hash coreCommon.Hash) bool {
@@ -70,7 +84,22 @@ func (d *DB) PutBlock(block coreTypes.Block) error {
 }
 
 func (d *DB) GetDKGPrivateKey(round, reset uint64) (coreDKG.PrivateKey, error) {
-	key := rawdb.ReadCoreDKGPrivateKey(d.db, round, reset)
+	if d.sealKey == nil {
+		key := rawdb.ReadCoreDKGPrivateKey(d.db, round, reset)
+		if key == nil {
+			return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
+		}
+		return *key, nil
+	}
+
+	data, err := d.readDKGPrivateKeyRLP(round)
+	if err != nil {
+		return coreDKG.PrivateKey{}, err
+	}
+	if len(data) == 0 {
+		return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
+	}
+	key := rawdb.DecodeCoreDKGPrivateKey(data, round, reset)
 	if key == nil {
 		return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
 	}
@@ -86,7 +115,101 @@ func (d *DB) PutDKGPrivateKey(round, reset uint64, key coreDKG.PrivateKey) error
 		return err
 	}
 
-	return rawdb.WriteCoreDKGPrivateKey(d.db, round, reset, &key)
+	if d.sealKey == nil {
+		return rawdb.WriteCoreDKGPrivateKey(d.db, round, reset, &key)
+	}
+	data, err := rawdb.EncodeCoreDKGPrivateKey(round, reset, &key)
+	if err != nil {
+		return err
+	}
+	return d.writeDKGPrivateKeyRLP(round, data)
+}
+
+// readDKGPrivateKeyRLP returns the RLP encoded DKG private key of a round.
+// The plaintext one written before sealing is enabled is sealed on the fly.
+func (d *DB) readDKGPrivateKeyRLP(round uint64) ([]byte, error) {
+	if sealed := rawdb.ReadCoreDKGSealedPrivateKey(d.db, round); len(sealed) > 0 {
+		return open(d.sealKey, round, sealed)
+	}
+	data := rawdb.ReadCoreDKGPrivateKeyRLP(d.db, round)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := d.writeDKGPrivateKeyRLP(round, data); err != nil {
+		return nil, err
+	}
+	rawdb.DeleteCoreDKGPrivateKey(d.db, round)
+	log.Info("Sealed plaintext DKG private key", "round", round)
+	return data, nil
+}
+
+func (d *DB) writeDKGPrivateKeyRLP(round uint64, data []byte) error {
+	sealed, err := seal(d.sealKey, round, data)
+	if err != nil {
+		return err
+	}
+	return rawdb.WriteCoreDKGSealedPrivateKey(d.db, round, sealed)
+}
+
+// MigrateDKGPrivateKeys seals all plaintext DKG private keys up to maxRound
+// and returns the number of keys sealed.
+func (d *DB) MigrateDKGPrivateKeys(maxRound uint64) (int, error) {
+	if d.sealKey == nil {
+		return 0, nil
+	}
+	count := 0
+	for round := uint64(0); round <= maxRound; round++ {
+		data := rawdb.ReadCoreDKGPrivateKeyRLP(d.db, round)
+		if len(data) == 0 {
+			continue
+		}
+		// The plaintext entry might be left behind if we crashed right after
+		// sealing it.
+		if sealed := rawdb.ReadCoreDKGSealedPrivateKey(d.db, round); len(sealed) == 0 {
+			if err := d.writeDKGPrivateKeyRLP(round, data); err != nil {
+				return count, err
+			}
+			count++
+		}
+		rawdb.DeleteCoreDKGPrivateKey(d.db, round)
+	}
+	return count, nil
+}
+
+// RotateDKGSealKey reseals all DKG private keys up to maxRound with newKey
+// and returns the number of keys resealed. Keys already sealed by newKey are
+// skipped so an interrupted rotation can be resumed.
+func (d *DB) RotateDKGSealKey(newKey []byte, maxRound uint64) (int, error) {
+	if d.sealKey == nil {
+		return 0, errors.New("database is not sealed")
+	}
+	if _, err := d.MigrateDKGPrivateKeys(maxRound); err != nil {
+		return 0, err
+	}
+	count := 0
+	for round := uint64(0); round <= maxRound; round++ {
+		sealed := rawdb.ReadCoreDKGSealedPrivateKey(d.db, round)
+		if len(sealed) == 0 {
+			continue
+		}
+		data, err := open(d.sealKey, round, sealed)
+		if err != nil {
+			if _, errNew := open(newKey, round, sealed); errNew == nil {
+				continue
+			}
+			return count, fmt.Errorf("round %d: %v", round, err)
+		}
+		resealed, err := seal(newKey, round, data)
+		if err != nil {
+			return count, err
+		}
+		if err := rawdb.WriteCoreDKGSealedPrivateKey(d.db, round, resealed); err != nil {
+			return count, err
+		}
+		count++
+	}
+	d.sealKey = newKey
+	return count, nil
 }
 
 func (d *DB) PutCompactionChainTipInfo(hash coreCommon.Hash, height uint64) error {
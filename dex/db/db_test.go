@@ -0,0 +1,192 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
+
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+)
+
+func newSealKey(t *testing.T) []byte {
+	prv, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return DeriveDKGSealKey(prv)
+}
+
+func TestSealedDKGPrivateKey(t *testing.T) {
+	memdb := ethdb.NewMemDatabase()
+	key := newSealKey(t)
+	db := NewSealedDatabase(memdb, key)
+
+	prv := coreDKG.NewPrivateKey()
+	if err := db.PutDKGPrivateKey(1, 0, *prv); err != nil {
+		t.Fatalf("failed to put DKG private key: %v", err)
+	}
+	if err := db.PutDKGPrivateKey(1, 0, *prv); err != coreDb.ErrDKGPrivateKeyExists {
+		t.Errorf("expect ErrDKGPrivateKeyExists, got %v", err)
+	}
+	if data := rawdb.ReadCoreDKGPrivateKeyRLP(memdb, 1); len(data) != 0 {
+		t.Errorf("plaintext DKG private key is stored")
+	}
+	sealed := rawdb.ReadCoreDKGSealedPrivateKey(memdb, 1)
+	if len(sealed) == 0 {
+		t.Fatalf("sealed DKG private key is not stored")
+	}
+	if bytes.Contains(sealed, prv.Bytes()) {
+		t.Errorf("sealed data contains the private key")
+	}
+
+	got, err := db.GetDKGPrivateKey(1, 0)
+	if err != nil {
+		t.Fatalf("failed to get DKG private key: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), prv.Bytes()) {
+		t.Errorf("DKG private key mismatch")
+	}
+	if _, err := db.GetDKGPrivateKey(1, 1); err != coreDb.ErrDKGPrivateKeyDoesNotExist {
+		t.Errorf("expect ErrDKGPrivateKeyDoesNotExist for other reset, got %v", err)
+	}
+	if _, err := db.GetDKGPrivateKey(2, 0); err != coreDb.ErrDKGPrivateKeyDoesNotExist {
+		t.Errorf("expect ErrDKGPrivateKeyDoesNotExist, got %v", err)
+	}
+
+	// Opening with a wrong key must fail.
+	wrong := NewSealedDatabase(memdb, newSealKey(t))
+	if _, err := wrong.GetDKGPrivateKey(1, 0); err != ErrInvalidSealedData {
+		t.Errorf("expect ErrInvalidSealedData, got %v", err)
+	}
+
+	// The sealed key of a round can not be opened as the one of another round.
+	if _, err := open(key, 1, sealed); err != nil {
+		t.Errorf("failed to open sealed data: %v", err)
+	}
+	if _, err := open(key, 2, sealed); err != ErrInvalidSealedData {
+		t.Errorf("expect ErrInvalidSealedData, got %v", err)
+	}
+	if err := rawdb.WriteCoreDKGSealedPrivateKey(memdb, 2, sealed); err != nil {
+		t.Fatalf("failed to write sealed DKG private key: %v", err)
+	}
+	if _, err := db.GetDKGPrivateKey(2, 0); err != ErrInvalidSealedData {
+		t.Errorf("expect ErrInvalidSealedData, got %v", err)
+	}
+}
+
+func TestMigrateDKGPrivateKeys(t *testing.T) {
+	memdb := ethdb.NewMemDatabase()
+	plain := NewDatabase(memdb)
+
+	prvs := []*coreDKG.PrivateKey{}
+	for round := uint64(0); round < 3; round++ {
+		prv := coreDKG.NewPrivateKey()
+		if err := plain.PutDKGPrivateKey(round, 0, *prv); err != nil {
+			t.Fatalf("failed to put DKG private key: %v", err)
+		}
+		prvs = append(prvs, prv)
+	}
+
+	db := NewSealedDatabase(memdb, newSealKey(t))
+	// Plaintext key is sealed on access.
+	got, err := db.GetDKGPrivateKey(0, 0)
+	if err != nil {
+		t.Fatalf("failed to get DKG private key: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), prvs[0].Bytes()) {
+		t.Errorf("DKG private key mismatch")
+	}
+	if data := rawdb.ReadCoreDKGPrivateKeyRLP(memdb, 0); len(data) != 0 {
+		t.Errorf("plaintext DKG private key is not removed")
+	}
+
+	count, err := db.MigrateDKGPrivateKeys(5)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expect 2 keys migrated, got %d", count)
+	}
+	for round, prv := range prvs {
+		if data := rawdb.ReadCoreDKGPrivateKeyRLP(memdb, uint64(round)); len(data) != 0 {
+			t.Errorf("plaintext DKG private key of round %d is not removed", round)
+		}
+		got, err := db.GetDKGPrivateKey(uint64(round), 0)
+		if err != nil {
+			t.Fatalf("failed to get DKG private key: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), prv.Bytes()) {
+			t.Errorf("DKG private key mismatch at round %d", round)
+		}
+	}
+}
+
+func TestRotateDKGSealKey(t *testing.T) {
+	memdb := ethdb.NewMemDatabase()
+	oldKey, newKey := newSealKey(t), newSealKey(t)
+	db := NewSealedDatabase(memdb, oldKey)
+
+	prvs := []*coreDKG.PrivateKey{}
+	for round := uint64(0); round < 3; round++ {
+		prv := coreDKG.NewPrivateKey()
+		if err := db.PutDKGPrivateKey(round, 0, *prv); err != nil {
+			t.Fatalf("failed to put DKG private key: %v", err)
+		}
+		prvs = append(prvs, prv)
+	}
+
+	count, err := NewSealedDatabase(memdb, oldKey).RotateDKGSealKey(newKey, 2)
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expect 3 keys resealed, got %d", count)
+	}
+	if _, err := NewSealedDatabase(memdb, oldKey).GetDKGPrivateKey(0, 0); err != ErrInvalidSealedData {
+		t.Errorf("expect ErrInvalidSealedData with old key, got %v", err)
+	}
+	rotated := NewSealedDatabase(memdb, newKey)
+	for round, prv := range prvs {
+		got, err := rotated.GetDKGPrivateKey(uint64(round), 0)
+		if err != nil {
+			t.Fatalf("failed to get DKG private key: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), prv.Bytes()) {
+			t.Errorf("DKG private key mismatch at round %d", round)
+		}
+	}
+
+	// Resuming a finished rotation is a no-op.
+	count, err = NewSealedDatabase(memdb, oldKey).RotateDKGSealKey(newKey, 2)
+	if err != nil {
+		t.Fatalf("failed to resume rotation: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expect 0 keys resealed, got %d", count)
+	}
+
+	if _, err := NewDatabase(memdb).RotateDKGSealKey(newKey, 2); err == nil {
+		t.Errorf("expect error rotating an unsealed database")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+// ErrInvalidSealedData is returned when the sealed data can not be opened by
+// the sealing key.
+var ErrInvalidSealedData = errors.New("invalid sealed data")
+
+var dkgSealKeySalt = []byte("dexon dkg private key seal")
+
+// DeriveDKGSealKey derives the key used to seal DKG private keys at rest from
+// an ECDSA private key, which is either the node key or a dedicated key from
+// a keystore file.
+func DeriveDKGSealKey(prv *ecdsa.PrivateKey) []byte {
+	return crypto.Keccak256(dkgSealKeySalt, crypto.FromECDSA(prv))
+}
+
+// sealAdditionalData binds the sealed data to its round, so the sealed key of
+// a round can not be opened as the one of another round.
+func sealAdditionalData(round uint64) []byte {
+	ad := make([]byte, 8)
+	binary.BigEndian.PutUint64(ad, round)
+	return ad
+}
+
+// seal encrypts data of the round with AES-256-GCM, the random nonce is
+// prepended to the ciphertext.
+func seal(key []byte, round uint64, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, sealAdditionalData(round)), nil
+}
+
+// open decrypts data of the round sealed by seal.
+func open(key []byte, round uint64, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidSealedData
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	data, err := gcm.Open(nil, nonce, ciphertext, sealAdditionalData(round))
+	if err != nil {
+		return nil, ErrInvalidSealedData
+	}
+	return data, nil
+}
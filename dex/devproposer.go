@@ -0,0 +1,350 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/db"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// devProposer proposes all blocks of a developer chain in which the node is
+// the only node. It runs a degenerate consensus: the node is the only DKG
+// participant with threshold 1, and the randomness of a block is the threshold
+// signature of its own DKG private key.
+type devProposer struct {
+	mu      sync.Mutex
+	running int32
+	dex     *Dexon
+	period  time.Duration
+
+	signer *coreUtils.Signer
+	dkgID  coreDKG.ID
+	db     *db.DB
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func newDevProposer(dex *Dexon, period time.Duration) *devProposer {
+	privkey := coreEcdsa.NewPrivateKeyFromECDSA(dex.config.PrivateKey)
+	return &devProposer{
+		dex:    dex,
+		period: period,
+		signer: coreUtils.NewSigner(privkey),
+		dkgID:  coreDKG.NewID(coreTypes.NewNodeID(privkey.PublicKey()).Bytes()),
+	}
+}
+
+func (p *devProposer) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&p.running, 0, 1) {
+		return fmt.Errorf("block proposer is already running")
+	}
+	log.Info("Started developer block proposer", "period", p.period)
+
+	p.db = newCoreDatabase(p.dex)
+	p.stopCh = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer atomic.StoreInt32(&p.running, 0)
+		p.loop()
+	}()
+	return nil
+}
+
+func (p *devProposer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if atomic.LoadInt32(&p.running) == 1 {
+		close(p.stopCh)
+		p.wg.Wait()
+	}
+	log.Info("Developer block proposer stopped")
+}
+
+func (p *devProposer) IsCoreSyncing() bool {
+	return false
+}
+
+func (p *devProposer) IsProposing() bool {
+	return atomic.LoadInt32(&p.running) == 1
+}
+
+func (p *devProposer) loop() {
+	// Blocks are proposed periodically, or on transaction arrival if the
+	// period is zero.
+	var (
+		txCh = make(chan core.NewTxsEvent, txChanSize)
+		tick <-chan time.Time
+	)
+	if p.period > 0 {
+		ticker := time.NewTicker(p.period)
+		defer ticker.Stop()
+		tick = ticker.C
+	} else {
+		sub := p.dex.txPool.SubscribeNewTxsEvent(txCh)
+		defer sub.Unsubscribe()
+	}
+
+	p.prepareNextRound()
+	for {
+		select {
+		case <-txCh:
+		case <-tick:
+		case <-p.stopCh:
+			return
+		}
+		for {
+			if p.period == 0 {
+				if pending, _ := p.dex.txPool.Stats(); pending == 0 {
+					break
+				}
+			}
+			txs, err := p.proposeBlock()
+			if err != nil {
+				log.Error("Failed to propose block", "err", err)
+				break
+			}
+			p.prepareNextRound()
+
+			// Keep proposing until the pending transactions are drained.
+			if p.period > 0 || txs == 0 {
+				break
+			}
+		}
+	}
+}
+
+// nextPosition returns the position of the block following head. The next
+// round starts once the current round reaches its length and the DKG and CRS
+// of the next round are ready, the current round is extended otherwise.
+func (p *devProposer) nextPosition(head *types.Block) coreTypes.Position {
+	gov := p.dex.governance
+	round := head.Round()
+	height := head.NumberU64() + 1
+
+	roundEnd := gov.GetRoundHeight(round) + gov.Configuration(round).RoundLength
+	// Round 0 starts at height 1 instead of height 0.
+	if round == 0 {
+		roundEnd++
+	}
+	if height >= roundEnd && p.roundReady(round+1) {
+		round++
+	}
+	return coreTypes.Position{Round: round, Height: height}
+}
+
+func (p *devProposer) roundReady(round uint64) bool {
+	gov := p.dex.governance
+	if round > dexCore.DKGDelayRound && gov.CRSRound() < round {
+		return false
+	}
+	if !gov.IsDKGFinal(round) {
+		return false
+	}
+	_, err := p.db.GetDKGPrivateKey(round, gov.DKGResetCount(round))
+	return err == nil
+}
+
+// proposeBlock proposes and delivers a block on top of the current block,
+// returns the number of transactions in it.
+func (p *devProposer) proposeBlock() (int, error) {
+	head := p.dex.blockchain.CurrentBlock()
+	position := p.nextPosition(head)
+
+	payload, err := p.dex.app.PreparePayload(position)
+	if err != nil {
+		return 0, err
+	}
+	witness, err := p.dex.app.PrepareWitness(head.NumberU64())
+	if err != nil {
+		return 0, err
+	}
+	block := &coreTypes.Block{
+		Position:  position,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+		Witness:   witness,
+	}
+	if head.NumberU64() > 0 {
+		var parent coreTypes.Block
+		if err := rlp.DecodeBytes(head.Header().DexconMeta, &parent); err != nil {
+			return 0, err
+		}
+		block.ParentHash = parent.Hash
+		if !block.Timestamp.After(parent.Timestamp) {
+			block.Timestamp = parent.Timestamp.Add(time.Millisecond)
+		}
+	}
+	if err := p.signer.SignBlock(block); err != nil {
+		return 0, err
+	}
+	if status := p.dex.app.VerifyBlock(block); status != coreTypes.VerifyOK {
+		return 0, fmt.Errorf("block verification failed: %v", status)
+	}
+
+	randomness := dexCore.NoRand
+	if position.Round >= dexCore.DKGDelayRound {
+		if randomness, err = p.tsig(position.Round, block.Hash); err != nil {
+			return 0, err
+		}
+	}
+
+	var txs types.Transactions
+	if len(payload) > 0 {
+		if err := rlp.DecodeBytes(payload, &txs); err != nil {
+			return 0, err
+		}
+	}
+	p.dex.app.BlockConfirmed(*block)
+	p.dex.app.BlockDelivered(block.Hash, block.Position, randomness)
+	log.Debug("Developer block proposed", "position", position, "txs", len(txs))
+	return len(txs), nil
+}
+
+// tsig signs hash with the DKG private key of round. The node is the only
+// DKG participant so the threshold signature is recovered from its own
+// partial signature.
+func (p *devProposer) tsig(round uint64, hash coreCommon.Hash) ([]byte, error) {
+	key, err := p.db.GetDKGPrivateKey(round, p.dex.governance.DKGResetCount(round))
+	if err != nil {
+		return nil, fmt.Errorf("DKG private key of round %d: %v", round, err)
+	}
+	psig, err := key.Sign(hash)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := coreDKG.RecoverSignature(
+		[]coreDKG.PartialSignature{coreDKG.PartialSignature(psig)},
+		coreDKG.IDs{p.dkgID})
+	if err != nil {
+		return nil, err
+	}
+	return sig.Signature, nil
+}
+
+// prepareNextRound sends the governance transactions to propose the CRS and
+// run the DKG of the next round.
+func (p *devProposer) prepareNextRound() {
+	gov := p.dex.governance
+	round := p.dex.blockchain.CurrentBlock().Round()
+	next := round + 1
+
+	// Wait until the governance transactions sent are included.
+	statedb, err := p.dex.blockchain.State()
+	if err != nil {
+		log.Error("Failed to get state", "err", err)
+		return
+	}
+	addr := crypto.PubkeyToAddress(p.dex.config.PrivateKey.PublicKey)
+	if p.dex.txPool.State().GetNonce(addr) != statedb.GetNonce(addr) {
+		return
+	}
+
+	// CRS of next round is signed with the DKG of current round, it must be
+	// proposed before the DKG of next round starts.
+	if next > dexCore.DKGDelayRound && gov.CRSRound() < next {
+		signedCRS, err := p.tsig(round, gov.CRS(round))
+		if err != nil {
+			log.Error("Failed to sign CRS", "round", round, "err", err)
+			return
+		}
+		gov.ProposeCRS(next, signedCRS)
+	}
+	if gov.IsDKGFinal(next) {
+		return
+	}
+	if err := p.runDKG(next); err != nil {
+		log.Error("Failed to run DKG", "round", next, "err", err)
+	}
+}
+
+// runDKG runs the DKG of round with the node as the only participant.
+func (p *devProposer) runDKG(round uint64) error {
+	gov := p.dex.governance
+	reset := gov.DKGResetCount(round)
+	threshold := coreUtils.GetDKGThreshold(gov.Configuration(round))
+
+	ids := coreDKG.IDs{p.dkgID}
+	prvShares, pubShares := coreDKG.NewPrivateKeyShares(threshold)
+	prvShares.SetParticipants(ids)
+	share, ok := prvShares.Share(p.dkgID)
+	if !ok {
+		return fmt.Errorf("private share not found")
+	}
+	received := coreDKG.NewEmptyPrivateKeyShares()
+	if err := received.AddShare(p.dkgID, share); err != nil {
+		return err
+	}
+	key, err := received.RecoverPrivateKey(ids)
+	if err != nil {
+		return err
+	}
+	if err := p.db.PutDKGPrivateKey(round, reset, *key); err != nil {
+		return err
+	}
+
+	mpk := &dkgTypes.MasterPublicKey{
+		Round:           round,
+		Reset:           reset,
+		DKGID:           p.dkgID,
+		PublicKeyShares: *pubShares.Move(),
+	}
+	ready := &dkgTypes.MPKReady{Round: round, Reset: reset}
+	final := &dkgTypes.Finalize{Round: round, Reset: reset}
+	success := &dkgTypes.Success{Round: round, Reset: reset}
+	if err := p.signer.SignDKGMasterPublicKey(mpk); err != nil {
+		return err
+	}
+	if err := p.signer.SignDKGMPKReady(ready); err != nil {
+		return err
+	}
+	if err := p.signer.SignDKGFinalize(final); err != nil {
+		return err
+	}
+	if err := p.signer.SignDKGSuccess(success); err != nil {
+		return err
+	}
+	gov.AddDKGMasterPublicKey(mpk)
+	gov.AddDKGMPKReady(ready)
+	gov.AddDKGFinalize(final)
+	gov.AddDKGSuccess(success)
+	log.Info("Developer DKG prepared", "round", round, "reset", reset)
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func newDevDexon(nodeKey *ecdsa.PrivateKey, faucet common.Address,
+	roundLength uint64) (*Dexon, error) {
+	db := ethdb.NewMemDatabase()
+
+	genesis := core.DexconDeveloperGenesisBlock(faucet, &nodeKey.PublicKey)
+	genesis.Config.Dexcon.RoundLength = roundLength
+	chainConfig, _, err := core.SetupGenesisBlock(db, genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{PrivateKey: nodeKey, Dev: true, DevFaucet: faucet}
+	engine := dexcon.New()
+	dex := &Dexon{
+		config:      config,
+		chainDb:     db,
+		chainConfig: chainConfig,
+		engine:      engine,
+	}
+	dex.blockchain, err = core.NewBlockChain(db, nil, chainConfig, engine,
+		vm.Config{IsBlockProposer: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	txPoolConfig := core.DefaultTxPoolConfig
+	txPoolConfig.Journal = ""
+	dex.txPool = core.NewTxPool(txPoolConfig, chainConfig, dex.blockchain)
+	dex.APIBackend = &DexAPIBackend{dex, nil}
+	dex.governance = NewDexconGovernance(dex.APIBackend, chainConfig, nodeKey)
+	engine.SetGovStateFetcher(dex.governance)
+	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, db, config)
+	return dex, nil
+}
+
+func TestDevProposerRounds(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, common.Address{1}, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	if size := dex.governance.Configuration(0).NotarySetSize; size != 1 {
+		t.Fatalf("notary set size mismatch: have %d, want 1", size)
+	}
+
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 3 {
+		if dex.blockchain.CurrentBlock().NumberU64() > 50 {
+			t.Fatalf("round not advanced, round %d",
+				dex.blockchain.CurrentBlock().Round())
+		}
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+
+	if round := dex.governance.CRSRound(); round < 3 {
+		t.Errorf("CRS round mismatch: have %d, want at least 3", round)
+	}
+	for i := uint64(1); i <= dex.blockchain.CurrentBlock().NumberU64(); i++ {
+		header := dex.blockchain.GetHeaderByNumber(i)
+		if header.Round < dexCore.DKGDelayRound {
+			continue
+		}
+		if err := dex.engine.VerifySeal(dex.blockchain, header); err != nil {
+			t.Errorf("Verify seal of block %d fail: %v", i, err)
+		}
+		if header.Coinbase != crypto.PubkeyToAddress(nodeKey.PublicKey) {
+			t.Errorf("coinbase mismatch at block %d", i)
+		}
+	}
+	for round := uint64(1); round <= 3; round++ {
+		if _, ok := dex.blockchain.GetRoundHeight(round); !ok {
+			t.Errorf("round height of round %d not found", round)
+		}
+		if !dex.IsNotary(round) {
+			t.Errorf("node not in notary set of round %d", round)
+		}
+	}
+	// The developer chain delivers the blocks once they are confirmed.
+	if height, want := dex.FinalizedHeight(),
+		dex.blockchain.CurrentBlock().NumberU64(); height != want {
+		t.Errorf("finalized height mismatch: have %d, want %d", height, want)
+	}
+}
+
+func TestDevProposerOnTransaction(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	faucetKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, crypto.PubkeyToAddress(faucetKey.PublicKey), 60)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+
+	p := newDevProposer(dex, 0)
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start proposer fail: %v", err)
+	}
+	defer p.Stop()
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{2},
+		big.NewInt(1), params.TxGas, big.NewInt(params.GWei), nil), signer, faucetKey)
+	if err != nil {
+		t.Fatalf("Sign tx fail: %v", err)
+	}
+	if err := dex.txPool.AddRemote(tx); err != nil {
+		t.Fatalf("Add tx fail: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		state, err := dex.blockchain.State()
+		if err != nil {
+			t.Fatalf("Get state fail: %v", err)
+		}
+		// The DKG transactions are included as well.
+		pending, _ := dex.txPool.Stats()
+		if state.GetBalance(common.Address{2}).Cmp(big.NewInt(1)) == 0 &&
+			pending == 0 && dex.governance.IsDKGFinal(1) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transactions not included, pending %d", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDevChainHeaderSync(t *testing.T) {
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	dex, err := newDevDexon(nodeKey, common.Address{1}, 5)
+	if err != nil {
+		t.Fatalf("New dexon fail: %v", err)
+	}
+	p := newDevProposer(dex, 0)
+	p.db = newCoreDatabase(dex)
+	p.prepareNextRound()
+	for dex.blockchain.CurrentBlock().Round() < 3 {
+		if _, err := p.proposeBlock(); err != nil {
+			t.Fatalf("Propose block fail: %v", err)
+		}
+		p.prepareNextRound()
+	}
+
+	newChain := func() *core.BlockChain {
+		db := ethdb.NewMemDatabase()
+		genesis := core.DexconDeveloperGenesisBlock(common.Address{1},
+			&nodeKey.PublicKey)
+		genesis.Config.Dexcon.RoundLength = 5
+		chainConfig, _, err := core.SetupGenesisBlock(db, genesis)
+		if err != nil {
+			t.Fatalf("Setup genesis fail: %v", err)
+		}
+		chain, err := core.NewBlockChain(db, nil, chainConfig, dexcon.New(),
+			vm.Config{}, nil)
+		if err != nil {
+			t.Fatalf("New blockchain fail: %v", err)
+		}
+		return chain
+	}
+	var headers []*types.HeaderWithGovState
+	for i := uint64(1); i <= dex.blockchain.CurrentBlock().NumberU64(); i++ {
+		headers = append(headers, &types.HeaderWithGovState{
+			Header: dex.blockchain.GetHeaderByNumber(i),
+		})
+	}
+	cache := dexCore.NewTSigVerifierCache(dex.governance, 5)
+
+	// A header with a wrong reward is rejected by the engine even though its
+	// randomness is valid.
+	last := len(headers) - 1
+	tampered := make([]*types.HeaderWithGovState, len(headers))
+	copy(tampered, headers)
+	header := types.CopyHeader(headers[last].Header)
+	header.Reward = new(big.Int).Add(header.Reward, big.NewInt(1))
+	tampered[last] = &types.HeaderWithGovState{Header: header}
+	chain := newChain()
+	if i, err := chain.InsertDexonHeaderChain(tampered, dex.governance,
+		cache); err == nil || i != last {
+		t.Errorf("tampered header accepted: index %d, err %v", i, err)
+	}
+
+	chain = newChain()
+	if _, err := chain.InsertDexonHeaderChain(headers, dex.governance,
+		cache); err != nil {
+		t.Fatalf("Insert header chain fail: %v", err)
+	}
+	if chain.CurrentHeader().Hash() != dex.blockchain.CurrentHeader().Hash() {
+		t.Errorf("current header mismatch")
+	}
+}
@@ -19,6 +19,7 @@ package downloader
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -50,6 +51,8 @@ var (
 	MaxReceiptFetch = 256 // Amount of transaction receipts to allow fetching per request
 	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
 
+	MaxGovStateFetch = 1024 // Amount of governance storage slots to allow fetching per request
+
 	MaxForkAncestry  = 3 * params.EpochDuration // Maximum chain reorganisation
 	rttMinEstimate   = 2 * time.Second          // Minimum round-trip time to target for download requests
 	rttMaxEstimate   = 20 * time.Second         // Maximum round-trip time to target for download requests
@@ -88,6 +91,7 @@ var (
 	errInvalidBlock            = errors.New("retrieved block is invalid")
 	errInvalidBody             = errors.New("retrieved block body is invalid")
 	errInvalidReceipt          = errors.New("retrieved receipt is invalid")
+	errInvalidGovState         = errors.New("retrieved governance state is invalid")
 	errCancelBlockFetch        = errors.New("block download canceled (requested)")
 	errCancelHeaderFetch       = errors.New("block header download canceled (requested)")
 	errCancelBodyFetch         = errors.New("block body download canceled (requested)")
@@ -108,6 +112,8 @@ type Downloader struct {
 	peers   *peerSet // Set of active peers from which download can proceed
 	stateDB ethdb.Database
 
+	checkpoint *rawdb.CheckpointMeta // Checkpoint the chain is bootstrapped from, headers before it are missing
+
 	gov           *governance
 	verifierCache *dexCore.TSigVerifierCache
 
@@ -246,6 +252,7 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 			processed: rawdb.ReadFastTrieProgress(stateDb),
 		},
 		trackStateReq: make(chan *stateReq),
+		checkpoint:    rawdb.ReadCheckpointMeta(stateDb),
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -340,7 +347,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, number uint64, mod
 
 	case errTimeout, errBadPeer, errStallingPeer,
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
-		errInvalidAncestor, errInvalidChain:
+		errInvalidAncestor, errInvalidChain, errInvalidGovState:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
 		if d.dropPeer == nil {
 			// The dropPeer method is nil when `--copydb` is used for a local copy.
@@ -469,25 +476,37 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 			}
 		}
 	}
+	// The headers and the states before the checkpoint the chain is
+	// bootstrapped from are missing, the sync starts from the checkpoint.
+	if d.checkpoint != nil && origin < d.checkpoint.Number {
+		origin = d.checkpoint.Number
+		if d.mode == FastSync && pivot <= origin && origin < height {
+			pivot = origin + 1
+		}
+	}
 	d.committed = 1
 	if d.mode == FastSync && pivot != 0 {
 		d.committed = 0
 	}
 
 	if d.mode == FastSync || d.mode == LightSync {
-		// fetch gov state
-		govState, err := d.fetchGovState(p, latest.Hash(), latest.Root)
-		if err != nil {
-			return err
-		}
-
 		originHeader := d.lightchain.GetHeaderByNumber(origin)
 		if originHeader == nil {
 			return fmt.Errorf("origin header not exists, number: %d", origin)
 		}
 
+		// fetch gov state, only the slots changed from the local state at
+		// the beginning of the origin round if possible.
+		d.gov = newGovernance()
+		base := d.roundGovState(originHeader)
+		if base != nil {
+			d.gov.StoreState(base)
+		}
+		if err := d.fetchGovState(p, latest, base); err != nil {
+			return err
+		}
+
 		// prepare state origin - 3
-		d.gov = newGovernance(govState)
 		for i := uint64(0); i < 4; i++ {
 			if originHeader.Round >= i {
 				h := d.gov.GetRoundHeight(originHeader.Round - i)
@@ -632,7 +651,91 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
-func (d *Downloader) fetchGovState(p *peerConnection,
+// fetchGovState retrieves the governance state of the header and stores it.
+// The state is assembled from slices if the peer serves them, which are the
+// slots changed from the base state if it's not nil.
+func (d *Downloader) fetchGovState(p *peerConnection, header *types.Header,
+	base *types.GovState) error {
+	slicePeer, ok := p.peer.(govStateSlicePeer)
+	if !ok || p.version < 65 {
+		govState, err := d.fetchFullGovState(p, header.Hash(), header.Root)
+		if err != nil {
+			return err
+		}
+		d.gov.StoreState(govState)
+		return nil
+	}
+
+	stateSync, err := d.gov.newStateSync(header, base)
+	if err != nil {
+		return err
+	}
+	for !stateSync.done {
+		go slicePeer.RequestGovStateSlice(header.Hash(), stateSync.base,
+			stateSync.origin, MaxGovStateFetch)
+
+		slice, err := d.waitGovStateSlice(p)
+		if err != nil {
+			return err
+		}
+		if err := stateSync.process(slice); err != nil {
+			p.log.Debug("Invalid gov state slice", "number", header.Number, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// waitGovStateSlice waits for a governance state slice from the peer.
+func (d *Downloader) waitGovStateSlice(p *peerConnection) (*types.GovStateSlice, error) {
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+		case packet := <-d.govStateCh:
+			if packet.PeerId() != p.id {
+				log.Debug("Received gov state from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			if pack, ok := packet.(*govStateSlicePack); ok {
+				return pack.slice, nil
+			}
+		case <-timeout:
+			p.log.Debug("Waiting for gov state slice timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+		}
+	}
+}
+
+// roundGovState returns the local governance state at the beginning of the
+// round of the header, nil if it's not available.
+func (d *Downloader) roundGovState(header *types.Header) *types.GovState {
+	number := sort.Search(int(header.Number.Uint64()), func(i int) bool {
+		h := d.lightchain.GetHeaderByNumber(uint64(i))
+		if h == nil && d.checkpoint != nil && uint64(i) < d.checkpoint.Number {
+			// The headers before the checkpoint are missing, except the
+			// ones at the round heights.
+			return uint64(i) >= d.checkpoint.RoundHeight &&
+				d.checkpoint.Round >= header.Round
+		}
+		return h != nil && h.Round >= header.Round
+	})
+	govState, err := d.lightchain.GetGovStateByNumber(uint64(number))
+	if err != nil {
+		log.Debug("Local gov state not available", "number", number, "err", err)
+		return nil
+	}
+	return govState
+}
+
+// fetchFullGovState retrieves the whole governance state of a block from peers
+// not serving slices of it.
+func (d *Downloader) fetchFullGovState(p *peerConnection,
 	hash common.Hash, root common.Hash) (*types.GovState, error) {
 	go p.peer.RequestGovStateByHash(hash)
 
@@ -649,7 +752,11 @@ func (d *Downloader) fetchGovState(p *peerConnection,
 			}
 
 			// TODO(sonic): refactor this.
-			govState := packet.(*govStatePack).govState
+			pack, ok := packet.(*govStatePack)
+			if !ok {
+				break
+			}
+			govState := pack.govState
 
 			// reconstruct the gov state
 			db := ethdb.NewMemDatabase()
@@ -792,6 +899,10 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 			}
 		}
 	}
+	// The checkpoint the chain is bootstrapped from must be a common ancestor.
+	if d.checkpoint != nil && floor < int64(d.checkpoint.Number)-1 {
+		floor = int64(d.checkpoint.Number) - 1
+	}
 	from, count, skip, max := calculateRequestSpan(remoteHeight, localHeight)
 
 	p.log.Trace("Span searching for common ancestor", "count", count, "from", from, "skip", skip)
@@ -1775,6 +1886,12 @@ func (d *Downloader) DeliverGovState(id string, govState *types.GovState) error
 	return d.deliver(id, d.govStateCh, &govStatePack{id, govState}, govStateInMeter, govStateDropMeter)
 }
 
+// DeliverGovStateSlice injects a slice of a governance state received from a
+// remote node.
+func (d *Downloader) DeliverGovStateSlice(id string, slice *types.GovStateSlice) error {
+	return d.deliver(id, d.govStateCh, &govStateSlicePack{id, slice}, govStateInMeter, govStateDropMeter)
+}
+
 // DeliverBodies injects a new batch of block bodies received from a remote node.
 func (d *Downloader) DeliverBodies(id string, transactions [][]*types.Transaction, uncles [][]*types.Header) (err error) {
 	return d.deliver(id, d.bodyCh, &bodyPack{id, transactions, uncles}, bodyInMeter, bodyDropMeter)
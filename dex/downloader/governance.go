@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"bytes"
 	"fmt"
 	"sync"
 
@@ -8,9 +9,11 @@ import (
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/dexon-foundation/dexon/trie"
 )
 
@@ -41,9 +44,6 @@ func (g *governanceStateDB) StoreState(s *types.GovState) {
 	defer g.mu.Unlock()
 	log.Debug("Store state", "height", s.Number.Uint64())
 
-	// Store the height -> root mapping.
-	g.height2Root[s.Number.Uint64()] = s.Root
-
 	// Store the account.
 	for _, node := range s.Proof {
 		g.db.Put(crypto.Keccak256(node), node)
@@ -61,10 +61,18 @@ func (g *governanceStateDB) StoreState(s *types.GovState) {
 	t.Commit(nil)
 	triedb.Commit(t.Hash(), false)
 
-	if s.Number.Uint64() > g.headHeight {
-		log.Debug("Governance head root changed", "number", s.Number.Uint64())
-		g.headRoot = s.Root
-		g.headHeight = s.Number.Uint64()
+	g.storeRoot(s.Number.Uint64(), s.Root)
+}
+
+// storeRoot stores the height -> root mapping of a stored state, the lock
+// must be held.
+func (g *governanceStateDB) storeRoot(height uint64, root common.Hash) {
+	g.height2Root[height] = root
+
+	if g.headRoot == (common.Hash{}) || height > g.headHeight {
+		log.Debug("Governance head root changed", "number", height)
+		g.headRoot = root
+		g.headHeight = height
 	}
 }
 
@@ -74,15 +82,12 @@ type governance struct {
 	db *governanceStateDB
 }
 
-func newGovernance(headState *types.GovState) *governance {
+func newGovernance() *governance {
 	db := ethdb.NewMemDatabase()
 	govStateDB := &governanceStateDB{
 		db:          db,
-		headRoot:    headState.Root,
-		headHeight:  headState.Number.Uint64(),
 		height2Root: make(map[uint64]common.Hash),
 	}
-	govStateDB.StoreState(headState)
 	return &governance{
 		Governance: core.NewGovernance(govStateDB),
 		db:         govStateDB,
@@ -92,3 +97,153 @@ func newGovernance(headState *types.GovState) *governance {
 func (g *governance) StoreState(s *types.GovState) {
 	g.db.StoreState(s)
 }
+
+// govStateSync assembles the governance state of a block from the slices
+// retrieved from a peer. Each slot is verified against the state root of the
+// block before it's applied, and the assembled storage must match the storage
+// root once the last slice is applied. Only the slots of the previous state
+// may be deleted, so the slots retrieved are bounded by the states and a peer
+// can't stall the sync with endless slices.
+type govStateSync struct {
+	db     *governanceStateDB
+	header *types.Header
+	base   common.Hash // Block hash of the base state, zero for a range
+
+	triedb *trie.Database
+	trie   *trie.Trie
+	origin []byte // Key the next slice starts from
+	done   bool
+}
+
+// newStateSync creates a sync of the governance state of the header. The
+// slots changed from the base state are retrieved if base is not nil, the base
+// state must be stored already.
+func (g *governance) newStateSync(header *types.Header,
+	base *types.GovState) (*govStateSync, error) {
+	s := &govStateSync{
+		db:     g.db,
+		header: header,
+		triedb: trie.NewDatabase(g.db.db),
+	}
+	if base != nil {
+		account, err := verifyGovAccount(base.Root, base.Proof)
+		if err == nil {
+			s.trie, err = trie.New(account.Root, s.triedb)
+		}
+		if err == nil {
+			s.base = base.BlockHash
+			return s, nil
+		}
+		log.Debug("Base gov state not usable", "number", base.Number, "err", err)
+	}
+	t, err := trie.New(common.Hash{}, s.triedb)
+	if err != nil {
+		return nil, err
+	}
+	s.trie = t
+	return s, nil
+}
+
+// process verifies and applies a slice, and stores the state once it's
+// complete.
+func (s *govStateSync) process(slice *types.GovStateSlice) error {
+	if slice.BlockHash != s.header.Hash() || slice.Root != s.header.Root {
+		return errInvalidGovState
+	}
+	if slice.Base != s.base {
+		// The base state is not available to the peer, a range of the
+		// storage is served instead.
+		if slice.Base != (common.Hash{}) || s.origin != nil {
+			return errInvalidGovState
+		}
+		t, err := trie.New(common.Hash{}, s.triedb)
+		if err != nil {
+			return err
+		}
+		s.base, s.trie = common.Hash{}, t
+	}
+	account, err := verifyGovAccount(s.header.Root, slice.Proof)
+	if err != nil {
+		return errInvalidGovState
+	}
+	proofDB := ethdb.NewMemDatabase()
+	for _, node := range slice.StorageProof {
+		proofDB.Put(crypto.Keccak256(node), node)
+	}
+	prev := s.origin
+	for i, kv := range slice.Storage {
+		// The keys are ascending from the origin.
+		if cmp := bytes.Compare(kv[0], prev); cmp < 0 || (i > 0 && cmp == 0) {
+			return errInvalidGovState
+		}
+		prev = kv[0]
+		value, _, err := trie.VerifyProof(account.Root, kv[0], proofDB)
+		if err != nil || !bytes.Equal(value, kv[1]) {
+			return errInvalidGovState
+		}
+		if len(kv[1]) == 0 {
+			// Only the slots of the previous state are deleted, the absence
+			// of any other key is provable as well.
+			if old, err := s.trie.TryGet(kv[0]); err != nil || len(old) == 0 {
+				return errInvalidGovState
+			}
+			err = s.trie.TryDelete(kv[0])
+		} else {
+			err = s.trie.TryUpdate(kv[0], kv[1])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(slice.Next) > 0 {
+		if len(slice.Storage) == 0 || bytes.Compare(slice.Next, prev) <= 0 {
+			return errInvalidGovState
+		}
+		s.origin = slice.Next
+		return nil
+	}
+
+	root, err := s.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != account.Root {
+		log.Debug("Gov state storage mismatch", "number", s.header.Number,
+			"have", root, "want", account.Root)
+		return errInvalidGovState
+	}
+	if err := s.triedb.Commit(root, false); err != nil {
+		return err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	for _, node := range slice.Proof {
+		s.db.db.Put(crypto.Keccak256(node), node)
+	}
+	s.db.storeRoot(s.header.Number.Uint64(), s.header.Root)
+	s.done = true
+	return nil
+}
+
+// verifyGovAccount verifies the merkle proof of the governance contract
+// account against the state root, and returns the account.
+func verifyGovAccount(root common.Hash, proof [][]byte) (*state.Account, error) {
+	proofDB := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		proofDB.Put(crypto.Keccak256(node), node)
+	}
+	key := crypto.Keccak256(vm.GovernanceContractAddress.Bytes())
+	data, _, err := trie.VerifyProof(root, key, proofDB)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("governance account not found")
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(data, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
@@ -0,0 +1,201 @@
+package downloader
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/trie"
+)
+
+// govStateTester serves the governance state slices of the committed states.
+type govStateTester struct {
+	t  *testing.T
+	db state.Database
+}
+
+func (g *govStateTester) commit(number int64,
+	slots map[common.Hash]common.Hash) (*types.Header, *state.StateDB) {
+	statedb, _ := state.New(common.Hash{}, g.db)
+	statedb.SetNonce(vm.GovernanceContractAddress, 1)
+	for key, value := range slots {
+		statedb.SetState(vm.GovernanceContractAddress, key, value)
+	}
+	root, err := statedb.Commit(true)
+	if err != nil {
+		g.t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := g.db.TrieDB().Commit(root, false); err != nil {
+		g.t.Fatalf("failed to commit trie: %v", err)
+	}
+	statedb, _ = state.New(root, g.db)
+	return &types.Header{Number: big.NewInt(number), Root: root}, statedb
+}
+
+func (g *govStateTester) storage(statedb *state.StateDB) *trie.Trie {
+	t, err := state.GetGovStorageTrie(statedb, vm.GovernanceContractAddress)
+	if err != nil {
+		g.t.Fatalf("failed to get storage trie: %v", err)
+	}
+	return t
+}
+
+func (g *govStateTester) slice(header *types.Header, statedb *state.StateDB,
+	base *state.StateDB, origin []byte) *types.GovStateSlice {
+	proof, err := statedb.GetProof(vm.GovernanceContractAddress)
+	if err != nil {
+		g.t.Fatalf("failed to get proof: %v", err)
+	}
+	var baseStorage *trie.Trie
+	if base != nil {
+		baseStorage = g.storage(base)
+	}
+	slice, err := state.GetGovStateSlice(header, proof, g.storage(statedb),
+		baseStorage, origin, 16)
+	if err != nil {
+		g.t.Fatalf("failed to get slice: %v", err)
+	}
+	return slice
+}
+
+func TestGovStateSync(t *testing.T) {
+	tester := &govStateTester{t: t, db: state.NewDatabase(ethdb.NewMemDatabase())}
+
+	slots := make(map[common.Hash]common.Hash)
+	for i := int64(0); i < 100; i++ {
+		slots[common.BigToHash(big.NewInt(i))] = common.BigToHash(big.NewInt(i + 1))
+	}
+	baseHeader, baseState := tester.commit(10, slots)
+	baseGovState, err := state.GetGovState(baseState, baseHeader,
+		vm.GovernanceContractAddress)
+	if err != nil {
+		t.Fatalf("failed to get gov state: %v", err)
+	}
+
+	changed := []common.Hash{
+		common.BigToHash(big.NewInt(10)),
+		common.BigToHash(big.NewInt(20)),
+		common.BigToHash(big.NewInt(200)),
+	}
+	slots[changed[0]] = common.BigToHash(big.NewInt(1000))
+	delete(slots, changed[1])
+	slots[changed[2]] = common.BigToHash(big.NewInt(2000))
+	header, statedb := tester.commit(20, slots)
+
+	// sync runs a sync of the state, with the slots changed from the base
+	// state if it's not nil, and returns the slots retrieved.
+	sync := func(base *types.GovState, serveBase bool,
+		tamper func(*types.GovStateSlice)) (*governance, int, error) {
+		gov := newGovernance()
+		if base != nil {
+			gov.StoreState(base)
+		}
+		s, err := gov.newStateSync(header, base)
+		if err != nil {
+			t.Fatalf("failed to create sync: %v", err)
+		}
+		retrieved := 0
+		for !s.done {
+			var slice *types.GovStateSlice
+			if s.base != (common.Hash{}) && serveBase {
+				slice = tester.slice(header, statedb, baseState, s.origin)
+				slice.Base = s.base
+			} else {
+				slice = tester.slice(header, statedb, nil, s.origin)
+			}
+			retrieved += len(slice.Storage)
+			if tamper != nil {
+				tamper(slice)
+			}
+			if err := s.process(slice); err != nil {
+				return gov, retrieved, err
+			}
+		}
+		return gov, retrieved, nil
+	}
+	check := func(name string, gov *governance) {
+		synced, err := gov.db.StateAt(header.Number.Uint64())
+		if err != nil {
+			t.Fatalf("%s: state not stored: %v", name, err)
+		}
+		for i := int64(0); i <= 200; i++ {
+			key := common.BigToHash(big.NewInt(i))
+			have := synced.GetState(vm.GovernanceContractAddress, key)
+			if want := slots[key]; have != want {
+				t.Errorf("%s: slot %d mismatch: have %x, want %x", name, i, have, want)
+			}
+		}
+	}
+
+	gov, retrieved, err := sync(nil, false, nil)
+	if err != nil {
+		t.Fatalf("range sync failed: %v", err)
+	}
+	if retrieved != len(slots) {
+		t.Errorf("range sync retrieved %d slots, want %d", retrieved, len(slots))
+	}
+	check("range", gov)
+
+	gov, retrieved, err = sync(baseGovState, true, nil)
+	if err != nil {
+		t.Fatalf("diff sync failed: %v", err)
+	}
+	if retrieved != len(changed) {
+		t.Errorf("diff sync retrieved %d slots, want %d", retrieved, len(changed))
+	}
+	check("diff", gov)
+
+	// A range is served if the base state is not available to the peer.
+	gov, _, err = sync(baseGovState, false, nil)
+	if err != nil {
+		t.Fatalf("fallback sync failed: %v", err)
+	}
+	check("fallback", gov)
+
+	// Tampered and omitted slots are detected.
+	_, _, err = sync(nil, false, func(slice *types.GovStateSlice) {
+		slice.Storage[0][1] = []byte{1}
+	})
+	if err != errInvalidGovState {
+		t.Errorf("tampered slot not detected: %v", err)
+	}
+	_, _, err = sync(baseGovState, true, func(slice *types.GovStateSlice) {
+		slice.Storage = slice.Storage[1:]
+	})
+	if err != errInvalidGovState {
+		t.Errorf("omitted slot not detected: %v", err)
+	}
+
+	// Only the slots of the previous state may be deleted, so a peer can't
+	// send endless deletions of absent keys.
+	_, _, err = sync(nil, false, func(slice *types.GovStateSlice) {
+		diff := tester.slice(header, statedb, baseState, nil)
+		slice.Storage, slice.StorageProof = diff.Storage, diff.StorageProof
+	})
+	if err != errInvalidGovState {
+		t.Errorf("deletion of slot out of range state not detected: %v", err)
+	}
+	absent := crypto.Keccak256(common.BigToHash(big.NewInt(300)).Bytes())
+	proofDB := ethdb.NewMemDatabase()
+	if err := tester.storage(statedb).Prove(absent, 0, proofDB); err != nil {
+		t.Fatalf("failed to prove absent slot: %v", err)
+	}
+	_, _, err = sync(baseGovState, true, func(slice *types.GovStateSlice) {
+		slice.Storage = [][2][]byte{{absent, nil}}
+		slice.StorageProof = nil
+		for _, key := range proofDB.Keys() {
+			node, _ := proofDB.Get(key)
+			slice.StorageProof = append(slice.StorageProof, node)
+		}
+		slice.Next = common.BigToHash(new(big.Int).Add(
+			new(big.Int).SetBytes(absent), big.NewInt(1))).Bytes()
+	})
+	if err != errInvalidGovState {
+		t.Errorf("deletion of slot out of base state not detected: %v", err)
+	}
+}
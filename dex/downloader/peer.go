@@ -82,6 +82,12 @@ type LightPeer interface {
 	RequestGovStateByHash(common.Hash) error
 }
 
+// govStateSlicePeer is a peer able to serve slices of the governance state,
+// required from dex/65 peers.
+type govStateSlicePeer interface {
+	RequestGovStateSlice(hash, base common.Hash, origin []byte, amount int) error
+}
+
 // Peer encapsulates the methods required to synchronise with a remote full peer.
 type Peer interface {
 	LightPeer
@@ -102,8 +108,8 @@ func (w *lightPeerWrapper) RequestHeadersByHash(h common.Hash, amount int, skip
 func (w *lightPeerWrapper) RequestHeadersByNumber(i uint64, amount int, skip int, reverse, withGov bool) error {
 	return w.peer.RequestHeadersByNumber(i, amount, skip, reverse, withGov)
 }
-func (w *lightPeerWrapper) RequestGovStateByHash(common.Hash) error {
-	panic("RequestGovStateByHash not supported in light client mode sync")
+func (w *lightPeerWrapper) RequestGovStateByHash(h common.Hash) error {
+	return w.peer.RequestGovStateByHash(h)
 }
 func (w *lightPeerWrapper) DownloadBodies([]common.Hash) error {
 	panic("DownloadBodies not supported in light client mode sync")
@@ -473,7 +479,7 @@ func (ps *peerSet) HeaderIdlePeers() ([]*peerConnection, int) {
 		defer p.lock.RUnlock()
 		return p.headerThroughput
 	}
-	return ps.idlePeers(62, 64, idle, throughput)
+	return ps.idlePeers(62, 65, idle, throughput)
 }
 
 // BodyIdlePeers retrieves a flat list of all the currently body-idle peers within
@@ -487,7 +493,7 @@ func (ps *peerSet) BodyIdlePeers() ([]*peerConnection, int) {
 		defer p.lock.RUnlock()
 		return p.blockThroughput
 	}
-	return ps.idlePeers(62, 64, idle, throughput)
+	return ps.idlePeers(62, 65, idle, throughput)
 }
 
 // ReceiptIdlePeers retrieves a flat list of all the currently receipt-idle peers
@@ -501,7 +507,7 @@ func (ps *peerSet) ReceiptIdlePeers() ([]*peerConnection, int) {
 		defer p.lock.RUnlock()
 		return p.receiptThroughput
 	}
-	return ps.idlePeers(63, 64, idle, throughput)
+	return ps.idlePeers(63, 65, idle, throughput)
 }
 
 // NodeDataIdlePeers retrieves a flat list of all the currently node-data-idle
@@ -515,7 +521,7 @@ func (ps *peerSet) NodeDataIdlePeers() ([]*peerConnection, int) {
 		defer p.lock.RUnlock()
 		return p.stateThroughput
 	}
-	return ps.idlePeers(63, 64, idle, throughput)
+	return ps.idlePeers(63, 65, idle, throughput)
 }
 
 // idlePeers retrieves a flat list of all currently idle peers satisfying the
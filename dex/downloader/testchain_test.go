@@ -23,6 +23,7 @@ import (
 
 	dexCore "github.com/dexon-foundation/dexon-consensus/core"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreTypesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus/dexcon"
@@ -343,6 +344,26 @@ func (g *govStateFetcher) GetStateForConfigAtRound(round uint64) *vm.GovernanceS
 	return nil
 }
 
+func (g *govStateFetcher) GetRoundHeight(round uint64) uint64 {
+	return 0
+}
+
 func (g *govStateFetcher) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
 	return make(map[common.Address]struct{}), nil
 }
+
+func (g *govStateFetcher) Configuration(round uint64) *coreTypes.Config {
+	return &coreTypes.Config{}
+}
+
+func (g *govStateFetcher) DKGComplaints(round uint64) []*coreTypesDKG.Complaint {
+	return nil
+}
+
+func (g *govStateFetcher) DKGMasterPublicKeys(round uint64) []*coreTypesDKG.MasterPublicKey {
+	return nil
+}
+
+func (g *govStateFetcher) IsDKGFinal(round uint64) bool {
+	return false
+}
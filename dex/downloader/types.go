@@ -51,6 +51,16 @@ func (p *govStatePack) PeerId() string { return p.peerID }
 func (p *govStatePack) Items() int     { return 1 }
 func (p *govStatePack) Stats() string  { return "1" }
 
+// govStateSlicePack is a slice of a governance state returned by a peer.
+type govStateSlicePack struct {
+	peerID string
+	slice  *types.GovStateSlice
+}
+
+func (p *govStateSlicePack) PeerId() string { return p.peerID }
+func (p *govStateSlicePack) Items() int     { return len(p.slice.Storage) }
+func (p *govStateSlicePack) Stats() string  { return fmt.Sprintf("%d", len(p.slice.Storage)) }
+
 // bodyPack is a batch of block bodies returned by a peer.
 type bodyPack struct {
 	peerID       string
@@ -0,0 +1,188 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"sync"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// maxForkWatchPositions is the number of latest positions whose votes and
+// blocks are kept to detect forks.
+const maxForkWatchPositions = 128
+
+// maxForkWatchEntries is the number of votes, and of blocks, kept for each
+// position.
+const maxForkWatchEntries = 256
+
+// evidenceGovernance is the governance the fork evidence is reported to.
+type evidenceGovernance interface {
+	IsFined(hash common.Hash) bool
+	ReportForkEvidence(key *ecdsa.PrivateKey, evidence *rawdb.ForkEvidence) error
+}
+
+// voteForkKey identifies the votes conflicting with each other at a position.
+type voteForkKey struct {
+	ProposerID coreTypes.NodeID
+	Type       coreTypes.VoteType
+	Period     uint64
+}
+
+// forkWatcher detects the forks of the votes and blocks received from the
+// network. The evidence is persisted, and reported to the governance contract
+// if a reporter key is set, so forks are fined even if no node of the notary
+// set observes them.
+type forkWatcher struct {
+	db       ethdb.Database
+	gov      evidenceGovernance
+	reporter *ecdsa.PrivateKey
+
+	lock      sync.Mutex
+	votes     map[coreTypes.Position]map[voteForkKey]*coreTypes.Vote
+	blocks    map[coreTypes.Position]map[coreTypes.NodeID]*coreTypes.Block
+	positions []coreTypes.Position
+
+	reportLock sync.Mutex
+}
+
+func newForkWatcher(db ethdb.Database, gov evidenceGovernance,
+	reporter *ecdsa.PrivateKey) *forkWatcher {
+	return &forkWatcher{
+		db:       db,
+		gov:      gov,
+		reporter: reporter,
+		votes:    make(map[coreTypes.Position]map[voteForkKey]*coreTypes.Vote),
+		blocks:   make(map[coreTypes.Position]map[coreTypes.NodeID]*coreTypes.Block),
+	}
+}
+
+// watch starts watching the position, the oldest position is dropped if too
+// many are watched. It's called with the lock held.
+func (w *forkWatcher) watch(pos coreTypes.Position) {
+	if _, exist := w.votes[pos]; exist {
+		return
+	}
+	if len(w.positions) >= maxForkWatchPositions {
+		delete(w.votes, w.positions[0])
+		delete(w.blocks, w.positions[0])
+		w.positions = w.positions[1:]
+	}
+	w.positions = append(w.positions, pos)
+	w.votes[pos] = make(map[voteForkKey]*coreTypes.Vote)
+	w.blocks[pos] = make(map[coreTypes.NodeID]*coreTypes.Block)
+}
+
+// checkVote checks if the vote conflicts with a vote received before. The
+// vote must be verified and proposed by the notary set of its position, so
+// the watched positions are not taken by votes of throwaway keys.
+func (w *forkWatcher) checkVote(vote *coreTypes.Vote) {
+	key := voteForkKey{
+		ProposerID: vote.ProposerID,
+		Type:       vote.Type,
+		Period:     vote.Period,
+	}
+
+	w.lock.Lock()
+	w.watch(vote.Position)
+	votes := w.votes[vote.Position]
+	old, exist := votes[key]
+	if !exist && len(votes) < maxForkWatchEntries {
+		votes[key] = vote
+	}
+	w.lock.Unlock()
+
+	if !exist || old.BlockHash == vote.BlockHash {
+		return
+	}
+	if fork, err := coreUtils.NeedPenaltyForkVote(old, vote); err != nil || !fork {
+		return
+	}
+	w.found(vm.FineTypeForkVote, old, vote)
+}
+
+// checkBlock checks if the block conflicts with a block received before. The
+// block must be verified and proposed by the notary set of its position.
+func (w *forkWatcher) checkBlock(block *coreTypes.Block) {
+	// Forks are reported without payloads.
+	block = block.Clone()
+	block.Payload = nil
+	block.Randomness = nil
+
+	w.lock.Lock()
+	w.watch(block.Position)
+	blocks := w.blocks[block.Position]
+	old, exist := blocks[block.ProposerID]
+	if !exist && len(blocks) < maxForkWatchEntries {
+		blocks[block.ProposerID] = block
+	}
+	w.lock.Unlock()
+
+	if !exist || old.Hash == block.Hash {
+		return
+	}
+	if fork, err := coreUtils.NeedPenaltyForkBlock(old, block); err != nil || !fork {
+		return
+	}
+	w.found(vm.FineTypeForkBlock, old, block)
+}
+
+// found persists the evidence of a fork and reports it if it's not fined yet.
+func (w *forkWatcher) found(fineType vm.FineType, v1, v2 interface{}) {
+	arg1, err := rlp.EncodeToBytes(v1)
+	if err != nil {
+		log.Error("Failed to encode fork evidence", "err", err)
+		return
+	}
+	arg2, err := rlp.EncodeToBytes(v2)
+	if err != nil {
+		log.Error("Failed to encode fork evidence", "err", err)
+		return
+	}
+
+	w.reportLock.Lock()
+	defer w.reportLock.Unlock()
+
+	hash := common.Hash(vm.FineRecordHash(arg1, arg2))
+	if rawdb.HasForkEvidence(w.db, hash) {
+		return
+	}
+	evidence := &rawdb.ForkEvidence{
+		Type: uint64(fineType),
+		Arg1: arg1,
+		Arg2: arg2,
+	}
+	rawdb.WriteForkEvidence(w.db, hash, evidence)
+	log.Info("Found fork evidence", "type", fineType, "hash", hash)
+
+	if w.reporter == nil || w.gov.IsFined(hash) {
+		return
+	}
+	if err := w.gov.ReportForkEvidence(w.reporter, evidence); err != nil {
+		log.Error("Failed to report fork evidence", "hash", hash, "err", err)
+	}
+}
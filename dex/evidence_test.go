@@ -0,0 +1,238 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+type testEvidenceGovernance struct {
+	fined   map[common.Hash]bool
+	reports []*rawdb.ForkEvidence
+}
+
+func (g *testEvidenceGovernance) IsFined(hash common.Hash) bool {
+	return g.fined[hash]
+}
+
+func (g *testEvidenceGovernance) ReportForkEvidence(key *ecdsa.PrivateKey,
+	evidence *rawdb.ForkEvidence) error {
+	g.reports = append(g.reports, evidence)
+	return nil
+}
+
+func newTestSigner(t *testing.T) *coreUtils.Signer {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	return coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+}
+
+func TestForkWatcherVote(t *testing.T) {
+	reporter, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Generate key fail: %v", err)
+	}
+	db := ethdb.NewMemDatabase()
+	gov := &testEvidenceGovernance{fined: make(map[common.Hash]bool)}
+	watcher := newForkWatcher(db, gov, reporter)
+	signer := newTestSigner(t)
+
+	newVote := func(hash coreCommon.Hash) *coreTypes.Vote {
+		vote := coreTypes.NewVote(coreTypes.VotePreCom, hash, 1)
+		vote.Position = coreTypes.Position{Round: 1, Height: 10}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("Sign vote fail: %v", err)
+		}
+		return vote
+	}
+	vote1 := newVote(coreCommon.NewRandomHash())
+	vote2 := newVote(coreCommon.NewRandomHash())
+
+	watcher.checkVote(vote1)
+	watcher.checkVote(vote1)
+	if len(gov.reports) != 0 {
+		t.Fatalf("reports mismatch: have %d, want 0", len(gov.reports))
+	}
+
+	watcher.checkVote(vote2)
+	if len(gov.reports) != 1 {
+		t.Fatalf("reports mismatch: have %d, want 1", len(gov.reports))
+	}
+	evidence := gov.reports[0]
+	if evidence.Type != vm.FineTypeForkVote {
+		t.Errorf("fine type mismatch: have %d, want %d", evidence.Type,
+			vm.FineTypeForkVote)
+	}
+	hash := common.Hash(vm.FineRecordHash(evidence.Arg1, evidence.Arg2))
+	if rawdb.ReadForkEvidence(db, hash) == nil {
+		t.Error("fork evidence not persisted")
+	}
+
+	// The same evidence is reported once.
+	watcher.checkVote(vote2)
+	if len(gov.reports) != 1 {
+		t.Errorf("reports mismatch: have %d, want 1", len(gov.reports))
+	}
+
+	// Evidence already fined is persisted only.
+	vote3 := newVote(coreCommon.NewRandomHash())
+	arg1, arg2 := mustEncodeRLP(t, vote1), mustEncodeRLP(t, vote3)
+	gov.fined[common.Hash(vm.FineRecordHash(arg1, arg2))] = true
+	watcher.checkVote(vote3)
+	if len(gov.reports) != 1 {
+		t.Errorf("reports mismatch: have %d, want 1", len(gov.reports))
+	}
+	if !rawdb.HasForkEvidence(db, common.Hash(vm.FineRecordHash(arg1, arg2))) {
+		t.Error("fined fork evidence not persisted")
+	}
+}
+
+func TestForkWatcherEntriesLimit(t *testing.T) {
+	watcher := newForkWatcher(ethdb.NewMemDatabase(),
+		&testEvidenceGovernance{}, nil)
+	signer := newTestSigner(t)
+
+	// Votes of growing periods are kept up to the limit of the position.
+	for period := uint64(0); period < maxForkWatchEntries+10; period++ {
+		vote := coreTypes.NewVote(coreTypes.VotePreCom,
+			coreCommon.NewRandomHash(), period)
+		vote.Position = coreTypes.Position{Round: 1, Height: 10}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("Sign vote fail: %v", err)
+		}
+		watcher.checkVote(vote)
+	}
+	pos := coreTypes.Position{Round: 1, Height: 10}
+	if n := len(watcher.votes[pos]); n != maxForkWatchEntries {
+		t.Errorf("votes mismatch: have %d, want %d", n, maxForkWatchEntries)
+	}
+}
+
+func TestForkWatcherBlock(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	gov := &testEvidenceGovernance{}
+	watcher := newForkWatcher(db, gov, nil)
+	signer := newTestSigner(t)
+
+	newBlock := func() *coreTypes.Block {
+		block := &coreTypes.Block{
+			Position:  coreTypes.Position{Round: 1, Height: 10},
+			Timestamp: time.Now().UTC(),
+			Payload:   coreCommon.NewRandomHash().Bytes(),
+		}
+		if err := signer.SignBlock(block); err != nil {
+			t.Fatalf("Sign block fail: %v", err)
+		}
+		return block
+	}
+	block1 := newBlock()
+	block2 := newBlock()
+	block2.Timestamp = block1.Timestamp.Add(time.Second)
+	if err := signer.SignBlock(block2); err != nil {
+		t.Fatalf("Sign block fail: %v", err)
+	}
+
+	watcher.checkBlock(block1)
+	watcher.checkBlock(block2)
+	if len(gov.reports) != 0 {
+		t.Errorf("reported without reporter key")
+	}
+	if len(block1.Payload) == 0 {
+		t.Error("payload of received block stripped")
+	}
+
+	block1.Payload, block2.Payload = nil, nil
+	hash := common.Hash(vm.FineRecordHash(mustEncodeRLP(t, block1),
+		mustEncodeRLP(t, block2)))
+	evidence := rawdb.ReadForkEvidence(db, hash)
+	if evidence == nil {
+		t.Fatal("fork evidence not persisted")
+	}
+	if evidence.Type != vm.FineTypeForkBlock {
+		t.Errorf("fine type mismatch: have %d, want %d", evidence.Type,
+			vm.FineTypeForkBlock)
+	}
+}
+
+func mustEncodeRLP(t *testing.T, v interface{}) []byte {
+	data, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		t.Fatalf("Encode RLP fail: %v", err)
+	}
+	return data
+}
+
+func TestWatchForksNotarySet(t *testing.T) {
+	notaryKey := hex.EncodeToString(crypto.FromECDSAPub(&testAccount.PublicKey))
+	gov := &testGovernance{
+		lenCRSFunc: func() uint64 { return 1 },
+		notarySetFunc: func(uint64) (map[string]struct{}, error) {
+			return map[string]struct{}{notaryKey: {}}, nil
+		},
+	}
+	v := newCoreMsgVerifier(gov, nil, func(interface{}) bool { return true },
+		func(string, error) {})
+	pm := &ProtocolManager{
+		forkWatcher: newForkWatcher(ethdb.NewMemDatabase(),
+			&testEvidenceGovernance{}, nil),
+	}
+
+	newVote := func(signer *coreUtils.Signer, round, height uint64) *coreTypes.Vote {
+		vote := coreTypes.NewVote(coreTypes.VotePreCom,
+			coreCommon.NewRandomHash(), 0)
+		vote.Position = coreTypes.Position{Round: round, Height: height}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("Sign vote fail: %v", err)
+		}
+		return vote
+	}
+
+	// Votes of proposers out of the notary set, or of rounds whose notary
+	// set is not known, don't take the watched positions.
+	other := newTestSigner(t)
+	for height := uint64(0); height < maxForkWatchPositions+10; height++ {
+		pm.watchForks(v, newVote(other, 1, height))
+		pm.watchForks(v, newVote(testSigner, 2, height))
+	}
+	if n := len(pm.forkWatcher.positions); n != 0 {
+		t.Errorf("positions mismatch: have %d, want 0", n)
+	}
+
+	vote := newVote(testSigner, 1, 10)
+	pm.watchForks(v, vote)
+	if len(pm.forkWatcher.votes[vote.Position]) != 1 {
+		t.Errorf("notary vote not kept")
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/crypto"
@@ -64,12 +65,18 @@ func (d *DexconGovernance) DexconConfiguration(round uint64) *params.DexconConfi
 }
 
 func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
+	return d.sendGovTxFrom(ctx, d.privateKey, data)
+}
+
+// sendGovTxFrom sends a governance transaction signed by the given key.
+func (d *DexconGovernance) sendGovTxFrom(ctx context.Context,
+	key *ecdsa.PrivateKey, data []byte) error {
 	gasPrice, err := d.b.SuggestPrice(ctx)
 	if err != nil {
 		return err
 	}
 
-	nonce, err := d.b.GetPoolNonce(ctx, d.address)
+	nonce, err := d.b.GetPoolNonce(ctx, crypto.PubkeyToAddress(key.PublicKey))
 	if err != nil {
 		return err
 	}
@@ -93,7 +100,7 @@ func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
 
 	signer := types.NewEIP155Signer(d.chainConfig.ChainID)
 
-	tx, err = types.SignTx(tx, signer, d.privateKey)
+	tx, err = types.SignTx(tx, signer, key)
 	if err != nil {
 		return err
 	}
@@ -219,6 +226,24 @@ func (d *DexconGovernance) ReportForkBlock(block1, block2 *coreTypes.Block) {
 	}
 }
 
+// IsFined reports whether the fine of the record hash is charged at the
+// chain head.
+func (d *DexconGovernance) IsFined(hash common.Hash) bool {
+	return d.GetHeadState().FineRecords(vm.Bytes32(hash))
+}
+
+// ReportForkEvidence reports the fork evidence with a transaction signed by
+// the given key.
+func (d *DexconGovernance) ReportForkEvidence(key *ecdsa.PrivateKey,
+	evidence *rawdb.ForkEvidence) error {
+	data, err := vm.PackReport(vm.FineType(evidence.Type), evidence.Arg1,
+		evidence.Arg2)
+	if err != nil {
+		return err
+	}
+	return d.sendGovTxFrom(context.Background(), key, data)
+}
+
 func (d *DexconGovernance) ResetDKG(newSignedCRS []byte) {
 	data, err := vm.PackResetDKG(newSignedCRS)
 	if err != nil {
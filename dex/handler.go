@@ -39,6 +39,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -49,6 +50,7 @@ import (
 	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus"
@@ -109,6 +111,10 @@ type ProtocolManager struct {
 	blockchain    *core.BlockChain
 	chainconfig   *params.ChainConfig
 	cache         *cache
+	forkWatcher   *forkWatcher // Detects forks of core messages, nil if disabled
+	journal       *coreJournal // Records received core messages, nil if disabled
+	reputation    *peerReputation
+	verifier      *coreMsgVerifier
 	nextPullVote  *sync.Map
 	nextPullBlock *sync.Map
 	maxPeers      int
@@ -125,6 +131,15 @@ type ProtocolManager struct {
 
 	whitelist map[uint64]common.Hash
 
+	// Sentry relay, the node is either protected by sentries relaying its
+	// core messages or a sentry of validators.
+	sentries  []*enode.Node
+	sentryFor []*enode.Node
+
+	relayVerifier *coreMsgVerifier // Verifies the core messages relayed as a sentry
+	relayCh       chan coreTypes.Msg
+	relayedMsgs   *lru.Cache // Core messages relayed as a sentry
+
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
 	txsyncCh    chan *txsync
@@ -187,6 +202,34 @@ func NewProtocolManager(
 		app:                app,
 		blockNumberGauge:   metrics.GetOrRegisterGauge("dex/blocknumber", nil),
 	}
+	manager.reputation = newPeerReputation(chaindb, func(id string) bool {
+		return manager.peers != nil && manager.peers.IsNotaryDirectPeer(id)
+	})
+	manager.verifier = newCoreMsgVerifier(gov, manager.receiveCh,
+		func(payload interface{}) bool {
+			manager.watchForks(manager.verifier, payload)
+			if atomic.LoadInt32(&manager.receiveCoreMessage) == 0 {
+				return false
+			}
+			switch msg := payload.(type) {
+			case *coreTypes.Block:
+				manager.cache.addBlocks([]*coreTypes.Block{msg})
+			case *coreTypes.Vote:
+				if msg.Type >= coreTypes.VotePreCom {
+					manager.cache.addVote(msg)
+				}
+			}
+			return true
+		},
+		manager.rejectCoreMessage)
+	manager.relayCh = make(chan coreTypes.Msg, 1024)
+	manager.relayVerifier = newCoreMsgVerifier(gov, manager.relayCh,
+		func(payload interface{}) bool {
+			manager.watchForks(manager.relayVerifier, payload)
+			return true
+		},
+		manager.rejectCoreMessage)
+	manager.relayedMsgs, _ = lru.New(relayedMsgCacheSize)
 
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -230,7 +273,7 @@ func NewProtocolManager(
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.dropUselessPeer)
 
 	validator := func(header *types.Header) error {
 		return blockchain.VerifyDexonHeader(header)
@@ -247,11 +290,43 @@ func NewProtocolManager(
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertDexonChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.dropUselessPeer)
 
 	return manager, nil
 }
 
+// rejectCoreMessage penalizes the peer which sent an invalid core message.
+// Messages of proposers out of the notary set are dropped only, the notary set
+// of the peer might differ after a DKG reset not known to this node yet.
+func (pm *ProtocolManager) rejectCoreMessage(id string, err error) {
+	if err == errNotNotary {
+		return
+	}
+	if pm.reputation.adjust(id, scoreBadPeer) {
+		pm.removePeer(id)
+	}
+}
+
+// watchForks checks a core message verified by v for forks. Only the messages
+// proposed by the notary set of their positions are watched, so the messages
+// signed by throwaway keys never push the ones of the notary set out of the
+// watcher.
+func (pm *ProtocolManager) watchForks(v *coreMsgVerifier, payload interface{}) {
+	if pm.forkWatcher == nil {
+		return
+	}
+	switch msg := payload.(type) {
+	case *coreTypes.Block:
+		if v.isKnownNotary(msg.Position.Round, msg.ProposerID) {
+			pm.forkWatcher.checkBlock(msg)
+		}
+	case *coreTypes.Vote:
+		if v.isKnownNotary(msg.Position.Round, msg.ProposerID) {
+			pm.forkWatcher.checkVote(msg)
+		}
+	}
+}
+
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -278,10 +353,23 @@ func (pm *ProtocolManager) removePeer(id string) {
 	log.Debug("peer removed", "id", id)
 }
 
+// dropUselessPeer penalizes a peer whose sync responses are rejected by the
+// downloader or the fetcher, and disconnects it.
+func (pm *ProtocolManager) dropUselessPeer(id string) {
+	pm.reputation.adjust(id, scoreUselessResponse)
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start(srvr p2pServer, maxPeers int) {
 	pm.maxPeers = maxPeers
 	pm.srvr = srvr
 	pm.peers = newPeerSet(pm.gov, pm.srvr)
+	if len(pm.sentries) > 0 {
+		pm.peers.BuildSentryConnection(sentryset, pm.sentries)
+	}
+	if len(pm.sentryFor) > 0 {
+		pm.peers.BuildSentryConnection(protectedset, pm.sentryFor)
+	}
 
 	// broadcast transactions
 	pm.txsCh = make(chan core.NewTxsEvent, txChanSize)
@@ -308,6 +396,13 @@ func (pm *ProtocolManager) Start(srvr p2pServer, maxPeers int) {
 
 	// Listen to bad peer and disconnect it.
 	go pm.badPeerWatchLoop()
+
+	// Verify the core messages before passing them to the consensus core.
+	pm.verifier.start()
+
+	// Verify the core messages before relaying them as a sentry.
+	pm.relayVerifier.start()
+	go pm.relayLoop()
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -333,9 +428,19 @@ func (pm *ProtocolManager) Stop() {
 	// will exit when they try to register.
 	pm.peers.Close()
 
+	// Handlers waiting to queue core messages are released.
+	pm.verifier.stop()
+	pm.relayVerifier.stop()
+
 	// Wait for all peer handler goroutines and the loops to come down.
 	pm.wg.Wait()
 
+	if pm.journal != nil {
+		if err := pm.journal.close(); err != nil {
+			log.Warn("Failed to close consensus journal", "err", err)
+		}
+	}
+
 	log.Info("DEXON protocol stopped")
 }
 
@@ -352,6 +457,7 @@ func (pm *ProtocolManager) badPeerWatchLoop() {
 		select {
 		case id := <-pm.reportBadPeerChan:
 			log.Debug("Bad peer detected, removing", "id", id.(string))
+			pm.reputation.adjust(id.(string), scoreBadPeer)
 			pm.removePeer(id.(string))
 		case <-pm.quitSync:
 			return
@@ -366,6 +472,15 @@ func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *p
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
+	if pm.reputation.banned(p.id) {
+		p.Log().Debug("Rejected banned peer")
+		return p2p.DiscUselessPeer
+	}
+	// A validator protected by sentries is connected to them only
+	if pm.peers.IsProtected() && !pm.peers.HasLabel(p.id, peerLabel{set: sentryset}) {
+		p.Log().Debug("Rejected peer not being a sentry")
+		return p2p.DiscUselessPeer
+	}
 	// Ignore maxPeers if this is a trusted peer
 	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
 		return p2p.DiscTooManyPeers
@@ -419,7 +534,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
-func (pm *ProtocolManager) handleMsg(p *peer) error {
+func (pm *ProtocolManager) handleMsg(p *peer) (err error) {
 	ch := make(chan struct{})
 	defer close(ch)
 
@@ -447,11 +562,31 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		return err
 	}
 	ch <- struct{}{}
+	defer func() {
+		event := scoreUsefulMessage
+		if err != nil {
+			event = scoreProtocolError
+		} else if !rewarded(msg.Code) {
+			return
+		}
+		if pm.reputation.adjust(p.id, event) && err == nil {
+			err = errPeerBanned
+		}
+	}()
 	if msg.Size > ProtocolMaxMsgSize {
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	defer msg.Discard()
 
+	if pm.journal != nil && journaled(msg.Code) {
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		msg.Payload = bytes.NewReader(payload)
+		pm.journal.record(p.ID().String(), msg.Code, payload)
+	}
+
 	go func() {
 		start := time.Now()
 		for {
@@ -850,24 +985,24 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		types.GlobalSigCache.Add(types.NewEIP155Signer(pm.blockchain.Config().ChainID), txs)
 		pm.txpool.AddRemotes(txs)
 
+	// Core messages relayed by a sentry.
+	case relayed(msg.Code) && pm.peers.IsSentry():
+		return pm.relayCoreMessage(p, msg)
+
 	// Block proposer-only messages.
 	case msg.Code == CoreBlockMsg:
-		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
+		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 && pm.forkWatcher == nil {
 			break
 		}
 		var blocks []*coreTypes.Block
 		if err := msg.Decode(&blocks); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		pm.cache.addBlocks(blocks)
 		for _, block := range blocks {
-			pm.receiveCh <- coreTypes.Msg{
-				PeerID:  p.ID().String(),
-				Payload: block,
-			}
+			pm.verifier.enqueue(p.ID().String(), block)
 		}
 	case msg.Code == VoteMsg:
-		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
+		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 && pm.forkWatcher == nil {
 			break
 		}
 		var votes []*coreTypes.Vote
@@ -875,13 +1010,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		for _, vote := range votes {
-			if vote.Type >= coreTypes.VotePreCom {
-				pm.cache.addVote(vote)
-			}
-			pm.receiveCh <- coreTypes.Msg{
-				PeerID:  p.ID().String(),
-				Payload: vote,
-			}
+			pm.verifier.enqueue(p.ID().String(), vote)
 		}
 	case msg.Code == AgreementMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
@@ -938,6 +1067,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if ok {
 			nextTime := next.(time.Time)
 			if nextTime.After(time.Now()) {
+				if pm.reputation.adjust(p.id, scorePullAbuse) {
+					return errPeerBanned
+				}
 				break
 			}
 		}
@@ -957,6 +1089,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if ok {
 			nextTime := next.(time.Time)
 			if nextTime.After(time.Now()) {
+				if pm.reputation.adjust(p.id, scorePullAbuse) {
+					return errPeerBanned
+				}
 				break
 			}
 		}
@@ -991,6 +1126,30 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := pm.downloader.DeliverGovState(p.id, &govState); err != nil {
 			log.Debug("Failed to deliver govstates", "err", err)
 		}
+	case msg.Code == GetGovStateSliceMsg:
+		var query getGovStateSliceData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		amount := int(query.Amount)
+		if query.Amount > uint64(downloader.MaxGovStateFetch) {
+			amount = downloader.MaxGovStateFetch
+		}
+		slice, err := pm.blockchain.GetGovStateSlice(query.Hash, query.Base,
+			query.Origin, amount)
+		if err != nil {
+			p.Log().Debug("Invalid gov state slice msg", "hash", query.Hash.String(), "err", err)
+			return errResp(ErrInvalidGovStateMsg, "hash=%v", query.Hash.String())
+		}
+		return p.SendGovStateSlice(slice)
+	case msg.Code == GovStateSliceMsg:
+		var slice types.GovStateSlice
+		if err := msg.Decode(&slice); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverGovStateSlice(p.id, &slice); err != nil {
+			log.Debug("Failed to deliver gov state slice", "err", err)
+		}
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -1099,11 +1258,7 @@ func (pm *ProtocolManager) BroadcastFinalizedBlock(block *coreTypes.Block) {
 	pm.cache.addFinalizedBlock(block)
 
 	// send to notary nodes first (direct)
-	label := peerLabel{
-		set:   notaryset,
-		round: block.Position.Round,
-	}
-	peers := pm.peers.PeersWithLabel(label)
+	peers := pm.consensusPeers(block.Position.Round)
 	count := maxFinalizedBlockBroadcast
 	for _, peer := range peers {
 		if count <= 0 {
@@ -1119,11 +1274,7 @@ func (pm *ProtocolManager) BroadcastFinalizedBlock(block *coreTypes.Block) {
 func (pm *ProtocolManager) BroadcastCoreBlock(block *coreTypes.Block) {
 	pm.cache.addBlock(block)
 	// send to notary nodes only.
-	label := peerLabel{
-		set:   notaryset,
-		round: block.Position.Round,
-	}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
+	for _, peer := range pm.consensusPeers(block.Position.Round) {
 		peer.AsyncSendCoreBlocks([]*coreTypes.Block{block})
 	}
 }
@@ -1133,11 +1284,7 @@ func (pm *ProtocolManager) BroadcastVote(vote *coreTypes.Vote) {
 	if vote.Type >= coreTypes.VotePreCom {
 		pm.cache.addVote(vote)
 	}
-	label := peerLabel{
-		set:   notaryset,
-		round: vote.Position.Round,
-	}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
+	for _, peer := range pm.consensusPeers(vote.Position.Round) {
 		peer.AsyncSendVotes([]*coreTypes.Vote{vote})
 	}
 }
@@ -1151,11 +1298,7 @@ func (pm *ProtocolManager) BroadcastAgreementResult(
 	}
 
 	// send to notary nodes first (direct)
-	label := peerLabel{
-		set:   notaryset,
-		round: agreement.Position.Round,
-	}
-	peers := pm.peers.PeersWithLabel(label)
+	peers := pm.consensusPeers(agreement.Position.Round)
 	count := maxAgreementResultBroadcast
 	for _, peer := range peers {
 		if peer.MarkAgreement(agreement.Position) {
@@ -1185,6 +1328,11 @@ func (pm *ProtocolManager) SendDKGPrivateShare(
 
 	if p := pm.peers.Peer(id.String()); p != nil {
 		p.AsyncSendDKGPrivateShare(privateShare)
+	} else if pm.peers.IsProtected() {
+		// The sentries relay the share to the receiver only.
+		for _, p := range pm.peers.PeersWithLabel(peerLabel{set: sentryset}) {
+			p.AsyncSendDKGPrivateShare(privateShare)
+		}
 	} else {
 		log.Error("Failed to send DKG private share", "publicKey", id.String())
 	}
@@ -1192,8 +1340,7 @@ func (pm *ProtocolManager) SendDKGPrivateShare(
 
 func (pm *ProtocolManager) BroadcastDKGPrivateShare(
 	privateShare *dkgTypes.PrivateShare) {
-	label := peerLabel{set: notaryset, round: privateShare.Round}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
+	for _, peer := range pm.consensusPeers(privateShare.Round) {
 		if !peer.knownDKGPrivateShares.Contains(rlpHash(privateShare)) {
 			peer.AsyncSendDKGPrivateShare(privateShare)
 		}
@@ -1202,8 +1349,7 @@ func (pm *ProtocolManager) BroadcastDKGPrivateShare(
 
 func (pm *ProtocolManager) BroadcastDKGPartialSignature(
 	psig *dkgTypes.PartialSignature) {
-	label := peerLabel{set: notaryset, round: psig.Round}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
+	for _, peer := range pm.consensusPeers(psig.Round) {
 		peer.AsyncSendDKGPartialSignature(psig)
 	}
 }
@@ -1221,11 +1367,7 @@ func (pm *ProtocolManager) BroadcastPullBlocks(
 
 func (pm *ProtocolManager) BroadcastPullVotes(
 	pos coreTypes.Position) {
-	label := peerLabel{
-		set:   notaryset,
-		round: pos.Round,
-	}
-	for idx, peer := range pm.peers.PeersWithLabel(label) {
+	for idx, peer := range pm.consensusPeers(pos.Round) {
 		if idx >= maxPullVotePeers {
 			break
 		}
@@ -1325,7 +1467,9 @@ func (pm *ProtocolManager) peerSetLoop() {
 		case event := <-pm.chainHeadCh:
 			pm.blockNumberGauge.Update(int64(event.Block.NumberU64()))
 
-			if !pm.isBlockProposer {
+			// Sentries keep up the notary set connections for the
+			// validators they protect.
+			if !pm.isBlockProposer && len(pm.sentryFor) == 0 {
 				break
 			}
 
@@ -1350,6 +1494,7 @@ func (pm *ProtocolManager) peerSetLoop() {
 			} else if newRound == round && resetCount+1 == reset {
 				pm.peers.ForgetLabelConnection(peerLabel{set: notaryset, round: newRound})
 				pm.gov.PurgeNotarySet(newRound)
+				pm.verifier.purgeNotarySet(newRound)
 				pm.peers.BuildConnection(newRound)
 			} else {
 				// just forget all network connection and rebuild.
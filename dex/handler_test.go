@@ -444,3 +444,42 @@ func testGetReceipt(t *testing.T, protocol int) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that slices of the governance state can be retrieved by dex/65 peers.
+func TestGetGovStateSlice(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	peer, _ := newTestPeer("peer", dex65, pm, true)
+	defer peer.close()
+
+	head := pm.blockchain.CurrentBlock().Hash()
+	genesis := pm.blockchain.Genesis().Hash()
+	tests := []struct {
+		base   common.Hash
+		amount uint64
+		slots  int
+	}{
+		{amount: 1, slots: 1}, // A single slot
+		{amount: 10 * uint64(downloader.MaxGovStateFetch), slots: 3}, // Capped amount
+		{base: genesis, amount: 4, slots: 0},                         // No slots changed
+		{base: common.Hash{1}, amount: 4, slots: 3},                  // Unknown base
+	}
+	for i, tt := range tests {
+		amount := int(tt.amount)
+		if amount > downloader.MaxGovStateFetch {
+			amount = downloader.MaxGovStateFetch
+		}
+		slice, err := pm.blockchain.GetGovStateSlice(head, tt.base, nil, amount)
+		if err != nil {
+			t.Fatalf("test %d: failed to get slice: %v", i, err)
+		}
+		if len(slice.Storage) != tt.slots {
+			t.Errorf("test %d: slots mismatch: have %d, want %d", i, len(slice.Storage), tt.slots)
+		}
+		p2p.Send(peer.app, GetGovStateSliceMsg, &getGovStateSliceData{
+			Hash: head, Base: tt.base, Amount: tt.amount,
+		})
+		if err := p2p.ExpectMsg(peer.app, GovStateSliceMsg, slice); err != nil {
+			t.Errorf("test %d: slice mismatch: %v", i, err)
+		}
+	}
+}
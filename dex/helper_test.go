@@ -117,7 +117,14 @@ func newTestProtocolManager(mode downloader.SyncMode, blocks int, generator func
 		db     = ethdb.NewMemDatabase()
 		gspec  = &core.Genesis{
 			Config: params.TestChainConfig,
-			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000), Staked: big.NewInt(0)}},
+			Alloc: core.GenesisAlloc{
+				testBank: {Balance: big.NewInt(1000000), Staked: big.NewInt(0)},
+				vm.GovernanceContractAddress: {
+					Balance: big.NewInt(0),
+					Staked:  big.NewInt(0),
+					Storage: map[common.Hash]common.Hash{{1}: {1}, {2}: {2}, {3}: {3}},
+				},
+			},
 		}
 		genesis       = gspec.MustCommit(db)
 		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil)
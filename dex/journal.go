@@ -0,0 +1,271 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+const (
+	journalFilePrefix = "journal-"
+	journalFileSuffix = ".rlp"
+
+	journalFileSize  = 64 * 1024 * 1024 // Size of a journal file to rotate at
+	journalFileLimit = 16               // Number of journal files to keep
+)
+
+// journaled reports whether messages of the code are recorded to the
+// consensus journal.
+func journaled(code uint64) bool {
+	switch code {
+	case CoreBlockMsg, VoteMsg, AgreementMsg, DKGPrivateShareMsg,
+		DKGPartialSignatureMsg, PullBlocksMsg, PullVotesMsg:
+		return true
+	}
+	return false
+}
+
+// journalEntry is a consensus message received from a peer.
+type journalEntry struct {
+	Time    uint64 // Unix time in nanoseconds the message was received
+	PeerID  string
+	Code    uint64
+	Payload []byte // RLP encoded message as received
+}
+
+// messages decodes the payload to the messages passed to the consensus core,
+// pull requests are answered by the protocol manager and have none.
+func (e *journalEntry) messages() ([]interface{}, error) {
+	var msgs []interface{}
+	switch e.Code {
+	case CoreBlockMsg:
+		var blocks []*coreTypes.Block
+		if err := rlp.DecodeBytes(e.Payload, &blocks); err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			msgs = append(msgs, block)
+		}
+	case VoteMsg:
+		var votes []*coreTypes.Vote
+		if err := rlp.DecodeBytes(e.Payload, &votes); err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			msgs = append(msgs, vote)
+		}
+	case AgreementMsg:
+		var agreement coreTypes.AgreementResult
+		if err := rlp.DecodeBytes(e.Payload, &agreement); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &agreement)
+	case DKGPrivateShareMsg:
+		var ps dkgTypes.PrivateShare
+		if err := rlp.DecodeBytes(e.Payload, &ps); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &ps)
+	case DKGPartialSignatureMsg:
+		var psig dkgTypes.PartialSignature
+		if err := rlp.DecodeBytes(e.Payload, &psig); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &psig)
+	}
+	return msgs, nil
+}
+
+// coreJournal records the consensus messages received from peers to a
+// rotating set of files, so the decision path of the consensus core can be
+// reconstructed after a stall. The journal contains the DKG private shares
+// sent to the node and must be kept as private as the node key.
+type coreJournal struct {
+	dir       string
+	fileSize  int64
+	fileLimit int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newCoreJournal(dir string) (*coreJournal, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &coreJournal{
+		dir:       dir,
+		fileSize:  journalFileSize,
+		fileLimit: journalFileLimit,
+	}, nil
+}
+
+// record appends a message to the journal. Failures are logged only, the
+// journal never interrupts the message handling.
+func (j *coreJournal) record(peerID string, code uint64, payload []byte) {
+	data, err := rlp.EncodeToBytes(&journalEntry{
+		Time:    uint64(time.Now().UnixNano()),
+		PeerID:  peerID,
+		Code:    code,
+		Payload: payload,
+	})
+	if err != nil {
+		log.Warn("Failed to encode journal entry", "code", code, "err", err)
+		return
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.file == nil || j.size+int64(len(data)) > j.fileSize {
+		if err := j.rotate(); err != nil {
+			log.Warn("Failed to rotate consensus journal", "err", err)
+			return
+		}
+	}
+	n, err := j.file.Write(data)
+	j.size += int64(n)
+	if err != nil {
+		log.Warn("Failed to write consensus journal", "err", err)
+	}
+}
+
+// rotate closes the current journal file, opens a new one and removes the
+// oldest files beyond the limit.
+func (j *coreJournal) rotate() error {
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			return err
+		}
+		j.file = nil
+	}
+	files, err := journalFiles(j.dir)
+	if err != nil {
+		return err
+	}
+	for len(files) >= j.fileLimit {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	name := fmt.Sprintf("%s%019d%s",
+		journalFilePrefix, time.Now().UnixNano(), journalFileSuffix)
+	file, err := os.OpenFile(filepath.Join(j.dir, name),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	j.file, j.size = file, 0
+	return nil
+}
+
+func (j *coreJournal) close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// journalFiles returns the journal files in the directory, oldest first.
+func journalFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, journalFilePrefix) ||
+			!strings.HasSuffix(name, journalFileSuffix) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readCoreJournal calls fn with each entry of a journal, path is either a
+// journal file or a journal directory whose files are read oldest first.
+func readCoreJournal(path string, fn func(*journalEntry) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = journalFiles(path); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if err := readCoreJournalFile(file, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCoreJournalFile(path string, fn func(*journalEntry) error) error {
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := rlp.NewStream(bufio.NewReader(input), 0)
+	for {
+		entry := new(journalEntry)
+		if err := stream.Decode(entry); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// The last entry is cut off if the node crashed while writing.
+			if err == io.ErrUnexpectedEOF {
+				log.Warn("Truncated consensus journal", "file", path)
+				return nil
+			}
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func readTestJournal(t *testing.T, path string) []*journalEntry {
+	var entries []*journalEntry
+	err := readCoreJournal(path, func(entry *journalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	return entries
+}
+
+func TestCoreJournalRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	journal, err := newCoreJournal(dir)
+	if err != nil {
+		t.Fatalf("failed to create journal: %v", err)
+	}
+	// Each file holds two entries.
+	sample, err := rlp.EncodeToBytes(&journalEntry{
+		Time:    uint64(time.Now().UnixNano()),
+		PeerID:  "peer",
+		Code:    PullBlocksMsg,
+		Payload: []byte{0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.fileSize = int64(2 * len(sample))
+	journal.fileLimit = 3
+
+	for i := 0; i < 10; i++ {
+		journal.record("peer", PullBlocksMsg, []byte{byte(i)})
+	}
+	if err := journal.close(); err != nil {
+		t.Fatalf("failed to close journal: %v", err)
+	}
+
+	files, err := journalFiles(dir)
+	if err != nil {
+		t.Fatalf("failed to list journal files: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("journal files mismatch: have %d, want 3", len(files))
+	}
+	entries := readTestJournal(t, dir)
+	if len(entries) != 6 {
+		t.Fatalf("journal entries mismatch: have %d, want 6", len(entries))
+	}
+	for i, entry := range entries {
+		if want := []byte{byte(i + 4)}; !reflect.DeepEqual(entry.Payload, want) {
+			t.Errorf("entry %d payload mismatch: have %x, want %x",
+				i, entry.Payload, want)
+		}
+		if i > 0 && entry.Time < entries[i-1].Time {
+			t.Errorf("entry %d out of order", i)
+		}
+	}
+
+	// A truncated entry of the last file is dropped.
+	last := files[len(files)-1]
+	data, err := ioutil.ReadFile(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(last, data[:len(data)-1], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if entries := readTestJournal(t, dir); len(entries) != 5 {
+		t.Errorf("journal entries mismatch: have %d, want 5", len(entries))
+	}
+}
+
+func TestRecvJournaled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+	if pm.journal, err = newCoreJournal(dir); err != nil {
+		t.Fatalf("failed to create journal: %v", err)
+	}
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	vote := &coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			Period:   10,
+			Position: coreTypes.Position{Round: 12, Height: 13},
+		},
+		PartialSignature: coreDKG.PartialSignature{
+			Type:      "456",
+			Signature: []byte("psig"),
+		},
+	}
+	if err := testSigner.SignVote(vote); err != nil {
+		t.Fatalf("sign vote error: %v", err)
+	}
+	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{vote}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+
+	// The journaled message is still handled.
+	select {
+	case msg := <-pm.ReceiveChan():
+		if !reflect.DeepEqual(msg.Payload, vote) {
+			t.Errorf("vote mismatch")
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("no vote received within 1 seconds")
+	}
+	if err := pm.journal.close(); err != nil {
+		t.Fatalf("failed to close journal: %v", err)
+	}
+
+	entries := readTestJournal(t, dir)
+	if len(entries) != 1 {
+		t.Fatalf("journal entries mismatch: have %d, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Code != VoteMsg || entry.PeerID != p.ID().String() {
+		t.Errorf("entry mismatch: code %d, peer %s", entry.Code, entry.PeerID)
+	}
+	msgs, err := entry.messages()
+	if err != nil {
+		t.Fatalf("failed to decode entry: %v", err)
+	}
+	if len(msgs) != 1 || !reflect.DeepEqual(msgs[0], vote) {
+		t.Errorf("journaled vote mismatch: %v", msgs)
+	}
+	want, _ := rlp.EncodeToBytes([]*coreTypes.Vote{vote})
+	if !reflect.DeepEqual(entry.Payload, want) {
+		t.Errorf("payload mismatch: have %x, want %x", entry.Payload, want)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"sync"
+
+	"github.com/dexon-foundation/dexon/accounts"
+	"github.com/dexon-foundation/dexon/consensus"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/event"
+	"github.com/dexon-foundation/dexon/internal/ethapi"
+	"github.com/dexon-foundation/dexon/light"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+// LightDexon implements the DEXON light client service.
+type LightDexon struct {
+	config      *dex.Config
+	chainConfig *params.ChainConfig
+
+	// Channel for shutting down the service
+	shutdownChan chan bool
+
+	// Handlers
+	peers      *peerSet
+	txPool     *light.TxPool
+	blockchain *light.LightChain
+	downloader *downloader.Downloader
+	odr        *LesOdr
+	relay      *txRelay
+
+	// DB interfaces
+	chainDb ethdb.Database // Block chain database
+
+	eventMux       *event.TypeMux
+	engine         consensus.Engine
+	accountManager *accounts.Manager
+
+	ApiBackend *LesApiBackend
+
+	networkId     uint64
+	netRPCService *ethapi.PublicNetAPI
+
+	syncCh   chan struct{}
+	quitSync chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a light client of the DEXON network.
+func New(ctx *node.ServiceContext, config *dex.Config) (*LightDexon, error) {
+	chainDb, err := dex.CreateDB(ctx, config, "lightchaindata")
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb,
+		config.Genesis)
+	if _, isCompat := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !isCompat {
+		return nil, genesisErr
+	}
+	log.Info("Initialised chain configuration", "config", chainConfig)
+
+	peers := newPeerSet()
+	ldex := &LightDexon{
+		config:         config,
+		chainConfig:    chainConfig,
+		chainDb:        chainDb,
+		eventMux:       ctx.EventMux,
+		accountManager: ctx.AccountManager,
+		engine:         dexcon.New(),
+		shutdownChan:   make(chan bool),
+		networkId:      config.NetworkId,
+		peers:          peers,
+		odr:            newLesOdr(chainDb, peers),
+		relay:          newTxRelay(peers),
+		syncCh:         make(chan struct{}, 1),
+		quitSync:       make(chan struct{}),
+	}
+	if ldex.blockchain, err = light.NewLightChain(ldex.odr, ldex.chainConfig, ldex.engine); err != nil {
+		return nil, err
+	}
+
+	// Rewind the chain in case of an incompatible config upgrade.
+	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
+		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
+		ldex.blockchain.SetHead(compat.RewindTo)
+		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
+	}
+
+	ldex.txPool = light.NewTxPool(ldex.chainConfig, ldex.blockchain, ldex.relay)
+	ldex.downloader = downloader.New(downloader.LightSync, chainDb, ldex.eventMux,
+		nil, ldex.blockchain, ldex.removePeer)
+	ldex.ApiBackend = &LesApiBackend{ldex}
+	return ldex, nil
+}
+
+// APIs returns the collection of RPC services the light client offers.
+func (s *LightDexon) APIs() []rpc.API {
+	return append(ethapi.GetAPIs(s.ApiBackend), []rpc.API{
+		{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   downloader.NewPublicDownloaderAPI(s.downloader, s.eventMux),
+			Public:    true,
+		}, {
+			Namespace: "net",
+			Version:   "1.0",
+			Service:   s.netRPCService,
+			Public:    true,
+		},
+	}...)
+}
+
+// Protocols implements node.Service, returning all the currently configured
+// network protocols to start.
+func (s *LightDexon) Protocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				peer := newPeer(int(version), p, rw)
+				s.wg.Add(1)
+				defer s.wg.Done()
+				return s.handle(peer)
+			},
+		})
+	}
+	return protocols
+}
+
+// Start implements node.Service, starting all internal goroutines needed by the
+// light client.
+func (s *LightDexon) Start(srvr *p2p.Server) error {
+	log.Warn("Light client mode is an experimental feature")
+	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.networkId)
+
+	s.wg.Add(1)
+	go s.syncer()
+	if srvr.DiscV5 != nil {
+		s.wg.Add(1)
+		go s.discoverServers(srvr)
+	}
+	return nil
+}
+
+// Stop implements node.Service, terminating all internal goroutines used by the
+// light client.
+func (s *LightDexon) Stop() error {
+	s.downloader.Terminate()
+	close(s.quitSync)
+	s.peers.Close()
+	s.wg.Wait()
+
+	s.blockchain.Stop()
+	s.txPool.Stop()
+	s.eventMux.Stop()
+	s.chainDb.Close()
+	close(s.shutdownChan)
+	log.Info("Light DEXON stopped")
+	return nil
+}
+
+// removePeer drops the peer misbehaving in the header sync.
+func (s *LightDexon) removePeer(id string) {
+	if p := s.peers.Peer(id); p != nil {
+		p.Disconnect(p2p.DiscUselessPeer)
+	}
+}
+
+func (s *LightDexon) BlockChain() *light.LightChain      { return s.blockchain }
+func (s *LightDexon) TxPool() *light.TxPool              { return s.txPool }
+func (s *LightDexon) Engine() consensus.Engine           { return s.engine }
+func (s *LightDexon) LesVersion() int                    { return int(ProtocolVersions[0]) }
+func (s *LightDexon) Downloader() *downloader.Downloader { return s.downloader }
+func (s *LightDexon) EventMux() *event.TypeMux           { return s.eventMux }
+func (s *LightDexon) ChainDb() ethdb.Database            { return s.chainDb }
+func (s *LightDexon) NetVersion() uint64                 { return s.networkId }
@@ -0,0 +1,213 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"time"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/discv5"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+const (
+	forceSyncCycle  = 10 * time.Second // Time interval to force syncs, even if few peers are available
+	discoveryPeriod = time.Minute      // Time interval between the searches of the server topic
+	maxServers      = 5                // Maximum number of servers connected by discovery
+
+	dexVersion = 64 // equivalent dex version for the downloader
+)
+
+// handle is the callback invoked to manage the life cycle of a server peer.
+func (s *LightDexon) handle(p *peer) error {
+	p.Log().Debug("Light server connected", "name", p.Name())
+
+	head := s.blockchain.CurrentHeader()
+	genesis := s.blockchain.Genesis()
+	if err := p.Handshake(s.networkId, head.Number.Uint64(), head.Hash(),
+		genesis.Hash(), false); err != nil {
+		p.Log().Debug("Light server handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		p.Log().Error("Light server registration failed", "err", err)
+		return err
+	}
+	defer s.peers.Unregister(p.id)
+
+	if err := s.downloader.RegisterLightPeer(p.id, dexVersion, p); err != nil {
+		return err
+	}
+	defer s.downloader.UnregisterPeer(p.id)
+
+	s.triggerSync()
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light server message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+func (s *LightDexon) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case AnnounceMsg:
+		var data announceData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		p.SetHead(data.Hash, data.Number)
+		if data.Number > s.blockchain.CurrentHeader().Number.Uint64() {
+			s.triggerSync()
+		}
+
+	case BlockHeadersMsg:
+		var resp blockHeadersResp
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if resp.ReqID != downloaderReqID {
+			return errResp(ErrUnexpectedResponse, "reqid %d", resp.ReqID)
+		}
+		if err := s.downloader.DeliverHeaders(p.id, resp.Headers); err != nil {
+			log.Debug("Failed to deliver headers", "err", err)
+		}
+
+	case GovStateMsg:
+		var resp govStateResp
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if resp.ReqID != downloaderReqID {
+			return errResp(ErrUnexpectedResponse, "reqid %d", resp.ReqID)
+		}
+		// The downloader times the request out if the server doesn't have it.
+		if resp.GovState != nil {
+			if err := s.downloader.DeliverGovState(p.id, resp.GovState); err != nil {
+				log.Debug("Failed to deliver gov state", "err", err)
+			}
+		}
+
+	case BlockBodiesMsg, ReceiptsMsg:
+		var resp rawResp
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		s.odr.deliver(resp.ReqID, resp.Data)
+
+	case NodeDataMsg:
+		var resp nodeDataResp
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		s.odr.deliver(resp.ReqID, resp.Data)
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// triggerSync wakes the syncer up without blocking.
+func (s *LightDexon) triggerSync() {
+	select {
+	case s.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+// syncer is responsible for periodically synchronising the headers with the
+// best server.
+func (s *LightDexon) syncer() {
+	defer s.wg.Done()
+
+	forceSync := time.NewTicker(forceSyncCycle)
+	defer forceSync.Stop()
+
+	for {
+		select {
+		case <-s.syncCh:
+		case <-forceSync.C:
+		case <-s.quitSync:
+			return
+		}
+		s.synchronise(s.peers.BestPeer())
+	}
+}
+
+// synchronise synchronises the headers with the peer if it's ahead of us.
+func (s *LightDexon) synchronise(p *peer) {
+	if p == nil {
+		return
+	}
+	hash, number := p.Head()
+	if number <= s.blockchain.CurrentHeader().Number.Uint64() {
+		return
+	}
+	s.downloader.Synchronise(p.id, hash, number, downloader.LightSync)
+}
+
+// discoverServers searches the servers of the chain in the discovery network
+// and connects to them.
+func (s *LightDexon) discoverServers(srvr *p2p.Server) {
+	defer s.wg.Done()
+
+	setPeriod := make(chan time.Duration, 1)
+	setPeriod <- discoveryPeriod
+	found := make(chan *discv5.Node)
+	go func() {
+		srvr.DiscV5.SearchTopic(lesTopic(s.blockchain.Genesis().Hash()),
+			setPeriod, found, nil)
+		close(found)
+	}()
+	go func() {
+		<-s.quitSync
+		close(setPeriod)
+	}()
+
+	for n := range found {
+		if s.peers.Len() >= maxServers {
+			continue
+		}
+		pubkey, err := decodePubkey64(n.ID[:])
+		if err != nil {
+			continue
+		}
+		srvr.AddPeer(enode.NewV4(pubkey, n.IP, int(n.TCP), int(n.UDP)))
+	}
+}
+
+func decodePubkey64(b []byte) (*ecdsa.PublicKey, error) {
+	return crypto.UnmarshalPubkey(append([]byte{0x04}, b...))
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/light"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func newTestNode(t *testing.T, key *ecdsa.PrivateKey,
+	constructor node.ServiceConstructor) *node.Node {
+	stack, err := node.New(&node.Config{
+		NoUSB: true,
+		P2P: p2p.Config{
+			PrivateKey:  key,
+			ListenAddr:  "127.0.0.1:0",
+			NoDiscovery: true,
+			MaxPeers:    10,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New node fail: %v", err)
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("Register service fail: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("Start node fail: %v", err)
+	}
+	return stack
+}
+
+// waitFor polls cond until it's true or the timeout is reached.
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestLightClient(t *testing.T) {
+	serverKey, _ := crypto.GenerateKey()
+	faucetKey, _ := crypto.GenerateKey()
+	faucet := crypto.PubkeyToAddress(faucetKey.PublicKey)
+	genesis := core.DexconDeveloperGenesisBlock(faucet, &serverKey.PublicKey)
+
+	// The server is a developer chain proposing a block every 10ms, the
+	// randomness is verified by the light client from round 1.
+	server := newTestNode(t, serverKey, func(ctx *node.ServiceContext) (node.Service, error) {
+		config := dex.DefaultConfig
+		config.PrivateKey = serverKey
+		config.Dev = true
+		config.DevFaucet = faucet
+		config.DevPeriod = 10 * time.Millisecond
+		config.BlockProposerEnabled = true
+		config.LightServ = 50
+		config.LightPeers = 5
+		full, err := dex.New(ctx, &config)
+		if err != nil {
+			return nil, err
+		}
+		ls, _ := NewLesServer(full, &config)
+		full.AddLesServer(ls)
+		return full, nil
+	})
+	defer server.Stop()
+	var full *dex.Dexon
+	if err := server.Service(&full); err != nil {
+		t.Fatalf("Get dexon service fail: %v", err)
+	}
+
+	clientKey, _ := crypto.GenerateKey()
+	client := newTestNode(t, clientKey, func(ctx *node.ServiceContext) (node.Service, error) {
+		config := dex.DefaultConfig
+		config.Genesis = genesis
+		config.SyncMode = downloader.LightSync
+		return New(ctx, &config)
+	})
+	defer client.Stop()
+	var ldex *LightDexon
+	if err := client.Service(&ldex); err != nil {
+		t.Fatalf("Get light dexon service fail: %v", err)
+	}
+	client.Server().AddPeer(server.Server().Self())
+
+	// Sync the headers of round 2.
+	waitFor(t, 30*time.Second, "header sync", func() bool {
+		return ldex.BlockChain().CurrentHeader().Round >= 2
+	})
+	head := ldex.BlockChain().CurrentHeader()
+	if hash := full.BlockChain().GetHeaderByNumber(head.Number.Uint64()).Hash(); hash != head.Hash() {
+		t.Fatalf("header mismatch: have %x, want %x", head.Hash(), hash)
+	}
+	for round := uint64(1); round <= 2; round++ {
+		height, ok := ldex.BlockChain().GetRoundHeight(round)
+		if !ok {
+			t.Fatalf("round height of round %d not found", round)
+		}
+		if want, _ := full.BlockChain().GetRoundHeight(round); height != want {
+			t.Errorf("round height of round %d mismatch: have %d, want %d",
+				round, height, want)
+		}
+	}
+
+	// Relay a transaction through the light client and retrieve its block
+	// and receipt on demand.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	signer := types.NewEIP155Signer(genesis.Config.ChainID)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{2},
+		big.NewInt(1), params.TxGas, big.NewInt(params.GWei), nil), signer, faucetKey)
+	if err != nil {
+		t.Fatalf("Sign tx fail: %v", err)
+	}
+	if err := ldex.ApiBackend.SendTx(ctx, tx); err != nil {
+		t.Fatalf("Send tx fail: %v", err)
+	}
+	var (
+		blockHash   common.Hash
+		blockNumber uint64
+	)
+	waitFor(t, 30*time.Second, "transaction inclusion", func() bool {
+		hash, number, _ := rawdb.ReadTxLookupEntry(full.ChainDb(), tx.Hash())
+		if hash == (common.Hash{}) || ldex.BlockChain().GetHeaderByHash(hash) == nil {
+			return false
+		}
+		blockHash, blockNumber = hash, number
+		return true
+	})
+
+	block, err := ldex.ApiBackend.GetBlock(ctx, blockHash)
+	if err != nil {
+		t.Fatalf("Get block fail: %v", err)
+	}
+	if block.Transaction(tx.Hash()) == nil {
+		t.Errorf("transaction not found in block %d", blockNumber)
+	}
+	receipts, err := ldex.ApiBackend.GetReceipts(ctx, blockHash)
+	if err != nil {
+		t.Fatalf("Get receipts fail: %v", err)
+	}
+	if len(receipts) != len(block.Transactions()) {
+		t.Errorf("receipt count mismatch: have %d, want %d",
+			len(receipts), len(block.Transactions()))
+	}
+	statedb := light.NewState(ctx, block.Header(), ldex.odr)
+	if balance := statedb.GetBalance(common.Address{2}); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1", balance)
+	}
+	if nonce := statedb.GetNonce(faucet); nonce != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", nonce)
+	}
+	if err := statedb.Error(); err != nil {
+		t.Errorf("Retrieve state fail: %v", err)
+	}
+	price, err := ldex.ApiBackend.SuggestPrice(ctx)
+	if err != nil {
+		t.Fatalf("Suggest price fail: %v", err)
+	}
+	if price.Cmp(genesis.Config.Dexcon.MinGasPrice) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", price,
+			genesis.Config.Dexcon.MinGasPrice)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/light"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/trie"
+)
+
+// retrieveTimeout is the time a server is given to answer a retrieval before
+// the next server is tried.
+const retrieveTimeout = 5 * time.Second
+
+var (
+	errUnknownRequest = errors.New("unknown odr request")
+	errTimeout        = errors.New("retrieval timeout")
+)
+
+// LesOdr retrieves the block bodies, receipts and states on demand from the
+// servers. Every response is validated against the local header chain, whose
+// headers are certified by the randomness, before it's accepted.
+type LesOdr struct {
+	db    ethdb.Database
+	peers *peerSet
+
+	reqID   uint64 // Last request id, accessed atomically
+	lock    sync.Mutex
+	pending map[uint64]chan interface{}
+}
+
+// newLesOdr creates an ODR backend retrieving from the given peers.
+func newLesOdr(db ethdb.Database, peers *peerSet) *LesOdr {
+	return &LesOdr{
+		db:      db,
+		peers:   peers,
+		pending: make(map[uint64]chan interface{}),
+	}
+}
+
+// Database implements light.OdrBackend.
+func (odr *LesOdr) Database() ethdb.Database {
+	return odr.db
+}
+
+// ChtIndexer implements light.OdrBackend. Headers are verified by their
+// randomness, the canonical hash tries are not used.
+func (odr *LesOdr) ChtIndexer() *core.ChainIndexer {
+	return nil
+}
+
+// BloomTrieIndexer implements light.OdrBackend.
+func (odr *LesOdr) BloomTrieIndexer() *core.ChainIndexer {
+	return nil
+}
+
+// BloomIndexer implements light.OdrBackend.
+func (odr *LesOdr) BloomIndexer() *core.ChainIndexer {
+	return nil
+}
+
+// IndexerConfig implements light.OdrBackend.
+func (odr *LesOdr) IndexerConfig() *light.IndexerConfig {
+	return light.DefaultClientIndexerConfig
+}
+
+// Retrieve implements light.OdrBackend. The result is stored into the local
+// database once it's retrieved and validated.
+func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) error {
+	var err error
+	switch r := req.(type) {
+	case *light.BlockRequest:
+		err = odr.retrieveBlock(ctx, r)
+	case *light.ReceiptsRequest:
+		err = odr.retrieveReceipts(ctx, r)
+	case *light.TrieRequest:
+		err = odr.retrieveTrie(ctx, r)
+	case *light.CodeRequest:
+		err = odr.retrieveCode(ctx, r)
+	default:
+		err = errUnknownRequest
+	}
+	if err != nil {
+		return err
+	}
+	req.StoreResult(odr.db)
+	return nil
+}
+
+func (odr *LesOdr) retrieveBlock(ctx context.Context, req *light.BlockRequest) error {
+	header := rawdb.ReadHeader(odr.db, req.Hash, req.Number)
+	if header == nil {
+		return fmt.Errorf("header %x not found", req.Hash)
+	}
+	send := func(p *peer, reqID uint64) error {
+		return p.RequestBodies(reqID, []common.Hash{req.Hash})
+	}
+	return odr.request(ctx, req.Number, send, func(resp interface{}) bool {
+		bodies, ok := resp.([]rlp.RawValue)
+		if !ok || len(bodies) != 1 {
+			return false
+		}
+		var body blockBody
+		if err := rlp.DecodeBytes(bodies[0], &body); err != nil {
+			return false
+		}
+		if types.DeriveSha(types.Transactions(body.Transactions)) != header.TxHash ||
+			types.CalcUncleHash(body.Uncles) != header.UncleHash {
+			return false
+		}
+		req.Rlp = bodies[0]
+		return true
+	})
+}
+
+func (odr *LesOdr) retrieveReceipts(ctx context.Context, req *light.ReceiptsRequest) error {
+	header := rawdb.ReadHeader(odr.db, req.Hash, req.Number)
+	if header == nil {
+		return fmt.Errorf("header %x not found", req.Hash)
+	}
+	send := func(p *peer, reqID uint64) error {
+		return p.RequestReceipts(reqID, []common.Hash{req.Hash})
+	}
+	return odr.request(ctx, req.Number, send, func(resp interface{}) bool {
+		data, ok := resp.([]rlp.RawValue)
+		if !ok || len(data) != 1 {
+			return false
+		}
+		var receipts types.Receipts
+		if err := rlp.DecodeBytes(data[0], &receipts); err != nil {
+			return false
+		}
+		if types.DeriveSha(receipts) != header.ReceiptHash {
+			return false
+		}
+		req.Receipts = receipts
+		return true
+	})
+}
+
+// retrieveTrie walks down the trie from the root certified by the header,
+// fetching the missing nodes one by one until the key is resolved.
+func (odr *LesOdr) retrieveTrie(ctx context.Context, req *light.TrieRequest) error {
+	req.Proof = light.NewNodeSet()
+	for {
+		t, err := trie.New(req.Id.Root, trie.NewDatabase(odr.db))
+		if err == nil {
+			_, err = t.TryGet(req.Key)
+		}
+		missing, ok := err.(*trie.MissingNodeError)
+		if !ok {
+			return err
+		}
+		node, err := odr.retrieveNodeData(ctx, req.Id.BlockNumber, missing.NodeHash)
+		if err != nil {
+			return err
+		}
+		req.Proof.Put(missing.NodeHash[:], node)
+		odr.db.Put(missing.NodeHash[:], node)
+	}
+}
+
+func (odr *LesOdr) retrieveCode(ctx context.Context, req *light.CodeRequest) error {
+	code, err := odr.retrieveNodeData(ctx, req.Id.BlockNumber, req.Hash)
+	if err != nil {
+		return err
+	}
+	req.Data = code
+	return nil
+}
+
+// retrieveNodeData retrieves the trie node or contract code of hash.
+func (odr *LesOdr) retrieveNodeData(ctx context.Context, number uint64,
+	hash common.Hash) ([]byte, error) {
+	var data []byte
+	send := func(p *peer, reqID uint64) error {
+		return p.RequestNodeData(reqID, []common.Hash{hash})
+	}
+	err := odr.request(ctx, number, send, func(resp interface{}) bool {
+		nodes, ok := resp.([][]byte)
+		if !ok || len(nodes) != 1 || crypto.Keccak256Hash(nodes[0]) != hash {
+			return false
+		}
+		data = nodes[0]
+		return true
+	})
+	return data, err
+}
+
+// request sends the request to the servers having the block of number in
+// random order, until one of them answers with a valid response.
+func (odr *LesOdr) request(ctx context.Context, number uint64,
+	send func(p *peer, reqID uint64) error, valid func(resp interface{}) bool) error {
+	peers := odr.peers.Peers()
+	for _, i := range rand.Perm(len(peers)) {
+		p := peers[i]
+		if _, head := p.Head(); head < number {
+			continue
+		}
+		reqID := atomic.AddUint64(&odr.reqID, 1)
+		ch := make(chan interface{}, 1)
+		odr.lock.Lock()
+		odr.pending[reqID] = ch
+		odr.lock.Unlock()
+
+		resp, err := odr.wait(ctx, p, reqID, ch, send)
+
+		odr.lock.Lock()
+		delete(odr.pending, reqID)
+		odr.lock.Unlock()
+
+		if err != nil {
+			if err == ctx.Err() {
+				return err
+			}
+			p.Log().Debug("Retrieval failed", "reqid", reqID, "err", err)
+			continue
+		}
+		if !valid(resp) {
+			p.Log().Debug("Invalid retrieval response", "reqid", reqID)
+			continue
+		}
+		return nil
+	}
+	return light.ErrNoPeers
+}
+
+func (odr *LesOdr) wait(ctx context.Context, p *peer, reqID uint64,
+	ch chan interface{}, send func(p *peer, reqID uint64) error) (interface{}, error) {
+	if err := send(p, reqID); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(retrieveTimeout)
+	defer timeout.Stop()
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-timeout.C:
+		return nil, errTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// deliver delivers the response of a retrieval, the response is dropped if
+// the request is not pending anymore.
+func (odr *LesOdr) deliver(reqID uint64, resp interface{}) {
+	odr.lock.Lock()
+	ch, ok := odr.pending[reqID]
+	odr.lock.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case ch <- resp:
+	default:
+	}
+}
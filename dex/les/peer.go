@@ -0,0 +1,347 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+var (
+	errClosed            = errors.New("peer set is closed")
+	errAlreadyRegistered = errors.New("peer is already registered")
+	errNotRegistered     = errors.New("peer is not registered")
+)
+
+const handshakeTimeout = 5 * time.Second
+
+// downloaderReqID is the request id of the requests sent by the header
+// downloader.
+const downloaderReqID = 0
+
+type peer struct {
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	version int    // Protocol version negotiated
+	id      string // Unique ID for the peer, cached
+	server  bool   // Whether the peer serves the light clients
+
+	head   common.Hash
+	number uint64
+	lock   sync.RWMutex
+}
+
+func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	return &peer{
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		id:      fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+	}
+}
+
+// Head retrieves a copy of the current head hash and number of the peer.
+func (p *peer) Head() (hash common.Hash, number uint64) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	copy(hash[:], p.head[:])
+	return hash, p.number
+}
+
+// SetHead updates the head hash and number of the peer.
+func (p *peer) SetHead(hash common.Hash, number uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	copy(p.head[:], hash[:])
+	p.number = number
+}
+
+// SendAnnounce announces the new head of the chain to a light client.
+func (p *peer) SendAnnounce(hash common.Hash, number uint64) error {
+	return p2p.Send(p.rw, AnnounceMsg, &announceData{Hash: hash, Number: number})
+}
+
+// SendBlockHeaders sends a batch of block headers to the remote peer.
+func (p *peer) SendBlockHeaders(reqID uint64, headers []*types.HeaderWithGovState) error {
+	return p2p.Send(p.rw, BlockHeadersMsg, &blockHeadersResp{ReqID: reqID, Headers: headers})
+}
+
+// SendBlockBodiesRLP sends a batch of block contents to the remote peer from
+// an already RLP encoded format.
+func (p *peer) SendBlockBodiesRLP(reqID uint64, bodies []rlp.RawValue) error {
+	return p2p.Send(p.rw, BlockBodiesMsg, &rawResp{ReqID: reqID, Data: bodies})
+}
+
+// SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
+// ones requested from an already RLP encoded format.
+func (p *peer) SendReceiptsRLP(reqID uint64, receipts []rlp.RawValue) error {
+	return p2p.Send(p.rw, ReceiptsMsg, &rawResp{ReqID: reqID, Data: receipts})
+}
+
+// SendNodeData sends a batch of trie nodes and contract codes, corresponding
+// to the hashes requested.
+func (p *peer) SendNodeData(reqID uint64, data [][]byte) error {
+	return p2p.Send(p.rw, NodeDataMsg, &nodeDataResp{ReqID: reqID, Data: data})
+}
+
+// SendGovState sends the governance state of a block, nil if it's not
+// available.
+func (p *peer) SendGovState(reqID uint64, govState *types.GovState) error {
+	return p2p.Send(p.rw, GovStateMsg, &govStateResp{ReqID: reqID, GovState: govState})
+}
+
+// SendTxs relays the transactions to a server.
+func (p *peer) SendTxs(txs types.Transactions) error {
+	return p2p.Send(p.rw, SendTxMsg, txs)
+}
+
+// RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
+// specified header query, based on the hash of an origin block.
+func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse, withGov bool) error {
+	p.Log().Debug("Fetching batch of headers", "count", amount, "fromhash", origin, "skip", skip, "reverse", reverse, "withgov", withGov)
+	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersReq{
+		ReqID: downloaderReqID,
+		Query: getBlockHeadersData{Origin: hashOrNumber{Hash: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse, WithGov: withGov},
+	})
+}
+
+// RequestHeadersByNumber fetches a batch of blocks' headers corresponding to the
+// specified header query, based on the number of an origin block.
+func (p *peer) RequestHeadersByNumber(origin uint64, amount int, skip int, reverse, withGov bool) error {
+	p.Log().Debug("Fetching batch of headers", "count", amount, "fromnum", origin, "skip", skip, "reverse", reverse, "withgov", withGov)
+	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersReq{
+		ReqID: downloaderReqID,
+		Query: getBlockHeadersData{Origin: hashOrNumber{Number: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse, WithGov: withGov},
+	})
+}
+
+// RequestGovStateByHash fetches the governance state of a block for the
+// header downloader.
+func (p *peer) RequestGovStateByHash(hash common.Hash) error {
+	p.Log().Debug("Fetching one gov state", "hash", hash)
+	return p2p.Send(p.rw, GetGovStateMsg, &getGovStateReq{ReqID: downloaderReqID, Hash: hash})
+}
+
+// RequestBodies fetches a batch of blocks' bodies corresponding to the hashes
+// specified.
+func (p *peer) RequestBodies(reqID uint64, hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of block bodies", "count", len(hashes), "reqid", reqID)
+	return p2p.Send(p.rw, GetBlockBodiesMsg, &hashesReq{ReqID: reqID, Hashes: hashes})
+}
+
+// RequestReceipts fetches a batch of transaction receipts from a remote node.
+func (p *peer) RequestReceipts(reqID uint64, hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of receipts", "count", len(hashes), "reqid", reqID)
+	return p2p.Send(p.rw, GetReceiptsMsg, &hashesReq{ReqID: reqID, Hashes: hashes})
+}
+
+// RequestNodeData fetches a batch of trie nodes or contract codes,
+// corresponding to the specified hashes.
+func (p *peer) RequestNodeData(reqID uint64, hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of state data", "count", len(hashes), "reqid", reqID)
+	return p2p.Send(p.rw, GetNodeDataMsg, &hashesReq{ReqID: reqID, Hashes: hashes})
+}
+
+// Handshake executes the ldex protocol handshake, negotiating version number,
+// network IDs, difficulties, head and genesis blocks. A light client only
+// talks to the servers, and a server only to the light clients.
+func (p *peer) Handshake(network uint64, number uint64, head common.Hash, genesis common.Hash, server bool) error {
+	// Send out own handshake in a new thread
+	errc := make(chan error, 2)
+	var status statusData // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+			ProtocolVersion: uint32(p.version),
+			NetworkId:       network,
+			Number:          number,
+			CurrentBlock:    head,
+			GenesisBlock:    genesis,
+			Server:          server,
+		})
+	}()
+	go func() {
+		errc <- p.readStatus(network, &status, genesis)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return p2p.DiscReadTimeout
+		}
+	}
+	if status.Server == server {
+		return errResp(ErrUselessPeer, "server %v", status.Server)
+	}
+	p.server = status.Server
+	p.number, p.head = status.Number, status.CurrentBlock
+	return nil
+}
+
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != StatusMsg {
+		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, StatusMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	// Decode the handshake and make sure everything matches
+	if err := msg.Decode(&status); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if status.GenesisBlock != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
+	}
+	if status.NetworkId != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
+	}
+	if int(status.ProtocolVersion) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (p *peer) String() string {
+	return fmt.Sprintf("Peer %s [%s]", p.id,
+		fmt.Sprintf("ldex/%2d", p.version),
+	)
+}
+
+// peerSet represents the collection of active peers currently participating in
+// the light DEXON sub-protocol.
+type peerSet struct {
+	peers  map[string]*peer
+	lock   sync.RWMutex
+	closed bool
+}
+
+// newPeerSet creates a new peer set to track the active participants.
+func newPeerSet() *peerSet {
+	return &peerSet{
+		peers: make(map[string]*peer),
+	}
+}
+
+// Register injects a new peer into the working set, or returns an error if the
+// peer is already known.
+func (ps *peerSet) Register(p *peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errClosed
+	}
+	if _, ok := ps.peers[p.id]; ok {
+		return errAlreadyRegistered
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// Unregister removes a remote peer from the active set.
+func (ps *peerSet) Unregister(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(ps.peers, id)
+	return nil
+}
+
+// Peer retrieves the registered peer with the given id.
+func (ps *peerSet) Peer(id string) *peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.peers[id]
+}
+
+// Len returns if the current number of peers in the set.
+func (ps *peerSet) Len() int {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.peers)
+}
+
+// Peers returns all the registered peers.
+func (ps *peerSet) Peers() []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		list = append(list, p)
+	}
+	return list
+}
+
+// BestPeer retrieves the known peer with the highest head.
+func (ps *peerSet) BestPeer() *peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	var (
+		bestPeer   *peer
+		bestNumber uint64
+	)
+	for _, p := range ps.peers {
+		if _, number := p.Head(); bestPeer == nil || number > bestNumber {
+			bestPeer, bestNumber = p, number
+		}
+	}
+	return bestPeer
+}
+
+// Close disconnects all peers. No new peers can be registered after Close
+// has returned.
+func (ps *peerSet) Close() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, p := range ps.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	ps.closed = true
+}
+
+func errResp(code errCode, format string, v ...interface{}) error {
+	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+}
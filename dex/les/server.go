@@ -0,0 +1,412 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/discv5"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+const (
+	softResponseLimit = 2 * 1024 * 1024 // Target maximum size of returned blocks, headers or node data.
+	estHeaderRlpSize  = 500             // Approximate size of an RLP encoded block header
+
+	chainHeadChanSize = 10
+)
+
+// LesServer serves the headers, governance states and the on-demand
+// retrievals of the light clients.
+type LesServer struct {
+	blockchain *core.BlockChain
+	txPool     *core.TxPool
+	networkId  uint64
+	maxPeers   int
+
+	peers *peerSet
+
+	quitSync chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewLesServer creates a light server of the full node.
+func NewLesServer(dex *dex.Dexon, config *dex.Config) (*LesServer, error) {
+	return newLesServer(dex.BlockChain(), dex.TxPool(), config.NetworkId,
+		config.LightPeers), nil
+}
+
+func newLesServer(blockchain *core.BlockChain, txPool *core.TxPool,
+	networkId uint64, maxPeers int) *LesServer {
+	return &LesServer{
+		blockchain: blockchain,
+		txPool:     txPool,
+		networkId:  networkId,
+		maxPeers:   maxPeers,
+		peers:      newPeerSet(),
+		quitSync:   make(chan struct{}),
+	}
+}
+
+// Protocols implements dex.LesServer.
+func (s *LesServer) Protocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				peer := newPeer(int(version), p, rw)
+				s.wg.Add(1)
+				defer s.wg.Done()
+				return s.handle(peer)
+			},
+		})
+	}
+	return protocols
+}
+
+// Start implements dex.LesServer. The server registers itself in the topic
+// of the light clients, and announces the new heads to them.
+func (s *LesServer) Start(srvr *p2p.Server) {
+	if srvr.DiscV5 != nil {
+		topic := lesTopic(s.blockchain.Genesis().Hash())
+		go func() {
+			logger := log.New("topic", topic)
+			logger.Info("Starting topic registration")
+			defer logger.Info("Terminated topic registration")
+
+			srvr.DiscV5.RegisterTopic(topic, s.quitSync)
+		}()
+	}
+	s.wg.Add(1)
+	go s.announceLoop()
+}
+
+// Stop implements dex.LesServer.
+func (s *LesServer) Stop() {
+	close(s.quitSync)
+	s.peers.Close()
+	s.wg.Wait()
+	log.Info("Light DEXON server stopped")
+}
+
+func (s *LesServer) announceLoop() {
+	defer s.wg.Done()
+
+	ch := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.blockchain.SubscribeChainHeadEvent(ch)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-ch:
+			hash, number := ev.Block.Hash(), ev.Block.NumberU64()
+			for _, p := range s.peers.Peers() {
+				if err := p.SendAnnounce(hash, number); err != nil {
+					p.Log().Debug("Announce failed", "err", err)
+				}
+			}
+		case <-sub.Err():
+			return
+		case <-s.quitSync:
+			return
+		}
+	}
+}
+
+func (s *LesServer) handle(p *peer) error {
+	if s.peers.Len() >= s.maxPeers {
+		return p2p.DiscTooManyPeers
+	}
+	p.Log().Debug("Light client connected", "name", p.Name())
+
+	head := s.blockchain.CurrentBlock()
+	genesis := s.blockchain.Genesis()
+	if err := p.Handshake(s.networkId, head.NumberU64(), head.Hash(),
+		genesis.Hash(), true); err != nil {
+		p.Log().Debug("Light client handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		p.Log().Error("Light client registration failed", "err", err)
+		return err
+	}
+	defer s.peers.Unregister(p.id)
+
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light client message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+func (s *LesServer) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case GetBlockHeadersMsg:
+		var req getBlockHeadersReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return p.SendBlockHeaders(req.ReqID, s.getHeaders(p, &req.Query))
+
+	case GetBlockBodiesMsg:
+		var req hashesReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		var (
+			bytes  int
+			bodies []rlp.RawValue
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit || len(bodies) >= downloader.MaxBlockFetch {
+				break
+			}
+			// Retrieve the requested block body, stopping if enough was found
+			if data := s.blockchain.GetBodyRLP(hash); len(data) != 0 {
+				bodies = append(bodies, data)
+				bytes += len(data)
+			}
+		}
+		return p.SendBlockBodiesRLP(req.ReqID, bodies)
+
+	case GetReceiptsMsg:
+		var req hashesReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		var (
+			bytes    int
+			receipts []rlp.RawValue
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit || len(receipts) >= downloader.MaxReceiptFetch {
+				break
+			}
+			// Retrieve the requested block's receipts, skipping if unknown to us
+			results := s.blockchain.GetReceiptsByHash(hash)
+			if results == nil {
+				if header := s.blockchain.GetHeaderByHash(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
+					continue
+				}
+			}
+			// If known, encode and queue for response packet
+			if encoded, err := rlp.EncodeToBytes(results); err != nil {
+				log.Error("Failed to encode receipt", "err", err)
+			} else {
+				receipts = append(receipts, encoded)
+				bytes += len(encoded)
+			}
+		}
+		return p.SendReceiptsRLP(req.ReqID, receipts)
+
+	case GetNodeDataMsg:
+		var req hashesReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		var (
+			bytes int
+			data  [][]byte
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit || len(data) >= downloader.MaxStateFetch {
+				break
+			}
+			// Retrieve the requested trie node or code, stopping if enough
+			// was found
+			if entry, err := s.blockchain.TrieNode(hash); err == nil {
+				data = append(data, entry)
+				bytes += len(entry)
+			}
+		}
+		return p.SendNodeData(req.ReqID, data)
+
+	case GetGovStateMsg:
+		var req getGovStateReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		govState, err := s.blockchain.GetGovStateByHash(req.Hash)
+		if err != nil {
+			p.Log().Debug("Gov state not available", "hash", req.Hash, "err", err)
+			govState = nil
+		}
+		return p.SendGovState(req.ReqID, govState)
+
+	case SendTxMsg:
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		s.txPool.AddRemotes(txs)
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// getHeaders gathers the headers of the query. The governance states at the
+// heights of the rounds in the headers are attached if the query asks for
+// them, the light clients build the DKG group public keys of the rounds with
+// them.
+func (s *LesServer) getHeaders(p *peer, query *getBlockHeadersData) []*types.HeaderWithGovState {
+	hashMode := query.Origin.Hash != (common.Hash{})
+	first := true
+	maxNonCanonical := uint64(100)
+
+	round := map[uint64]uint64{}
+	// Gather headers until the fetch or network limits is reached
+	var (
+		bytes   common.StorageSize
+		headers []*types.HeaderWithGovState
+		unknown bool
+	)
+	for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
+		// Retrieve the next header satisfying the query
+		var origin *types.Header
+		if hashMode {
+			if first {
+				first = false
+				origin = s.blockchain.GetHeaderByHash(query.Origin.Hash)
+				if origin != nil {
+					query.Origin.Number = origin.Number.Uint64()
+				}
+			} else {
+				origin = s.blockchain.GetHeader(query.Origin.Hash, query.Origin.Number)
+			}
+		} else {
+			origin = s.blockchain.GetHeaderByNumber(query.Origin.Number)
+		}
+		if origin == nil {
+			break
+		}
+		headers = append(headers, &types.HeaderWithGovState{Header: origin})
+		if _, ok := round[origin.Round]; !ok {
+			round[origin.Round] = origin.Number.Uint64()
+		}
+		bytes += estHeaderRlpSize
+
+		// Advance to the next header of the query
+		switch {
+		case hashMode && query.Reverse:
+			// Hash based traversal towards the genesis block
+			ancestor := query.Skip + 1
+			if ancestor == 0 {
+				unknown = true
+			} else {
+				query.Origin.Hash, query.Origin.Number = s.blockchain.GetAncestor(query.Origin.Hash, query.Origin.Number, ancestor, &maxNonCanonical)
+				unknown = (query.Origin.Hash == common.Hash{})
+			}
+		case hashMode && !query.Reverse:
+			// Hash based traversal towards the leaf block
+			var (
+				current = origin.Number.Uint64()
+				next    = current + query.Skip + 1
+			)
+			if next <= current {
+				p.Log().Warn("GetBlockHeaders skip overflow attack", "current", current, "skip", query.Skip, "next", next)
+				unknown = true
+			} else {
+				if header := s.blockchain.GetHeaderByNumber(next); header != nil {
+					nextHash := header.Hash()
+					expOldHash, _ := s.blockchain.GetAncestor(nextHash, next, query.Skip+1, &maxNonCanonical)
+					if expOldHash == query.Origin.Hash {
+						query.Origin.Hash, query.Origin.Number = nextHash, next
+					} else {
+						unknown = true
+					}
+				} else {
+					unknown = true
+				}
+			}
+		case query.Reverse:
+			// Number based traversal towards the genesis block
+			if query.Origin.Number >= query.Skip+1 {
+				query.Origin.Number -= query.Skip + 1
+			} else {
+				unknown = true
+			}
+
+		case !query.Reverse:
+			// Number based traversal towards the leaf block
+			query.Origin.Number += query.Skip + 1
+		}
+	}
+	if !query.WithGov || len(headers) == 0 {
+		return headers
+	}
+
+	// Do not reply if we don't have the gov state of the last header.
+	last := headers[len(headers)-1]
+	if s.blockchain.CurrentBlock().NumberU64() < last.Number.Uint64() {
+		return []*types.HeaderWithGovState{}
+	}
+	snapshotHeight := map[uint64]struct{}{}
+	for r, height := range round {
+		if r == 0 {
+			continue
+		}
+		if h, ok := s.blockchain.GetRoundHeight(r); ok && h != 0 {
+			height = h
+		}
+		snapshotHeight[height] = struct{}{}
+	}
+	for _, header := range headers {
+		if _, exist := snapshotHeight[header.Number.Uint64()]; !exist {
+			continue
+		}
+		govState, err := s.blockchain.GetGovStateByHash(header.Hash())
+		if err != nil {
+			log.Warn("Get gov state by hash fail", "number", header.Number.Uint64(), "err", err)
+			return []*types.HeaderWithGovState{}
+		}
+		header.GovState = govState
+	}
+	return headers
+}
+
+// lesTopic returns the discovery topic of the light servers of the chain.
+func lesTopic(genesisHash common.Hash) discv5.Topic {
+	return discv5.Topic(fmt.Sprintf("LDEX@%x", genesisHash.Bytes()[:8]))
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"sync"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+// txRelay forwards the transactions of the light transaction pool to the
+// servers. The transactions not mined yet are sent again on every new head,
+// in case no server was connected or the servers dropped them.
+type txRelay struct {
+	peers *peerSet
+
+	lock    sync.Mutex
+	pending map[common.Hash]*types.Transaction
+}
+
+func newTxRelay(peers *peerSet) *txRelay {
+	return &txRelay{
+		peers:   peers,
+		pending: make(map[common.Hash]*types.Transaction),
+	}
+}
+
+// Send implements light.TxRelayBackend.
+func (r *txRelay) Send(txs types.Transactions) {
+	r.lock.Lock()
+	for _, tx := range txs {
+		r.pending[tx.Hash()] = tx
+	}
+	r.lock.Unlock()
+
+	r.send(txs)
+}
+
+// NewHead implements light.TxRelayBackend.
+func (r *txRelay) NewHead(head common.Hash, mined []common.Hash, rollback []common.Hash) {
+	r.lock.Lock()
+	for _, hash := range mined {
+		delete(r.pending, hash)
+	}
+	txs := make(types.Transactions, 0, len(r.pending))
+	for _, tx := range r.pending {
+		txs = append(txs, tx)
+	}
+	r.lock.Unlock()
+
+	if len(txs) > 0 {
+		r.send(txs)
+	}
+}
+
+// Discard implements light.TxRelayBackend.
+func (r *txRelay) Discard(hashes []common.Hash) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, hash := range hashes {
+		delete(r.pending, hash)
+	}
+}
+
+// send sends the transactions to all servers without blocking the caller.
+func (r *txRelay) send(txs types.Transactions) {
+	for _, p := range r.peers.Peers() {
+		go func(p *peer) {
+			if err := p.SendTxs(txs); err != nil {
+				p.Log().Debug("Failed to relay transactions", "err", err)
+			}
+		}(p)
+	}
+}
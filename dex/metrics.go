@@ -23,6 +23,7 @@ import (
 
 var (
 	propBlockConfirmLatency                = metrics.NewRegisteredGauge("dex/prop/blockconfirm/latency", nil)
+	propBlockRandomnessLatency             = metrics.NewRegisteredGauge("dex/prop/blockrandomness/latency", nil)
 	propTxnInPacketsMeter                  = metrics.NewRegisteredMeter("dex/prop/txns/in/packets", nil)
 	propTxnInTrafficMeter                  = metrics.NewRegisteredMeter("dex/prop/txns/in/traffic", nil)
 	propTxnOutPacketsMeter                 = metrics.NewRegisteredMeter("dex/prop/txns/out/packets", nil)
@@ -79,6 +80,10 @@ var (
 	miscInTrafficMeter                     = metrics.NewRegisteredMeter("dex/misc/in/traffic", nil)
 	miscOutPacketsMeter                    = metrics.NewRegisteredMeter("dex/misc/out/packets", nil)
 	miscOutTrafficMeter                    = metrics.NewRegisteredMeter("dex/misc/out/traffic", nil)
+
+	verifyQueueGauge     = metrics.NewRegisteredGauge("dex/verify/queue", nil)
+	verifyInvalidMeter   = metrics.NewRegisteredMeter("dex/verify/invalid", nil)
+	verifyDuplicateMeter = metrics.NewRegisteredMeter("dex/verify/duplicate", nil)
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -108,6 +108,10 @@ type setType uint32
 
 const (
 	notaryset = iota
+	// sentryset labels the sentries relaying the consensus messages of the
+	// node, and protectedset the validators the node is a sentry of.
+	sentryset
+	protectedset
 )
 
 type peerLabel struct {
@@ -120,6 +124,10 @@ func (p peerLabel) String() string {
 	switch p.set {
 	case notaryset:
 		t = fmt.Sprintf("NotarySet round: %d", p.round)
+	case sentryset:
+		t = "Sentries"
+	case protectedset:
+		t = "Protected validators"
 	}
 	return t
 }
@@ -530,6 +538,11 @@ func (p *peer) SendGovState(govState *types.GovState) error {
 	return p.logSend(p2p.Send(p.rw, GovStateMsg, govState), GovStateMsg)
 }
 
+// SendGovStateSlice sends a slice of the governance state to the remote peer.
+func (p *peer) SendGovStateSlice(slice *types.GovStateSlice) error {
+	return p.logSend(p2p.Send(p.rw, GovStateSliceMsg, slice), GovStateSliceMsg)
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *peer) RequestOneHeader(hash common.Hash) error {
@@ -561,6 +574,13 @@ func (p *peer) RequestGovStateByHash(hash common.Hash) error {
 	return p2p.Send(p.rw, GetGovStateMsg, hash)
 }
 
+// RequestGovStateSlice fetches a slice of the governance state of a block,
+// the slots changed from the state of the base block if base is not zero.
+func (p *peer) RequestGovStateSlice(hash, base common.Hash, origin []byte, amount int) error {
+	p.Log().Debug("Fetching gov state slice", "hash", hash, "base", base, "origin", common.Bytes2Hex(origin), "count", amount)
+	return p2p.Send(p.rw, GetGovStateSliceMsg, &getGovStateSliceData{Hash: hash, Base: base, Origin: origin, Amount: uint64(amount)})
+}
+
 // RequestBodies fetches a batch of blocks' bodies corresponding to the hashes
 // specified.
 func (p *peer) RequestBodies(flag uint8, hashes []common.Hash) error {
@@ -866,7 +886,12 @@ func (ps *peerSet) BuildConnection(round uint64) {
 		nodes := ps.pksToNodes(notaryPKs)
 		ps.label2Nodes[notaryLabel] = nodes
 
-		if _, exists := nodes[ps.srvr.Self().ID().String()]; exists {
+		// A validator protected by sentries is connected to them only, the
+		// sentries join the notary set connections on its behalf.
+		if len(ps.label2Nodes[peerLabel{set: sentryset}]) > 0 {
+			return
+		}
+		if ps.representsNotary(nodes) {
 			ps.buildDirectConn(notaryLabel)
 		} else {
 			ps.buildGroupConn(notaryLabel)
@@ -874,6 +899,23 @@ func (ps *peerSet) BuildConnection(round uint64) {
 	}
 }
 
+// BuildSentryConnection connects the node directly to the nodes of a sentry
+// relationship, the sentries of the node for sentryset or the validators it's
+// a sentry of for protectedset. The connection is kept across rounds.
+func (ps *peerSet) BuildSentryConnection(set setType, nodes []*enode.Node) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	label := peerLabel{set: set}
+	ps.forgetDirectConn(label)
+	ps.label2Nodes[label] = make(map[string]*enode.Node)
+	for _, node := range nodes {
+		ps.label2Nodes[label][node.ID().String()] = node
+	}
+	log.Info("Build sentry connection", "label", label, "nodes", len(nodes))
+	ps.buildDirectConn(label)
+}
+
 func (ps *peerSet) ForgetLabelConnection(label peerLabel) {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -893,19 +935,19 @@ func (ps *peerSet) ForgetConnection(round uint64) {
 	log.Debug("Forget connection", "round", round)
 
 	for label := range ps.directConn {
-		if label.round <= round {
+		if label.set == notaryset && label.round <= round {
 			ps.forgetDirectConn(label)
 		}
 	}
 
 	for label := range ps.groupConnPeers {
-		if label.round <= round {
+		if label.set == notaryset && label.round <= round {
 			ps.forgetGroupConn(label)
 		}
 	}
 
 	for label := range ps.label2Nodes {
-		if label.round <= round {
+		if label.set == notaryset && label.round <= round {
 			delete(ps.label2Nodes, label)
 		}
 	}
@@ -992,6 +1034,60 @@ func (ps *peerSet) removeDirectPeer(id string, label peerLabel) {
 	}
 }
 
+// IsNotaryDirectPeer reports whether the peer is a direct peer of a notary
+// set, or of a sentry relationship of the node.
+func (ps *peerSet) IsNotaryDirectPeer(id string) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	for label := range ps.allDirectPeers[id] {
+		switch label.set {
+		case notaryset, sentryset, protectedset:
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected reports whether the node is a validator protected by sentries.
+func (ps *peerSet) IsProtected() bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.label2Nodes[peerLabel{set: sentryset}]) > 0
+}
+
+// IsSentry reports whether the node is a sentry of validators.
+func (ps *peerSet) IsSentry() bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.label2Nodes[peerLabel{set: protectedset}]) > 0
+}
+
+// HasLabel reports whether the peer is one of the nodes of the label.
+func (ps *peerSet) HasLabel(id string, label peerLabel) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	_, ok := ps.label2Nodes[label][id]
+	return ok
+}
+
+// representsNotary reports whether the node takes part in the connections of
+// the notary set as a member, or as a sentry of a member.
+func (ps *peerSet) representsNotary(nodes map[string]*enode.Node) bool {
+	if _, exists := nodes[ps.srvr.Self().ID().String()]; exists {
+		return true
+	}
+	for id := range ps.label2Nodes[peerLabel{set: protectedset}] {
+		if _, exists := nodes[id]; exists {
+			return true
+		}
+	}
+	return false
+}
+
 func (ps *peerSet) pksToNodes(pks map[string]struct{}) map[string]*enode.Node {
 	nodes := map[string]*enode.Node{}
 	for pk := range pks {
@@ -221,6 +221,100 @@ func TestPeerSetBuildAndForgetConn(t *testing.T) {
 	}
 }
 
+func TestPeerSetSentryConn(t *testing.T) {
+	var nodes []*enode.Node
+	for i := 0; i < 6; i++ {
+		nodes = append(nodes, randomV4CompactNode())
+	}
+	validator, sentries := nodes[0], nodes[1:3]
+
+	gov := &testGovernance{}
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		m := map[uint64][]*enode.Node{
+			10: {validator, nodes[3], nodes[4]},
+			11: {nodes[3], nodes[4], nodes[5]},
+		}
+		return newTestNodeSet(m[round]), nil
+	}
+
+	// The validator is connected to its sentries only.
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(key)
+	ps := newPeerSet(gov, server)
+	ps.BuildSentryConnection(sentryset, sentries)
+	ps.BuildConnection(10)
+	ps.BuildConnection(11)
+
+	if !ps.IsProtected() || ps.IsSentry() {
+		t.Errorf("validator role mismatch")
+	}
+	if len(server.direct) != len(sentries) {
+		t.Errorf("direct peers mismatch: have %d, want %d",
+			len(server.direct), len(sentries))
+	}
+	for _, sentry := range sentries {
+		if _, ok := server.direct[sentry.ID()]; !ok {
+			t.Errorf("sentry %v not connected", sentry.ID())
+		}
+		if !ps.IsNotaryDirectPeer(sentry.ID().String()) {
+			t.Errorf("sentry %v not exempted", sentry.ID())
+		}
+	}
+	if len(ps.label2Nodes[peerLabel{set: notaryset, round: 10}]) != 3 {
+		t.Errorf("notary set of round 10 not labelled")
+	}
+
+	ps.ForgetConnection(11)
+	if len(server.direct) != len(sentries) ||
+		!ps.HasLabel(sentries[0].ID().String(), peerLabel{set: sentryset}) {
+		t.Errorf("sentry connection forgotten")
+	}
+
+	// The sentry joins the notary set connections of the validator.
+	key, err = crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = newTestP2PServer(key)
+	ps = newPeerSet(gov, server)
+	ps.BuildSentryConnection(protectedset, []*enode.Node{validator})
+	ps.BuildConnection(10)
+	ps.BuildConnection(11)
+
+	if ps.IsProtected() || !ps.IsSentry() {
+		t.Errorf("sentry role mismatch")
+	}
+	expectedDirectConn := map[peerLabel]struct{}{
+		{set: protectedset}:         {},
+		{set: notaryset, round: 10}: {},
+	}
+	if !reflect.DeepEqual(ps.directConn, expectedDirectConn) {
+		t.Errorf("direct conn not match")
+	}
+	if _, ok := ps.groupConnPeers[peerLabel{set: notaryset, round: 11}]; !ok {
+		t.Errorf("group conn of round 11 not built")
+	}
+	for _, node := range []*enode.Node{validator, nodes[3], nodes[4]} {
+		if _, ok := server.direct[node.ID()]; !ok {
+			t.Errorf("node %v not connected", node.ID())
+		}
+	}
+
+	ps.ForgetConnection(10)
+	expectedDirectConn = map[peerLabel]struct{}{
+		{set: protectedset}: {},
+	}
+	if !reflect.DeepEqual(ps.directConn, expectedDirectConn) {
+		t.Errorf("direct conn not match")
+	}
+	if _, ok := server.direct[validator.ID()]; !ok {
+		t.Errorf("validator connection forgotten")
+	}
+}
+
 func newTestNodeSet(nodes []*enode.Node) map[string]struct{} {
 	m := make(map[string]struct{})
 	for _, node := range nodes {
@@ -50,16 +50,17 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	dex64 = 64
+	dex65 = 65
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "dex"
 
 // ProtocolVersions are the supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{dex64}
+var ProtocolVersions = []uint{dex65, dex64}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{43}
+var ProtocolLengths = []uint64{45, 43}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -92,6 +93,10 @@ const (
 
 	GetGovStateMsg = 0x29
 	GovStateMsg    = 0x2a
+
+	// Protocol messages belonging to dex/65
+	GetGovStateSliceMsg = 0x2b
+	GovStateSliceMsg    = 0x2c
 )
 
 type errCode int
@@ -236,6 +241,14 @@ func (hn *hashOrNumber) DecodeRLP(s *rlp.Stream) error {
 	return err
 }
 
+// getGovStateSliceData represents a governance state slice query.
+type getGovStateSliceData struct {
+	Hash   common.Hash // Block hash of the governance state
+	Base   common.Hash // Block hash of the state to diff against, zero for a range
+	Origin []byte      // Hashed storage key the slice starts from
+	Amount uint64      // Maximum number of storage slots to retrieve
+}
+
 // headersData is the network packet for header content distribution.
 type headersData struct {
 	Flag    uint8
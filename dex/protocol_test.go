@@ -27,8 +27,10 @@ import (
 	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
 	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core/types"
@@ -45,6 +47,9 @@ func init() {
 
 var testAccount, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 
+// testSigner signs the core messages which are verified when received.
+var testSigner = coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(testAccount))
+
 // Tests that handshake failures are detected and reported correctly.
 func TestStatusMsgErrors62(t *testing.T) { testStatusMsgErrors(t, 62) }
 func TestStatusMsgErrors63(t *testing.T) { testStatusMsgErrors(t, 63) }
@@ -240,9 +245,7 @@ func TestRecvCoreBlocks(t *testing.T) {
 	defer p.close()
 
 	block := coreTypes.Block{
-		ProposerID: coreTypes.NodeID{coreCommon.Hash{1, 2, 3}},
 		ParentHash: coreCommon.Hash{1, 1, 1, 1, 1},
-		Hash:       coreCommon.Hash{2, 2, 2, 2, 2},
 		Position: coreTypes.Position{
 			Round:  12,
 			Height: 13,
@@ -254,15 +257,14 @@ func TestRecvCoreBlocks(t *testing.T) {
 			Data:   []byte{4, 4, 4, 4, 4},
 		},
 		Randomness: []byte{5, 5, 5, 5, 5},
-		Signature: coreCrypto.Signature{
-			Type:      "signature",
-			Signature: []byte("signature"),
-		},
 		CRSSignature: coreCrypto.Signature{
 			Type:      "crs-signature",
 			Signature: []byte("crs-signature"),
 		},
 	}
+	if err := testSigner.SignBlock(&block); err != nil {
+		t.Fatalf("sign block error: %v", err)
+	}
 
 	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{&block}); err != nil {
 		t.Fatalf("send error: %v", err)
@@ -391,8 +393,7 @@ func TestRecvVotes(t *testing.T) {
 
 	vote := coreTypes.Vote{
 		VoteHeader: coreTypes.VoteHeader{
-			ProposerID: coreTypes.NodeID{coreCommon.Hash{1, 2, 3}},
-			Period:     10,
+			Period: 10,
 			Position: coreTypes.Position{
 				Round:  12,
 				Height: 13,
@@ -402,10 +403,9 @@ func TestRecvVotes(t *testing.T) {
 			Type:      "456",
 			Signature: []byte("psig"),
 		},
-		Signature: coreCrypto.Signature{
-			Type:      "123",
-			Signature: []byte("sig"),
-		},
+	}
+	if err := testSigner.SignVote(&vote); err != nil {
+		t.Fatalf("sign vote error: %v", err)
 	}
 
 	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{&vote}); err != nil {
@@ -18,22 +18,23 @@
 package dex
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"math/big"
-	"strconv"
 	"strings"
 
+	"github.com/dexon-foundation/dexon"
 	"github.com/dexon-foundation/dexon/accounts/abi"
+	"github.com/dexon-foundation/dexon/accounts/abi/bind"
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethclient"
 	"github.com/dexon-foundation/dexon/log"
-	"github.com/dexon-foundation/dexon/params"
-	"github.com/dexon-foundation/dexon/rlp"
-	"github.com/onrik/ethrpc"
+	"github.com/dexon-foundation/dexon/rpc"
 )
 
 const numConfirmation = 1
@@ -256,70 +257,147 @@ func init() {
 	}
 }
 
+// RecoveryBackend is the place where notary nodes vote for skipping a stuck
+// height.
+type RecoveryBackend interface {
+	// VoteForSkipBlock casts the vote of this node for skipping height.
+	VoteForSkipBlock(height uint64) error
+
+	// Voters returns the addresses among candidates which voted for
+	// skipping height.
+	Voters(height uint64, candidates map[common.Address]struct{}) ([]common.Address, error)
+}
+
+// recoveryGovernance is the governance information needed by recovery.
+type recoveryGovernance interface {
+	Round() uint64
+	NotarySet(uint64) (map[string]struct{}, error)
+	DKGSetNodeKeyAddresses(uint64) (map[common.Address]struct{}, error)
+}
+
+// Recovery implements the dexon-consensus Recovery interface on top of a
+// RecoveryBackend.
 type Recovery struct {
-	gov          *DexconGovernance
-	contract     common.Address
-	confirmation int
-	publicKey    string
-	privateKey   *ecdsa.PrivateKey
-	nodeAddress  common.Address
-	client       *ethrpc.EthRPC
+	gov       recoveryGovernance
+	publicKey string
+	backend   RecoveryBackend
 }
 
-func NewRecovery(config *params.RecoveryConfig, networkRPC string,
-	gov *DexconGovernance, privKey *ecdsa.PrivateKey) *Recovery {
-	client := ethrpc.New(networkRPC)
+func NewRecovery(gov recoveryGovernance, privKey *ecdsa.PrivateKey,
+	backend RecoveryBackend) *Recovery {
 	return &Recovery{
-		gov:          gov,
-		contract:     config.Contract,
-		confirmation: config.Confirmation,
-		publicKey:    hex.EncodeToString(crypto.FromECDSAPub(&privKey.PublicKey)),
-		privateKey:   privKey,
-		nodeAddress:  crypto.PubkeyToAddress(privKey.PublicKey),
-		client:       client,
+		gov:       gov,
+		publicKey: hex.EncodeToString(crypto.FromECDSAPub(&privKey.PublicKey)),
+		backend:   backend,
 	}
 }
 
-func (r *Recovery) callRPC(data []byte, tag string) ([]byte, error) {
-	res, err := r.client.EthCall(ethrpc.T{
-		From: r.nodeAddress.String(),
-		To:   r.contract.String(),
-		Data: "0x" + hex.EncodeToString(data),
-	}, tag)
+func (r *Recovery) ProposeSkipBlock(height uint64) error {
+	notarySet, err := r.gov.NotarySet(r.gov.Round())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if _, ok := notarySet[r.publicKey]; !ok {
+		return errors.New("not in notary set")
 	}
 
-	resBytes, err := hex.DecodeString(res[2:])
-	if err != nil {
-		return nil, err
+	err = r.backend.VoteForSkipBlock(height)
+	if err == errAlreadyVoted {
+		return nil
 	}
-	return resBytes, nil
+	return err
 }
 
-func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, error) {
-	netVersion, err := r.client.NetVersion()
+func (r *Recovery) Votes(height uint64) (uint64, error) {
+	notarySet, err := r.gov.DKGSetNodeKeyAddresses(r.gov.Round())
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	networkID, err := strconv.Atoi(netVersion)
+	voters, err := r.backend.Voters(height, notarySet)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	count := uint64(0)
+	for _, addr := range voters {
+		if _, ok := notarySet[addr]; ok {
+			count += 1
+		}
 	}
+	return count, nil
+}
+
+// RecoveryContractBackend is the chain access needed to vote on the recovery
+// contract. It's satisfied by RecoveryRPCClient.
+type RecoveryContractBackend interface {
+	bind.ContractCaller
+	bind.ContractTransactor
+
+	// BlockNumber returns the most recent block number.
+	BlockNumber(ctx context.Context) (uint64, error)
+
+	// NetworkID returns the network ID used to sign transactions.
+	NetworkID(ctx context.Context) (*big.Int, error)
+}
+
+// batchContractCaller is implemented by backends which are able to execute
+// multiple contract calls in a single round trip.
+type batchContractCaller interface {
+	BatchCallContract(ctx context.Context, msgs []dexon.CallMsg,
+		blockNumber *big.Int) ([][]byte, error)
+}
 
-	data, err := abiObject.Pack("voted", big.NewInt(int64(height)), r.nodeAddress)
+// ContractRecoveryBackend votes on the recovery contract deployed on another
+// network, e.g. an Ethereum network or a secondary DEXON network.
+type ContractRecoveryBackend struct {
+	backend      RecoveryContractBackend
+	contract     common.Address
+	confirmation uint64
+	privateKey   *ecdsa.PrivateKey
+	nodeAddress  common.Address
+}
+
+func NewContractRecoveryBackend(backend RecoveryContractBackend,
+	contract common.Address, privKey *ecdsa.PrivateKey) *ContractRecoveryBackend {
+	return &ContractRecoveryBackend{
+		backend:      backend,
+		contract:     contract,
+		confirmation: numConfirmation,
+		privateKey:   privKey,
+		nodeAddress:  crypto.PubkeyToAddress(privKey.PublicKey),
+	}
+}
+
+func (b *ContractRecoveryBackend) call(ctx context.Context, result interface{},
+	blockNumber *big.Int, method string, args ...interface{}) error {
+	data, err := abiObject.Pack(method, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	resBytes, err := b.backend.CallContract(ctx, dexon.CallMsg{
+		From: b.nodeAddress,
+		To:   &b.contract,
+		Data: data,
+	}, blockNumber)
+	if err != nil {
+		return err
+	}
+	return abiObject.Unpack(result, method, resBytes)
+}
 
-	resBytes, err := r.callRPC(data, "latest")
+func (b *ContractRecoveryBackend) genVoteForSkipBlockTx(
+	height uint64) (*types.Transaction, error) {
+	ctx := context.Background()
+
+	networkID, err := b.backend.NetworkID(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var voted bool
-	err = abiObject.Unpack(&voted, "voted", resBytes)
+	err = b.call(ctx, &voted, nil, "voted",
+		new(big.Int).SetUint64(height), b.nodeAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -329,130 +407,191 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		return nil, errAlreadyVoted
 	}
 
-	data, err = abiObject.Pack("depositValue")
-	if err != nil {
-		return nil, err
-	}
-
-	resBytes, err = r.callRPC(data, "latest")
-	if err != nil {
-		return nil, err
-	}
-
 	var depositValue *big.Int
-	err = abiObject.Unpack(&depositValue, "depositValue", resBytes)
+	err = b.call(ctx, &depositValue, nil, "depositValue")
 	if err != nil {
 		return nil, err
 	}
 
-	data, err = abiObject.Pack("voteForSkipBlock", new(big.Int).SetUint64(height))
+	data, err := abiObject.Pack("voteForSkipBlock", new(big.Int).SetUint64(height))
 	if err != nil {
 		return nil, err
 	}
 
-	gasPrice, err := r.client.EthGasPrice()
+	gasPrice, err := b.backend.SuggestGasPrice(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce, err := r.client.EthGetTransactionCount(r.nodeAddress.String(), "pending")
+	nonce, err := b.backend.PendingNonceAt(ctx, b.nodeAddress)
 	if err != nil {
 		return nil, err
 	}
 
 	// Increase gasPrice to 3 times of suggested gas price to make sure it will
 	// be included in time.
-	useGasPrice := new(big.Int).Mul(&gasPrice, big.NewInt(3))
+	useGasPrice := new(big.Int).Mul(gasPrice, big.NewInt(3))
 
 	tx := types.NewTransaction(
-		uint64(nonce),
-		r.contract,
+		nonce,
+		b.contract,
 		depositValue,
 		uint64(100000),
 		useGasPrice,
 		data)
 
-	signer := types.NewEIP155Signer(big.NewInt(int64(networkID)))
-	return types.SignTx(tx, signer, r.privateKey)
+	signer := types.NewEIP155Signer(networkID)
+	return types.SignTx(tx, signer, b.privateKey)
 }
 
-func (r *Recovery) ProposeSkipBlock(height uint64) error {
-	notarySet, err := r.gov.NotarySet(r.gov.Round())
+// VoteForSkipBlock implements RecoveryBackend.
+func (b *ContractRecoveryBackend) VoteForSkipBlock(height uint64) error {
+	tx, err := b.genVoteForSkipBlockTx(height)
 	if err != nil {
 		return err
 	}
-	if _, ok := notarySet[r.publicKey]; !ok {
-		return errors.New("not in notary set")
-	}
+	return b.backend.SendTransaction(context.Background(), tx)
+}
 
-	tx, err := r.genVoteForSkipBlockTx(height)
-	if err == errAlreadyVoted {
-		return nil
-	}
-	if err != nil {
-		return err
-	}
+// Voters implements RecoveryBackend. The votes of the candidates are read at a
+// confirmed block and fetched in a single batch if the backend supports it.
+// Anyone is able to vote on the contract by paying the deposit, so the votes
+// are looked up by candidate instead of being enumerated.
+func (b *ContractRecoveryBackend) Voters(height uint64,
+	candidates map[common.Address]struct{}) ([]common.Address, error) {
+	ctx := context.Background()
 
-	txData, err := rlp.EncodeToBytes(tx)
+	bn, err := b.backend.BlockNumber(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = r.client.EthSendRawTransaction("0x" + hex.EncodeToString(txData))
-	return err
-}
-
-func (r *Recovery) Votes(height uint64) (uint64, error) {
-	data, err := abiObject.Pack("numVotes", new(big.Int).SetUint64(height))
-	if err != nil {
-		return 0, err
+	if bn < b.confirmation {
+		return nil, nil
 	}
+	snapshotHeight := new(big.Int).SetUint64(bn - b.confirmation)
 
-	bn, err := r.client.EthBlockNumber()
-	if err != nil {
-		return 0, err
+	addrs := make([]common.Address, 0, len(candidates))
+	msgs := make([]dexon.CallMsg, 0, len(candidates))
+	for addr := range candidates {
+		data, err := abiObject.Pack("voted", new(big.Int).SetUint64(height), addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+		msgs = append(msgs, dexon.CallMsg{
+			From: b.nodeAddress,
+			To:   &b.contract,
+			Data: data,
+		})
 	}
 
-	snapshotHeight := bn - numConfirmation
-
-	resBytes, err := r.callRPC(data, fmt.Sprintf("0x%x", snapshotHeight))
-	if err != nil {
-		return 0, err
+	var results [][]byte
+	if batch, ok := b.backend.(batchContractCaller); ok {
+		results, err = batch.BatchCallContract(ctx, msgs, snapshotHeight)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		results = make([][]byte, len(msgs))
+		for i, msg := range msgs {
+			results[i], err = b.backend.CallContract(ctx, msg, snapshotHeight)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	votes := new(big.Int)
-	err = abiObject.Unpack(&votes, "numVotes", resBytes)
-	if err != nil {
-		return 0, err
+	voters := make([]common.Address, 0, len(results))
+	for i, resBytes := range results {
+		var voted bool
+		if err := abiObject.Unpack(&voted, "voted", resBytes); err != nil {
+			return nil, err
+		}
+		if voted {
+			voters = append(voters, addrs[i])
+		}
 	}
+	return voters, nil
+}
 
-	notarySet, err := r.gov.DKGSetNodeKeyAddresses(r.gov.Round())
+// RecoveryRPCClient is a RecoveryContractBackend talking to a JSON-RPC
+// endpoint. It only relies on the standard eth_ methods so it works with
+// both Ethereum and DEXON networks.
+type RecoveryRPCClient struct {
+	*ethclient.Client
+	c *rpc.Client
+}
+
+// DialRecoveryRPCClient connects a client to the given URL.
+func DialRecoveryRPCClient(rawurl string) (*RecoveryRPCClient, error) {
+	c, err := rpc.Dial(rawurl)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return NewRecoveryRPCClient(c), nil
+}
 
-	count := uint64(0)
+// NewRecoveryRPCClient creates a client that uses the given RPC client.
+func NewRecoveryRPCClient(c *rpc.Client) *RecoveryRPCClient {
+	return &RecoveryRPCClient{Client: ethclient.NewClient(c), c: c}
+}
 
-	for i := uint64(0); i < votes.Uint64(); i++ {
-		data, err = abiObject.Pack(
-			"votes", new(big.Int).SetUint64(height), new(big.Int).SetUint64(i))
-		if err != nil {
-			return 0, err
-		}
+// BlockNumber returns the most recent block number.
+func (c *RecoveryRPCClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var result hexutil.Uint64
+	err := c.c.CallContext(ctx, &result, "eth_blockNumber")
+	return uint64(result), err
+}
 
-		resBytes, err := r.callRPC(data, fmt.Sprintf("0x%x", snapshotHeight))
-		if err != nil {
-			return 0, err
+// BatchCallContract executes multiple message calls at the given block in a
+// single batch request.
+func (c *RecoveryRPCClient) BatchCallContract(ctx context.Context,
+	msgs []dexon.CallMsg, blockNumber *big.Int) ([][]byte, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	block := "latest"
+	if blockNumber != nil {
+		block = hexutil.EncodeBig(blockNumber)
+	}
+	results := make([]hexutil.Bytes, len(msgs))
+	reqs := make([]rpc.BatchElem, len(msgs))
+	for i, msg := range msgs {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{toCallArg(msg), block},
+			Result: &results[i],
 		}
-
-		var addr common.Address
-		err = abiObject.Unpack(&addr, "votes", resBytes)
-		if err != nil {
-			return 0, err
+	}
+	if err := c.c.BatchCallContext(ctx, reqs); err != nil {
+		return nil, err
+	}
+	ret := make([][]byte, len(msgs))
+	for i := range reqs {
+		if reqs[i].Error != nil {
+			return nil, reqs[i].Error
 		}
+		ret[i] = results[i]
+	}
+	return ret, nil
+}
 
-		if _, ok := notarySet[addr]; ok {
-			count += 1
-		}
+func toCallArg(msg dexon.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
 	}
-	return count, nil
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
 }
@@ -0,0 +1,165 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+var recoveryVotePrefix = []byte("dexon recovery vote for skip block")
+
+var errInvalidRecoveryVote = errors.New("invalid recovery vote")
+
+// recoveryVote is the signed vote exchanged through the vote directory.
+type recoveryVote struct {
+	Height    hexutil.Uint64 `json:"height"`
+	Voter     common.Address `json:"voter"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// recoveryVoteHash returns the hash signed by the voters, the chain ID is
+// included so votes are not valid on other chains.
+func recoveryVoteHash(chainID *big.Int, height uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, height)
+	return crypto.Keccak256(recoveryVotePrefix, common.BigToHash(chainID).Bytes(), b)
+}
+
+func (v *recoveryVote) verify(chainID *big.Int) error {
+	pub, err := crypto.SigToPub(recoveryVoteHash(chainID, uint64(v.Height)), v.Signature)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pub) != v.Voter {
+		return errInvalidRecoveryVote
+	}
+	return nil
+}
+
+// FileRecoveryBackend exchanges signed votes among notary nodes through a
+// directory, votes of a height are stored as one file per voter under a sub
+// directory named by the height. Distributing the directory among nodes is
+// left to the operators, e.g. a shared volume or a periodic sync job.
+type FileRecoveryBackend struct {
+	dir         string
+	chainID     *big.Int
+	privateKey  *ecdsa.PrivateKey
+	nodeAddress common.Address
+}
+
+func NewFileRecoveryBackend(dir string, chainID *big.Int,
+	privKey *ecdsa.PrivateKey) *FileRecoveryBackend {
+	return &FileRecoveryBackend{
+		dir:         dir,
+		chainID:     chainID,
+		privateKey:  privKey,
+		nodeAddress: crypto.PubkeyToAddress(privKey.PublicKey),
+	}
+}
+
+func (b *FileRecoveryBackend) heightDir(height uint64) string {
+	return filepath.Join(b.dir, strconv.FormatUint(height, 10))
+}
+
+// VoteForSkipBlock implements RecoveryBackend.
+func (b *FileRecoveryBackend) VoteForSkipBlock(height uint64) error {
+	dir := b.heightDir(height)
+	path := filepath.Join(dir, b.nodeAddress.Hex())
+	if _, err := os.Stat(path); err == nil {
+		log.Info("Already voted for skip block", "height", height)
+		return errAlreadyVoted
+	}
+
+	sig, err := crypto.Sign(recoveryVoteHash(b.chainID, height), b.privateKey)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&recoveryVote{
+		Height:    hexutil.Uint64(height),
+		Voter:     b.nodeAddress,
+		Signature: sig,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so other nodes never see a partial
+	// vote.
+	f, err := ioutil.TempFile(dir, "."+b.nodeAddress.Hex())
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Voters implements RecoveryBackend. Votes with invalid signatures, including
+// the ones signed for other chains, are ignored.
+func (b *FileRecoveryBackend) Voters(height uint64,
+	candidates map[common.Address]struct{}) ([]common.Address, error) {
+	dir := b.heightDir(height)
+
+	voters := make([]common.Address, 0, len(candidates))
+	for addr := range candidates {
+		data, err := ioutil.ReadFile(filepath.Join(dir, addr.Hex()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var vote recoveryVote
+		if err := json.Unmarshal(data, &vote); err != nil {
+			log.Warn("Malformed recovery vote", "voter", addr, "err", err)
+			continue
+		}
+		if uint64(vote.Height) != height || vote.Voter != addr {
+			log.Warn("Mismatched recovery vote", "voter", addr)
+			continue
+		}
+		if err := vote.verify(b.chainID); err != nil {
+			log.Warn("Invalid recovery vote", "voter", addr, "err", err)
+			continue
+		}
+		voters = append(voters, vote.Voter)
+	}
+	return voters, nil
+}
@@ -18,26 +18,535 @@
 package dex
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/dexon-foundation/dexon"
+	"github.com/dexon-foundation/dexon/accounts/abi/bind/backends"
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/asm"
+	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rpc"
 )
 
-func TestRecoveryVoteTxGeneration(t *testing.T) {
-	key, err := crypto.GenerateKey()
+// recoveryContractAsm is a minimal implementation of the recovery contract.
+// depositValue is stored at slot 0, voted[h][addr] at keccak256(h, addr),
+// numVotes[h] at keccak256(h) and votes[h][i] at keccak256(h, i, 1).
+const recoveryContractAsm = `
+	push 0x100000000000000000000000000000000000000000000000000000000
+	push 0
+	calldataload
+	div
+	dup1
+	push 0x%x
+	eq
+	jumpi @voted
+	dup1
+	push 0x%x
+	eq
+	jumpi @deposit
+	dup1
+	push 0x%x
+	eq
+	jumpi @numvotes
+	dup1
+	push 0x%x
+	eq
+	jumpi @votes
+	dup1
+	push 0x%x
+	eq
+	jumpi @vote
+	jump @fail
+voted:
+	push 4
+	calldataload
+	push 0
+	mstore
+	push 36
+	calldataload
+	push 32
+	mstore
+	push 64
+	push 0
+	sha3
+	sload
+	jump @ret
+deposit:
+	push 0
+	sload
+	jump @ret
+numvotes:
+	push 4
+	calldataload
+	push 0
+	mstore
+	push 32
+	push 0
+	sha3
+	sload
+	jump @ret
+votes:
+	push 4
+	calldataload
+	push 0
+	mstore
+	push 36
+	calldataload
+	push 32
+	mstore
+	push 1
+	push 64
+	mstore
+	push 96
+	push 0
+	sha3
+	sload
+	jump @ret
+vote:
+	push 0
+	sload
+	callvalue
+	lt
+	jumpi @fail
+	push 4
+	calldataload
+	push 0
+	mstore
+	caller
+	push 32
+	mstore
+	push 64
+	push 0
+	sha3
+	dup1
+	sload
+	jumpi @fail
+	push 1
+	swap1
+	sstore
+	push 32
+	push 0
+	sha3
+	dup1
+	sload
+	dup1
+	push 32
+	mstore
+	push 1
+	push 64
+	mstore
+	caller
+	push 96
+	push 0
+	sha3
+	sstore
+	push 1
+	add
+	swap1
+	sstore
+	stop
+ret:
+	push 0
+	mstore
+	push 32
+	push 0
+	return
+fail:
+	push 0
+	dup1
+	revert
+`
+
+var (
+	testRecoveryContract = common.HexToAddress("0x5ec0")
+	testRecoveryDeposit  = big.NewInt(1000)
+)
+
+func recoveryContractCode(t *testing.T) []byte {
+	src := fmt.Sprintf(recoveryContractAsm,
+		abiObject.Methods["voted"].Id(),
+		abiObject.Methods["depositValue"].Id(),
+		abiObject.Methods["numVotes"].Id(),
+		abiObject.Methods["votes"].Id(),
+		abiObject.Methods["voteForSkipBlock"].Id())
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex("recovery", []byte(src), false))
+	bin, errs := compiler.Compile()
+	if len(errs) != 0 {
+		t.Fatalf("failed to compile recovery contract: %v", errs)
+	}
+	code, err := hex.DecodeString(bin)
 	if err != nil {
-		t.Fatalf("failed to generate keypair: %v", err)
+		t.Fatalf("failed to decode recovery contract: %v", err)
 	}
+	return code
+}
+
+// simulatedRecoveryBackend adapts the simulated backend to
+// RecoveryContractBackend.
+type simulatedRecoveryBackend struct {
+	*backends.SimulatedBackend
+}
 
-	r := NewRecovery(&params.RecoveryConfig{
-		Contract:     common.HexToAddress("f675c0e9bf4b949f50dcec5b224a70f0361d4680"),
-		Timeout:      30,
-		Confirmation: 1,
-	}, "https://rinkeby.infura.io", nil, key)
-	_, err = r.genVoteForSkipBlockTx(0)
+func (b *simulatedRecoveryBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	header, err := b.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
+
+func (b *simulatedRecoveryBackend) NetworkID(ctx context.Context) (*big.Int, error) {
+	return params.AllEthashProtocolChanges.ChainID, nil
+}
+
+func newTestRecoveryBackend(t *testing.T, keys []*ecdsa.PrivateKey) *simulatedRecoveryBackend {
+	alloc := core.GenesisAlloc{
+		testRecoveryContract: {
+			Code:    recoveryContractCode(t),
+			Balance: big.NewInt(0),
+			Storage: map[common.Hash]common.Hash{
+				{}: common.BigToHash(testRecoveryDeposit),
+			},
+		},
+	}
+	for _, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance: big.NewInt(params.Ether),
+		}
+	}
+	return &simulatedRecoveryBackend{backends.NewSimulatedBackend(alloc, 10000000)}
+}
+
+func newTestRecoveryKeys(t *testing.T, n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate keypair: %v", err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// testRecoveryGovernance is a fake governance for recovery tests.
+type testRecoveryGovernance struct {
+	notarySet map[string]struct{}
+	dkgSet    map[common.Address]struct{}
+}
+
+func newTestRecoveryGovernance(keys []*ecdsa.PrivateKey) *testRecoveryGovernance {
+	gov := &testRecoveryGovernance{
+		notarySet: make(map[string]struct{}),
+		dkgSet:    make(map[common.Address]struct{}),
+	}
+	for _, key := range keys {
+		gov.notarySet[hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))] = struct{}{}
+		gov.dkgSet[crypto.PubkeyToAddress(key.PublicKey)] = struct{}{}
+	}
+	return gov
+}
+
+func (g *testRecoveryGovernance) Round() uint64 { return 0 }
+
+func (g *testRecoveryGovernance) NotarySet(uint64) (map[string]struct{}, error) {
+	return g.notarySet, nil
+}
+
+func (g *testRecoveryGovernance) DKGSetNodeKeyAddresses(uint64) (map[common.Address]struct{}, error) {
+	return g.dkgSet, nil
+}
+
+func sortedAddresses(addrs []common.Address) []common.Address {
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Big().Cmp(addrs[j].Big()) < 0
+	})
+	return addrs
+}
+
+func TestRecoveryVoteTxGeneration(t *testing.T) {
+	keys := newTestRecoveryKeys(t, 1)
+	backend := newTestRecoveryBackend(t, keys)
+	r := NewContractRecoveryBackend(backend, testRecoveryContract, keys[0])
+
+	tx, err := r.genVoteForSkipBlockTx(0)
 	if err != nil {
 		t.Fatalf("failed to generate voteForSkipBlock tx: %v", err)
 	}
+	if *tx.To() != testRecoveryContract {
+		t.Errorf("tx to mismatch: have %x, want %x", tx.To(), testRecoveryContract)
+	}
+	if tx.Value().Cmp(testRecoveryDeposit) != 0 {
+		t.Errorf("tx value mismatch: have %v, want %v", tx.Value(), testRecoveryDeposit)
+	}
+	if tx.GasPrice().Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("tx gas price mismatch: have %v, want 3", tx.GasPrice())
+	}
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	sender, err := types.Sender(signer, tx)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if sender != crypto.PubkeyToAddress(keys[0].PublicKey) {
+		t.Errorf("tx sender mismatch")
+	}
+}
+
+func TestContractRecoveryBackend(t *testing.T) {
+	keys := newTestRecoveryKeys(t, 4)
+	backend := newTestRecoveryBackend(t, keys)
+	// The last node is not in the notary set.
+	gov := newTestRecoveryGovernance(keys[:3])
+
+	recoveries := make([]*Recovery, len(keys))
+	for i, key := range keys {
+		recoveries[i] = NewRecovery(gov, key,
+			NewContractRecoveryBackend(backend, testRecoveryContract, key))
+	}
+
+	if err := recoveries[3].ProposeSkipBlock(10); err == nil {
+		t.Errorf("expect error proposing skip block out of notary set")
+	}
+	for _, r := range recoveries[:2] {
+		if err := r.ProposeSkipBlock(10); err != nil {
+			t.Fatalf("failed to propose skip block: %v", err)
+		}
+	}
+	backend.Commit()
+
+	// Votes are not confirmed yet.
+	votes, err := recoveries[0].Votes(10)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 0 {
+		t.Errorf("expect 0 confirmed votes, got %d", votes)
+	}
+	backend.Commit()
+
+	votes, err = recoveries[0].Votes(10)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 2 {
+		t.Errorf("expect 2 votes, got %d", votes)
+	}
+
+	// Voting again is ignored.
+	if _, err := recoveries[0].backend.(*ContractRecoveryBackend).genVoteForSkipBlockTx(10); err != errAlreadyVoted {
+		t.Errorf("expect errAlreadyVoted, got %v", err)
+	}
+	if err := recoveries[0].ProposeSkipBlock(10); err != nil {
+		t.Errorf("failed to propose skip block again: %v", err)
+	}
+
+	// Votes out of the DKG set are not counted.
+	voter := NewContractRecoveryBackend(backend, testRecoveryContract, keys[3])
+	if err := voter.VoteForSkipBlock(10); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	backend.Commit()
+	backend.Commit()
+
+	voters, err := voter.Voters(10, newTestRecoveryGovernance(keys).dkgSet)
+	if err != nil {
+		t.Fatalf("failed to get voters: %v", err)
+	}
+	if len(voters) != 3 {
+		t.Errorf("expect 3 voters, got %d", len(voters))
+	}
+	votes, err = recoveries[0].Votes(10)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 2 {
+		t.Errorf("expect 2 votes, got %d", votes)
+	}
+	if votes, err := recoveries[0].Votes(11); err != nil || votes != 0 {
+		t.Errorf("expect no votes for other height, got %d, %v", votes, err)
+	}
+}
+
+func TestContractRecoveryBackendVoteSpam(t *testing.T) {
+	keys := newTestRecoveryKeys(t, 8)
+	backend := newTestRecoveryBackend(t, keys)
+	// Only the first two nodes are in the notary set, the others outnumber
+	// them on the contract.
+	gov := newTestRecoveryGovernance(keys[:2])
+
+	for _, key := range keys[1:] {
+		voter := NewContractRecoveryBackend(backend, testRecoveryContract, key)
+		if err := voter.VoteForSkipBlock(10); err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+	backend.Commit()
+	backend.Commit()
+
+	r := NewRecovery(gov, keys[0],
+		NewContractRecoveryBackend(backend, testRecoveryContract, keys[0]))
+	votes, err := r.Votes(10)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 1 {
+		t.Errorf("expect 1 vote, got %d", votes)
+	}
+}
+
+// RecoveryTestEthAPI serves the eth_ methods used by RecoveryRPCClient.Voters
+// from a simulated backend.
+type RecoveryTestEthAPI struct {
+	b     *simulatedRecoveryBackend
+	calls int
+}
+
+type RecoveryTestCallArgs struct {
+	From common.Address  `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+func (api *RecoveryTestEthAPI) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
+	number, err := api.b.BlockNumber(ctx)
+	return hexutil.Uint64(number), err
+}
+
+func (api *RecoveryTestEthAPI) Call(ctx context.Context,
+	args RecoveryTestCallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	api.calls++
+	return api.b.CallContract(ctx, dexon.CallMsg{
+		From: args.From,
+		To:   args.To,
+		Data: args.Data,
+	}, big.NewInt(blockNr.Int64()))
+}
+
+func TestRecoveryRPCClientVoters(t *testing.T) {
+	keys := newTestRecoveryKeys(t, 3)
+	backend := newTestRecoveryBackend(t, keys)
+
+	want := []common.Address{}
+	for _, key := range keys {
+		voter := NewContractRecoveryBackend(backend, testRecoveryContract, key)
+		if err := voter.VoteForSkipBlock(5); err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+		want = append(want, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	backend.Commit()
+	backend.Commit()
+
+	api := &RecoveryTestEthAPI{b: backend}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := NewRecoveryRPCClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	voters, err := NewContractRecoveryBackend(
+		client, testRecoveryContract, keys[0]).Voters(5,
+		newTestRecoveryGovernance(keys).dkgSet)
+	if err != nil {
+		t.Fatalf("failed to get voters: %v", err)
+	}
+	if fmt.Sprint(sortedAddresses(voters)) != fmt.Sprint(sortedAddresses(want)) {
+		t.Errorf("voters mismatch: have %v, want %v", voters, want)
+	}
+	// One call for each candidate.
+	if api.calls != len(keys) {
+		t.Errorf("expect %d calls, got %d", len(keys), api.calls)
+	}
+}
+
+func TestFileRecoveryBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recovery-votes")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keys := newTestRecoveryKeys(t, 4)
+	gov := newTestRecoveryGovernance(keys[:3])
+
+	recoveries := make([]*Recovery, len(keys))
+	for i, key := range keys {
+		recoveries[i] = NewRecovery(gov, key,
+			NewFileRecoveryBackend(dir, big.NewInt(1), key))
+	}
+	for _, r := range recoveries[:2] {
+		if err := r.ProposeSkipBlock(7); err != nil {
+			t.Fatalf("failed to propose skip block: %v", err)
+		}
+	}
+	if err := recoveries[0].backend.VoteForSkipBlock(7); err != errAlreadyVoted {
+		t.Errorf("expect errAlreadyVoted, got %v", err)
+	}
+	if err := recoveries[3].backend.VoteForSkipBlock(7); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	votes, err := recoveries[2].Votes(7)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 2 {
+		t.Errorf("expect 2 votes, got %d", votes)
+	}
+	if votes, err := recoveries[2].Votes(8); err != nil || votes != 0 {
+		t.Errorf("expect no votes for other height, got %d, %v", votes, err)
+	}
+
+	// Votes copied or forged for another node are ignored.
+	path := filepath.Join(dir, "7", crypto.PubkeyToAddress(keys[2].PublicKey).Hex())
+	copied, err := ioutil.ReadFile(
+		filepath.Join(dir, "7", crypto.PubkeyToAddress(keys[0].PublicKey).Hex()))
+	if err != nil {
+		t.Fatalf("failed to read vote: %v", err)
+	}
+	forged := fmt.Sprintf(`{"height":"0x7","voter":"%s","signature":"0x%x"}`,
+		crypto.PubkeyToAddress(keys[2].PublicKey).Hex(), make([]byte, 65))
+	for _, data := range [][]byte{copied, []byte(forged), []byte("garbage")} {
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("failed to write vote: %v", err)
+		}
+		votes, err = recoveries[2].Votes(7)
+		if err != nil {
+			t.Fatalf("failed to get votes: %v", err)
+		}
+		if votes != 2 {
+			t.Errorf("expect 2 votes, got %d", votes)
+		}
+	}
+
+	// Votes signed for another chain are ignored.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove vote: %v", err)
+	}
+	other := NewFileRecoveryBackend(dir, big.NewInt(2), keys[2])
+	if err := other.VoteForSkipBlock(7); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	votes, err = recoveries[2].Votes(7)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if votes != 2 {
+		t.Errorf("expect 2 votes, got %d", votes)
+	}
 }
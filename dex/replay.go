@@ -0,0 +1,291 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	"github.com/dexon-foundation/dexon-consensus/core/syncer"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+var errReplayStopped = errors.New("replay stopped")
+
+// replayNetwork is the network of a consensus core replaying a journal. The
+// journaled messages are received from it, and the messages sent by the core
+// are logged instead, which is the decision path being reproduced.
+type replayNetwork struct {
+	receiveCh       chan coreTypes.Msg
+	reportBadPeerCh chan interface{}
+}
+
+func newReplayNetwork() *replayNetwork {
+	return &replayNetwork{
+		receiveCh:       make(chan coreTypes.Msg, 1024),
+		reportBadPeerCh: make(chan interface{}, 128),
+	}
+}
+
+// PullBlocks tries to pull blocks from the DEXON network.
+func (n *replayNetwork) PullBlocks(hashes coreCommon.Hashes) {
+	log.Debug("Replay pull blocks", "hashes", hashes)
+}
+
+// PullVotes tries to pull votes from the DEXON network.
+func (n *replayNetwork) PullVotes(pos coreTypes.Position) {
+	log.Debug("Replay pull votes", "position", &pos)
+}
+
+// BroadcastVote broadcasts vote to all nodes in DEXON network.
+func (n *replayNetwork) BroadcastVote(vote *coreTypes.Vote) {
+	log.Info("Replay broadcast vote", "vote", vote)
+}
+
+// BroadcastBlock broadcasts block to all nodes in DEXON network.
+func (n *replayNetwork) BroadcastBlock(block *coreTypes.Block) {
+	log.Info("Replay broadcast block", "block", block,
+		"finalized", block.IsFinalized())
+}
+
+// SendDKGPrivateShare sends PrivateShare to a DKG participant.
+func (n *replayNetwork) SendDKGPrivateShare(
+	pub coreCrypto.PublicKey, prvShare *dkgTypes.PrivateShare) {
+	log.Info("Replay send DKG private share", "round", prvShare.Round,
+		"reset", prvShare.Reset, "receiver", prvShare.ReceiverID.String())
+}
+
+// BroadcastDKGPrivateShare broadcasts PrivateShare to all DKG participants.
+func (n *replayNetwork) BroadcastDKGPrivateShare(
+	prvShare *dkgTypes.PrivateShare) {
+	log.Info("Replay broadcast DKG private share", "round", prvShare.Round,
+		"reset", prvShare.Reset, "receiver", prvShare.ReceiverID.String())
+}
+
+// BroadcastDKGPartialSignature broadcasts partialSignature to all
+// DKG participants.
+func (n *replayNetwork) BroadcastDKGPartialSignature(
+	psig *dkgTypes.PartialSignature) {
+	log.Info("Replay broadcast DKG partial signature", "round", psig.Round,
+		"hash", psig.Hash.String())
+}
+
+// BroadcastAgreementResult broadcasts rand request to DKG set.
+func (n *replayNetwork) BroadcastAgreementResult(
+	result *coreTypes.AgreementResult) {
+	log.Info("Replay broadcast agreement result", "result", result)
+}
+
+// ReceiveChan returns a channel to receive messages from DEXON network.
+func (n *replayNetwork) ReceiveChan() <-chan coreTypes.Msg {
+	return n.receiveCh
+}
+
+// ReportBadPeerChan returns a channel to receive messages from DEXON network.
+func (n *replayNetwork) ReportBadPeerChan() chan<- interface{} {
+	return n.reportBadPeerCh
+}
+
+// replayProposer runs a consensus core on top of the local chain and feeds it
+// the messages of a consensus journal at the pace they were received, to
+// reproduce the decision path of the node which recorded the journal. The
+// node must be isolated from the network, and should run on a copy of the
+// recording node's data directory taken before the journaled messages.
+type replayProposer struct {
+	mu      sync.Mutex
+	running int32
+	dex     *Dexon
+	path    string
+	dMoment time.Time
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func newReplayProposer(dex *Dexon, path string,
+	dMoment time.Time) *replayProposer {
+	return &replayProposer{
+		dex:     dex,
+		path:    path,
+		dMoment: dMoment,
+	}
+}
+
+func (p *replayProposer) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&p.running, 0, 1) {
+		return fmt.Errorf("block proposer is already running")
+	}
+	log.Info("Started replaying consensus journal", "path", p.path)
+
+	p.stopCh = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer atomic.StoreInt32(&p.running, 0)
+
+		network := newReplayNetwork()
+		c, err := p.newConsensus(network)
+		if err != nil {
+			log.Error("Failed to create consensus core to replay", "err", err)
+			return
+		}
+		go c.Run()
+
+		err = p.replay(network)
+		switch err {
+		case nil:
+			log.Info("Consensus journal replayed, the core keeps running")
+		case errReplayStopped:
+		default:
+			log.Error("Failed to replay consensus journal", "err", err)
+		}
+		<-p.stopCh
+		c.Stop()
+	}()
+	return nil
+}
+
+func (p *replayProposer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if atomic.LoadInt32(&p.running) == 1 {
+		close(p.stopCh)
+		p.wg.Wait()
+	}
+	log.Info("Consensus journal replay stopped")
+}
+
+func (p *replayProposer) IsCoreSyncing() bool {
+	return false
+}
+
+func (p *replayProposer) IsProposing() bool {
+	return atomic.LoadInt32(&p.running) == 1
+}
+
+// newConsensus creates a consensus core continuing the local chain.
+func (p *replayProposer) newConsensus(
+	network dexCore.Network) (*dexCore.Consensus, error) {
+	db := newCoreDatabase(p.dex)
+	privkey := coreEcdsa.NewPrivateKeyFromECDSA(p.dex.config.PrivateKey)
+
+	head := p.dex.blockchain.CurrentBlock()
+	if head.NumberU64() == 0 {
+		return dexCore.NewConsensus(p.dMoment, p.dex.app, p.dex.governance,
+			db, network, privkey, log.Root()), nil
+	}
+
+	consensusSync := syncer.NewConsensus(head.NumberU64(), p.dMoment,
+		p.dex.app, p.dex.governance, db, network, privkey, log.Root())
+	_, coreHeight := db.GetCompactionChainTipInfo()
+	var blocks []*coreTypes.Block
+	for height := coreHeight + 1; height <= head.NumberU64(); height++ {
+		var block coreTypes.Block
+		header := p.dex.blockchain.GetHeaderByNumber(height)
+		if err := rlp.DecodeBytes(header.DexconMeta, &block); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+	if len(blocks) > 0 {
+		if _, err := consensusSync.SyncBlocks(blocks, false); err != nil {
+			return nil, err
+		}
+	}
+	var last coreTypes.Block
+	if err := rlp.DecodeBytes(head.Header().DexconMeta, &last); err != nil {
+		return nil, err
+	}
+	consensusSync.ForceSync(last.Position, false)
+	return consensusSync.GetSyncedConsensus()
+}
+
+// replay feeds the journal to the network keeping the intervals between the
+// messages.
+func (p *replayProposer) replay(network *replayNetwork) error {
+	// Peers reported by the core are only logged, there's nothing to remove.
+	go func() {
+		for {
+			select {
+			case id := <-network.reportBadPeerCh:
+				log.Info("Replay report bad peer", "id", id)
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+
+	var (
+		start    = time.Now()
+		first    uint64
+		entries  int
+		messages int
+	)
+	err := readCoreJournal(p.path, func(entry *journalEntry) error {
+		if entries == 0 {
+			first = entry.Time
+		}
+		entries++
+		if entry.Time > first {
+			delay := time.Duration(entry.Time-first) - time.Since(start)
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-p.stopCh:
+					return errReplayStopped
+				}
+			}
+		}
+		msgs, err := entry.messages()
+		if err != nil {
+			log.Warn("Failed to decode journal entry", "code", entry.Code,
+				"peer", entry.PeerID, "err", err)
+			return nil
+		}
+		for _, msg := range msgs {
+			select {
+			case network.receiveCh <- coreTypes.Msg{
+				PeerID:  entry.PeerID,
+				Payload: msg,
+			}:
+				messages++
+			case <-p.stopCh:
+				return errReplayStopped
+			}
+		}
+		return nil
+	})
+	log.Info("Replayed consensus journal", "entries", entries,
+		"messages", messages)
+	return err
+}
@@ -0,0 +1,273 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+const (
+	scoreHalfLife      = 10 * time.Minute // Time for a score to decay to half
+	maxPeerScore       = 50               // Score rewards are capped at
+	banPeerScore       = -100             // Score a peer is banned at
+	tempBanDuration    = time.Hour        // Duration of a temporary ban
+	persistentBanCount = 3                // Number of bans a peer is banned persistently at
+	banRecordTTL       = 7 * 24 * time.Hour
+	maxTrackedScores   = 1024 // Number of scores kept before pruning the decayed ones
+)
+
+var errPeerBanned = errors.New("peer banned")
+
+// scoreEvent is a behaviour of a peer which adjusts its score.
+type scoreEvent int
+
+const (
+	scoreBadPeer         scoreEvent = iota // Invalid vote or block
+	scoreProtocolError                     // Undecodable or unexpected message
+	scorePullAbuse                         // Pull request within the rate limit
+	scoreUselessResponse                   // Sync response rejected by the downloader or fetcher
+	scoreUsefulMessage                     // Core message or sync response handled
+)
+
+var scoreEventValues = map[scoreEvent]float64{
+	scoreBadPeer:         -50,
+	scoreProtocolError:   -20,
+	scorePullAbuse:       -5,
+	scoreUselessResponse: -10,
+	scoreUsefulMessage:   1,
+}
+
+func (e scoreEvent) String() string {
+	switch e {
+	case scoreBadPeer:
+		return "bad peer"
+	case scoreProtocolError:
+		return "protocol error"
+	case scorePullAbuse:
+		return "pull abuse"
+	case scoreUselessResponse:
+		return "useless response"
+	case scoreUsefulMessage:
+		return "useful message"
+	}
+	return "unknown"
+}
+
+// rewarded reports whether handling a message of the code rewards the peer.
+func rewarded(code uint64) bool {
+	switch code {
+	case CoreBlockMsg, VoteMsg, AgreementMsg, DKGPrivateShareMsg,
+		DKGPartialSignatureMsg, BlockHeadersMsg, BlockBodiesMsg, ReceiptsMsg,
+		NodeDataMsg, GovStateMsg, GovStateSliceMsg:
+		return true
+	}
+	return false
+}
+
+// PeerScore is the reputation of a peer.
+type PeerScore struct {
+	ID     string  `json:"id"`
+	Score  float64 `json:"score"`
+	Exempt bool    `json:"exempt"`
+	Banned bool    `json:"banned"`
+	Until  uint64  `json:"until,omitempty"` // Unix time the ban expires at, zero if persistent
+	Bans   uint64  `json:"bans"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+type peerScore struct {
+	value   float64
+	updated time.Time
+}
+
+// peerReputation scores the peers by their behaviour. The scores decay over
+// time, and a peer is banned once its score drops to banPeerScore. The bans
+// are persisted, and become persistent after persistentBanCount bans. The
+// direct peers of the notary sets are never banned, the consensus depends on
+// them.
+type peerReputation struct {
+	db     ethdb.Database
+	exempt func(id string) bool
+	now    func() time.Time
+
+	lock   sync.Mutex
+	scores map[string]*peerScore
+	bans   map[string]*rawdb.PeerBan
+}
+
+func newPeerReputation(db ethdb.Database,
+	exempt func(id string) bool) *peerReputation {
+	r := &peerReputation{
+		db:     db,
+		exempt: exempt,
+		now:    time.Now,
+		scores: make(map[string]*peerScore),
+		bans:   make(map[string]*rawdb.PeerBan),
+	}
+	for _, ban := range rawdb.ReadPeerBans(db) {
+		r.bans[ban.ID] = ban
+	}
+	return r
+}
+
+// score returns the score of the peer decayed to now, the lock must be held.
+func (r *peerReputation) score(id string) *peerScore {
+	now := r.now()
+	s, ok := r.scores[id]
+	if !ok {
+		s = &peerScore{updated: now}
+		r.scores[id] = s
+		return s
+	}
+	elapsed := now.Sub(s.updated)
+	s.value *= math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+	s.updated = now
+	return s
+}
+
+// adjust adjusts the score of the peer by the event, and reports whether the
+// peer is banned by it.
+func (r *peerReputation) adjust(id string, event scoreEvent) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.scores) >= maxTrackedScores {
+		r.prune()
+	}
+	s := r.score(id)
+	s.value = math.Min(s.value+scoreEventValues[event], maxPeerScore)
+	if s.value > banPeerScore || r.exempt(id) {
+		return false
+	}
+	delete(r.scores, id)
+	r.ban(id, event.String())
+	return true
+}
+
+// ban bans the peer, the lock must be held.
+func (r *peerReputation) ban(id string, reason string) {
+	now := r.now()
+	ban, ok := r.bans[id]
+	if !ok {
+		ban = &rawdb.PeerBan{ID: id}
+		r.bans[id] = ban
+	}
+	ban.Count++
+	ban.Reason = reason
+	if ban.Count >= persistentBanCount {
+		ban.Until = 0
+	} else {
+		ban.Until = uint64(now.Add(tempBanDuration).Unix())
+	}
+	log.Info("Banned peer", "id", id, "reason", reason, "count", ban.Count,
+		"persistent", ban.Until == 0)
+	r.persist()
+}
+
+// banned reports whether the peer is banned.
+func (r *peerReputation) banned(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ban, ok := r.bans[id]
+	if !ok {
+		return false
+	}
+	return ban.Until == 0 || ban.Until > uint64(r.now().Unix())
+}
+
+// unban lifts the ban of the peer and forgets its previous bans, and reports
+// whether the peer was banned.
+func (r *peerReputation) unban(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.bans[id]; !ok {
+		return false
+	}
+	delete(r.bans, id)
+	delete(r.scores, id)
+	r.persist()
+	log.Info("Unbanned peer", "id", id)
+	return true
+}
+
+// prune forgets the decayed scores, the lock must be held.
+func (r *peerReputation) prune() {
+	for id := range r.scores {
+		if s := r.score(id); math.Abs(s.value) < 1 {
+			delete(r.scores, id)
+		}
+	}
+}
+
+// persist stores the bans, the expired temporary bans are forgotten after
+// banRecordTTL. The lock must be held.
+func (r *peerReputation) persist() {
+	expiry := uint64(r.now().Add(-banRecordTTL).Unix())
+	bans := make([]*rawdb.PeerBan, 0, len(r.bans))
+	for id, ban := range r.bans {
+		if ban.Until != 0 && ban.Until < expiry {
+			delete(r.bans, id)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].ID < bans[j].ID })
+	rawdb.WritePeerBans(r.db, bans)
+}
+
+// peerScores returns the reputation of the scored and banned peers.
+func (r *peerReputation) peerScores() []*PeerScore {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	infos := make(map[string]*PeerScore)
+	info := func(id string) *PeerScore {
+		if _, ok := infos[id]; !ok {
+			infos[id] = &PeerScore{ID: id, Exempt: r.exempt(id)}
+		}
+		return infos[id]
+	}
+	for id := range r.scores {
+		info(id).Score = r.score(id).value
+	}
+	now := uint64(r.now().Unix())
+	for id, ban := range r.bans {
+		i := info(id)
+		i.Banned = ban.Until == 0 || ban.Until > now
+		i.Until = ban.Until
+		i.Bans = ban.Count
+		i.Reason = ban.Reason
+	}
+
+	list := make([]*PeerScore, 0, len(infos))
+	for _, i := range infos {
+		list = append(list, i)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+func TestPeerReputation(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	now := time.Now()
+	exempt := map[string]bool{"notary": true}
+	newReputation := func() *peerReputation {
+		r := newPeerReputation(db, func(id string) bool { return exempt[id] })
+		r.now = func() time.Time { return now }
+		return r
+	}
+	r := newReputation()
+
+	// Scores decay by half every half life.
+	r.adjust("peer", scoreBadPeer)
+	now = now.Add(scoreHalfLife)
+	if score := r.peerScores()[0].Score; math.Abs(score+25) > 1e-9 {
+		t.Errorf("decayed score mismatch: have %v, want -25", score)
+	}
+	// Rewards are capped.
+	for i := 0; i < 2*maxPeerScore; i++ {
+		r.adjust("good", scoreUsefulMessage)
+	}
+	for _, s := range r.peerScores() {
+		if s.ID == "good" && s.Score != maxPeerScore {
+			t.Errorf("capped score mismatch: have %v, want %v",
+				s.Score, maxPeerScore)
+		}
+	}
+
+	// Peers are banned temporarily, and persistently after persistentBanCount
+	// bans.
+	for count := 1; count <= persistentBanCount; count++ {
+		if r.adjust("peer", scoreBadPeer) {
+			t.Fatalf("peer banned before reaching the ban score")
+		}
+		if !r.adjust("peer", scoreBadPeer) {
+			t.Fatalf("peer not banned at the ban score")
+		}
+		if !r.banned("peer") {
+			t.Fatalf("peer not banned")
+		}
+		now = now.Add(tempBanDuration + time.Second)
+		if banned := r.banned("peer"); banned != (count == persistentBanCount) {
+			t.Errorf("ban %d persistence mismatch: banned %t", count, banned)
+		}
+	}
+
+	// Exempted peers are never banned.
+	for i := 0; i < 10; i++ {
+		if r.adjust("notary", scoreBadPeer) {
+			t.Fatalf("exempted peer banned")
+		}
+	}
+
+	// Bans survive restarts until lifted.
+	r = newReputation()
+	if !r.banned("peer") {
+		t.Fatalf("persistent ban not restored")
+	}
+	if !r.unban("peer") || r.banned("peer") {
+		t.Errorf("peer not unbanned")
+	}
+	if r.unban("peer") {
+		t.Errorf("unbanned peer unbanned again")
+	}
+	if r = newReputation(); r.banned("peer") {
+		t.Errorf("unbanned peer banned after restart")
+	}
+}
+
+func TestRejectBannedPeer(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := enode.NewV4(&key.PublicKey, net.IP{}, 0, 0)
+	_, pipenet := p2p.MsgPipe()
+	peer := pm.newPeer(dex64, p2p.NewPeerWithEnode(node, "peer", nil), pipenet)
+
+	for !pm.reputation.adjust(peer.id, scoreBadPeer) {
+	}
+	if err := pm.handle(peer); err != p2p.DiscUselessPeer {
+		t.Errorf("banned peer not rejected: %v", err)
+	}
+}
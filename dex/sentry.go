@@ -0,0 +1,237 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/p2p"
+)
+
+const (
+	relayedMsgCacheSize = 8192 // Number of relayed core messages to dedupe
+)
+
+// relayed reports whether messages of the code are relayed by sentries.
+func relayed(code uint64) bool {
+	switch code {
+	case CoreBlockMsg, VoteMsg, AgreementMsg, DKGPrivateShareMsg,
+		DKGPartialSignatureMsg, PullBlocksMsg, PullVotesMsg:
+		return true
+	}
+	return false
+}
+
+// consensusPeers returns the peers to send the consensus messages of the round
+// to, the sentries relay them if the node is protected by sentries.
+func (pm *ProtocolManager) consensusPeers(round uint64) []*peer {
+	if pm.peers.IsProtected() {
+		return pm.peers.PeersWithLabel(peerLabel{set: sentryset})
+	}
+	return pm.peers.PeersWithLabel(peerLabel{set: notaryset, round: round})
+}
+
+// relayCoreMessage relays a consensus message as a sentry. The messages of the
+// protected validators are sent to the notary set of the round, and the other
+// ones to the validators, which verify them before passing them to consensus.
+// The votes, core blocks and agreement results are verified and relayed once.
+// The DKG private shares are sent to their receivers only, and pull requests
+// are only relayed for the validators.
+func (pm *ProtocolManager) relayCoreMessage(p *peer, msg p2p.Msg) error {
+	fromValidator := pm.peers.HasLabel(p.id, peerLabel{set: protectedset})
+	targets := func(round uint64) []*peer {
+		return pm.relayTargets(p.id, round)
+	}
+
+	switch msg.Code {
+	case CoreBlockMsg:
+		var blocks []*coreTypes.Block
+		if err := msg.Decode(&blocks); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, block := range blocks {
+			if !pm.relayedMsgs.Contains(relayKey(block)) {
+				pm.relayVerifier.enqueue(p.id, block)
+			}
+		}
+	case VoteMsg:
+		var votes []*coreTypes.Vote
+		if err := msg.Decode(&votes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, vote := range votes {
+			if !pm.relayedMsgs.Contains(relayKey(vote)) {
+				pm.relayVerifier.enqueue(p.id, vote)
+			}
+		}
+	case AgreementMsg:
+		var agreement coreTypes.AgreementResult
+		if err := msg.Decode(&agreement); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.MarkAgreement(agreement.Position)
+		if !pm.relayedMsgs.Contains(relayKey(&agreement)) {
+			pm.relayVerifier.enqueue(p.id, &agreement)
+		}
+	case DKGPrivateShareMsg:
+		var ps dkgTypes.PrivateShare
+		if err := msg.Decode(&ps); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		hash := rlpHash(&ps)
+		p.MarkDKGPrivateShares(hash)
+		// The shares are never broadcast, the ones of the validators are
+		// sent to the receiver if it's connected, and the other ones to the
+		// validator being the receiver.
+		var peers []*peer
+		if fromValidator {
+			peers = pm.peers.Peers()
+		} else {
+			peers = pm.peers.PeersWithLabel(peerLabel{set: protectedset})
+		}
+		peer := shareReceiver(peers, ps.ReceiverID)
+		if peer == nil || peer == p {
+			log.Debug("DKG private share receiver not connected",
+				"receiver", ps.ReceiverID)
+			break
+		}
+		if !peer.knownDKGPrivateShares.Contains(hash) {
+			peer.AsyncSendDKGPrivateShare(&ps)
+		}
+	case DKGPartialSignatureMsg:
+		var psig dkgTypes.PartialSignature
+		if err := msg.Decode(&psig); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for _, peer := range targets(psig.Round) {
+			peer.AsyncSendDKGPartialSignature(&psig)
+		}
+	case PullBlocksMsg:
+		if !fromValidator {
+			break
+		}
+		var hashes coreCommon.Hashes
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for idx, peer := range targets(pm.gov.Round()) {
+			if idx >= maxPullPeers {
+				break
+			}
+			peer.AsyncSendPullBlocks(hashes)
+		}
+	case PullVotesMsg:
+		if !fromValidator {
+			break
+		}
+		var pos coreTypes.Position
+		if err := msg.Decode(&pos); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		for idx, peer := range targets(pos.Round) {
+			if idx >= maxPullVotePeers {
+				break
+			}
+			peer.AsyncSendPullVotes(pos)
+		}
+	}
+	return nil
+}
+
+// shareReceiver returns the peer being the receiver of a DKG private share, nil
+// if it's not one of the peers.
+func shareReceiver(peers []*peer, receiverID coreTypes.NodeID) *peer {
+	for _, p := range peers {
+		pubkey, err := coreEcdsa.NewPublicKeyFromByteSlice(
+			crypto.FromECDSAPub(p.Node().Pubkey()))
+		if err != nil {
+			continue
+		}
+		if coreTypes.NewNodeID(pubkey) == receiverID {
+			return p
+		}
+	}
+	return nil
+}
+
+// relayTargets returns the peers to relay a core message of the round from the
+// peer to.
+func (pm *ProtocolManager) relayTargets(from string, round uint64) []*peer {
+	var peers []*peer
+	if pm.peers.HasLabel(from, peerLabel{set: protectedset}) {
+		peers = pm.peers.PeersWithLabel(peerLabel{set: notaryset, round: round})
+	} else {
+		peers = pm.peers.PeersWithLabel(peerLabel{set: protectedset})
+	}
+	for i, peer := range peers {
+		if peer.id == from {
+			return append(peers[:i], peers[i+1:]...)
+		}
+	}
+	return peers
+}
+
+// relayLoop relays the core messages verified by the relay verifier, each
+// message is relayed once.
+func (pm *ProtocolManager) relayLoop() {
+	for {
+		select {
+		case msg := <-pm.relayCh:
+			if seen, _ := pm.relayedMsgs.ContainsOrAdd(
+				relayKey(msg.Payload), struct{}{}); seen {
+				continue
+			}
+			from, _ := msg.PeerID.(string)
+			switch payload := msg.Payload.(type) {
+			case *coreTypes.Block:
+				for _, peer := range pm.relayTargets(from, payload.Position.Round) {
+					peer.AsyncSendCoreBlocks([]*coreTypes.Block{payload})
+				}
+			case *coreTypes.Vote:
+				for _, peer := range pm.relayTargets(from, payload.Position.Round) {
+					peer.AsyncSendVotes([]*coreTypes.Vote{payload})
+				}
+			case *coreTypes.AgreementResult:
+				for _, peer := range pm.relayTargets(from, payload.Position.Round) {
+					if peer.MarkAgreement(payload.Position) {
+						peer.AsyncSendAgreement(payload)
+					}
+				}
+			}
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
+
+// relayKey returns the key of a relayed core message in the seen cache.
+func relayKey(payload interface{}) coreCommon.Hash {
+	switch msg := payload.(type) {
+	case *coreTypes.Block:
+		return msg.Hash
+	case *coreTypes.Vote:
+		return coreUtils.HashVote(msg)
+	}
+	return coreCommon.Hash(rlpHash(payload))
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+func TestRelayCoreMessages(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	validator, _ := newTestPeer("validator", dex64, pm, true)
+	defer validator.close()
+	notary, _ := newTestPeer("notary", dex64, pm, true)
+	defer notary.close()
+	waitForRegister(pm, 2)
+
+	// The sentry relays for the validator in the notary set of round 10.
+	pm.peers.label2Nodes = map[peerLabel]map[string]*enode.Node{
+		{set: protectedset}: {
+			validator.id: validator.Node(),
+		},
+		{set: notaryset, round: 10}: {
+			validator.id: validator.Node(),
+			notary.id:    notary.Node(),
+		},
+	}
+
+	newVote := func(period uint64) *coreTypes.Vote {
+		vote := &coreTypes.Vote{
+			VoteHeader: coreTypes.VoteHeader{
+				Period:   period,
+				Position: coreTypes.Position{Round: 10, Height: 13},
+			},
+			PartialSignature: dkg.PartialSignature{
+				Type:      "456",
+				Signature: []byte("psig"),
+			},
+		}
+		if err := testSigner.SignVote(vote); err != nil {
+			t.Fatalf("sign vote error: %v", err)
+		}
+		return vote
+	}
+	relay := func(from, to *testPeer, vote *coreTypes.Vote) {
+		if err := p2p.Send(from.app, VoteMsg, []*coreTypes.Vote{vote}); err != nil {
+			t.Fatalf("send error: %v", err)
+		}
+		msg, err := to.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("%v: read error: %v", to.Peer, err)
+		}
+		if msg.Code != VoteMsg {
+			t.Fatalf("%v: got code %d, want %d", to.Peer, msg.Code, VoteMsg)
+		}
+		var votes []*coreTypes.Vote
+		if err := msg.Decode(&votes); err != nil {
+			t.Fatalf("%v: %v", to.Peer, err)
+		}
+		if !reflect.DeepEqual(votes, []*coreTypes.Vote{vote}) {
+			t.Errorf("vote mismatch")
+		}
+	}
+	vote := newVote(1)
+	relay(validator, notary, vote)
+	relay(notary, validator, newVote(2))
+
+	// The agreements are relayed once as well.
+	agreement := &coreTypes.AgreementResult{
+		Position:   coreTypes.Position{Round: 10, Height: 13},
+		Randomness: []byte("randomness"),
+	}
+	for i := 0; i < 2; i++ {
+		if err := p2p.Send(validator.app, AgreementMsg, agreement); err != nil {
+			t.Fatalf("send error: %v", err)
+		}
+	}
+	if err := p2p.ExpectMsg(notary.app, AgreementMsg, agreement); err != nil {
+		t.Errorf("agreement mismatch: %v", err)
+	}
+
+	// The relayed and the forged votes are not relayed.
+	forged := newVote(3)
+	forged.Period = 4
+	if err := p2p.Send(notary.app, VoteMsg, []*coreTypes.Vote{vote, forged}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		validator.close()
+		notary.close()
+	}()
+	if _, err := validator.app.ReadMsg(); err != p2p.ErrPipeClosed {
+		t.Errorf("err mismatch: got %v, want %v (vote not relayed)",
+			err, p2p.ErrPipeClosed)
+	}
+	if _, err := notary.app.ReadMsg(); err != p2p.ErrPipeClosed {
+		t.Errorf("err mismatch: got %v, want %v (agreement not relayed)",
+			err, p2p.ErrPipeClosed)
+	}
+
+	// The sentry doesn't pass the messages to the consensus.
+	select {
+	case <-pm.ReceiveChan():
+		t.Errorf("relayed message received")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRelayDKGPrivateShare(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	validator, _ := newTestPeer("validator", dex64, pm, true)
+	defer validator.close()
+	receiver, _ := newTestPeer("receiver", dex64, pm, true)
+	defer receiver.close()
+	other, _ := newTestPeer("other", dex64, pm, true)
+	waitForRegister(pm, 3)
+
+	pm.peers.label2Nodes = map[peerLabel]map[string]*enode.Node{
+		{set: protectedset}: {
+			validator.id: validator.Node(),
+		},
+		{set: notaryset, round: 10}: {
+			validator.id: validator.Node(),
+			receiver.id:  receiver.Node(),
+			other.id:     other.Node(),
+		},
+	}
+
+	nodeID := func(p *testPeer) coreTypes.NodeID {
+		pubkey, err := coreEcdsa.NewPublicKeyFromByteSlice(
+			crypto.FromECDSAPub(p.Node().Pubkey()))
+		if err != nil {
+			t.Fatalf("invalid pubkey: %v", err)
+		}
+		return coreTypes.NewNodeID(pubkey)
+	}
+	relay := func(from, to *testPeer, receiverID coreTypes.NodeID) {
+		ps := &dkgTypes.PrivateShare{
+			ReceiverID:   receiverID,
+			Round:        10,
+			PrivateShare: *dkg.NewPrivateKey(),
+			Signature: coreCrypto.Signature{
+				Type:      "DKGPrivateShare",
+				Signature: []byte("DKGPrivateShare"),
+			},
+		}
+		if err := p2p.Send(from.app, DKGPrivateShareMsg, ps); err != nil {
+			t.Fatalf("send error: %v", err)
+		}
+		msg, err := to.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("%v: read error: %v", to.Peer, err)
+		}
+		if msg.Code != DKGPrivateShareMsg {
+			t.Fatalf("%v: got code %d, want %d", to.Peer, msg.Code, DKGPrivateShareMsg)
+		}
+		var relayed dkgTypes.PrivateShare
+		if err := msg.Decode(&relayed); err != nil {
+			t.Fatalf("%v: %v", to.Peer, err)
+		}
+		if !reflect.DeepEqual(&relayed, ps) {
+			t.Errorf("DKG private share mismatch")
+		}
+	}
+
+	// The shares are sent to their receivers only.
+	relay(validator, receiver, nodeID(receiver))
+	relay(receiver, validator, nodeID(validator))
+
+	// The shares of the other receivers are not relayed for the validator,
+	// and never to the peers not being the receiver.
+	if err := p2p.Send(receiver.app, DKGPrivateShareMsg, &dkgTypes.PrivateShare{
+		ReceiverID:   nodeID(other),
+		Round:        10,
+		PrivateShare: *dkg.NewPrivateKey(),
+	}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		other.close()
+	}()
+	if _, err := other.app.ReadMsg(); err != p2p.ErrPipeClosed {
+		t.Errorf("err mismatch: got %v, want %v (not receiver peer)",
+			err, p2p.ErrPipeClosed)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+// link is a directed connection between two simulated nodes.
+type link struct {
+	from, to enode.ID
+}
+
+// linkConditions holds the network conditions applied to the messages sent
+// between simulated nodes.
+type linkConditions struct {
+	lock    sync.RWMutex
+	group   map[enode.ID]int
+	latency map[link]time.Duration
+}
+
+func newLinkConditions() *linkConditions {
+	return &linkConditions{
+		latency: make(map[link]time.Duration),
+	}
+}
+
+// partition splits the nodes into groups, messages between nodes of
+// different groups are dropped. Nodes not in any group can reach everyone.
+func (c *linkConditions) partition(groups [][]enode.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.group = make(map[enode.ID]int)
+	for i, ids := range groups {
+		for _, id := range ids {
+			c.group[id] = i
+		}
+	}
+}
+
+func (c *linkConditions) heal() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.group = nil
+}
+
+func (c *linkConditions) setLatency(from, to enode.ID, latency time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if latency <= 0 {
+		delete(c.latency, link{from, to})
+		return
+	}
+	c.latency[link{from, to}] = latency
+}
+
+// get returns the latency of the link and whether it's reachable.
+func (c *linkConditions) get(from, to enode.ID) (time.Duration, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	fromGroup, fromOK := c.group[from]
+	toGroup, toOK := c.group[to]
+	if fromOK && toOK && fromGroup != toGroup {
+		return 0, false
+	}
+	return c.latency[link{from, to}], true
+}
+
+// conditionedRW applies the link conditions to the messages written to a
+// peer, the messages are delayed by the latency of the link and dropped if
+// the peer is unreachable.
+type conditionedRW struct {
+	p2p.MsgReadWriter
+	link
+	conds *linkConditions
+}
+
+func (rw *conditionedRW) WriteMsg(msg p2p.Msg) error {
+	latency, reachable := rw.conds.get(rw.from, rw.to)
+	if !reachable {
+		return msg.Discard()
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return rw.MsgReadWriter.WriteMsg(msg)
+}
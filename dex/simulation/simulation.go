@@ -0,0 +1,400 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// Package simulation runs a network of DEXON full nodes in a single process.
+// The nodes are connected by in-memory pipes and run the real consensus core,
+// the harness can partition the network, delay messages and stop proposers
+// to test the behavior of the nodes end to end.
+package simulation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+	"github.com/dexon-foundation/dexon/p2p/simulations"
+	"github.com/dexon-foundation/dexon/p2p/simulations/adapters"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+const serviceName = "dex"
+
+// pollInterval is the interval to check the state of the nodes when waiting.
+const pollInterval = 100 * time.Millisecond
+
+// Config is the configuration of the simulated network.
+type Config struct {
+	Nodes            int           // Number of nodes, all of them are block proposers
+	RoundLength      uint64        // Number of blocks of a round
+	LambdaBA         uint64        // BA timeout in milliseconds
+	LambdaDKG        uint64        // DKG phase length in milliseconds
+	MinBlockInterval uint64        // Minimum block interval in milliseconds
+	DMomentDelay     time.Duration // Delay between the network creation and the first block
+
+	// Configure, if set, modifies the config of the i-th node before the node
+	// is created, e.g. to make it byzantine.
+	Configure func(i int, config *dex.Config)
+}
+
+// DefaultConfig is a small network which passes a few rounds in minutes. A
+// DKG phase lasts LambdaDKG/MinBlockInterval blocks, the round has to be long
+// enough for the DKG of the next round to finish in its second half.
+var DefaultConfig = Config{
+	Nodes:            4,
+	RoundLength:      100,
+	LambdaBA:         250,
+	LambdaDKG:        1000,
+	MinBlockInterval: 500,
+	DMomentDelay:     5 * time.Second,
+}
+
+// Simulation is a network of DEXON full nodes running in the same process.
+type Simulation struct {
+	net     *simulations.Network
+	genesis *core.Genesis
+	keys    map[enode.ID]*ecdsa.PrivateKey
+	ids     []enode.ID
+	conds   *linkConditions
+	voteDir string
+
+	configure func(i int, config *dex.Config)
+}
+
+// New creates the nodes of the network, connects them to each other and
+// starts them. The consensus starts at DMomentDelay after the call.
+func New(config Config) (*Simulation, error) {
+	if config.Nodes <= 0 {
+		return nil, fmt.Errorf("invalid number of nodes: %d", config.Nodes)
+	}
+	voteDir, err := ioutil.TempDir("", "dex-simulation")
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulation{
+		keys:    make(map[enode.ID]*ecdsa.PrivateKey),
+		conds:   newLinkConditions(),
+		voteDir: voteDir,
+
+		configure: config.Configure,
+	}
+	keys := make([]*ecdsa.PrivateKey, config.Nodes)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			os.RemoveAll(voteDir)
+			return nil, err
+		}
+		keys[i] = key
+	}
+	s.genesis = newGenesis(config, keys)
+
+	adapter := adapters.NewSimAdapter(map[string]adapters.ServiceFunc{
+		serviceName: s.newService,
+	})
+	s.net = simulations.NewNetwork(adapter, &simulations.NetworkConfig{
+		ID:             "dex",
+		DefaultService: serviceName,
+	})
+	for i, key := range keys {
+		id := enode.PubkeyToIDV4(&key.PublicKey)
+		_, err := s.net.NewNodeWithConfig(&adapters.NodeConfig{
+			ID:         id,
+			PrivateKey: key,
+			Name:       fmt.Sprintf("node%02d", i),
+			Services:   []string{serviceName},
+		})
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.keys[id] = key
+		s.ids = append(s.ids, id)
+	}
+	if err := s.net.StartAll(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.net.ConnectNodesFull(s.ids); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// newGenesis creates the genesis of the network with the given nodes staked.
+func newGenesis(config Config, keys []*ecdsa.PrivateKey) *core.Genesis {
+	dexconConfig := *params.TestnetChainConfig.Dexcon
+	dexconConfig.RoundLength = config.RoundLength
+	dexconConfig.LambdaBA = config.LambdaBA
+	dexconConfig.LambdaDKG = config.LambdaDKG
+	dexconConfig.MinBlockInterval = config.MinBlockInterval
+
+	chainConfig := *params.TestnetChainConfig
+	chainConfig.ChainID = big.NewInt(1337)
+	chainConfig.DMoment = uint64(time.Now().Add(config.DMomentDelay).Unix()) + 1
+	chainConfig.Dexcon = &dexconConfig
+
+	alloc := make(core.GenesisAlloc)
+	for i, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance:   new(big.Int).Mul(dexconConfig.MinStake, big.NewInt(2)),
+			Staked:    new(big.Int).Set(dexconConfig.MinStake),
+			PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+			NodeInfo:  core.NodeInfo{Name: fmt.Sprintf("node%02d", i)},
+		}
+	}
+	return &core.Genesis{
+		Config:     &chainConfig,
+		GasLimit:   dexconConfig.BlockGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+}
+
+// service is the dex service run by the simulated nodes, the messages sent
+// to the peers pass through the link conditions of the simulation.
+type service struct {
+	*dex.Dexon
+	id    enode.ID
+	conds *linkConditions
+}
+
+func (s *Simulation) newService(ctx *adapters.ServiceContext) (node.Service, error) {
+	config := dex.DefaultConfig
+	config.Genesis = s.genesis
+	config.NetworkId = s.genesis.Config.ChainID.Uint64()
+	config.PrivateKey = ctx.Config.PrivateKey
+	config.BlockProposerEnabled = true
+	config.RecoveryVoteDir = s.voteDir
+	config.TxPool.Journal = ""
+	if s.configure != nil {
+		for i, id := range s.ids {
+			if id == ctx.Config.ID {
+				s.configure(i, &config)
+			}
+		}
+	}
+
+	dexon, err := dex.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &service{Dexon: dexon, id: ctx.Config.ID, conds: s.conds}, nil
+}
+
+func (s *service) Protocols() []p2p.Protocol {
+	protos := append([]p2p.Protocol{}, s.Dexon.Protocols()...)
+	for i := range protos {
+		run := protos[i].Run
+		protos[i].Run = func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return run(p, &conditionedRW{
+				MsgReadWriter: rw,
+				link:          link{from: s.id, to: p.ID()},
+				conds:         s.conds,
+			})
+		}
+	}
+	return protos
+}
+
+// Close stops all the nodes and removes the temporary files.
+func (s *Simulation) Close() {
+	s.net.Shutdown()
+	os.RemoveAll(s.voteDir)
+}
+
+// NodeIDs returns the IDs of all the nodes.
+func (s *Simulation) NodeIDs() []enode.ID {
+	return append([]enode.ID{}, s.ids...)
+}
+
+// Genesis returns the genesis of the network.
+func (s *Simulation) Genesis() *core.Genesis {
+	return s.genesis
+}
+
+// Key returns the node key of the node.
+func (s *Simulation) Key(id enode.ID) *ecdsa.PrivateKey {
+	return s.keys[id]
+}
+
+// Dexon returns the dex service of the node, nil if the node is not running.
+func (s *Simulation) Dexon(id enode.ID) *dex.Dexon {
+	n := s.net.GetNode(id)
+	if n == nil || !n.Up() {
+		return nil
+	}
+	simNode, ok := n.Node.(*adapters.SimNode)
+	if !ok {
+		return nil
+	}
+	srv, ok := simNode.Service(serviceName).(*service)
+	if !ok {
+		return nil
+	}
+	return srv.Dexon
+}
+
+// Governance returns the governance of the node at its current chain head.
+func (s *Simulation) Governance(id enode.ID) *core.Governance {
+	dexon := s.Dexon(id)
+	if dexon == nil {
+		return nil
+	}
+	return core.NewGovernance(core.NewGovernanceStateDB(dexon.BlockChain()))
+}
+
+// Partition splits the network into the given groups. Messages between nodes
+// of different groups are dropped, nodes not in any group reach everyone.
+func (s *Simulation) Partition(groups ...[]enode.ID) {
+	s.conds.partition(groups)
+}
+
+// Heal removes the partition of the network.
+func (s *Simulation) Heal() {
+	s.conds.heal()
+}
+
+// SetLatency delays the messages sent from one node to another, zero latency
+// removes the delay.
+func (s *Simulation) SetLatency(from, to enode.ID, latency time.Duration) {
+	s.conds.setLatency(from, to, latency)
+}
+
+// StopProposer stops the block proposer of the node, the node keeps syncing
+// the chain from its peers.
+func (s *Simulation) StopProposer(id enode.ID) error {
+	dexon := s.Dexon(id)
+	if dexon == nil {
+		return fmt.Errorf("node not running: %s", id)
+	}
+	dexon.StopProposing()
+	return nil
+}
+
+// StartProposer starts the block proposer of the node again.
+func (s *Simulation) StartProposer(id enode.ID) error {
+	dexon := s.Dexon(id)
+	if dexon == nil {
+		return fmt.Errorf("node not running: %s", id)
+	}
+	return dexon.StartProposing()
+}
+
+// StopNode shuts the node down, it can't be started again since the chain
+// of the node is kept in memory.
+func (s *Simulation) StopNode(id enode.ID) error {
+	return s.net.Stop(id)
+}
+
+// Height returns the current block number of the node.
+func (s *Simulation) Height(id enode.ID) uint64 {
+	dexon := s.Dexon(id)
+	if dexon == nil {
+		return 0
+	}
+	return dexon.BlockChain().CurrentBlock().NumberU64()
+}
+
+// WaitHeight waits until all the given nodes have the block of the height in
+// their chain. All running nodes are waited if no node is given.
+func (s *Simulation) WaitHeight(ctx context.Context, height uint64, ids ...enode.ID) error {
+	return s.wait(ctx, ids, func(dexon *dex.Dexon) bool {
+		return dexon.BlockChain().CurrentBlock().NumberU64() >= height
+	})
+}
+
+// WaitRound waits until the chains of all the given nodes enter the round.
+// All running nodes are waited if no node is given.
+func (s *Simulation) WaitRound(ctx context.Context, round uint64, ids ...enode.ID) error {
+	return s.wait(ctx, ids, func(dexon *dex.Dexon) bool {
+		return dexon.BlockChain().CurrentBlock().Round() >= round
+	})
+}
+
+func (s *Simulation) wait(ctx context.Context, ids []enode.ID, done func(*dex.Dexon) bool) error {
+	if len(ids) == 0 {
+		for _, id := range s.ids {
+			if s.Dexon(id) != nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending := 0
+		for _, id := range ids {
+			dexon := s.Dexon(id)
+			if dexon == nil {
+				return fmt.Errorf("node not running: %s", id)
+			}
+			if !done(dexon) {
+				pending++
+			}
+		}
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%d nodes not done: %v", pending, ctx.Err())
+		}
+	}
+}
+
+// CheckConsistency checks that the running nodes agree on the canonical
+// chain up to the lowest height among them.
+func (s *Simulation) CheckConsistency() error {
+	var (
+		chains []*core.BlockChain
+		height = ^uint64(0)
+	)
+	for _, id := range s.ids {
+		dexon := s.Dexon(id)
+		if dexon == nil {
+			continue
+		}
+		chain := dexon.BlockChain()
+		if h := chain.CurrentBlock().NumberU64(); h < height {
+			height = h
+		}
+		chains = append(chains, chain)
+	}
+	for number := uint64(1); len(chains) > 1 && number <= height; number++ {
+		hash := chains[0].GetHeaderByNumber(number).Hash()
+		for _, chain := range chains[1:] {
+			if other := chain.GetHeaderByNumber(number).Hash(); other != hash {
+				return fmt.Errorf("block %d mismatch: %x != %x",
+					number, hash, other)
+			}
+		}
+	}
+	return nil
+}
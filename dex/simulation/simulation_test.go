@@ -0,0 +1,179 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+func TestLinkConditions(t *testing.T) {
+	var a, b, c enode.ID
+	a[0], b[0], c[0] = 1, 2, 3
+
+	conds := newLinkConditions()
+	conds.setLatency(a, b, time.Second)
+	if latency, ok := conds.get(a, b); !ok || latency != time.Second {
+		t.Errorf("latency mismatch: have %v, %v", latency, ok)
+	}
+	if latency, ok := conds.get(b, a); !ok || latency != 0 {
+		t.Errorf("reverse latency mismatch: have %v, %v", latency, ok)
+	}
+
+	conds.partition([][]enode.ID{{a}, {b}})
+	if _, ok := conds.get(a, b); ok {
+		t.Error("partitioned link is reachable")
+	}
+	if _, ok := conds.get(a, c); !ok {
+		t.Error("node not in any group is unreachable")
+	}
+	conds.heal()
+	if _, ok := conds.get(b, a); !ok {
+		t.Error("healed link is unreachable")
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping simulation in short mode")
+	}
+	sim, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("failed to create simulation: %v", err)
+	}
+	defer sim.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Rounds after the genesis round need the DKG run by the nodes.
+	if err := sim.WaitRound(ctx, 2); err != nil {
+		t.Fatalf("failed to reach round 2: %v", err)
+	}
+	ids := sim.NodeIDs()
+	gov := sim.Governance(ids[0])
+	if !gov.IsDKGFinal(2) {
+		t.Error("DKG of round 2 not final")
+	}
+	if len(gov.DKGMasterPublicKeys(2)) != len(ids) {
+		t.Errorf("master public keys mismatch: have %d, want %d",
+			len(gov.DKGMasterPublicKeys(2)), len(ids))
+	}
+
+	// The network tolerates a faulty node.
+	if err := sim.StopProposer(ids[0]); err != nil {
+		t.Fatalf("failed to stop proposer: %v", err)
+	}
+	if sim.Dexon(ids[0]).IsProposing() {
+		t.Error("stopped node is proposing")
+	}
+	height := sim.Height(ids[1]) + 10
+	if err := sim.WaitHeight(ctx, height, ids[1:]...); err != nil {
+		t.Fatalf("failed to progress without a proposer: %v", err)
+	}
+	if err := sim.StartProposer(ids[0]); err != nil {
+		t.Fatalf("failed to restart proposer: %v", err)
+	}
+	if err := sim.WaitHeight(ctx, height+10); err != nil {
+		t.Fatalf("failed to progress after restart: %v", err)
+	}
+
+	// No group of a half partition is able to confirm blocks, and the
+	// network recovers once the partition heals.
+	for _, id := range ids {
+		sim.SetLatency(ids[0], id, 50*time.Millisecond)
+	}
+	sim.Partition(ids[:2], ids[2:])
+	time.Sleep(5 * time.Second)
+	stalled := make(map[enode.ID]uint64)
+	for _, id := range ids {
+		stalled[id] = sim.Height(id)
+	}
+	time.Sleep(5 * time.Second)
+	for _, id := range ids {
+		if h := sim.Height(id); h > stalled[id]+1 {
+			t.Errorf("partitioned node confirmed blocks: height %d, stalled at %d",
+				h, stalled[id])
+		}
+	}
+	sim.Heal()
+	if err := sim.WaitHeight(ctx, stalled[ids[0]]+10); err != nil {
+		t.Fatalf("failed to progress after partition: %v", err)
+	}
+	if err := sim.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSimulationByzantine(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping simulation in short mode")
+	}
+	config := DefaultConfig
+	config.Configure = func(i int, config *dex.Config) {
+		if i == 0 {
+			config.Byzantine = dex.ByzantineForkVote | dex.ByzantineForkBlock
+		}
+	}
+	sim, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create simulation: %v", err)
+	}
+	defer sim.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// The forks of the byzantine node are reported by the others and the
+	// node gets fined.
+	ids := sim.NodeIDs()
+	addr := crypto.PubkeyToAddress(sim.Key(ids[0]).PublicKey)
+	fined := func() *big.Int {
+		state := sim.Governance(ids[1]).GetHeadState()
+		offset := state.NodesOffsetByAddress(addr)
+		if offset.Sign() < 0 {
+			return nil
+		}
+		return state.Node(offset).Fined
+	}
+	for {
+		if f := fined(); f != nil && f.Sign() > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("byzantine node not fined: %v", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+
+	// The honest nodes keep confirming blocks.
+	height := sim.Height(ids[1]) + 10
+	if err := sim.WaitHeight(ctx, height, ids[1:]...); err != nil {
+		t.Fatalf("failed to progress with a byzantine node: %v", err)
+	}
+	if err := sim.CheckConsistency(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/hex"
+	"errors"
+	"runtime"
+	"sync"
+
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+const (
+	verifyQueueSize       = 1024 // Number of messages waiting for verification
+	verifiedVoteCacheSize = 8192 // Number of verified votes to dedupe
+	maxNotarySetCache     = 4    // Number of rounds whose notary sets are cached
+)
+
+var (
+	errInvalidSignature = errors.New("invalid signature")
+	errNotNotary        = errors.New("proposer not in notary set")
+	errDuplicateVote    = errors.New("duplicate vote")
+	errInvalidAgreement = errors.New("invalid agreement result")
+)
+
+// verifyTask is a vote or a core block received from a peer.
+type verifyTask struct {
+	peerID  string
+	payload interface{}
+}
+
+// coreMsgVerifier verifies the votes, core blocks and agreement results
+// received from the network with a pool of workers before they are passed to
+// the consensus core, so the single threaded consensus loop is not delayed by
+// invalid messages. The signatures and the proposers' membership of the notary
+// set of the position are checked, and the votes verified before are dropped.
+// The randomness of the agreement results is verified if the governance
+// provides the DKG results.
+type coreMsgVerifier struct {
+	gov      governance
+	out      chan<- coreTypes.Msg
+	verified func(payload interface{}) bool
+	reject   func(peerID string, err error)
+
+	taskCh chan *verifyTask
+	quit   chan struct{}
+	wg     sync.WaitGroup
+
+	verifiedVotes *lru.Cache
+	tsigVerifiers *dexCore.TSigVerifierCache // nil if not provided by the governance
+
+	lock       sync.RWMutex
+	notarySets map[uint64]map[coreTypes.NodeID]struct{}
+}
+
+// newCoreMsgVerifier creates a verifier passing the verified messages to out,
+// verified is called before a message is passed and the message is dropped if
+// it returns false, reject is called with the peer which sent an invalid
+// message.
+func newCoreMsgVerifier(gov governance, out chan<- coreTypes.Msg,
+	verified func(payload interface{}) bool,
+	reject func(peerID string, err error)) *coreMsgVerifier {
+	verifiedVotes, _ := lru.New(verifiedVoteCacheSize)
+	v := &coreMsgVerifier{
+		gov:           gov,
+		out:           out,
+		verified:      verified,
+		reject:        reject,
+		taskCh:        make(chan *verifyTask, verifyQueueSize),
+		quit:          make(chan struct{}),
+		verifiedVotes: verifiedVotes,
+		notarySets:    make(map[uint64]map[coreTypes.NodeID]struct{}),
+	}
+	if dkgGov, ok := gov.(dexCore.TSigVerifierCacheInterface); ok {
+		v.tsigVerifiers = dexCore.NewTSigVerifierCache(dkgGov, maxNotarySetCache)
+	}
+	return v
+}
+
+func (v *coreMsgVerifier) start() {
+	for i := 0; i < runtime.NumCPU(); i++ {
+		v.wg.Add(1)
+		go v.loop()
+	}
+}
+
+func (v *coreMsgVerifier) stop() {
+	close(v.quit)
+	v.wg.Wait()
+}
+
+// enqueue queues the message for verification, it blocks if the queue is
+// full.
+func (v *coreMsgVerifier) enqueue(peerID string, payload interface{}) {
+	select {
+	case v.taskCh <- &verifyTask{peerID: peerID, payload: payload}:
+		verifyQueueGauge.Update(int64(len(v.taskCh)))
+	case <-v.quit:
+	}
+}
+
+func (v *coreMsgVerifier) loop() {
+	defer v.wg.Done()
+	for {
+		select {
+		case task := <-v.taskCh:
+			verifyQueueGauge.Update(int64(len(v.taskCh)))
+			if err := v.verify(task.payload); err != nil {
+				if err == errDuplicateVote {
+					verifyDuplicateMeter.Mark(1)
+					continue
+				}
+				verifyInvalidMeter.Mark(1)
+				log.Debug("Dropped invalid core message", "peer", task.peerID,
+					"msg", task.payload, "err", err)
+				v.reject(task.peerID, err)
+				continue
+			}
+			if !v.verified(task.payload) {
+				continue
+			}
+			select {
+			case v.out <- coreTypes.Msg{
+				PeerID:  task.peerID,
+				Payload: task.payload,
+			}:
+			case <-v.quit:
+				return
+			}
+		case <-v.quit:
+			return
+		}
+	}
+}
+
+func (v *coreMsgVerifier) verify(payload interface{}) error {
+	switch msg := payload.(type) {
+	case *coreTypes.Vote:
+		hash := coreUtils.HashVote(msg)
+		if v.verifiedVotes.Contains(hash) {
+			return errDuplicateVote
+		}
+		if ok, err := coreUtils.VerifyVoteSignature(msg); err != nil || !ok {
+			return errInvalidSignature
+		}
+		if !v.isNotary(msg.Position.Round, msg.ProposerID) {
+			return errNotNotary
+		}
+		v.verifiedVotes.Add(hash, struct{}{})
+	case *coreTypes.Block:
+		// Empty blocks are not signed, they are confirmed by the agreement.
+		if msg.IsEmpty() {
+			hash, err := coreUtils.HashBlock(msg)
+			if err != nil || hash != msg.Hash {
+				return errInvalidSignature
+			}
+			return nil
+		}
+		if err := coreUtils.VerifyBlockSignature(msg); err != nil {
+			return errInvalidSignature
+		}
+		if !v.isNotary(msg.Position.Round, msg.ProposerID) {
+			return errNotNotary
+		}
+	case *coreTypes.AgreementResult:
+		return v.verifyAgreement(msg)
+	}
+	return nil
+}
+
+// verifyAgreement verifies the votes or the randomness of an agreement result,
+// they are left to the consensus core if the notary set or the DKG result of
+// the round is not known yet.
+func (v *coreMsgVerifier) verifyAgreement(result *coreTypes.AgreementResult) error {
+	round := result.Position.Round
+	if round < dexCore.DKGDelayRound {
+		set := v.notarySet(round)
+		if len(set) == 0 {
+			return nil
+		}
+		if err := dexCore.VerifyAgreementResult(result, set); err != nil {
+			return errInvalidAgreement
+		}
+		return nil
+	}
+	if len(result.Randomness) == 0 {
+		return errInvalidAgreement
+	}
+	if v.tsigVerifiers == nil {
+		return nil
+	}
+	verifier, ok, err := v.tsigVerifiers.UpdateAndGet(round)
+	if err != nil || !ok {
+		return nil
+	}
+	if !verifier.VerifySignature(result.BlockHash, coreCrypto.Signature{
+		Type:      "bls",
+		Signature: result.Randomness,
+	}) {
+		return errInvalidAgreement
+	}
+	return nil
+}
+
+// isNotary reports whether the node is in the notary set of the round. The
+// membership is left to the consensus core if the notary set is not known yet.
+func (v *coreMsgVerifier) isNotary(round uint64, id coreTypes.NodeID) bool {
+	set := v.notarySet(round)
+	if len(set) == 0 {
+		return true
+	}
+	_, ok := set[id]
+	return ok
+}
+
+// isKnownNotary reports whether the node is in the notary set of the round,
+// unlike isNotary it's false if the notary set is not known yet.
+func (v *coreMsgVerifier) isKnownNotary(round uint64, id coreTypes.NodeID) bool {
+	_, ok := v.notarySet(round)[id]
+	return ok
+}
+
+// notarySet returns the notary set of the round, nil if it's not known.
+func (v *coreMsgVerifier) notarySet(round uint64) map[coreTypes.NodeID]struct{} {
+	v.lock.RLock()
+	set, exist := v.notarySets[round]
+	v.lock.RUnlock()
+	if exist {
+		return set
+	}
+
+	if round > v.gov.CRSRound() {
+		return nil
+	}
+	keys, err := v.gov.NotarySet(round)
+	if err != nil {
+		log.Debug("Failed to get notary set", "round", round, "err", err)
+		return nil
+	}
+	set = make(map[coreTypes.NodeID]struct{}, len(keys))
+	for key := range keys {
+		b, err := hex.DecodeString(key)
+		if err != nil {
+			return nil
+		}
+		pubkey, err := coreEcdsa.NewPublicKeyFromByteSlice(b)
+		if err != nil {
+			return nil
+		}
+		set[coreTypes.NewNodeID(pubkey)] = struct{}{}
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	for len(v.notarySets) >= maxNotarySetCache {
+		first, oldest := true, uint64(0)
+		for r := range v.notarySets {
+			if first || r < oldest {
+				first, oldest = false, r
+			}
+		}
+		delete(v.notarySets, oldest)
+	}
+	v.notarySets[round] = set
+	return set
+}
+
+// purgeNotarySet drops the cached notary set and DKG result of the round,
+// they're changed by a DKG reset.
+func (v *coreMsgVerifier) purgeNotarySet(round uint64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	delete(v.notarySets, round)
+	if v.tsigVerifiers != nil {
+		v.tsigVerifiers.Purge(round)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/p2p"
+)
+
+func TestCoreMsgVerifier(t *testing.T) {
+	notaryKey := hex.EncodeToString(crypto.FromECDSAPub(&testAccount.PublicKey))
+	gov := &testGovernance{
+		lenCRSFunc: func() uint64 { return 1 },
+		notarySetFunc: func(uint64) (map[string]struct{}, error) {
+			return map[string]struct{}{notaryKey: {}}, nil
+		},
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+
+	out := make(chan coreTypes.Msg, 16)
+	rejected := make(chan error, 16)
+	v := newCoreMsgVerifier(gov, out, func(interface{}) bool { return true },
+		func(id string, err error) { rejected <- err })
+	v.start()
+	defer v.stop()
+
+	expect := func(name string, payload interface{}, want error) {
+		v.enqueue("peer", payload)
+		select {
+		case msg := <-out:
+			if want != nil {
+				t.Errorf("%s: delivered, want %v", name, want)
+			} else if msg.Payload != payload || msg.PeerID != "peer" {
+				t.Errorf("%s: delivered message mismatch", name)
+			}
+		case err := <-rejected:
+			if err != want {
+				t.Errorf("%s: rejected with %v, want %v", name, err, want)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: neither delivered nor rejected", name)
+		}
+	}
+	newVote := func(signer *coreUtils.Signer, round uint64) *coreTypes.Vote {
+		vote := &coreTypes.Vote{
+			VoteHeader: coreTypes.VoteHeader{
+				BlockHash: coreCommon.Hash{1},
+				Position:  coreTypes.Position{Round: round, Height: 10},
+			},
+		}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatal(err)
+		}
+		return vote
+	}
+	newBlock := func(signer *coreUtils.Signer, round uint64) *coreTypes.Block {
+		block := &coreTypes.Block{
+			Position:  coreTypes.Position{Round: round, Height: 10},
+			Timestamp: time.Now().UTC(),
+			Payload:   []byte{1, 2, 3},
+		}
+		if err := signer.SignBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+
+	vote := newVote(testSigner, 1)
+	expect("vote", vote, nil)
+	// The duplicates are dropped silently, so they're verified directly.
+	if err := v.verify(vote); err != errDuplicateVote {
+		t.Errorf("duplicate vote: have %v, want %v", err, errDuplicateVote)
+	}
+	// A copy of a verified vote is a duplicate even with another signature.
+	forged := newVote(other, 1)
+	forged.ProposerID = vote.ProposerID
+	if err := v.verify(forged); err != errDuplicateVote {
+		t.Errorf("forged duplicate vote: have %v, want %v", err, errDuplicateVote)
+	}
+	forged = newVote(other, 1)
+	forged.ProposerID = vote.ProposerID
+	forged.Period = 1
+	expect("forged vote", forged, errInvalidSignature)
+	expect("non-notary vote", newVote(other, 1), errNotNotary)
+	// The notary set of a round without CRS is unknown.
+	expect("future vote", newVote(other, 2), nil)
+
+	expect("block", newBlock(testSigner, 1), nil)
+	tampered := newBlock(testSigner, 1)
+	tampered.Payload = []byte{4, 5, 6}
+	expect("tampered block", tampered, errInvalidSignature)
+	expect("non-notary block", newBlock(other, 1), errNotNotary)
+
+	empty := &coreTypes.Block{
+		Position: coreTypes.Position{Round: 1, Height: 11},
+	}
+	if empty.Hash, err = coreUtils.HashBlock(empty); err != nil {
+		t.Fatal(err)
+	}
+	expect("empty block", empty, nil)
+
+	// The agreements before the DKG are confirmed by the votes of the notary
+	// set, and the later ones by the randomness.
+	com := &coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			Type:      coreTypes.VoteCom,
+			BlockHash: coreCommon.Hash{2},
+			Position:  coreTypes.Position{Round: 0, Height: 10},
+		},
+	}
+	if err := testSigner.SignVote(com); err != nil {
+		t.Fatal(err)
+	}
+	agreement := &coreTypes.AgreementResult{
+		BlockHash: com.BlockHash,
+		Position:  com.Position,
+		Votes:     []coreTypes.Vote{*com},
+	}
+	expect("agreement", agreement, nil)
+	expect("agreement without votes", &coreTypes.AgreementResult{
+		BlockHash: com.BlockHash,
+		Position:  com.Position,
+	}, errInvalidAgreement)
+	expect("agreement without randomness", &coreTypes.AgreementResult{
+		BlockHash: com.BlockHash,
+		Position:  coreTypes.Position{Round: 1, Height: 10},
+	}, errInvalidAgreement)
+	expect("agreement with randomness", &coreTypes.AgreementResult{
+		BlockHash:  com.BlockHash,
+		Position:   coreTypes.Position{Round: 1, Height: 10},
+		Randomness: []byte{1},
+	}, nil)
+}
+
+func TestRecvInvalidVotes(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	vote := &coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			Period:   10,
+			Position: coreTypes.Position{Round: 12, Height: 13},
+		},
+	}
+	if err := testSigner.SignVote(vote); err != nil {
+		t.Fatalf("sign vote error: %v", err)
+	}
+	vote.Period = 11
+	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{vote}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+
+	select {
+	case <-pm.ReceiveChan():
+		t.Errorf("invalid vote received")
+	case <-time.After(300 * time.Millisecond):
+	}
+	penalized := false
+	for _, s := range pm.reputation.peerScores() {
+		if s.ID == p.id {
+			penalized = s.Score < 0
+		}
+	}
+	if !penalized {
+		t.Errorf("peer not penalized")
+	}
+}
+
+func TestRejectCoreMessage(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	score := func(id string) float64 {
+		for _, s := range pm.reputation.peerScores() {
+			if s.ID == id {
+				return s.Score
+			}
+		}
+		return 0
+	}
+
+	// Messages out of the notary set are dropped without penalty.
+	pm.rejectCoreMessage("peer", errNotNotary)
+	if s := score("peer"); s != 0 {
+		t.Errorf("peer penalized for non-notary message: score %v", s)
+	}
+	pm.rejectCoreMessage("peer", errInvalidSignature)
+	if s := score("peer"); s >= 0 {
+		t.Errorf("peer not penalized for invalid signature: score %v", s)
+	}
+}
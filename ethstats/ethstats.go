@@ -31,13 +31,14 @@ import (
 	"time"
 
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
 	"github.com/dexon-foundation/dexon/common/mclock"
 	"github.com/dexon-foundation/dexon/consensus"
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/types"
-	"github.com/dexon-foundation/dexon/eth"
+	"github.com/dexon-foundation/dexon/dex"
+	dexles "github.com/dexon-foundation/dexon/dex/les"
 	"github.com/dexon-foundation/dexon/event"
-	"github.com/dexon-foundation/dexon/les"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/p2p"
 	"github.com/dexon-foundation/dexon/rpc"
@@ -66,13 +67,14 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
-// Service implements an Ethereum netstats reporting daemon that pushes local
+// Service implements a DEXON netstats reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
-	server *p2p.Server        // Peer-to-peer server to retrieve networking infos
-	eth    *eth.Ethereum      // Full Ethereum service if monitoring a full node
-	les    *les.LightEthereum // Light Ethereum service if monitoring a light node
-	engine consensus.Engine   // Consensus engine to retrieve variadic block fields
+	server *p2p.Server          // Peer-to-peer server to retrieve networking infos
+	dex    *dex.Dexon           // Full DEXON service if monitoring a full node
+	les    *dexles.LightDexon   // Light DEXON service if monitoring a light node
+	dexcon *dex.PublicDexconAPI // Consensus state of the full node
+	engine consensus.Engine     // Consensus engine to retrieve variadic block fields
 
 	node string // Name of the node to display on the monitoring page
 	pass string // Password to authorize access to the monitoring page
@@ -83,7 +85,7 @@ type Service struct {
 }
 
 // New returns a monitoring service ready for stats reporting.
-func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Service, error) {
+func New(url string, dexServ *dex.Dexon, lesServ *dexles.LightDexon) (*Service, error) {
 	// Parse the netstats connection url
 	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
 	parts := re.FindStringSubmatch(url)
@@ -91,15 +93,20 @@ func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Servic
 		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
 	}
 	// Assemble and return the stats service
-	var engine consensus.Engine
-	if ethServ != nil {
-		engine = ethServ.Engine()
+	var (
+		engine consensus.Engine
+		dexcon *dex.PublicDexconAPI
+	)
+	if dexServ != nil {
+		engine = dexServ.Engine()
+		dexcon = dex.NewPublicDexconAPI(dexServ)
 	} else {
 		engine = lesServ.Engine()
 	}
 	return &Service{
-		eth:    ethServ,
+		dex:    dexServ,
 		les:    lesServ,
+		dexcon: dexcon,
 		engine: engine,
 		node:   parts[1],
 		pass:   parts[3],
@@ -138,9 +145,9 @@ func (s *Service) loop() {
 	// Subscribe to chain events to execute updates on
 	var blockchain blockChain
 	var txpool txPool
-	if s.eth != nil {
-		blockchain = s.eth.BlockChain()
-		txpool = s.eth.TxPool()
+	if s.dex != nil {
+		blockchain = s.dex.BlockChain()
+		txpool = s.dex.TxPool()
 	} else {
 		blockchain = s.les.BlockChain()
 		txpool = s.les.TxPool()
@@ -373,12 +380,12 @@ func (s *Service) login(conn *websocket.Conn) error {
 	infos := s.server.NodeInfo()
 
 	var network, protocol string
-	if info := infos.Protocols["eth"]; info != nil {
-		network = fmt.Sprintf("%d", info.(*eth.NodeInfo).Network)
-		protocol = fmt.Sprintf("eth/%d", eth.ProtocolVersions[0])
+	if info := infos.Protocols[dex.ProtocolName]; info != nil {
+		network = fmt.Sprintf("%d", info.(*dex.NodeInfo).Network)
+		protocol = fmt.Sprintf("%s/%d", dex.ProtocolName, dex.ProtocolVersions[0])
 	} else {
-		network = fmt.Sprintf("%d", infos.Protocols["les"].(*les.NodeInfo).Network)
-		protocol = fmt.Sprintf("les/%d", les.ClientProtocolVersions[0])
+		network = fmt.Sprintf("%d", s.les.NetVersion())
+		protocol = fmt.Sprintf("%s/%d", dexles.ProtocolName, dexles.ProtocolVersions[0])
 	}
 	auth := &authMsg{
 		ID: s.node,
@@ -481,6 +488,7 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+	Round      uint64         `json:"round"`
 }
 
 // txStats is the information to report about individual transactions.
@@ -527,13 +535,13 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		txs    []txStats
 		uncles []*types.Header
 	)
-	if s.eth != nil {
+	if s.dex != nil {
 		// Full nodes have all needed information available
 		if block == nil {
-			block = s.eth.BlockChain().CurrentBlock()
+			block = s.dex.BlockChain().CurrentBlock()
 		}
 		header = block.Header()
-		td = s.eth.BlockChain().GetTd(header.Hash(), header.Number.Uint64())
+		td = s.dex.BlockChain().GetTd(header.Hash(), header.Number.Uint64())
 
 		txs = make([]txStats, len(block.Transactions()))
 		for i, tx := range block.Transactions() {
@@ -567,6 +575,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		Round:      header.Round,
 	}
 }
 
@@ -581,8 +590,8 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 	} else {
 		// No indexes requested, send back the top ones
 		var head int64
-		if s.eth != nil {
-			head = s.eth.BlockChain().CurrentHeader().Number.Int64()
+		if s.dex != nil {
+			head = s.dex.BlockChain().CurrentHeader().Number.Int64()
 		} else {
 			head = s.les.BlockChain().CurrentHeader().Number.Int64()
 		}
@@ -599,8 +608,8 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 	for i, number := range indexes {
 		// Retrieve the next block if it's known to us
 		var block *types.Block
-		if s.eth != nil {
-			block = s.eth.BlockChain().GetBlockByNumber(number)
+		if s.dex != nil {
+			block = s.dex.BlockChain().GetBlockByNumber(number)
 		} else {
 			if header := s.les.BlockChain().GetHeaderByNumber(number); header != nil {
 				block = types.NewBlockWithHeader(header)
@@ -641,8 +650,8 @@ type pendStats struct {
 func (s *Service) reportPending(conn *websocket.Conn) error {
 	// Retrieve the pending count from the local blockchain
 	var pending int
-	if s.eth != nil {
-		pending, _ = s.eth.TxPool().Stats()
+	if s.dex != nil {
+		pending, _ = s.dex.TxPool().Stats()
 	} else {
 		pending = s.les.TxPool().Stats()
 	}
@@ -663,33 +672,87 @@ func (s *Service) reportPending(conn *websocket.Conn) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
+	Active   bool        `json:"active"`
+	Syncing  bool        `json:"syncing"`
+	Mining   bool        `json:"mining"`
+	Hashrate int         `json:"hashrate"`
+	Peers    int         `json:"peers"`
+	GasPrice int         `json:"gasPrice"`
+	Uptime   int         `json:"uptime"`
+	Dexon    *dexonStats `json:"dexon"`
+}
+
+// dexonStats is the information to report about the consensus state of the
+// local node. Only the round is known to light nodes.
+type dexonStats struct {
+	Round             uint64    `json:"round"`
+	Notary            bool      `json:"notary"`
+	Proposing         bool      `json:"proposing"`
+	CoreSyncing       bool      `json:"coreSyncing"`
+	DKG               *dkgStats `json:"dkg"`
+	RandomnessLatency int       `json:"randomnessLatency"`
+	FinalizedHeight   uint64    `json:"finalizedHeight"`
+	ExecutedHeight    uint64    `json:"executedHeight"`
+}
+
+// dkgStats is the progress of the DKG of the next round, which runs during
+// the current round.
+type dkgStats struct {
+	Round            uint64 `json:"round"`
+	ResetCount       uint64 `json:"resetCount"`
+	MasterPublicKeys int    `json:"masterPublicKeys"`
+	Complaints       int    `json:"complaints"`
+	MPKReady         bool   `json:"mpkReady"`
+	Final            bool   `json:"final"`
+	Success          bool   `json:"success"`
+}
+
+// assembleDexonStats retrieves the consensus state of the local node.
+func (s *Service) assembleDexonStats() *dexonStats {
+	if s.dex == nil {
+		return &dexonStats{Round: s.les.BlockChain().CurrentHeader().Round}
+	}
+	head := s.dex.BlockChain().CurrentBlock()
+	stats := &dexonStats{
+		Round:             head.Round(),
+		Notary:            s.dex.IsNotary(head.Round()),
+		Proposing:         s.dex.IsProposing(),
+		CoreSyncing:       s.dex.IsCoreSyncing(),
+		RandomnessLatency: int(s.dex.RandomnessLatency() / time.Millisecond),
+		FinalizedHeight:   s.dex.FinalizedHeight(),
+		ExecutedHeight:    head.NumberU64(),
+	}
+	if status, err := s.dexcon.GetDKGStatus(hexutil.Uint64(head.Round() + 1)); err == nil {
+		stats.DKG = &dkgStats{
+			Round:            uint64(status.Round),
+			ResetCount:       uint64(status.ResetCount),
+			MasterPublicKeys: int(status.MasterPublicKeys),
+			Complaints:       int(status.Complaints),
+			MPKReady:         status.MPKReady,
+			Final:            status.Final,
+			Success:          status.Success,
+		}
+	}
+	return stats
 }
 
-// reportPending retrieves various stats about the node at the networking and
-// mining layer and reports it to the stats server.
+// reportStats retrieves various stats about the node at the networking and
+// consensus layer and reports it to the stats server.
 func (s *Service) reportStats(conn *websocket.Conn) error {
-	// Gather the syncing and mining infos from the local miner instance
+	// Gather the syncing and proposing infos from the local node
 	var (
 		mining   bool
 		hashrate int
 		syncing  bool
 		gasprice int
 	)
-	if s.eth != nil {
-		mining = s.eth.Miner().Mining()
-		hashrate = int(s.eth.Miner().HashRate())
+	if s.dex != nil {
+		mining = s.dex.IsProposing()
 
-		sync := s.eth.Downloader().Progress()
-		syncing = s.eth.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		sync := s.dex.Downloader().Progress()
+		syncing = s.dex.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
 
-		price, _ := s.eth.APIBackend.SuggestPrice(context.Background())
+		price, _ := s.dex.APIBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
 	} else {
 		sync := s.les.Downloader().Progress()
@@ -708,6 +771,7 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 			GasPrice: gasprice,
 			Syncing:  syncing,
 			Uptime:   100,
+			Dexon:    s.assembleDexonStats(),
 		},
 	}
 	report := map[string][]interface{}{
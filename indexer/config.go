@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"fmt"
 	"plugin"
 
 	"github.com/dexon-foundation/dexon/core"
@@ -12,12 +13,18 @@ type Config struct {
 	// Used by dex/backend init flow.
 	Enable bool
 
+	// Name of a registered indexer, takes precedence over Plugin.
+	Name string
+
 	// Plugin path for building components.
 	Plugin string
 
 	// PluginFlags for construction if needed.
 	PluginFlags string
 
+	// DataDir is the directory for indexers to store their data.
+	DataDir string
+
 	// The genesis block from dex.Config
 	Genesis *core.Genesis
 
@@ -26,23 +33,57 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 }
 
-// NewIndexerFromConfig initialize exporter according to given config.
-func NewIndexerFromConfig(bc ReadOnlyBlockChain, c Config) (idx Indexer) {
-	if c.Plugin == "" {
-		// default
-		return
+// NewIndexerFromConfig initialize exporter according to given config. The
+// indexer is looked up from the registry by name, or loaded from the plugin.
+// A BlockIndexer is wrapped to be fed by the block stream.
+func NewIndexerFromConfig(bc ReadOnlyBlockChain, c Config) (Indexer, error) {
+	var newIndexer NewIndexerFunc
+	switch {
+	case c.Name != "":
+		fn, ok := lookup(c.Name)
+		if !ok {
+			return nil, fmt.Errorf("indexer %q is not registered", c.Name)
+		}
+		newIndexer = fn
+	case c.Plugin != "":
+		fn, err := loadPlugin(c.Plugin)
+		if err != nil {
+			return nil, err
+		}
+		newIndexer = fn
+	default:
+		return nil, fmt.Errorf("neither indexer name nor plugin is given")
+	}
+
+	idx, err := newIndexer(bc, c)
+	if err != nil {
+		return nil, err
+	}
+	if blockIdx, ok := idx.(BlockIndexer); ok {
+		return newStreamIndexer(bc, blockIdx), nil
 	}
+	return idx, nil
+}
 
-	plug, err := plugin.Open(c.Plugin)
+func loadPlugin(path string) (NewIndexerFunc, error) {
+	plug, err := plugin.Open(path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	symbol, err := plug.Lookup(NewIndexerFuncName)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	idx = symbol.(NewIndexerFunc)(bc, c)
-	return
+	switch fn := symbol.(type) {
+	case NewIndexerFunc:
+		return fn, nil
+	case legacyNewIndexerFunc:
+		return func(bc ReadOnlyBlockChain, c Config) (Indexer, error) {
+			return fn(bc, c), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected type of %s in plugin %s: %T",
+		NewIndexerFuncName, path, symbol)
 }
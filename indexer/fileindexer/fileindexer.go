@@ -0,0 +1,220 @@
+// Package fileindexer implements a reference indexer which appends blocks to
+// a flat file of JSON lines.
+package fileindexer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/indexer"
+)
+
+// Name is the registered name of the indexer.
+const Name = "file"
+
+const (
+	blocksFileName = "blocks.jsonl"
+	cursorFileName = "cursor.json"
+)
+
+func init() {
+	indexer.Register(Name, New)
+}
+
+// Record is a line in the blocks file.
+type Record struct {
+	Number       uint64         `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Time         uint64         `json:"timestamp"`
+	Round        uint64         `json:"round"`
+	Transactions []common.Hash  `json:"transactions"`
+	Receipts     types.Receipts `json:"receipts"`
+	GovEvents    []*types.Log   `json:"govEvents"`
+}
+
+// cursor is the last block written and the end of its record in the blocks
+// file.
+type cursor struct {
+	Number uint64 `json:"number"`
+	Offset int64  `json:"offset"`
+}
+
+// FileIndexer writes blocks to <datadir>/file/blocks.jsonl, one record per
+// line. The cursor is kept in cursor.json and data written after it is
+// discarded on start, so a restarted indexer continues right after the last
+// complete record.
+type FileIndexer struct {
+	dir string
+
+	mu        sync.Mutex
+	file      *os.File
+	cursor    cursor
+	hasCursor bool
+}
+
+// New creates a FileIndexer storing data under c.DataDir.
+func New(bc indexer.ReadOnlyBlockChain, c indexer.Config) (indexer.Indexer, error) {
+	if c.DataDir == "" {
+		return nil, errors.New("indexer data directory is not set")
+	}
+	return &FileIndexer{dir: filepath.Join(c.DataDir, Name)}, nil
+}
+
+// Start opens the blocks file and drops data written after the cursor.
+func (f *FileIndexer) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(f.dir, cursorFileName))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &f.cursor); err != nil {
+			return err
+		}
+		f.hasCursor = true
+	case os.IsNotExist(err):
+		f.cursor, f.hasCursor = cursor{}, false
+	default:
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(f.dir, blocksFileName),
+		os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(f.cursor.Offset); err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	return nil
+}
+
+// Stop closes the blocks file.
+func (f *FileIndexer) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Cursor implements indexer.BlockIndexer.
+func (f *FileIndexer) Cursor() (uint64, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.cursor.Number, f.hasCursor, nil
+}
+
+// HandleBlock implements indexer.BlockIndexer.
+func (f *FileIndexer) HandleBlock(d *indexer.BlockData) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return errors.New("indexer is not started")
+	}
+	record := Record{
+		Number:       d.Block.NumberU64(),
+		Hash:         d.Block.Hash(),
+		ParentHash:   d.Block.ParentHash(),
+		Time:         d.Block.Time(),
+		Round:        d.Block.Round(),
+		Transactions: make([]common.Hash, 0, len(d.Block.Transactions())),
+		Receipts:     d.Receipts,
+		GovEvents:    d.GovEvents,
+	}
+	for _, tx := range d.Block.Transactions() {
+		record.Transactions = append(record.Transactions, tx.Hash())
+	}
+	line, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// Drop anything left by a failed write before appending.
+	if err := f.file.Truncate(f.cursor.Offset); err != nil {
+		return err
+	}
+	if _, err := f.file.WriteAt(line, f.cursor.Offset); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+
+	next := cursor{
+		Number: record.Number,
+		Offset: f.cursor.Offset + int64(len(line)),
+	}
+	if err := f.writeCursor(next); err != nil {
+		return err
+	}
+	f.cursor, f.hasCursor = next, true
+	return nil
+}
+
+func (f *FileIndexer) writeCursor(c cursor) error {
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(f.dir, cursorFileName)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadRecords reads all complete records from the blocks file in dir.
+func ReadRecords(dir string) ([]*Record, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, Name, cursorFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join(dir, Name, blocksFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(io.LimitReader(file, c.Offset))
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		record := new(Record)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,181 @@
+package fileindexer
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus/ethash"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/indexer"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+type testChain struct {
+	db    ethdb.Database
+	chain *core.BlockChain
+}
+
+func newTestChain(t *testing.T) *testChain {
+	db := ethdb.NewMemDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddress: {Balance: big.NewInt(params.Ether)}},
+	}
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(),
+		vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return &testChain{db: db, chain: chain}
+}
+
+// extend inserts n blocks with a transfer in each of them.
+func (c *testChain) extend(t *testing.T, n int) {
+	signer := types.MakeSigner(params.TestChainConfig, nil)
+	blocks, _ := core.GenerateChain(params.TestChainConfig,
+		c.chain.CurrentBlock(), ethash.NewFaker(), c.db, n,
+		func(i int, gen *core.BlockGen) {
+			tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(testAddress),
+				common.Address{1}, big.NewInt(1), params.TxGas, nil, nil), signer, testKey)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			gen.AddTx(tx)
+		})
+	if _, err := c.chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+}
+
+func waitCursor(t *testing.T, idx indexer.Indexer, number uint64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		n, ok, err := idx.(indexer.BlockIndexer).Cursor()
+		if err != nil {
+			t.Fatalf("failed to get cursor: %v", err)
+		}
+		if ok && n == number {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("indexer did not reach block %d", number)
+}
+
+func checkRecords(t *testing.T, dir string, chain *core.BlockChain) {
+	records, err := ReadRecords(dir)
+	if err != nil {
+		t.Fatalf("failed to read records: %v", err)
+	}
+	head := chain.CurrentBlock().NumberU64()
+	if uint64(len(records)) != head+1 {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), head+1)
+	}
+	for i, record := range records {
+		block := chain.GetBlockByNumber(uint64(i))
+		if record.Number != uint64(i) || record.Hash != block.Hash() {
+			t.Errorf("record %d mismatch: have %d %x, want %x",
+				i, record.Number, record.Hash, block.Hash())
+		}
+		if len(record.Transactions) != len(block.Transactions()) ||
+			len(record.Receipts) != len(block.Transactions()) {
+			t.Errorf("record %d has %d txs and %d receipts, want %d",
+				i, len(record.Transactions), len(record.Receipts),
+				len(block.Transactions()))
+		}
+	}
+}
+
+func TestFileIndexerResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileindexer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestChain(t)
+	defer c.chain.Stop()
+	c.extend(t, 3)
+
+	config := indexer.Config{Enable: true, Name: Name, DataDir: dir}
+	idx, err := indexer.NewIndexerFromConfig(indexer.NewROBlockChain(c.chain), config)
+	if err != nil {
+		t.Fatalf("failed to create indexer: %v", err)
+	}
+	if err := idx.Start(); err != nil {
+		t.Fatalf("failed to start indexer: %v", err)
+	}
+	waitCursor(t, idx, 3)
+
+	// Blocks inserted after start are followed.
+	c.extend(t, 2)
+	waitCursor(t, idx, 5)
+	if err := idx.Stop(); err != nil {
+		t.Fatalf("failed to stop indexer: %v", err)
+	}
+	checkRecords(t, dir, c.chain)
+
+	// Simulate a crash in the middle of writing a record.
+	f, err := os.OpenFile(filepath.Join(dir, Name, blocksFileName),
+		os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("failed to open blocks file: %v", err)
+	}
+	f.WriteString(`{"number":6,"hash":`)
+	f.Close()
+
+	// Restarted indexer resumes from the cursor.
+	c.extend(t, 2)
+	idx, err = indexer.NewIndexerFromConfig(indexer.NewROBlockChain(c.chain), config)
+	if err != nil {
+		t.Fatalf("failed to create indexer: %v", err)
+	}
+	if err := idx.Start(); err != nil {
+		t.Fatalf("failed to start indexer: %v", err)
+	}
+	waitCursor(t, idx, 7)
+	if err := idx.Stop(); err != nil {
+		t.Fatalf("failed to stop indexer: %v", err)
+	}
+	checkRecords(t, dir, c.chain)
+}
+
+func TestNewIndexerFromConfig(t *testing.T) {
+	c := newTestChain(t)
+	defer c.chain.Stop()
+	bc := indexer.NewROBlockChain(c.chain)
+
+	if _, err := indexer.NewIndexerFromConfig(bc, indexer.Config{Name: "none"}); err == nil {
+		t.Errorf("expect error for unregistered indexer")
+	}
+	if _, err := indexer.NewIndexerFromConfig(bc, indexer.Config{Name: Name}); err == nil {
+		t.Errorf("expect error without data directory")
+	}
+	if _, err := indexer.NewIndexerFromConfig(bc, indexer.Config{}); err == nil {
+		t.Errorf("expect error without name and plugin")
+	}
+	found := false
+	for _, name := range indexer.Indexers() {
+		if name == Name {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("indexer %q is not registered", Name)
+	}
+}
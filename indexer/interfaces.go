@@ -1,10 +1,18 @@
 package indexer
 
+import (
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
 // NewIndexerFuncName plugin looks up name.
 var NewIndexerFuncName = "NewIndexer"
 
 // NewIndexerFunc init function alias.
-type NewIndexerFunc = func(ReadOnlyBlockChain, Config) Indexer
+type NewIndexerFunc = func(ReadOnlyBlockChain, Config) (Indexer, error)
+
+// legacyNewIndexerFunc is the init function signature of plugins built before
+// NewIndexerFunc returned an error.
+type legacyNewIndexerFunc = func(ReadOnlyBlockChain, Config) Indexer
 
 // Indexer defines indexer daemon interface. The daemon would hold a
 // core.Blockhain, passed by initialization function, to receiving latest block
@@ -17,3 +25,30 @@ type Indexer interface {
 	// terminating.
 	Stop() error
 }
+
+// BlockIndexer is an indexer fed by a checkpointed stream of blocks. The
+// stream is started after Start and stopped before Stop.
+type BlockIndexer interface {
+	Indexer
+
+	// Cursor returns the number of the last block handled and persisted by
+	// the indexer, ok is false if no block is handled yet.
+	Cursor() (number uint64, ok bool, err error)
+
+	// HandleBlock is called sequentially with blocks starting from the one
+	// after the cursor. The indexer should persist the cursor along with its
+	// data, the block is delivered again if an error is returned.
+	HandleBlock(*BlockData) error
+}
+
+// BlockData is the data of a block delivered by the stream.
+type BlockData struct {
+	Block    *types.Block
+	Receipts types.Receipts
+
+	// Logs are all logs emitted in the block.
+	Logs []*types.Log
+
+	// GovEvents are logs emitted by the governance contract.
+	GovEvents []*types.Log
+}
@@ -0,0 +1,49 @@
+package indexer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]NewIndexerFunc)
+)
+
+// Register makes an indexer available by the provided name, it's usually
+// called in the init function of the package implementing the indexer.
+// Register panics if it's called twice with the same name or fn is nil.
+func Register(name string, fn NewIndexerFunc) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if fn == nil {
+		panic("indexer: Register function is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("indexer: Register called twice for %q", name))
+	}
+	registry[name] = fn
+}
+
+// Indexers returns the sorted names of registered indexers.
+func Indexers() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (NewIndexerFunc, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	fn, ok := registry[name]
+	return fn, ok
+}
@@ -0,0 +1,167 @@
+package indexer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+const (
+	// streamChainHeadChanSize is the size of channel listening to
+	// ChainHeadEvent.
+	streamChainHeadChanSize = 16
+
+	// streamRetryInterval is the interval to retry after failing to deliver
+	// a block.
+	streamRetryInterval = 3 * time.Second
+)
+
+var errStreamStopped = errors.New("stream stopped")
+
+// Stream delivers blocks to a BlockIndexer in order, starting from the block
+// after the indexer cursor and following the chain head.
+type Stream struct {
+	bc      ReadOnlyBlockChain
+	indexer BlockIndexer
+
+	next uint64 // number of the next block to deliver
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStream creates a stream feeding blocks of bc to indexer.
+func NewStream(bc ReadOnlyBlockChain, indexer BlockIndexer) *Stream {
+	return &Stream{
+		bc:      bc,
+		indexer: indexer,
+	}
+}
+
+// Start resumes the stream from the indexer cursor.
+func (s *Stream) Start() error {
+	number, ok, err := s.indexer.Cursor()
+	if err != nil {
+		return err
+	}
+	s.next = 0
+	if ok {
+		s.next = number + 1
+	}
+	s.quit = make(chan struct{})
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// Stop terminates the stream and waits for the block being delivered.
+func (s *Stream) Stop() {
+	if s.quit == nil {
+		return
+	}
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *Stream) loop() {
+	defer s.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, streamChainHeadChanSize)
+	sub := s.bc.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	for {
+		var retry <-chan time.Time
+		if err := s.catchUp(); err == errStreamStopped {
+			return
+		} else if err != nil {
+			log.Error("Failed to deliver block to indexer", "number", s.next,
+				"err", err)
+			retry = time.After(streamRetryInterval)
+		}
+
+		select {
+		case <-headCh:
+		case <-retry:
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// catchUp delivers blocks up to the current head.
+func (s *Stream) catchUp() error {
+	head := s.bc.CurrentBlock().NumberU64()
+	for ; s.next <= head; s.next++ {
+		select {
+		case <-s.quit:
+			return errStreamStopped
+		default:
+		}
+
+		data, err := s.blockData(s.next)
+		if err != nil {
+			return err
+		}
+		if err := s.indexer.HandleBlock(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stream) blockData(number uint64) (*BlockData, error) {
+	block := s.bc.GetBlockByNumber(number)
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	receipts := s.bc.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, errors.New("receipts not found")
+	}
+
+	data := &BlockData{
+		Block:    block,
+		Receipts: receipts,
+	}
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			data.Logs = append(data.Logs, l)
+			if l.Address == vm.GovernanceContractAddress {
+				data.GovEvents = append(data.GovEvents, l)
+			}
+		}
+	}
+	return data, nil
+}
+
+// streamIndexer runs a BlockIndexer along with its stream.
+type streamIndexer struct {
+	BlockIndexer
+	stream *Stream
+}
+
+func newStreamIndexer(bc ReadOnlyBlockChain, idx BlockIndexer) *streamIndexer {
+	return &streamIndexer{
+		BlockIndexer: idx,
+		stream:       NewStream(bc, idx),
+	}
+}
+
+func (i *streamIndexer) Start() error {
+	if err := i.BlockIndexer.Start(); err != nil {
+		return err
+	}
+	return i.stream.Start()
+}
+
+func (i *streamIndexer) Stop() error {
+	i.stream.Stop()
+	return i.BlockIndexer.Stop()
+}
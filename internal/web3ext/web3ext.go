@@ -24,6 +24,8 @@ var Modules = map[string]string{
 	"clique":     Clique_JS,
 	"ethash":     Ethash_JS,
 	"debug":      Debug_JS,
+	"dex":        Dex_JS,
+	"dexcon":     Dexcon_JS,
 	"eth":        Eth_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
@@ -207,6 +209,11 @@ web3._extend({
 			name: 'stopProposing',
 			call: 'admin_stopProposing'
 		}),
+		new web3._extend.Method({
+			name: 'unban',
+			call: 'admin_unban',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -229,6 +236,10 @@ web3._extend({
 			name: 'isProposing',
 			getter: 'admin_isProposing'
 		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
 	]
 });
 `
@@ -464,6 +475,90 @@ web3._extend({
 });
 `
 
+const Dex_JS = `
+web3._extend({
+	property: 'dex',
+	methods: [
+		new web3._extend.Method({
+			name: 'getGovernanceEvents',
+			call: 'dex_getGovernanceEvents',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+	]
+});
+`
+
+const Dexcon_JS = `
+web3._extend({
+	property: 'dexcon',
+	methods: [
+		new web3._extend.Method({
+			name: 'getRoundHeight',
+			call: 'dexcon_getRoundHeight',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getNotarySet',
+			call: 'dexcon_getNotarySet',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getCRS',
+			call: 'dexcon_getCRS',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getDKGStatus',
+			call: 'dexcon_getDKGStatus',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getDKGResetCount',
+			call: 'dexcon_getDKGResetCount',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getRoundMeta',
+			call: 'dexcon_getRoundMeta',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getRoundStats',
+			call: 'dexcon_getRoundStats',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getDexconMetaByNumber',
+			call: 'dexcon_getDexconMetaByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getDexconMetaByHash',
+			call: 'dexcon_getDexconMetaByHash',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'round',
+			getter: 'dexcon_round',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+	]
+});
+`
+
 const Eth_JS = `
 web3._extend({
 	property: 'eth',
@@ -19,19 +19,27 @@ package light
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
 	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/consensus"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/event"
 	"github.com/dexon-foundation/dexon/log"
@@ -44,6 +52,11 @@ var (
 	blockCacheLimit = 256
 )
 
+var (
+	errNotSupported      = errors.New("not supported by light chain")
+	errInvalidRandomness = errors.New("invalid block randomness")
+)
+
 // LightChain represents a canonical chain that by default only handles block
 // headers, downloading block bodies and receipts on demand through an ODR
 // interface. It only does header validation during chain insertion.
@@ -394,6 +407,59 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 	return i, err
 }
 
+// InsertDexonHeaderChain attempts to insert the given header chain and the
+// governance states attached in to the local chain. Besides the checks done
+// by the header chain, the randomness of each header is verified to be the
+// threshold signature of the DKG set of its round, the DKG group public keys
+// are built from the governance states of gov.
+func (self *LightChain) InsertDexonHeaderChain(chain []*types.HeaderWithGovState,
+	gov dexcon.GovernanceStateFetcher, verifierCache *dexCore.TSigVerifierCache) (int, error) {
+	start := time.Now()
+	// The randomness is verified in ascending order of the rounds before the
+	// header chain validation, which fills the verifier cache from the last
+	// header and would purge the earlier rounds of the chain.
+	for i, header := range chain {
+		if err := verifyRandomness(header.Header, verifierCache); err != nil {
+			log.Debug("Invalid header randomness", "number", header.Number,
+				"round", header.Round, "err", err)
+			return i, err
+		}
+	}
+	if i, err := self.hc.ValidateDexonHeaderChain(chain, gov, verifierCache,
+		&witnessValidator{self.hc}); err != nil {
+		return i, err
+	}
+
+	// Make sure only one thread manipulates the chain at once
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+
+	self.wg.Add(1)
+	defer self.wg.Done()
+
+	var events []interface{}
+	whFunc := func(header *types.HeaderWithGovState) error {
+		self.mu.Lock()
+		defer self.mu.Unlock()
+
+		status, err := self.hc.WriteDexonHeader(header)
+
+		switch status {
+		case core.CanonStatTy:
+			log.Debug("Inserted new header", "number", header.Number, "hash", header.Hash())
+			events = append(events, core.ChainEvent{Block: types.NewBlockWithHeader(header.Header), Hash: header.Hash()})
+
+		case core.SideStatTy:
+			log.Debug("Inserted forked header", "number", header.Number, "hash", header.Hash())
+			events = append(events, core.ChainSideEvent{Block: types.NewBlockWithHeader(header.Header)})
+		}
+		return err
+	}
+	i, err := self.hc.InsertDexonHeaderChain(chain, whFunc, start)
+	self.postChainEvents(events)
+	return i, err
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internal cache.
 func (self *LightChain) CurrentHeader() *types.Header {
@@ -540,7 +606,95 @@ func (self *LightChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return nil, nil
 }
 
-// GetRoundHeight returns the height of a given round.
+// GetRoundHeight returns the height of a given round. Rounds never decrease
+// along the canonical chain, the height is the first canonical header of the
+// round.
 func (self *LightChain) GetRoundHeight(round uint64) (uint64, bool) {
-	return 0, false
+	head := self.CurrentHeader()
+	if head.Round < round {
+		return 0, false
+	}
+	number := sort.Search(int(head.Number.Uint64()), func(i int) bool {
+		header := self.GetHeaderByNumber(uint64(i))
+		return header == nil || header.Round >= round
+	})
+	return uint64(number), true
+}
+
+// GetGovStateByNumber returns the governance state of the canonical header
+// of the given number. Only the governance states come with the headers and
+// the ones of the local states, i.e. the genesis, are available.
+func (self *LightChain) GetGovStateByNumber(number uint64) (*types.GovState, error) {
+	header := self.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("header not found")
+	}
+	if govState := rawdb.ReadGovState(self.chainDb, header.Hash()); govState != nil {
+		return govState, nil
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(self.chainDb))
+	if err != nil {
+		return nil, err
+	}
+	return state.GetGovState(statedb, header, vm.GovernanceContractAddress)
+}
+
+// witnessValidator validates the witness of the headers against the local
+// header chain, the light chain has no block or state to validate.
+type witnessValidator struct {
+	hc *core.HeaderChain
+}
+
+func (v *witnessValidator) ValidateBody(block *types.Block) error {
+	return errNotSupported
+}
+
+func (v *witnessValidator) ValidateState(block, parent *types.Block,
+	state *state.StateDB, receipts types.Receipts, usedGas uint64) error {
+	return errNotSupported
+}
+
+func (v *witnessValidator) ValidateWitnessData(height uint64, hash common.Hash) error {
+	header := v.hc.GetHeaderByNumber(height)
+	if header == nil || header.Hash() != hash {
+		return consensus.ErrWitnessMismatch
+	}
+	return nil
+}
+
+// verifyRandomness verifies the randomness of the header is the threshold
+// signature of the consensus block it carries. Blocks of the rounds without
+// DKG carry no randomness.
+func verifyRandomness(header *types.Header,
+	verifierCache *dexCore.TSigVerifierCache) error {
+	if header.Round < dexCore.DKGDelayRound {
+		return nil
+	}
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+		return err
+	}
+	// The signed hash must be the one of the block content, otherwise the
+	// randomness of another block could be replayed.
+	hash, err := coreUtils.HashBlock(&coreBlock)
+	if err != nil {
+		return err
+	}
+	if hash != coreBlock.Hash {
+		return errInvalidRandomness
+	}
+	v, ok, err := verifierCache.UpdateAndGet(header.Round)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("DKG of round %d is not final", header.Round)
+	}
+	if !v.VerifySignature(coreBlock.Hash, coreCrypto.Signature{
+		Type:      "bls",
+		Signature: header.Randomness,
+	}) {
+		return errInvalidRandomness
+	}
+	return nil
 }
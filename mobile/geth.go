@@ -25,12 +25,12 @@ import (
 	"path/filepath"
 
 	"github.com/dexon-foundation/dexon/core"
-	"github.com/dexon-foundation/dexon/eth"
-	"github.com/dexon-foundation/dexon/eth/downloader"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/dex/les"
 	"github.com/dexon-foundation/dexon/ethclient"
 	"github.com/dexon-foundation/dexon/ethstats"
 	"github.com/dexon-foundation/dexon/internal/debug"
-	"github.com/dexon-foundation/dexon/les"
 	"github.com/dexon-foundation/dexon/node"
 	"github.com/dexon-foundation/dexon/p2p"
 	"github.com/dexon-foundation/dexon/p2p/nat"
@@ -153,22 +153,22 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 			}
 		}
 	}
-	// Register the Ethereum protocol if requested
+	// Register the DEXON light client if requested
 	if config.EthereumEnabled {
-		ethConf := eth.DefaultConfig
-		ethConf.Genesis = genesis
-		ethConf.SyncMode = downloader.LightSync
-		ethConf.NetworkId = uint64(config.EthereumNetworkID)
-		ethConf.DatabaseCache = config.EthereumDatabaseCache
+		dexConf := dex.DefaultConfig
+		dexConf.Genesis = genesis
+		dexConf.SyncMode = downloader.LightSync
+		dexConf.NetworkId = uint64(config.EthereumNetworkID)
+		dexConf.DatabaseCache = config.EthereumDatabaseCache
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, &ethConf)
+			return les.New(ctx, &dexConf)
 		}); err != nil {
 			return nil, fmt.Errorf("ethereum init: %v", err)
 		}
 		// If netstats reporting is requested, do it
 		if config.EthereumNetStats != "" {
 			if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-				var lesServ *les.LightEthereum
+				var lesServ *les.LightDexon
 				ctx.Service(&lesServ)
 
 				return ethstats.New(config.EthereumNetStats, nil, lesServ)